@@ -0,0 +1,112 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Checks configurations in process. Checkers should give up when the context is done
+type Checker interface {
+	Check(ctx context.Context, request model.CheckRequest) (model.CheckResponse, error)
+}
+
+// Adapts a function to the Checker interface
+type CheckerFunc func(ctx context.Context, request model.CheckRequest) (model.CheckResponse, error)
+
+func (f CheckerFunc) Check(ctx context.Context, request model.CheckRequest) (model.CheckResponse, error) {
+	return f(ctx, request)
+}
+
+// Checks configurations through the webhook declared by a definition
+type Webhook struct {
+	URL string
+	HTTP *http.Client
+}
+
+func (w Webhook) Check(ctx context.Context, request model.CheckRequest) (model.CheckResponse, error) {
+
+	var response model.CheckResponse
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return response, fmt.Errorf("Error encoding check request: %v", err)
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return response, fmt.Errorf("Error creating check request: %v", err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Accept", "application/json")
+
+	client := w.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return response, fmt.Errorf("Error calling check hook: %v", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("Check hook answered with status %d", httpResponse.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(httpResponse.Body, 1 << 20))
+	if err != nil {
+		return response, fmt.Errorf("Error reading check response: %v", err)
+	}
+	err = json.Unmarshal(data, &response)
+	if err != nil {
+		return response, fmt.Errorf("Invalid check response: %v", err)
+	}
+
+	return response, nil
+}
+
+// Runs the checker within the timeout. Checkers that fail, time out or answer something invalid
+// give an "error" result. Checkers that ignore the context are abandoned when it is done.
+func Run(checker Checker, request model.CheckRequest, timeout time.Duration) model.CheckResult {
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type outcome struct {
+		response model.CheckResponse
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		response, err := checker.Check(ctx, request)
+		done <- outcome{ response, err }
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-ctx.Done():
+		o = outcome{ err: ctx.Err() }
+	}
+
+	result := model.CheckResult{ Status: o.response.Status, Message: o.response.Message, CheckedAt: start, DurationMS: time.Since(start).Milliseconds() }
+	switch {
+	case errors.Is(o.err, context.DeadlineExceeded):
+		result.Status, result.Message = "error", fmt.Sprintf("Check timed out after %v", timeout)
+	case o.err != nil:
+		result.Status, result.Message = "error", o.err.Error()
+	default:
+		err := o.response.Validate()
+		if err != nil {
+			result.Status, result.Message = "error", fmt.Sprintf("Invalid check response: %v", err)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,91 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"smartgrowth-connectors/configapi/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request model.CheckRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch request.Configuration["password"] {
+		case "hunter2":
+			json.NewEncoder(w).Encode(model.CheckResponse{ Status: "succeeded" })
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+			json.NewEncoder(w).Encode(model.CheckResponse{ Status: "succeeded" })
+		case "invalid":
+			json.NewEncoder(w).Encode(model.CheckResponse{ Status: "maybe" })
+		case "crash":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			json.NewEncoder(w).Encode(model.CheckResponse{ Status: "failed", Message: "Authentication failed" })
+		}
+	}))
+	defer server.Close()
+
+	webhook := Webhook{ URL: server.URL, HTTP: server.Client() }
+	tests := []struct {
+		password string
+		status string
+		message string
+	}{
+		{ "hunter2", "succeeded", "" },
+		{ "wrong", "failed", "Authentication failed" },
+		{ "slow", "error", "timed out" },
+		{ "invalid", "error", "Invalid check response" },
+		{ "crash", "error", "status 500" },
+	}
+	for _, test := range tests {
+		request := model.CheckRequest{ Configuration: model.IntegrationConfig{ "password": test.password } }
+		result := Run(webhook, request, 50 * time.Millisecond)
+		if result.Status != test.status || !strings.Contains(result.Message, test.message) {
+			t.Errorf("Expected %s result with message %q for password %s, got %+v", test.status, test.message, test.password, result)
+		}
+	}
+}
+
+func TestRunInProcess(t *testing.T) {
+
+	failing := CheckerFunc(func(ctx context.Context, request model.CheckRequest) (model.CheckResponse, error) {
+		return model.CheckResponse{}, errors.New("Driver not installed")
+	})
+	result := Run(failing, model.CheckRequest{}, time.Second)
+	if result.Status != "error" || result.Message != "Driver not installed" {
+		t.Errorf("Expected checker errors to give an error result, got %+v", result)
+	}
+
+	// Checkers ignoring the context are abandoned
+	release := make(chan bool)
+	defer close(release)
+	stuck := CheckerFunc(func(ctx context.Context, request model.CheckRequest) (model.CheckResponse, error) {
+		<-release
+		return model.CheckResponse{ Status: "succeeded" }, nil
+	})
+	start := time.Now()
+	result = Run(stuck, model.CheckRequest{}, 20 * time.Millisecond)
+	if result.Status != "error" || time.Since(start) > time.Second {
+		t.Errorf("Expected stuck checker to time out, got %+v after %v", result, time.Since(start))
+	}
+
+	succeeding := CheckerFunc(func(ctx context.Context, request model.CheckRequest) (model.CheckResponse, error) {
+		return model.CheckResponse{ Status: "succeeded" }, nil
+	})
+	result = Run(succeeding, model.CheckRequest{}, time.Second)
+	if result.Status != "succeeded" || result.CheckedAt.IsZero() {
+		t.Errorf("Expected succeeded result, got %+v", result)
+	}
+}
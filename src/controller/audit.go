@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+)
+
+// Lists the audit log of the workspace, most recent first. Only for workspace admins
+func (ctr *Controller) ListAuditEntries(workspaceID string, offset int, limit int) ([]model.AuditEntry, error) {
+
+	var entries []model.AuditEntry
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return entries, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) && ctr.User.AppRole != "Super Admin" {
+		return entries, errors.New("User does not have permission to view the audit log of this workspace")
+	}
+
+	entries, err = ctr.db.ListAuditEntries(workspace.ID, offset, limit)
+	if err != nil {
+		return entries, fmt.Errorf("Error reading audit entries from database: %v", err)
+	}
+
+	return entries, nil
+}
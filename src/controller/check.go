@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/check"
+	"smartgrowth-connectors/configapi/model"
+	"reflect"
+	"time"
+)
+
+// Checks the saved configuration of an integration and records the result on it
+func (ctr *Controller) CheckIntegration(workspaceID string, id string) (model.CheckResult, error) {
+
+	var result model.CheckResult
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to check integrations in this workspace")
+	}
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	config, err := ctr.resolveVariables(integration, integration.Configuration, nil, "secret.check", time.Now())
+	if err != nil {
+		return result, err
+	}
+	request := model.NewCheckRequest(definition, integration.ID, config)
+
+	// Checkers receive the secrets
+	entries := []model.AuditEntry{}
+	for _, path := range request.Configuration.SecretPaths(definition.ConfigurationSchema) {
+		entries = append(entries, model.NewAuditEntry(workspace.ID, "secret.check", "integration", integration.ID, path, ctr.User.ID, time.Now()))
+	}
+	if len(entries) > 0 {
+		_, err = ctr.db.InsertAuditEntries(entries)
+		if err != nil {
+			return result, fmt.Errorf("Error inserting audit entries into database: %v", err)
+		}
+	}
+
+	result, err = ctr.runCheck(definition, request)
+	if err != nil {
+		return result, err
+	}
+
+	// The integration may have changed during the check. The result of an outdated configuration isn't recorded
+	current, err := ctr.db.GetIntegrationByID(integration.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integration from database: %v", err)
+	}
+	if current.DefinitionVersion != integration.DefinitionVersion || !reflect.DeepEqual(current.Configuration, integration.Configuration) {
+		return result, nil
+	}
+	integration = current
+	integration.LastCheck = &result
+	_, err = ctr.db.UpdateIntegration(integration)
+	if err != nil {
+		return result, fmt.Errorf("Error updating integration in database: %v", err)
+	}
+
+	return result, nil
+}
+
+// Checks a configuration that is not saved yet, against the version new integrations of the definition get
+func (ctr *Controller) CheckConfiguration(workspaceID string, definitionID string, config model.IntegrationConfig) (model.CheckResult, error) {
+
+	var result model.CheckResult
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to check integrations in this workspace")
+	}
+
+	versions, err := ctr.db.ListIntegrationDefinitionVersions(definitionID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+	definition, err := model.LatestPublishedVersion(versions)
+	if err != nil {
+		return result, fmt.Errorf("Can't check configuration: %v", err)
+	}
+	if !definition.Scope.Includes(workspace) {
+		return result, fmt.Errorf("Can't check configuration: definition %s is private to another %s", definitionID, definition.Scope.Visibility)
+	}
+
+	// Invalid configurations are not worth dispatching
+	config = config.Normalize(definition.ConfigurationSchema)
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	err = config.ValidateWithVariables(definition.ConfigurationSchema, variables)
+	if err != nil {
+		return result, fmt.Errorf("Invalid configuration: %v", err)
+	}
+	config, err = ctr.resolveVariables(model.Integration{ WorkspaceID: workspace.ID }, config, nil, "secret.check", time.Now())
+	if err != nil {
+		return result, err
+	}
+
+	return ctr.runCheck(definition, model.NewCheckRequest(definition, "", config))
+}
+
+// Dispatches the request to the in process checker registered for the definition, or to its check hook.
+// Only public definitions, managed by Super Admins, get in process checkers: anyone managing a private
+// definition could give it the name of one with a checker
+func (ctr *Controller) runCheck(definition model.IntegrationDefinition, request model.CheckRequest) (model.CheckResult, error) {
+
+	var result model.CheckResult
+
+	var checker check.Checker
+	ok := false
+	if definition.Scope.Public() {
+		checker, ok = ctr.Options.Checkers[definition.Name]
+	}
+	timeout := model.DefaultCheckTimeout
+	if definition.Check != nil {
+		timeout = definition.Check.Timeout()
+		if !ok {
+			checker = check.Webhook{ URL: definition.Check.URL }
+			ok = true
+		}
+	}
+	if !ok {
+		return result, fmt.Errorf("Definition %s version %s has no checker", definition.Name, definition.Version)
+	}
+
+	return check.Run(checker, request, timeout), nil
+}
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+func (ctr *Controller) CreateConnection(workspaceID string, name string, sourceID string, destinationID string, schedule model.Schedule, namespace model.NamespaceMapping, enabled bool) (model.Connection, error) {
+
+	var connection model.Connection
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return connection, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return connection, errors.New("User does not have permission to create connections in this workspace")
+	}
+
+	source, destination, err := ctr.connectionEnds(sourceID, destinationID)
+	if err != nil {
+		return connection, err
+	}
+
+	connection, err = model.NewConnection(name, workspace.ID, source, destination, schedule, namespace, enabled, time.Now())
+	if err != nil {
+		return connection, fmt.Errorf("Error creating connection: %v", err)
+	}
+
+	event := model.NewConnectionEvent(model.EventConnectionCreated, connection, ctr.principalID(), time.Now())
+	connection, err = ctr.db.InsertConnection(connection, event)
+	if err != nil {
+		return connection, fmt.Errorf("Error inserting connection into database: %v", err)
+	}
+	ctr.publish(event.WithResourceID(connection.ID))
+
+	return connection, nil
+}
+
+func (ctr *Controller) ListConnections(workspaceID string) ([]model.Connection, error) {
+
+	var connections []model.Connection
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return connections, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return connections, errors.New("User does not have permission to view workspace")
+	}
+
+	connections, err = ctr.db.ListConnectionsForWorkspace(workspace.ID)
+	if err != nil {
+		return connections, fmt.Errorf("Error reading connections from database: %v", err)
+	}
+
+	return connections, nil
+}
+
+func (ctr *Controller) ReadConnection(workspaceID string, id string) (model.Connection, error) {
+
+	var result model.Connection
+
+	workspace, connection, err := ctr.getWorkspaceConnection(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to view workspace")
+	}
+
+	return connection, nil
+}
+
+// The ends of a connection can't be changed. A new connection should be created instead
+func (ctr *Controller) UpdateConnection(workspaceID string, id string, name string, schedule model.Schedule, namespace model.NamespaceMapping, enabled bool) (model.Connection, error) {
+
+	var result model.Connection
+
+	workspace, connection, err := ctr.getWorkspaceConnection(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to edit connections in this workspace")
+	}
+
+	source, destination, err := ctr.connectionEnds(connection.SourceID, connection.DestinationID)
+	if err != nil {
+		return result, err
+	}
+
+	connection.Name = name
+	connection.Schedule = schedule
+	connection.Namespace = namespace
+	connection.Enabled = enabled
+	err = connection.Validate(source, destination, time.Now())
+	if err != nil {
+		return result, fmt.Errorf("Invalid connection: %v", err)
+	}
+
+	event := model.NewConnectionEvent(model.EventConnectionUpdated, connection, ctr.principalID(), time.Now())
+	connection, err = ctr.db.UpdateConnection(connection, event)
+	if err != nil {
+		return connection, fmt.Errorf("Error updating connection in database: %v", err)
+	}
+	ctr.publish(event)
+
+	return connection, nil
+}
+
+func (ctr *Controller) DeleteConnection(workspaceID string, id string) (model.Connection, error) {
+
+	var result model.Connection
+
+	workspace, connection, err := ctr.getWorkspaceConnection(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to delete connections in this workspace")
+	}
+
+	event := model.NewConnectionEvent(model.EventConnectionDeleted, connection, ctr.principalID(), time.Now())
+	deleted, err := ctr.db.DeleteConnectionByID(id, event)
+	if err != nil {
+		return deleted, fmt.Errorf("Error deleting connection from database: %v", err)
+	}
+	ctr.publish(event)
+
+	return deleted, nil
+}
+
+// Reads both ends of a connection with their definitions attached, so their types can be checked
+func (ctr *Controller) connectionEnds(sourceID string, destinationID string) (model.Integration, model.Integration, error) {
+
+	var source, destination model.Integration
+
+	source, err := ctr.db.GetIntegrationByID(sourceID)
+	if err != nil {
+		return source, destination, fmt.Errorf("Error reading source from database: %v", err)
+	}
+	destination, err = ctr.db.GetIntegrationByID(destinationID)
+	if err != nil {
+		return source, destination, fmt.Errorf("Error reading destination from database: %v", err)
+	}
+
+	return ctr.withDefinition(source), ctr.withDefinition(destination), nil
+}
+
+// Reads a connection, making sure it belongs to the workspace
+func (ctr *Controller) getWorkspaceConnection(workspaceID string, id string) (model.Workspace, model.Connection, error) {
+
+	var connection model.Connection
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return workspace, connection, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+
+	connection, err = ctr.db.GetConnectionByID(id)
+	if err != nil {
+		return workspace, connection, fmt.Errorf("Error reading connection from database: %v", err)
+	}
+	if connection.WorkspaceID != workspace.ID {
+		return workspace, connection, fmt.Errorf("Connection %s not found in workspace %s", id, workspaceID)
+	}
+
+	return workspace, connection, nil
+}
@@ -2,17 +2,67 @@ package controller
 
 import (
 	"fmt"
+	"smartgrowth-connectors/configapi/check"
 	"smartgrowth-connectors/configapi/database"
+	"smartgrowth-connectors/configapi/eventbus"
+	"smartgrowth-connectors/configapi/mailer"
 	"smartgrowth-connectors/configapi/model"
+	"smartgrowth-connectors/configapi/oauth"
+	"smartgrowth-connectors/configapi/webhook"
+	"time"
 )
 
 type Controller struct {
-	db database.Database
+	db database.Database // Restricted to the organizations of the user, see database.ForTenant
 	User *model.User
+	Scopes []string // Scopes granted to the token of the request
+	Options Options
+	root database.Database // Unrestricted. Only for flows authorized otherwise, e.g. by invitation tokens
 }
 
+// Deployment settings, shared by the controllers of every user
+type Options struct {
+	StateSizeLimit int // Maximum size in bytes of a connector state. 0 means no limit
+	RuntimeBundleKey []byte // Signs runtime config bundles. Bundles are disabled without a key
+	RuntimeBundleEncryptionKey []byte // Encrypts runtime config bundles, model.RuntimeBundleEncryptionKeySize bytes. Bundles are disabled without it
+	OAuthRedirectURL string // Public URL of the OAuth callback endpoint. OAuth is disabled without one
+	OAuthClients func(name string) (model.OAuthClient, bool) // Looks up the client credentials referenced by definitions
+	OAuth *oauth.Client // Defaults to a client with a 30 seconds timeout
+	Checkers map[string]check.Checker // In process checkers, by name of public definition. They take precedence over check hooks
+	Webhooks *webhook.Client // Defaults to a client with a 10 seconds timeout. Its Insecure option also applies to the validation of subscriptions
+	Events *eventbus.Bus // Live event streams of the workspaces. Disabled without a bus
+	Mailer mailer.Mailer // Sends invitations. Invitations are disabled without a mailer
+	InvitationKey []byte // Signs invitation tokens. Invitations are disabled without a key
+	InvitationURL string // Page accepting invitations, receiving the token in its query. Only the token is sent without one
+	InvitationTTL time.Duration // Defaults to model.DefaultInvitationTTL
+	TransferTTL time.Duration // Time new owners have to accept ownership transfers. Defaults to model.DefaultTransferTTL
+	TrashRetention time.Duration // Time deleted workspaces can be restored before they are purged. Defaults to model.DefaultTrashRetention
+}
+
+// Controllers of users are restricted to the organizations of the user. Super Admins, Client Apps and
+// Runtimes work across organizations, like the root controller, without user
 func NewController(db database.Database, user *model.User) (*Controller, error) {
-	return &Controller{db, user}, nil
+
+	ctr := &Controller{db, user, nil, Options{}, db}
+	if user == nil {
+		return ctr, nil
+	}
+	switch user.AppRole {
+	case "Super Admin", "Client App", "Runtime":
+		return ctr, nil
+	}
+
+	organizations, err := db.ListOrganizationsForMember(user.Principal())
+	if err != nil {
+		return ctr, fmt.Errorf("Error reading organizations of user from database: %v", err)
+	}
+	ids := []string{}
+	for _, organization := range organizations {
+		ids = append(ids, organization.ID)
+	}
+	ctr.db = database.ForTenant(db, user.ID, ids)
+
+	return ctr, nil
 }
 
 func (ctr *Controller) AsUser(sub string) (*Controller, error) {
@@ -21,15 +71,88 @@ func (ctr *Controller) AsUser(sub string) (*Controller, error) {
 	var newCtr *Controller 
 
 	// Fetch user from database
-	user, err := ctr.db.GetUserBySub(sub)
+	user, err := ctr.root.GetUserBySub(sub)
 	if err != nil {
 		return newCtr, fmt.Errorf("Error fetching user with sub %s from db: %v", sub, err)
 	}
 
-	newCtr, err = NewController(ctr.db, &user)
+	newCtr, err = NewController(ctr.root, &user)
 	if err != nil {
 		return newCtr, fmt.Errorf("Error creating user controller: %v", err)
 	}
+	newCtr.Options = ctr.Options
 
 	return newCtr, nil
 }
+
+// ID of the user behind the controller. Empty for the root controller, e.g. in background jobs
+func (ctr *Controller) principalID() string {
+	if ctr.User == nil {
+		return ""
+	}
+	return ctr.User.ID
+}
+
+// Principal ID of the user, which permissions reference. Empty without a user
+func (ctr *Controller) principal() string {
+	if ctr.User == nil {
+		return ""
+	}
+	return ctr.User.Principal()
+}
+
+// Principal IDs the user is granted access through
+func (ctr *Controller) principals() []string {
+	if ctr.User == nil {
+		return []string{}
+	}
+	return ctr.principalsOf(*ctr.User)
+}
+
+// Principal IDs of the user, of their groups and of their roles in their organizations and in the app,
+// the user's first. Groups and organizations that can't be read grant nothing
+func (ctr *Controller) principalsOf(user model.User) []string {
+
+	principal := user.Principal()
+	if principal == "" {
+		return []string{}
+	}
+
+	principals := []string{ principal }
+	groups, err := ctr.db.ListGroupsForMember(principal)
+	if err == nil {
+		for _, group := range groups {
+			principals = append(principals, group.Principal())
+		}
+	}
+	organizations, err := ctr.db.ListOrganizationsForMember(principal)
+	if err == nil {
+		for _, organization := range organizations {
+			principals = append(principals, model.OrganizationPrincipal(organization.ID, organization.RoleOf(principal)))
+		}
+	}
+	if user.AppRole == "Super Admin" {
+		principals = append(principals, model.SuperAdminPrincipal)
+	}
+
+	return principals
+}
+
+// Publishes the events of committed changes to the live event streams
+func (ctr *Controller) publish(events ...model.Event) {
+	if ctr.Options.Events == nil {
+		return
+	}
+	for _, event := range events {
+		ctr.Options.Events.Publish(event)
+	}
+}
+
+func (ctr *Controller) HasScope(scope string) bool {
+	for _, s := range ctr.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
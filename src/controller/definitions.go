@@ -0,0 +1,418 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Public definitions are managed by Super Admins. Private definitions, scoped to an organization or a
+// workspace, are managed by the admins of the organization or the owners of the workspace. They can't use
+// OAuth nor check hooks, which handle the credentials of the deployment and the secrets of configurations
+func (ctr *Controller) CreateIntegrationDefinition(name string, t string, schema model.ConfigurationSchema, streams []model.Stream, provider *model.OAuthProvider, hook *model.CheckHook, scope model.DefinitionScope) (model.IntegrationDefinition, error) {
+
+	var definition model.IntegrationDefinition
+
+	// Authorization
+	err := ctr.canManageDefinitions(scope)
+	if err != nil {
+		return definition, err
+	}
+
+	definition, err = model.NewIntegrationDefinition(name, t, schema)
+	if err != nil {
+		return definition, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+	definition, err = definition.WithScope(scope)
+	if err != nil {
+		return definition, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+	definition, err = definition.WithStreams(streams)
+	if err != nil {
+		return definition, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+	definition, err = definition.WithOAuth(provider)
+	if err != nil {
+		return definition, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+	definition, err = definition.WithCheck(hook)
+	if err != nil {
+		return definition, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+
+	definition, err = ctr.db.InsertIntegrationDefinition(definition)
+	if err != nil {
+		return definition, fmt.Errorf("Error inserting integration definition into database: %v", err)
+	}
+
+	return definition, nil
+}
+
+// Creates a draft definition from an Airbyte connector spec.
+// Returns the keywords of the spec that couldn't be mapped as warnings.
+func (ctr *Controller) ImportAirbyteDefinition(name string, t string, data []byte, scope model.DefinitionScope) (model.IntegrationDefinition, []string, error) {
+
+	var definition model.IntegrationDefinition
+
+	// Authorization
+	err := ctr.canManageDefinitions(scope)
+	if err != nil {
+		return definition, nil, err
+	}
+
+	spec, err := model.ParseAirbyteSpec(data)
+	if err != nil {
+		return definition, nil, err
+	}
+
+	definition, warnings, err := spec.Definition(name, t)
+	if err != nil {
+		return definition, warnings, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+	definition, err = definition.WithScope(scope)
+	if err != nil {
+		return definition, warnings, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+
+	definition, err = ctr.db.InsertIntegrationDefinition(definition)
+	if err != nil {
+		return definition, warnings, fmt.Errorf("Error inserting integration definition into database: %v", err)
+	}
+
+	return definition, warnings, nil
+}
+
+// The stream catalog, the OAuth provider and the check hook of the latest version are kept when streams, provider or hook are nil
+func (ctr *Controller) CreateIntegrationDefinitionVersion(id string, version string, schema model.ConfigurationSchema, migrations []model.MigrationStep, streams []model.Stream, provider *model.OAuthProvider, hook *model.CheckHook) (model.IntegrationDefinition, error) {
+
+	var definition model.IntegrationDefinition
+
+	// New versions are created on top of the latest one
+	latest, err := ctr.db.GetIntegrationDefinition(id, "")
+	if err != nil {
+		return definition, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	// Authorization
+	err = ctr.canManageDefinitions(latest.Scope)
+	if err != nil {
+		return definition, err
+	}
+
+	definition, err = latest.NewVersion(version, schema, migrations)
+	if err != nil {
+		return definition, fmt.Errorf("Error creating integration definition version: %v", err)
+	}
+	if streams != nil {
+		definition, err = definition.WithStreams(streams)
+		if err != nil {
+			return definition, fmt.Errorf("Error creating integration definition version: %v", err)
+		}
+	}
+	if provider != nil {
+		definition, err = definition.WithOAuth(provider)
+		if err != nil {
+			return definition, fmt.Errorf("Error creating integration definition version: %v", err)
+		}
+	}
+	if hook != nil {
+		definition, err = definition.WithCheck(hook)
+		if err != nil {
+			return definition, fmt.Errorf("Error creating integration definition version: %v", err)
+		}
+	}
+
+	definition, err = ctr.db.InsertIntegrationDefinitionVersion(definition)
+	if err != nil {
+		return definition, fmt.Errorf("Error inserting integration definition version into database: %v", err)
+	}
+
+	return definition, nil
+}
+
+// Any user can browse the public catalog, along with the private definitions of their organizations and
+// of the workspaces they view. With a workspace, only the definitions its integrations can use are listed
+func (ctr *Controller) ListIntegrationDefinitions(workspaceID string) ([]model.IntegrationDefinition, error) {
+
+	results := []model.IntegrationDefinition{}
+
+	var workspace *model.Workspace
+	if workspaceID != "" {
+		w, err := ctr.db.GetWorkspaceByID(workspaceID)
+		if err != nil {
+			return results, fmt.Errorf("Error reading workspace from database: %v", err)
+		}
+		if !w.ViewableBy(ctr.principals()...) {
+			return results, errors.New("User does not have permission to view workspace")
+		}
+		workspace = &w
+	}
+
+	definitions, err := ctr.db.ListIntegrationDefinitions()
+	if err != nil {
+		return results, fmt.Errorf("Error reading integration definitions from database: %v", err)
+	}
+
+	visible := map[model.DefinitionScope]bool{}
+	for _, definition := range definitions {
+		if workspace != nil {
+			if definition.Scope.Includes(*workspace) {
+				results = append(results, definition)
+			}
+			continue
+		}
+		ok, checked := visible[definition.Scope]
+		if !checked {
+			ok = ctr.canViewDefinitions(definition.Scope)
+			visible[definition.Scope] = ok
+		}
+		if ok {
+			results = append(results, definition)
+		}
+	}
+
+	return results, nil
+}
+
+func (ctr *Controller) ReadIntegrationDefinition(id string, version string) (model.IntegrationDefinition, error) {
+
+	var result model.IntegrationDefinition
+
+	definition, err := ctr.db.GetIntegrationDefinition(id, version)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+	if !ctr.canViewDefinitions(definition.Scope) {
+		return result, errors.New("User does not have permission to view integration definition")
+	}
+
+	return definition, nil
+}
+
+func (ctr *Controller) ListIntegrationDefinitionVersions(id string) ([]model.IntegrationDefinition, error) {
+
+	versions, err := ctr.db.ListIntegrationDefinitionVersions(id)
+	if err != nil {
+		return versions, fmt.Errorf("Error reading integration definition versions from database: %v", err)
+	}
+	if len(versions) > 0 && !ctr.canViewDefinitions(versions[0].Scope) {
+		return []model.IntegrationDefinition{}, errors.New("User does not have permission to view integration definition")
+	}
+
+	return versions, nil
+}
+
+func (ctr *Controller) UpgradeIntegrations(id string, version string, dryRun bool) (model.UpgradeReport, error) {
+
+	report := model.NewUpgradeReport(id, version, dryRun)
+
+	history, err := ctr.db.ListIntegrationDefinitionVersions(id)
+	if err != nil {
+		return report, fmt.Errorf("Error reading integration definition versions from database: %v", err)
+	}
+
+	// Authorization. Upgrading touches integrations across every workspace the definition can be used in
+	if len(history) == 0 {
+		return report, fmt.Errorf("Integration definition with id %s not found", id)
+	}
+	err = ctr.canManageDefinitions(history[0].Scope)
+	if err != nil {
+		return report, err
+	}
+
+	integrations, err := ctr.db.ListIntegrationsForDefinition(id)
+	if err != nil {
+		return report, fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+
+	for _, integration := range integrations {
+
+		// Already at or above the target version
+		cmp, err := model.CompareVersions(integration.DefinitionVersion, version)
+		if err == nil && cmp >= 0 {
+			continue
+		}
+
+		// Checked in the environments of its workspace, which it may change
+		workspace, err := ctr.db.GetWorkspaceByID(integration.WorkspaceID)
+		if err != nil {
+			failure := model.UpgradeFailure{ IntegrationID: integration.ID, WorkspaceID: integration.WorkspaceID, Error: fmt.Sprintf("Error reading workspace from database: %v", err) }
+			report.Failed = append(report.Failed, failure)
+			continue
+		}
+		upgraded, environments, err := ctr.upgradeIntegration(workspace, integration, history, version)
+		if err != nil {
+			failure := model.UpgradeFailure{ IntegrationID: integration.ID, WorkspaceID: integration.WorkspaceID, Error: err.Error() }
+			report.Failed = append(report.Failed, failure)
+			continue
+		}
+
+		if !dryRun {
+			_, err = ctr.storeUpgrade(upgraded, environments)
+			if err != nil {
+				failure := model.UpgradeFailure{ IntegrationID: integration.ID, WorkspaceID: integration.WorkspaceID, Error: err.Error() }
+				report.Failed = append(report.Failed, failure)
+				continue
+			}
+		}
+		report.Upgraded = append(report.Upgraded, integration.ID)
+	}
+
+	return report, nil
+}
+
+func (ctr *Controller) SetIntegrationDefinitionStatus(id string, version string, status string) (model.IntegrationDefinition, error) {
+
+	var definition model.IntegrationDefinition
+
+	definition, err := ctr.db.GetIntegrationDefinition(id, version)
+	if err != nil {
+		return definition, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	// Authorization. Private definitions are published without Super Admins
+	err = ctr.canManageDefinitions(definition.Scope)
+	if err != nil {
+		return model.IntegrationDefinition{}, err
+	}
+
+	// Retired versions can't validate configs anymore, so no integration can be pinned to them
+	if status == "retired" {
+		dependents, err := ctr.integrationsPinnedTo(id, version)
+		if err != nil {
+			return definition, err
+		}
+		if len(dependents) > 0 {
+			inUse := &model.InUseError{ Resource: fmt.Sprintf("Definition %s version %s", id, version), Dependents: model.IntegrationDependents(dependents) }
+			return definition, fmt.Errorf("Can't retire definition: %w", inUse)
+		}
+	}
+
+	previous := definition.Status
+	definition, err = definition.Transition(status)
+	if err != nil {
+		return definition, fmt.Errorf("Error changing definition status: %v", err)
+	}
+
+	events := []model.Event{}
+	if definition.Status == "published" && previous != "published" {
+		// Publications of private definitions only reach the workspaces that can use them
+		events = append(events, model.NewDefinitionEvent(model.EventDefinitionPublished, definition, ctr.principalID(), time.Now()))
+	}
+	definition, err = ctr.db.UpdateIntegrationDefinition(definition, events...)
+	if err != nil {
+		return definition, fmt.Errorf("Error updating integration definition in database: %v", err)
+	}
+	ctr.publish(events...)
+
+	return definition, nil
+}
+
+func (ctr *Controller) DeleteIntegrationDefinition(id string) ([]model.IntegrationDefinition, error) {
+
+	var deleted []model.IntegrationDefinition
+
+	// Authorization
+	latest, err := ctr.db.GetIntegrationDefinition(id, "")
+	if err != nil {
+		return deleted, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+	err = ctr.canManageDefinitions(latest.Scope)
+	if err != nil {
+		return deleted, err
+	}
+
+	dependents, err := ctr.integrationsPinnedTo(id, "")
+	if err != nil {
+		return deleted, err
+	}
+	if len(dependents) > 0 {
+		inUse := &model.InUseError{ Resource: fmt.Sprintf("Definition %s", id), Dependents: model.IntegrationDependents(dependents) }
+		return deleted, fmt.Errorf("Can't delete definition: %w", inUse)
+	}
+
+	deleted, err = ctr.db.DeleteIntegrationDefinitionByID(id)
+	if err != nil {
+		return deleted, fmt.Errorf("Error deleting integration definition from database: %v", err)
+	}
+
+	return deleted, nil
+}
+
+// Lists the integrations of a definition, including those of workspaces in the trash, which would come
+// back pinned to the version when restored. An empty version matches any version
+func (ctr *Controller) integrationsPinnedTo(id string, version string) ([]model.Integration, error) {
+
+	pinned := []model.Integration{}
+
+	integrations, err := ctr.db.ListIntegrationsForDefinition(id)
+	if err != nil {
+		return pinned, fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+	trashed, err := ctr.db.ListTrashedIntegrationsForDefinition(id)
+	if err != nil {
+		return pinned, fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+	integrations = append(integrations, trashed...)
+
+	for _, integration := range integrations {
+		if version == "" || integration.DefinitionVersion == version {
+			pinned = append(pinned, integration)
+		}
+	}
+
+	return pinned, nil
+}
+
+// Public definitions are managed by Super Admins, organization definitions by the admins of the
+// organization, and workspace definitions by the owners of the workspace
+func (ctr *Controller) canManageDefinitions(scope model.DefinitionScope) error {
+
+	if ctr.User.AppRole == "Super Admin" {
+		return nil
+	}
+
+	switch scope.Visibility {
+	case model.DefinitionOrganization:
+		organization, err := ctr.db.GetOrganizationByID(scope.OrganizationID)
+		if err != nil {
+			return fmt.Errorf("Error reading organization from database: %v", err)
+		}
+		if !organization.AdministeredBy(ctr.principal()) {
+			return errors.New("Only admins of the organization can manage its integration definitions")
+		}
+		return nil
+	case model.DefinitionWorkspace:
+		workspace, err := ctr.db.GetWorkspaceByID(scope.WorkspaceID)
+		if err != nil {
+			return fmt.Errorf("Error reading workspace from database: %v", err)
+		}
+		if !workspace.EditableBy(ctr.principals()...) {
+			return errors.New("Only owners of the workspace can manage its integration definitions")
+		}
+		return nil
+	}
+
+	return errors.New("Only Super Admins can manage public integration definitions")
+}
+
+// Public definitions are visible to every user, private ones to the members of their organization and to
+// the users viewing their workspace
+func (ctr *Controller) canViewDefinitions(scope model.DefinitionScope) bool {
+
+	if ctr.isTrustedRuntime() {
+		return true
+	}
+
+	switch scope.Visibility {
+	case model.DefinitionOrganization:
+		organization, err := ctr.db.GetOrganizationByID(scope.OrganizationID)
+		return err == nil && organization.RoleOf(ctr.principal()) != ""
+	case model.DefinitionWorkspace:
+		workspace, err := ctr.db.GetWorkspaceByID(scope.WorkspaceID)
+		return err == nil && workspace.ViewableBy(ctr.principals()...)
+	}
+
+	return scope.Public()
+}
@@ -0,0 +1,475 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Owners of the workspace create its environments. Environments given permissions narrow who changes them
+func (ctr *Controller) CreateEnvironment(workspaceID string, name string, permissions []model.WorkspacePermission) (model.Environment, error) {
+
+	var result model.Environment
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return result, errors.New("Only owners can manage the environments of the workspace")
+	}
+
+	permissions, err = ctr.resolvePrincipals(permissions, workspace.Permissions, workspace.OrganizationID)
+	if err != nil {
+		return result, err
+	}
+
+	environment, err := model.NewEnvironment(workspace.ID, name, dedupePermissions(permissions), time.Now())
+	if err != nil {
+		return result, fmt.Errorf("Error creating environment: %v", err)
+	}
+
+	environment, err = ctr.db.InsertEnvironment(environment)
+	if err != nil {
+		return result, fmt.Errorf("Error inserting environment into database: %v", err)
+	}
+
+	return ctr.environmentView(environment), nil
+}
+
+func (ctr *Controller) ListEnvironments(workspaceID string) ([]model.Environment, error) {
+
+	var environments []model.Environment
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return environments, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return environments, errors.New("User does not have permission to view workspace")
+	}
+
+	environments, err = ctr.db.ListEnvironments(workspace.ID)
+	if err != nil {
+		return environments, fmt.Errorf("Error reading environments from database: %v", err)
+	}
+
+	for idx, environment := range environments {
+		environments[idx] = ctr.environmentView(environment)
+	}
+
+	return environments, nil
+}
+
+func (ctr *Controller) ReadEnvironment(workspaceID string, name string) (model.Environment, error) {
+
+	var result model.Environment
+
+	workspace, environment, err := ctr.getEnvironment(workspaceID, name)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to view workspace")
+	}
+
+	return ctr.environmentView(environment), nil
+}
+
+// Replaces the permissions of the environment. Without permissions, it follows the workspace
+func (ctr *Controller) SetEnvironmentPermissions(workspaceID string, name string, permissions []model.WorkspacePermission) (model.Environment, error) {
+
+	var result model.Environment
+
+	workspace, environment, err := ctr.getEnvironment(workspaceID, name)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return result, errors.New("Only owners can manage the environments of the workspace")
+	}
+
+	permissions, err = ctr.resolvePrincipals(permissions, append(workspace.Permissions, environment.Permissions...), workspace.OrganizationID)
+	if err != nil {
+		return result, err
+	}
+	environment.Permissions = dedupePermissions(permissions)
+	environment.UpdatedAt = time.Now()
+	err = environment.Validate()
+	if err != nil {
+		return result, fmt.Errorf("Invalid environment: %v", err)
+	}
+
+	environment, err = ctr.db.UpdateEnvironment(environment)
+	if err != nil {
+		return result, fmt.Errorf("Error updating environment in database: %v", err)
+	}
+
+	return ctr.environmentView(environment), nil
+}
+
+func (ctr *Controller) DeleteEnvironment(workspaceID string, name string) (model.Environment, error) {
+
+	var result model.Environment
+
+	workspace, _, err := ctr.getEnvironment(workspaceID, name)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return result, errors.New("Only owners can manage the environments of the workspace")
+	}
+
+	environment, err := ctr.db.DeleteEnvironment(workspace.ID, name)
+	if err != nil {
+		return result, fmt.Errorf("Error deleting environment from database: %v", err)
+	}
+
+	return ctr.environmentView(environment), nil
+}
+
+// Sets the configuration of the integration in the environment. Only the fields that differ from the
+// configuration of the workspace are stored, as the override of the environment
+func (ctr *Controller) SetEnvironmentConfiguration(workspaceID string, name string, integrationID string, config model.IntegrationConfig) (model.Environment, error) {
+
+	var result model.Environment
+
+	workspace, environment, integration, definition, err := ctr.getEditableEnvironmentIntegration(workspaceID, name, integrationID)
+	if err != nil {
+		return result, err
+	}
+
+	// Secrets sent back masked keep their value in the environment
+	config = config.KeepMaskedSecrets(environment.Configuration(integration), definition.ConfigurationSchema)
+	config = config.Normalize(definition.ConfigurationSchema)
+	environment = environment.WithConfiguration(integration, config)
+
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	err = validateInEnvironment(environment, integration, definition, variables)
+	if err != nil {
+		return result, fmt.Errorf("Invalid integration: %v", err)
+	}
+
+	return ctr.updateEnvironment(environment)
+}
+
+// Drops the override of the integration, so the environment uses the configuration of the workspace
+func (ctr *Controller) ResetEnvironmentConfiguration(workspaceID string, name string, integrationID string) (model.Environment, error) {
+
+	var result model.Environment
+
+	workspace, environment, integration, definition, err := ctr.getEditableEnvironmentIntegration(workspaceID, name, integrationID)
+	if err != nil {
+		return result, err
+	}
+
+	environment = environment.WithConfiguration(integration, integration.Configuration)
+
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	err = validateInEnvironment(environment, integration, definition, variables)
+	if err != nil {
+		return result, fmt.Errorf("Invalid integration: %v", err)
+	}
+
+	return ctr.updateEnvironment(environment)
+}
+
+// Creates or replaces a variable of the environment. Like variables of the workspace, the integrations
+// are validated with the new value first
+func (ctr *Controller) SetEnvironmentVariable(workspaceID string, name string, variableName string, value string, secret bool) (model.Environment, error) {
+
+	var result model.Environment
+
+	workspace, environment, err := ctr.getEditableEnvironment(workspaceID, name)
+	if err != nil {
+		return result, err
+	}
+
+	variable, err := model.NewWorkspaceVariable(workspace.ID, variableName, value, secret, time.Now())
+	if err != nil {
+		return result, fmt.Errorf("Invalid variable: %v", err)
+	}
+	environment = environment.WithVariable(variable)
+
+	err = ctr.validateEnvironment(environment)
+	if err != nil {
+		return result, err
+	}
+
+	return ctr.updateEnvironment(environment)
+}
+
+// Removes a variable of the environment, so the variable of the workspace with the same name applies
+func (ctr *Controller) DeleteEnvironmentVariable(workspaceID string, name string, variableName string) (model.Environment, error) {
+
+	var result model.Environment
+
+	_, environment, err := ctr.getEditableEnvironment(workspaceID, name)
+	if err != nil {
+		return result, err
+	}
+
+	environment = environment.WithoutVariable(variableName)
+
+	err = ctr.validateEnvironment(environment)
+	if err != nil {
+		return result, err
+	}
+
+	return ctr.updateEnvironment(environment)
+}
+
+// Copies the configuration of the integration from an environment to another. The target keeps its own
+// secrets. Previews return the changes to the target without applying them, and only need to view
+// the workspace; applying them needs to edit the target environment
+func (ctr *Controller) PromoteIntegration(workspaceID string, integrationID string, from string, to string, apply bool) (model.Promotion, error) {
+
+	var result model.Promotion
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, integrationID)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to view workspace")
+	}
+	if from == to {
+		return result, errors.New("Can't promote a configuration to its own environment")
+	}
+
+	source, err := ctr.db.GetEnvironment(workspace.ID, from)
+	if err != nil {
+		return result, fmt.Errorf("Error reading environment from database: %v", err)
+	}
+	target, err := ctr.db.GetEnvironment(workspace.ID, to)
+	if err != nil {
+		return result, fmt.Errorf("Error reading environment from database: %v", err)
+	}
+	if apply && !target.EditableBy(workspace, ctr.principals()...) {
+		return result, fmt.Errorf("User does not have permission to promote to environment %s", to)
+	}
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	before := target.Configuration(integration)
+	after := model.PromotedConfiguration(source.Configuration(integration), before, definition.ConfigurationSchema)
+	promotion := model.Promotion{
+		IntegrationID: integration.ID,
+		From: from,
+		To: to,
+		Changes: model.DiffConfigurations(before, after, definition.ConfigurationSchema),
+	}
+
+	// Previews report invalid promotions too
+	promoted := target.WithConfiguration(integration, after)
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	err = validateInEnvironment(promoted, integration, definition, variables)
+	if err != nil {
+		return result, fmt.Errorf("Configuration is not valid in environment %s: %v", to, err)
+	}
+
+	if !apply || len(promotion.Changes) == 0 {
+		return promotion, nil
+	}
+
+	now := time.Now()
+	promoted.UpdatedAt = now
+	_, err = ctr.db.UpdateEnvironment(promoted)
+	if err != nil {
+		return result, fmt.Errorf("Error updating environment in database: %v", err)
+	}
+	entry := model.NewAuditEntry(workspace.ID, "environment.promoted", "integration", integration.ID, fmt.Sprintf("%s to %s", from, to), ctr.User.ID, now)
+	_, err = ctr.db.InsertAuditEntries([]model.AuditEntry{ entry })
+	if err != nil {
+		return result, fmt.Errorf("Error inserting audit entries into database: %v", err)
+	}
+	promotion.Applied = true
+
+	return promotion, nil
+}
+
+// Checks every integration of the workspace in its environments, e.g. after a change to the variables
+// they may reference
+func (ctr *Controller) checkEnvironments(workspaceID string, integrations []model.Integration, variables []model.WorkspaceVariable) error {
+
+	environments, err := ctr.db.ListEnvironments(workspaceID)
+	if err != nil {
+		return fmt.Errorf("Error reading environments from database: %v", err)
+	}
+
+	return ctr.checkInEnvironments(environments, integrations, variables)
+}
+
+// Checks the integrations in the environments given, e.g. environments about to be stored
+func (ctr *Controller) checkInEnvironments(environments []model.Environment, integrations []model.Integration, variables []model.WorkspaceVariable) error {
+
+	if len(environments) == 0 {
+		return nil
+	}
+
+	for _, integration := range integrations {
+		definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+		if err != nil {
+			return fmt.Errorf("Error reading integration definition from database: %v", err)
+		}
+		for _, environment := range environments {
+			err = validateInEnvironment(environment, integration, definition, variables)
+			if err != nil {
+				return fmt.Errorf("Integration %s would be invalid in environment %s: %v", integration.Name, environment.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Environments inherit the fields they don't override and the variables they don't define. Changing
+// those at the workspace level takes edit rights on every environment inheriting the change, or their
+// own permissions would be bypassed
+func (ctr *Controller) checkInheritingEnvironments(workspace model.Workspace, inherits func(model.Environment) bool) error {
+
+	environments, err := ctr.db.ListEnvironments(workspace.ID)
+	if err != nil {
+		return fmt.Errorf("Error reading environments from database: %v", err)
+	}
+
+	for _, environment := range environments {
+		if inherits(environment) && !environment.EditableBy(workspace, ctr.principals()...) {
+			return fmt.Errorf("User does not have permission to edit environment %s, which inherits the change", environment.Name)
+		}
+	}
+
+	return nil
+}
+
+// Checks every integration of the workspace in the environment
+func (ctr *Controller) validateEnvironment(environment model.Environment) error {
+
+	variables, err := ctr.db.ListWorkspaceVariables(environment.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	integrations, err := ctr.db.ListIntegrationsForWorkspace(environment.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+
+	for _, integration := range integrations {
+		definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+		if err != nil {
+			return fmt.Errorf("Error reading integration definition from database: %v", err)
+		}
+		err = validateInEnvironment(environment, integration, definition, variables)
+		if err != nil {
+			return fmt.Errorf("Integration %s would be invalid: %v", integration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateInEnvironment(environment model.Environment, integration model.Integration, definition model.IntegrationDefinition, variables []model.WorkspaceVariable) error {
+	integration.Configuration = environment.Configuration(integration)
+	return integration.ValidateWithVariables(definition, environment.EffectiveVariables(variables))
+}
+
+func (ctr *Controller) updateEnvironment(environment model.Environment) (model.Environment, error) {
+
+	var result model.Environment
+
+	environment.UpdatedAt = time.Now()
+	environment, err := ctr.db.UpdateEnvironment(environment)
+	if err != nil {
+		return result, fmt.Errorf("Error updating environment in database: %v", err)
+	}
+
+	return ctr.environmentView(environment), nil
+}
+
+func (ctr *Controller) getEnvironment(workspaceID string, name string) (model.Workspace, model.Environment, error) {
+
+	var environment model.Environment
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return workspace, environment, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+
+	environment, err = ctr.db.GetEnvironment(workspace.ID, name)
+	if err != nil {
+		return workspace, environment, fmt.Errorf("Error reading environment from database: %v", err)
+	}
+
+	return workspace, environment, nil
+}
+
+func (ctr *Controller) getEditableEnvironment(workspaceID string, name string) (model.Workspace, model.Environment, error) {
+
+	workspace, environment, err := ctr.getEnvironment(workspaceID, name)
+	if err != nil {
+		return workspace, environment, err
+	}
+	if !environment.EditableBy(workspace, ctr.principals()...) {
+		return workspace, environment, fmt.Errorf("User does not have permission to edit environment %s", name)
+	}
+
+	return workspace, environment, nil
+}
+
+func (ctr *Controller) getEditableEnvironmentIntegration(workspaceID string, name string, integrationID string) (model.Workspace, model.Environment, model.Integration, model.IntegrationDefinition, error) {
+
+	var integration model.Integration
+	var definition model.IntegrationDefinition
+
+	workspace, environment, err := ctr.getEditableEnvironment(workspaceID, name)
+	if err != nil {
+		return workspace, environment, integration, definition, err
+	}
+
+	_, integration, err = ctr.getWorkspaceIntegration(workspace.ID, integrationID)
+	if err != nil {
+		return workspace, environment, integration, definition, err
+	}
+
+	// Configurations are validated against the pinned version, like the one of the workspace
+	definition, err = ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return workspace, environment, integration, definition, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	return workspace, environment, integration, definition, nil
+}
+
+// Copy of the environment for display: names of the principals filled in, secrets masked
+func (ctr *Controller) environmentView(environment model.Environment) model.Environment {
+
+	environment.Permissions = ctr.withNames(model.Workspace{ Permissions: environment.Permissions })[0].Permissions
+
+	schemas := map[string]model.ConfigurationSchema{}
+	for id := range environment.Overrides {
+		integration, err := ctr.db.GetIntegrationByID(id)
+		if err != nil || integration.WorkspaceID != environment.WorkspaceID {
+			continue
+		}
+		definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+		if err != nil {
+			continue
+		}
+		schemas[id] = definition.ConfigurationSchema
+	}
+
+	return environment.Masked(schemas)
+}
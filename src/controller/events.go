@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/eventbus"
+)
+
+// Subscribes to the live events of the workspace, resuming after lastEventID when set.
+// The bus closes the subscription if the user loses access to the workspace. Callers must close it when done
+func (ctr *Controller) SubscribeWorkspaceEvents(workspaceID string, lastEventID string) (*eventbus.Subscription, error) {
+
+	if ctr.Options.Events == nil {
+		return nil, errors.New("Event streams are not enabled")
+	}
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return nil, errors.New("User does not have permission to view workspace")
+	}
+
+	return ctr.Options.Events.Subscribe(workspace.ID, workspace.OrganizationID, ctr.principals(), lastEventID), nil
+}
@@ -0,0 +1,343 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Groups are created by any user, who becomes their first manager. Groups of organizations are created
+// by their admins and members, and only have members of the organization. Managers, admins of the
+// organization and Super Admins manage them, their members can read them
+func (ctr *Controller) CreateGroup(organizationID string, name string, members []model.GroupMember) (model.Group, error) {
+
+	var group model.Group
+
+	principal := ctr.principal()
+	if principal == "" {
+		return group, errors.New("Only users can create groups")
+	}
+	if organizationID != "" {
+		organization, err := ctr.db.GetOrganizationByID(organizationID)
+		if err != nil {
+			return group, fmt.Errorf("Error reading organization from database: %v", err)
+		}
+		role := organization.RoleOf(principal)
+		if role != model.OrganizationAdmin && role != model.OrganizationMemberRole {
+			return group, fmt.Errorf("User does not have permission to create groups in organization %s", organization.Name)
+		}
+	}
+
+	members, err := ctr.resolveMembers(members, organizationID)
+	if err != nil {
+		return group, err
+	}
+	members = model.Group{ Members: members }.WithMember(model.GroupMember{ Principal: principal, Role: "manager" }).Members
+
+	group, err = model.NewGroup(organizationID, name, members, ctr.User.ID, time.Now())
+	if err != nil {
+		return group, fmt.Errorf("Error creating group: %v", err)
+	}
+
+	group, err = ctr.db.InsertGroup(group)
+	if err != nil {
+		return group, fmt.Errorf("Error inserting group into database: %v", err)
+	}
+
+	return ctr.withMemberEmails(group), nil
+}
+
+// Groups of the user. Super Admins list every group
+func (ctr *Controller) ListGroups() ([]model.Group, error) {
+
+	var groups []model.Group
+	var err error
+
+	if ctr.User.AppRole == "Super Admin" {
+		groups, err = ctr.db.ListGroups()
+	} else {
+		groups, err = ctr.db.ListGroupsForMember(ctr.principal())
+	}
+	if err != nil {
+		return groups, fmt.Errorf("Error reading groups from database: %v", err)
+	}
+
+	for idx, group := range groups {
+		groups[idx] = ctr.withMemberEmails(group)
+	}
+
+	return groups, nil
+}
+
+func (ctr *Controller) ReadGroup(id string) (model.Group, error) {
+
+	var result model.Group
+
+	group, err := ctr.db.GetGroupByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading group from database: %v", err)
+	}
+	if !group.HasMember(ctr.principal()) && !ctr.canManageGroup(group) {
+		return result, errors.New("User does not have permission to view group")
+	}
+
+	return ctr.withMemberEmails(group), nil
+}
+
+func (ctr *Controller) RenameGroup(id string, name string) (model.Group, error) {
+
+	var result model.Group
+
+	group, err := ctr.getManagedGroup(id)
+	if err != nil {
+		return result, err
+	}
+
+	group.Name = name
+	group.UpdatedAt = time.Now()
+	err = group.Validate()
+	if err != nil {
+		return result, fmt.Errorf("Invalid group: %v", err)
+	}
+
+	group, err = ctr.db.UpdateGroup(group)
+	if err != nil {
+		return result, fmt.Errorf("Error updating group in database: %v", err)
+	}
+
+	return ctr.withMemberEmails(group), nil
+}
+
+// Adds the member to the group, or changes their role. Members may be given by email
+func (ctr *Controller) SetGroupMember(id string, member model.GroupMember) (model.Group, error) {
+
+	var result model.Group
+
+	group, err := ctr.getManagedGroup(id)
+	if err != nil {
+		return result, err
+	}
+
+	members, err := ctr.resolveMembers([]model.GroupMember{ member }, group.OrganizationID)
+	if err != nil {
+		return result, err
+	}
+
+	group = group.WithMember(members[0])
+	if !group.Managed() {
+		return result, errors.New("Groups need at least one manager")
+	}
+
+	return ctr.updateGroupMembers(group)
+}
+
+// Removes the member from the group. Managers remove anyone, members remove themselves.
+// The live event streams of the workspaces the member can't view anymore are closed
+func (ctr *Controller) RemoveGroupMember(id string, principal string) (model.Group, error) {
+
+	var result model.Group
+
+	group, err := ctr.db.GetGroupByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading group from database: %v", err)
+	}
+	if principal != ctr.principal() && !ctr.canManageGroup(group) {
+		return result, errors.New("User does not have permission to manage group")
+	}
+	if !group.HasMember(principal) {
+		return result, fmt.Errorf("%s is not a member of group %s", principal, group.Name)
+	}
+
+	group = group.WithoutMember(principal)
+	if !group.Managed() {
+		return result, errors.New("Groups need at least one manager")
+	}
+
+	result, err = ctr.updateGroupMembers(group)
+	if err != nil {
+		return result, err
+	}
+
+	ctr.revokeStreams(group, principal)
+
+	return result, nil
+}
+
+// Deletes the group and the permissions granted to it
+func (ctr *Controller) DeleteGroup(id string) (model.Group, error) {
+
+	var result model.Group
+
+	group, err := ctr.getManagedGroup(id)
+	if err != nil {
+		return result, err
+	}
+
+	// Deleted groups grant nothing already, their permissions are removed so they don't linger
+	deleted, err := ctr.db.DeleteGroupByID(group.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error deleting group from database: %v", err)
+	}
+
+	workspaces, err := ctr.db.ListWorkspacesForPrincipal(group.Principal())
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspaces from database: %v", err)
+	}
+	for _, workspace := range workspaces {
+		permissions := []model.WorkspacePermission{}
+		for _, perm := range workspace.Permissions {
+			if perm.Principal != group.Principal() {
+				permissions = append(permissions, perm)
+			}
+		}
+		workspace.Permissions = permissions
+		workspace.UpdatedAt = time.Now()
+
+		event := model.NewEvent(model.EventWorkspacePermissionsChanged, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), workspace.UpdatedAt)
+		_, err := ctr.db.UpdateWorkspace(workspace, event)
+		if err != nil {
+			return result, fmt.Errorf("Error removing the permissions of the group from workspace %s: %v", workspace.ID, err)
+		}
+		ctr.publish(event)
+	}
+
+	return ctr.withMemberEmails(deleted), nil
+}
+
+// Members may be given by email, resolved to the user registered with it. Only users can be members,
+// and only members of the organization of the group
+func (ctr *Controller) resolveMembers(members []model.GroupMember, organizationID string) ([]model.GroupMember, error) {
+
+	var organization model.Organization
+	if organizationID != "" {
+		var err error
+		organization, err = ctr.db.GetOrganizationByID(organizationID)
+		if err != nil {
+			return []model.GroupMember{}, fmt.Errorf("Error reading organization from database: %v", err)
+		}
+	}
+
+	resolved := []model.GroupMember{}
+	for _, member := range members {
+		principal, err := ctr.resolveUser(member.Principal, member.Email)
+		if err != nil {
+			return resolved, err
+		}
+		if organizationID != "" && organization.RoleOf(principal) == "" {
+			return resolved, fmt.Errorf("%s is not a member of organization %s", principal, organization.Name)
+		}
+		resolved = append(resolved, model.GroupMember{ Principal: principal, Role: member.Role })
+	}
+
+	return resolved, nil
+}
+
+// Principal of a user given by principal, or by the email they registered with
+func (ctr *Controller) resolveUser(principal string, email string) (string, error) {
+
+	if principal == "" {
+		if email == "" {
+			return "", errors.New("User has no principal")
+		}
+		user, err := ctr.db.GetUserByEmail(email)
+		if err != nil {
+			return "", fmt.Errorf("%s is not a registered user", email)
+		}
+		return user.Principal(), nil
+	}
+
+	kind, _, err := model.ParsePrincipal(principal)
+	if err != nil || kind != model.PrincipalUser {
+		return "", fmt.Errorf("Invalid user %s", principal)
+	}
+	_, err = ctr.principalName(principal)
+	if err != nil {
+		return "", err
+	}
+
+	return principal, nil
+}
+
+func (ctr *Controller) updateGroupMembers(group model.Group) (model.Group, error) {
+
+	var result model.Group
+
+	group.UpdatedAt = time.Now()
+	err := group.Validate()
+	if err != nil {
+		return result, fmt.Errorf("Invalid group: %v", err)
+	}
+
+	group, err = ctr.db.UpdateGroup(group)
+	if err != nil {
+		return result, fmt.Errorf("Error updating group in database: %v", err)
+	}
+
+	return ctr.withMemberEmails(group), nil
+}
+
+// Closes the live event streams of the former member to the workspaces of the group they can't view anymore
+func (ctr *Controller) revokeStreams(group model.Group, principal string) {
+
+	if ctr.Options.Events == nil {
+		return
+	}
+
+	_, id, _ := model.ParsePrincipal(principal)
+	user, err := ctr.db.GetUserById(id)
+	if err != nil {
+		return
+	}
+
+	workspaces, err := ctr.db.ListWorkspacesForPrincipal(group.Principal())
+	if err != nil {
+		return
+	}
+	principals := ctr.principalsOf(user)
+	for _, workspace := range workspaces {
+		if !workspace.ViewableBy(principals...) {
+			ctr.Options.Events.Revoke(workspace.ID, principal)
+		}
+	}
+}
+
+func (ctr *Controller) getManagedGroup(id string) (model.Group, error) {
+
+	var result model.Group
+
+	group, err := ctr.db.GetGroupByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading group from database: %v", err)
+	}
+	if !ctr.canManageGroup(group) {
+		return result, errors.New("User does not have permission to manage group")
+	}
+
+	return group, nil
+}
+
+func (ctr *Controller) canManageGroup(group model.Group) bool {
+
+	if group.ManagedBy(ctr.principal()) || ctr.User.AppRole == "Super Admin" {
+		return true
+	}
+	if group.OrganizationID == "" {
+		return false
+	}
+	organization, err := ctr.db.GetOrganizationByID(group.OrganizationID)
+	return err == nil && organization.AdministeredBy(ctr.principal())
+}
+
+// Copy of the group with the current emails of its members, for display
+func (ctr *Controller) withMemberEmails(group model.Group) model.Group {
+
+	members := []model.GroupMember{}
+	for _, member := range group.Members {
+		member.Email, _ = ctr.principalName(member.Principal)
+		members = append(members, member)
+	}
+	group.Members = members
+
+	return group
+}
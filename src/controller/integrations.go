@@ -0,0 +1,350 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+func (ctr *Controller) CreateIntegration(workspaceID string, name string, definitionID string, config model.IntegrationConfig) (model.Integration, error) {
+
+	var integration model.Integration
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return integration, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return integration, errors.New("User does not have permission to create integrations in this workspace")
+	}
+
+	// New integrations are pinned to the latest published version of the definition
+	versions, err := ctr.db.ListIntegrationDefinitionVersions(definitionID)
+	if err != nil {
+		return integration, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+	definition, err := model.LatestPublishedVersion(versions)
+	if err != nil {
+		return integration, fmt.Errorf("Can't create integration: %v", err)
+	}
+	if !definition.Scope.Includes(workspace) {
+		return integration, fmt.Errorf("Can't create integration: definition %s is private to another %s", definitionID, definition.Scope.Visibility)
+	}
+
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return integration, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+
+	config = config.Normalize(definition.ConfigurationSchema)
+	integration, err = model.NewIntegration(name, workspace.ID, definition, config, variables)
+	if err != nil {
+		return integration, fmt.Errorf("Error creating integration: %v", err)
+	}
+
+	event := model.NewIntegrationEvent(model.EventIntegrationCreated, integration, definition.ConfigurationSchema, ctr.principalID(), time.Now())
+	integration, err = ctr.db.InsertIntegration(integration, event)
+	if err != nil {
+		return integration, fmt.Errorf("Error inserting integration into database: %v", err)
+	}
+	ctr.publish(event.WithResourceID(integration.ID))
+
+	return integration.WithDefinition(definition).Masked(), nil
+}
+
+func (ctr *Controller) ListIntegrations(workspaceID string) ([]model.Integration, error) {
+
+	var integrations []model.Integration
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return integrations, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return integrations, errors.New("User does not have permission to view workspace")
+	}
+
+	integrations, err = ctr.db.ListIntegrationsForWorkspace(workspace.ID)
+	if err != nil {
+		return integrations, fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+
+	for idx, integration := range integrations {
+		integrations[idx] = ctr.withHealth(ctr.withDefinition(integration)).Masked()
+	}
+
+	return integrations, nil
+}
+
+func (ctr *Controller) ReadIntegration(workspaceID string, id string) (model.Integration, error) {
+
+	var result model.Integration
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to view workspace")
+	}
+
+	return ctr.withHealth(ctr.withDefinition(integration)).Masked(), nil
+}
+
+func (ctr *Controller) UpdateIntegration(workspaceID string, id string, name string, config model.IntegrationConfig) (model.Integration, error) {
+
+	var result model.Integration
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to edit integrations in this workspace")
+	}
+
+	// Configuration is validated against the pinned version, not the latest one
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	// Secrets are shown masked, clients send them back as they were shown to keep them
+	previous := integration
+	integration.Name = name
+	config = config.KeepMaskedSecrets(integration.Configuration, definition.ConfigurationSchema)
+	config = config.Normalize(definition.ConfigurationSchema)
+	if !reflect.DeepEqual(config, integration.Configuration) {
+		integration.LastCheck = nil
+	}
+	integration.Configuration = config
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	err = integration.ValidateWithVariables(definition, variables)
+	if err != nil {
+		return result, fmt.Errorf("Invalid integration: %v", err)
+	}
+	err = ctr.checkInheritingEnvironments(workspace, func(environment model.Environment) bool {
+		return environment.InheritsChange(previous, integration)
+	})
+	if err != nil {
+		return result, err
+	}
+	err = ctr.checkEnvironments(workspace.ID, []model.Integration{ integration }, variables)
+	if err != nil {
+		return result, err
+	}
+
+	event := model.NewIntegrationEvent(model.EventIntegrationUpdated, integration, definition.ConfigurationSchema, ctr.principalID(), time.Now())
+	integration, err = ctr.db.UpdateIntegration(integration, event)
+	if err != nil {
+		return integration, fmt.Errorf("Error updating integration in database: %v", err)
+	}
+	ctr.publish(event)
+
+	return integration.WithDefinition(definition).Masked(), nil
+}
+
+func (ctr *Controller) DeleteIntegration(workspaceID string, id string) (model.Integration, error) {
+
+	var result model.Integration
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to delete integrations in this workspace")
+	}
+
+	// Connections would be left without one of their ends
+	connections, err := ctr.db.ListConnectionsForIntegration(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading connections from database: %v", err)
+	}
+	if len(connections) > 0 {
+		inUse := &model.InUseError{ Resource: fmt.Sprintf("Integration %s", id), Dependents: model.ConnectionDependents(connections) }
+		return result, fmt.Errorf("Can't delete integration: %w", inUse)
+	}
+
+	event, err := ctr.integrationEvent(model.EventIntegrationDeleted, integration)
+	if err != nil {
+		return result, err
+	}
+	deleted, err := ctr.db.DeleteIntegrationByID(id, event)
+	if err != nil {
+		return deleted, fmt.Errorf("Error deleting integration from database: %v", err)
+	}
+	ctr.publish(event)
+	deleted = ctr.withDefinition(deleted).Masked()
+
+	// The state would be orphaned. Resets are kept as history
+	err = ctr.db.DeleteConnectorState(id)
+	if err != nil {
+		return deleted, fmt.Errorf("Error deleting connector state from database: %v", err)
+	}
+
+	return deleted, nil
+}
+
+func (ctr *Controller) UpgradeIntegration(workspaceID string, id string, version string) (model.Integration, error) {
+
+	var result model.Integration
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to edit integrations in this workspace")
+	}
+
+	history, err := ctr.db.ListIntegrationDefinitionVersions(integration.DefinitionID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integration definition versions from database: %v", err)
+	}
+
+	// Defaults to the latest published version
+	if version == "" {
+		latest, err := model.LatestPublishedVersion(history)
+		if err != nil {
+			return result, fmt.Errorf("Can't upgrade integration: %v", err)
+		}
+		version = latest.Version
+	}
+
+	integration, environments, err := ctr.upgradeIntegration(workspace, integration, history, version)
+	if err != nil {
+		return result, err
+	}
+	integration, err = ctr.storeUpgrade(integration, environments)
+	if err != nil {
+		return integration, err
+	}
+
+	return integration.Masked(), nil
+}
+
+// Upgrades the integration and migrates its overrides in the environments of the workspace, checking it in
+// every environment. Returns the environments whose overrides changed, to store with the integration.
+// Editing the environments the upgrade changes takes edit rights on them
+func (ctr *Controller) upgradeIntegration(workspace model.Workspace, integration model.Integration, history []model.IntegrationDefinition, version string) (model.Integration, []model.Environment, error) {
+
+	var upgraded model.Integration
+	changed := []model.Environment{}
+
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return upgraded, changed, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	upgraded, err = integration.Upgrade(history, version, variables)
+	if err != nil {
+		return upgraded, changed, fmt.Errorf("Error upgrading integration: %v", err)
+	}
+
+	environments, err := ctr.db.ListEnvironments(workspace.ID)
+	if err != nil {
+		return upgraded, changed, fmt.Errorf("Error reading environments from database: %v", err)
+	}
+	migrated := map[string]model.Environment{}
+	upgradedEnvironments := []model.Environment{}
+	for _, environment := range environments {
+		m, err := environment.MigrateOverrides(integration.ID, history, integration.DefinitionVersion, upgraded.DefinitionVersion)
+		if err != nil {
+			return upgraded, changed, fmt.Errorf("Error upgrading integration: %v", err)
+		}
+		migrated[environment.Name] = m
+		upgradedEnvironments = append(upgradedEnvironments, m)
+		if !reflect.DeepEqual(m.Overrides, environment.Overrides) {
+			changed = append(changed, m)
+		}
+	}
+
+	err = ctr.checkInheritingEnvironments(workspace, func(environment model.Environment) bool {
+		return !reflect.DeepEqual(environment.Configuration(integration), migrated[environment.Name].Configuration(upgraded))
+	})
+	if err != nil {
+		return upgraded, changed, err
+	}
+	err = ctr.checkInEnvironments(upgradedEnvironments, []model.Integration{ upgraded }, variables)
+	if err != nil {
+		return upgraded, changed, err
+	}
+
+	return upgraded, changed, nil
+}
+
+// Stores the upgraded integration with the environments whose overrides were migrated
+func (ctr *Controller) storeUpgrade(integration model.Integration, environments []model.Environment) (model.Integration, error) {
+
+	event, err := ctr.integrationEvent(model.EventIntegrationUpdated, integration)
+	if err != nil {
+		return integration, err
+	}
+	integration, err = ctr.db.UpdateIntegration(integration, event)
+	if err != nil {
+		return integration, fmt.Errorf("Error updating integration in database: %v", err)
+	}
+	ctr.publish(event)
+
+	now := time.Now()
+	for _, environment := range environments {
+		environment.UpdatedAt = now
+		_, err = ctr.db.UpdateEnvironment(environment)
+		if err != nil {
+			return integration, fmt.Errorf("Error updating environment in database: %v", err)
+		}
+	}
+
+	return integration, nil
+}
+
+// Attaches the pinned definition version so responses carry its warnings.
+// A missing definition is not an error, the integration is returned as is
+func (ctr *Controller) withDefinition(integration model.Integration) model.Integration {
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return integration
+	}
+
+	return integration.WithDefinition(definition)
+}
+
+// Event about the integration, its secrets masked with the schema of its pinned version
+func (ctr *Controller) integrationEvent(t string, integration model.Integration) (model.Event, error) {
+
+	var event model.Event
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return event, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	return model.NewIntegrationEvent(t, integration, definition.ConfigurationSchema, ctr.principalID(), time.Now()), nil
+}
+
+// Reads an integration, making sure it belongs to the workspace
+func (ctr *Controller) getWorkspaceIntegration(workspaceID string, id string) (model.Workspace, model.Integration, error) {
+
+	var integration model.Integration
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return workspace, integration, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+
+	integration, err = ctr.db.GetIntegrationByID(id)
+	if err != nil {
+		return workspace, integration, fmt.Errorf("Error reading integration from database: %v", err)
+	}
+	if integration.WorkspaceID != workspace.ID {
+		return workspace, integration, fmt.Errorf("Integration %s not found in workspace %s", id, workspaceID)
+	}
+
+	return workspace, integration, nil
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"smartgrowth-connectors/configapi/mailer"
+	"smartgrowth-connectors/configapi/model"
+	"strings"
+	"time"
+)
+
+// Invites someone to the workspace by email. Only for owners. The token is only sent to the invitee
+func (ctr *Controller) CreateInvitation(workspaceID string, email string, role string) (model.Invitation, error) {
+
+	var result model.Invitation
+
+	if ctr.Options.Mailer == nil || len(ctr.Options.InvitationKey) == 0 {
+		return result, errors.New("Invitations are not enabled")
+	}
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to invite to this workspace")
+	}
+	user, err := ctr.db.GetUserByEmail(email)
+	if err == nil && workspace.ViewableBy(user.Principal()) {
+		return result, fmt.Errorf("%s already has access to the workspace", email)
+	}
+
+	now := time.Now()
+	pending, err := ctr.db.ListInvitations(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading invitations from database: %v", err)
+	}
+	for _, invitation := range pending {
+		if strings.EqualFold(invitation.Email, email) && invitation.WithState(now).Status == "pending" {
+			return result, fmt.Errorf("%s already has a pending invitation", email)
+		}
+	}
+
+	ttl := ctr.Options.InvitationTTL
+	if ttl == 0 {
+		ttl = model.DefaultInvitationTTL
+	}
+	invitation, err := model.NewInvitation(workspace.ID, email, role, ctr.User.ID, now, ttl)
+	if err != nil {
+		return result, fmt.Errorf("Error creating invitation: %v", err)
+	}
+
+	invitation, err = ctr.db.InsertInvitation(invitation)
+	if err != nil {
+		return result, fmt.Errorf("Error inserting invitation into database: %v", err)
+	}
+
+	// Invitations that couldn't be sent are revoked, so they can be created again
+	err = ctr.sendInvitation(workspace, invitation)
+	if err != nil {
+		revoked, revokeErr := invitation.Revoke(time.Now())
+		if revokeErr == nil {
+			ctr.db.UpdateInvitation(revoked)
+		}
+		return result, err
+	}
+
+	return invitation, nil
+}
+
+func (ctr *Controller) ListInvitations(workspaceID string) ([]model.Invitation, error) {
+
+	var invitations []model.Invitation
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return invitations, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return invitations, errors.New("User does not have permission to view the invitations of this workspace")
+	}
+
+	invitations, err = ctr.db.ListInvitations(workspace.ID)
+	if err != nil {
+		return invitations, fmt.Errorf("Error reading invitations from database: %v", err)
+	}
+
+	now := time.Now()
+	for idx, invitation := range invitations {
+		invitations[idx] = invitation.WithState(now)
+	}
+
+	return invitations, nil
+}
+
+func (ctr *Controller) RevokeInvitation(workspaceID string, id string) (model.Invitation, error) {
+
+	var result model.Invitation
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to revoke the invitations of this workspace")
+	}
+
+	invitation, err := ctr.db.GetInvitationByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading invitation from database: %v", err)
+	}
+	if invitation.WorkspaceID != workspace.ID {
+		return result, fmt.Errorf("Invitation %s not found in workspace %s", id, workspaceID)
+	}
+
+	invitation, err = invitation.Revoke(time.Now())
+	if err != nil {
+		return result, fmt.Errorf("Error revoking invitation: %v", err)
+	}
+
+	invitation, err = ctr.db.UpdateInvitation(invitation)
+	if err != nil {
+		return result, fmt.Errorf("Error updating invitation in database: %v", err)
+	}
+
+	return invitation, nil
+}
+
+// Accepts an invitation on behalf of the user, who must be its invitee.
+// Grants the role of the invitation, in place of any lower role the user had. Invitees who aren't members
+// of the organization of the workspace join it as guests. The invitee isn't in the organization yet,
+// the token authorizes reading the invitation and its workspace across organizations
+func (ctr *Controller) AcceptInvitation(token string) (model.Workspace, error) {
+
+	var result model.Workspace
+
+	id, err := model.ParseInvitationToken(token, ctr.Options.InvitationKey)
+	if err != nil {
+		return result, err
+	}
+
+	invitation, err := ctr.root.GetInvitationByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading invitation from database: %v", err)
+	}
+
+	now := time.Now()
+	invitation, err = invitation.Accept(*ctr.User, now)
+	if err != nil {
+		return result, fmt.Errorf("Can't accept invitation: %v", err)
+	}
+
+	workspace, err := ctr.root.GetWorkspaceByID(invitation.WorkspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	workspace = workspace.WithPermission(invitation.Permission(*ctr.User))
+	workspace.UpdatedAt = now
+
+	var joined *model.Organization
+	if workspace.OrganizationID != "" {
+		organization, err := ctr.root.GetOrganizationByID(workspace.OrganizationID)
+		if err != nil {
+			return result, fmt.Errorf("Error reading organization from database: %v", err)
+		}
+		if organization.RoleOf(ctr.principal()) == "" {
+			organization = organization.WithMember(model.OrganizationMember{ Principal: ctr.principal(), Role: model.OrganizationGuest })
+			organization.UpdatedAt = now
+			joined = &organization
+		}
+	}
+
+	event := model.NewEvent(model.EventWorkspacePermissionsChanged, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), now)
+	workspace, err = ctr.root.AcceptInvitation(invitation, workspace, joined, event)
+	if err != nil {
+		return result, fmt.Errorf("Error accepting invitation: %v", err)
+	}
+	ctr.publish(event)
+
+	return ctr.withNames(workspace)[0], nil
+}
+
+func (ctr *Controller) sendInvitation(workspace model.Workspace, invitation model.Invitation) error {
+
+	token, err := model.SignInvitationToken(invitation, ctr.Options.InvitationKey)
+	if err != nil {
+		return err
+	}
+
+	// The accept page receives the token in its query
+	link := token
+	if ctr.Options.InvitationURL != "" {
+		u, err := url.Parse(ctr.Options.InvitationURL)
+		if err != nil {
+			return fmt.Errorf("Invalid invitation URL: %v", err)
+		}
+		query := u.Query()
+		query.Set("token", token)
+		u.RawQuery = query.Encode()
+		link = u.String()
+	}
+
+	message := mailer.Message{
+		To: invitation.Email,
+		Subject: fmt.Sprintf("You are invited to the workspace %s", strings.Join(strings.Fields(workspace.Name), " ")),
+		Body: fmt.Sprintf("%s invited you to join the workspace %s as %s.\n\nAccept the invitation: %s\n\nThe invitation expires on %s.\n",
+			ctr.User.Email, workspace.Name, invitation.Role, link, invitation.ExpiresAt.UTC().Format(time.RFC1123)),
+	}
+
+	err = ctr.Options.Mailer.Send(message)
+	if err != nil {
+		return fmt.Errorf("Error sending invitation: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"smartgrowth-connectors/configapi/oauth"
+	"time"
+)
+
+// Access tokens expiring within this window are refreshed
+const oauthRefreshWindow = 10 * time.Minute
+
+// Starts the authorization of an integration through the OAuth provider of its definition.
+// Returns the URL the user should be sent to
+func (ctr *Controller) StartOAuth(workspaceID string, id string) (string, error) {
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return "", err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return "", errors.New("User does not have permission to edit integrations in this workspace")
+	}
+
+	provider, client, err := ctr.oauthProvider(integration)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := model.NewOAuthSession(workspace.ID, integration.ID, ctr.User.ID, time.Now())
+	if err != nil {
+		return "", err
+	}
+	err = ctr.db.InsertOAuthSession(session)
+	if err != nil {
+		return "", fmt.Errorf("Error inserting OAuth session into database: %v", err)
+	}
+
+	return provider.AuthorizationURL(client, ctr.Options.OAuthRedirectURL, session)
+}
+
+// Completes an authorization when the provider calls back. The caller is not authenticated,
+// the session identified by the state stands for the user who started the authorization.
+// The tokens are stored in the configuration of the integration.
+func (ctr *Controller) CompleteOAuth(state string, code string, providerError string) (model.Integration, error) {
+
+	var result model.Integration
+
+	// Sessions are single use, whatever the outcome
+	session, err := ctr.db.TakeOAuthSession(state)
+	if err != nil {
+		return result, errors.New("Unknown or already used OAuth state")
+	}
+	if session.Expired(time.Now()) {
+		return result, errors.New("OAuth authorization expired, start it again")
+	}
+	if providerError != "" {
+		return result, fmt.Errorf("Provider refused the authorization: %s", providerError)
+	}
+	if code == "" {
+		return result, errors.New("Provider didn't return an authorization code")
+	}
+
+	// The user may have lost access since the authorization started
+	user, err := ctr.db.GetUserById(session.PrincipalID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading user from database: %v", err)
+	}
+	workspace, integration, err := ctr.getWorkspaceIntegration(session.WorkspaceID, session.IntegrationID)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principalsOf(user)...) {
+		return result, errors.New("User does not have permission to edit integrations in this workspace")
+	}
+
+	provider, client, err := ctr.oauthProvider(integration)
+	if err != nil {
+		return result, err
+	}
+
+	token, err := ctr.oauthClient().Exchange(provider, client, code, session.CodeVerifier, ctr.Options.OAuthRedirectURL)
+	if err != nil {
+		return result, fmt.Errorf("Error exchanging authorization code: %v", err)
+	}
+
+	return ctr.storeOAuthToken(integration, provider, token, user.ID)
+}
+
+// Refreshes the access tokens expiring soon, across every workspace. Meant to run periodically
+// in the background. Failures don't stop the other refreshes, they are returned together.
+func (ctr *Controller) RefreshOAuthTokens(now time.Time) (int, []error) {
+
+	refreshed := 0
+	errs := []error{}
+
+	definitions, err := ctr.db.ListIntegrationDefinitions()
+	if err != nil {
+		return refreshed, append(errs, fmt.Errorf("Error reading integration definitions from database: %v", err))
+	}
+
+	for _, definition := range definitions {
+		integrations, err := ctr.db.ListIntegrationsForDefinition(definition.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error reading integrations from database: %v", err))
+			continue
+		}
+
+		for _, integration := range integrations {
+			// Integrations are pinned to versions that may not use OAuth
+			pinned, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+			if err != nil || pinned.OAuth == nil {
+				continue
+			}
+			if !integration.Configuration.NeedsOAuthRefresh(*pinned.OAuth, now.Add(oauthRefreshWindow)) {
+				continue
+			}
+
+			err = ctr.refreshOAuthToken(integration.WithDefinition(pinned))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Error refreshing token of integration %s: %v", integration.ID, err))
+				continue
+			}
+			refreshed++
+		}
+	}
+
+	return refreshed, errs
+}
+
+func (ctr *Controller) refreshOAuthToken(integration model.Integration) error {
+
+	provider, client, err := ctr.oauthProvider(integration)
+	if err != nil {
+		return err
+	}
+
+	refreshToken, _ := integration.Configuration[provider.RefreshTokenField].(string)
+	token, err := ctr.oauthClient().Refresh(provider, client, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctr.storeOAuthToken(integration, provider, token, ctr.principalID())
+	return err
+}
+
+// The principal is the user who authorized, or empty for background refreshes
+func (ctr *Controller) storeOAuthToken(integration model.Integration, provider model.OAuthProvider, token model.OAuthToken, principalID string) (model.Integration, error) {
+
+	var result model.Integration
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return result, fmt.Errorf("Error reading definition from database: %v", err)
+	}
+
+	variables, err := ctr.db.ListWorkspaceVariables(integration.WorkspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+
+	integration.Configuration = integration.Configuration.WithOAuthToken(provider, token, time.Now())
+	err = integration.Configuration.ValidateWithVariables(definition.ConfigurationSchema, variables)
+	if err != nil {
+		return result, fmt.Errorf("Invalid configuration with the new token: %v", err)
+	}
+
+	event := model.NewIntegrationEvent(model.EventIntegrationUpdated, integration, definition.ConfigurationSchema, principalID, time.Now())
+	integration, err = ctr.db.UpdateIntegration(integration, event)
+	if err != nil {
+		return result, fmt.Errorf("Error updating integration in database: %v", err)
+	}
+	ctr.publish(event)
+
+	return integration.WithDefinition(definition).Masked(), nil
+}
+
+// The OAuth provider of the pinned definition of the integration, with its client credentials
+func (ctr *Controller) oauthProvider(integration model.Integration) (model.OAuthProvider, model.OAuthClient, error) {
+
+	var provider model.OAuthProvider
+	var client model.OAuthClient
+
+	if ctr.Options.OAuthRedirectURL == "" || ctr.Options.OAuthClients == nil {
+		return provider, client, errors.New("OAuth is not enabled")
+	}
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return provider, client, fmt.Errorf("Error reading definition from database: %v", err)
+	}
+	if definition.OAuth == nil {
+		return provider, client, fmt.Errorf("Definition %s version %s doesn't use OAuth", definition.Name, definition.Version)
+	}
+	provider = *definition.OAuth
+
+	client, ok := ctr.Options.OAuthClients(provider.ClientCredentials)
+	if !ok {
+		return provider, client, fmt.Errorf("Client credentials %s are not configured", provider.ClientCredentials)
+	}
+
+	return provider, client, nil
+}
+
+func (ctr *Controller) oauthClient() *oauth.Client {
+	if ctr.Options.OAuth != nil {
+		return ctr.Options.OAuth
+	}
+	return oauth.NewClient(30 * time.Second)
+}
@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Organizations are created by Super Admins, e.g. when onboarding a customer, with their first admins.
+// Admins manage the organization, its members read it
+func (ctr *Controller) CreateOrganization(name string, members []model.OrganizationMember) (model.Organization, error) {
+
+	var organization model.Organization
+
+	if ctr.User.AppRole != "Super Admin" {
+		return organization, errors.New("Only Super Admins can create organizations")
+	}
+
+	resolved := []model.OrganizationMember{}
+	for _, member := range members {
+		principal, err := ctr.resolveUser(member.Principal, member.Email)
+		if err != nil {
+			return organization, err
+		}
+		resolved = append(resolved, model.OrganizationMember{ Principal: principal, Role: member.Role })
+	}
+
+	organization, err := model.NewOrganization(name, resolved, ctr.User.ID, time.Now())
+	if err != nil {
+		return organization, fmt.Errorf("Error creating organization: %v", err)
+	}
+
+	organization, err = ctr.db.InsertOrganization(organization)
+	if err != nil {
+		return organization, fmt.Errorf("Error inserting organization into database: %v", err)
+	}
+
+	return ctr.withOrganizationEmails(organization), nil
+}
+
+// Organizations of the user. Super Admins list every organization
+func (ctr *Controller) ListOrganizations() ([]model.Organization, error) {
+
+	var organizations []model.Organization
+	var err error
+
+	if ctr.User.AppRole == "Super Admin" {
+		organizations, err = ctr.db.ListOrganizations()
+	} else {
+		organizations, err = ctr.db.ListOrganizationsForMember(ctr.principal())
+	}
+	if err != nil {
+		return organizations, fmt.Errorf("Error reading organizations from database: %v", err)
+	}
+
+	for idx, organization := range organizations {
+		organizations[idx] = ctr.withOrganizationEmails(organization)
+	}
+
+	return organizations, nil
+}
+
+func (ctr *Controller) ReadOrganization(id string) (model.Organization, error) {
+
+	var result model.Organization
+
+	organization, err := ctr.db.GetOrganizationByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading organization from database: %v", err)
+	}
+	if organization.RoleOf(ctr.principal()) == "" && ctr.User.AppRole != "Super Admin" {
+		return result, errors.New("User does not have permission to view organization")
+	}
+
+	return ctr.withOrganizationEmails(organization), nil
+}
+
+func (ctr *Controller) RenameOrganization(id string, name string) (model.Organization, error) {
+
+	var result model.Organization
+
+	organization, err := ctr.getAdministeredOrganization(id)
+	if err != nil {
+		return result, err
+	}
+
+	organization.Name = name
+	organization.UpdatedAt = time.Now()
+	err = organization.Validate()
+	if err != nil {
+		return result, fmt.Errorf("Invalid organization: %v", err)
+	}
+
+	organization, err = ctr.db.UpdateOrganization(organization)
+	if err != nil {
+		return result, fmt.Errorf("Error updating organization in database: %v", err)
+	}
+
+	return ctr.withOrganizationEmails(organization), nil
+}
+
+// Organizations can only be deleted by Super Admins, once they own no workspace, group nor template
+func (ctr *Controller) DeleteOrganization(id string) (model.Organization, error) {
+
+	var result model.Organization
+
+	if ctr.User.AppRole != "Super Admin" {
+		return result, errors.New("Only Super Admins can delete organizations")
+	}
+
+	organization, err := ctr.db.DeleteOrganizationByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error deleting organization from database: %v", err)
+	}
+
+	return ctr.withOrganizationEmails(organization), nil
+}
+
+// Adds the member to the organization, or changes their role. Members may be given by email
+func (ctr *Controller) SetOrganizationMember(id string, member model.OrganizationMember) (model.Organization, error) {
+
+	var result model.Organization
+
+	organization, err := ctr.getAdministeredOrganization(id)
+	if err != nil {
+		return result, err
+	}
+
+	principal, err := ctr.resolveUser(member.Principal, member.Email)
+	if err != nil {
+		return result, err
+	}
+
+	organization = organization.WithMember(model.OrganizationMember{ Principal: principal, Role: member.Role })
+	if !organization.Administered() {
+		return result, errors.New("Organizations need at least one admin")
+	}
+
+	return ctr.updateOrganizationMembers(organization)
+}
+
+// Removes the member from the organization, with their memberships of its groups and their permissions in
+// its workspaces. Admins remove anyone, members remove themselves
+func (ctr *Controller) RemoveOrganizationMember(id string, principal string) (model.Organization, error) {
+
+	var result model.Organization
+
+	organization, err := ctr.db.GetOrganizationByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading organization from database: %v", err)
+	}
+	if principal != ctr.principal() && !organization.AdministeredBy(ctr.principal()) && ctr.User.AppRole != "Super Admin" {
+		return result, errors.New("User does not have permission to manage organization")
+	}
+	if organization.RoleOf(principal) == "" {
+		return result, fmt.Errorf("%s is not a member of organization %s", principal, organization.Name)
+	}
+
+	organization = organization.WithoutMember(principal)
+	if !organization.Administered() {
+		return result, errors.New("Organizations need at least one admin")
+	}
+
+	result, err = ctr.updateOrganizationMembers(organization)
+	if err != nil {
+		return result, err
+	}
+
+	return result, ctr.removeFromOrganization(organization, principal)
+}
+
+// Removes the former member from the groups and the permissions of the workspaces of the organization,
+// and closes their live event streams to its workspaces
+func (ctr *Controller) removeFromOrganization(organization model.Organization, principal string) error {
+
+	now := time.Now()
+
+	groups, err := ctr.db.ListGroupsForMember(principal)
+	if err != nil {
+		return fmt.Errorf("Error reading groups from database: %v", err)
+	}
+	for _, group := range groups {
+		if group.OrganizationID != organization.ID {
+			continue
+		}
+		group = group.WithoutMember(principal)
+		group.UpdatedAt = now
+		_, err := ctr.db.UpdateGroup(group)
+		if err != nil {
+			return fmt.Errorf("Error removing member from group %s: %v", group.ID, err)
+		}
+	}
+
+	workspaces, err := ctr.db.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("Error reading workspaces from database: %v", err)
+	}
+	for _, workspace := range workspaces {
+		if workspace.OrganizationID != organization.ID {
+			continue
+		}
+
+		permissions := []model.WorkspacePermission{}
+		for _, perm := range workspace.Permissions {
+			if perm.Principal != principal {
+				permissions = append(permissions, perm)
+			}
+		}
+		if len(permissions) != len(workspace.Permissions) {
+			workspace.Permissions = permissions
+			workspace.UpdatedAt = now
+
+			event := model.NewEvent(model.EventWorkspacePermissionsChanged, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), now)
+			_, err := ctr.db.UpdateWorkspace(workspace, event)
+			if err != nil {
+				return fmt.Errorf("Error removing the permissions of the member from workspace %s: %v", workspace.ID, err)
+			}
+			ctr.publish(event)
+		}
+
+		if ctr.Options.Events != nil {
+			ctr.Options.Events.Revoke(workspace.ID, principal)
+		}
+	}
+
+	return nil
+}
+
+func (ctr *Controller) updateOrganizationMembers(organization model.Organization) (model.Organization, error) {
+
+	var result model.Organization
+
+	organization.UpdatedAt = time.Now()
+	err := organization.Validate()
+	if err != nil {
+		return result, fmt.Errorf("Invalid organization: %v", err)
+	}
+
+	organization, err = ctr.db.UpdateOrganization(organization)
+	if err != nil {
+		return result, fmt.Errorf("Error updating organization in database: %v", err)
+	}
+
+	return ctr.withOrganizationEmails(organization), nil
+}
+
+func (ctr *Controller) getAdministeredOrganization(id string) (model.Organization, error) {
+
+	var result model.Organization
+
+	organization, err := ctr.db.GetOrganizationByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading organization from database: %v", err)
+	}
+	if !organization.AdministeredBy(ctr.principal()) && ctr.User.AppRole != "Super Admin" {
+		return result, errors.New("User does not have permission to manage organization")
+	}
+
+	return organization, nil
+}
+
+// Copy of the organization with the current emails of its members, for display
+func (ctr *Controller) withOrganizationEmails(organization model.Organization) model.Organization {
+
+	members := []model.OrganizationMember{}
+	for _, member := range organization.Members {
+		member.Email, _ = ctr.principalName(member.Principal)
+		members = append(members, member)
+	}
+	organization.Members = members
+
+	return organization
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Records a run of an integration or of a connection. Only for connector runtimes
+func (ctr *Controller) ReportRun(workspaceID string, integrationID string, connectionID string, report model.RunReport) (model.Run, error) {
+
+	var run model.Run
+
+	if !ctr.isTrustedRuntime() {
+		return run, errors.New("Only Runtimes, Client Apps and Super Admins can report runs")
+	}
+	if (integrationID == "") == (connectionID == "") {
+		return run, errors.New("A run belongs to either an integration or a connection")
+	}
+
+	var err error
+	if connectionID != "" {
+		var connection model.Connection
+		_, connection, err = ctr.getWorkspaceConnection(workspaceID, connectionID)
+		if err != nil {
+			return run, err
+		}
+		run, err = model.NewConnectionRun(connection, report, ctr.User.ID)
+	} else {
+		var integration model.Integration
+		_, integration, err = ctr.getWorkspaceIntegration(workspaceID, integrationID)
+		if err != nil {
+			return run, err
+		}
+		run, err = model.NewIntegrationRun(integration, report, ctr.User.ID)
+	}
+	if err != nil {
+		return run, fmt.Errorf("Error creating run: %v", err)
+	}
+
+	events := runEvents(model.Run{}, run, ctr.User.ID)
+	run, err = ctr.db.InsertRun(run, events...)
+	if err != nil {
+		return run, fmt.Errorf("Error inserting run into database: %v", err)
+	}
+	for _, event := range events {
+		ctr.publish(event.WithResourceID(run.ID))
+	}
+
+	return run, nil
+}
+
+// Reports the progress or the outcome of a running run. Only for connector runtimes
+func (ctr *Controller) UpdateRun(workspaceID string, id string, report model.RunReport) (model.Run, error) {
+
+	var result model.Run
+
+	if !ctr.isTrustedRuntime() {
+		return result, errors.New("Only Runtimes, Client Apps and Super Admins can report runs")
+	}
+
+	_, run, err := ctr.getWorkspaceRun(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+
+	previous := run
+	run, err = run.Apply(report)
+	if err != nil {
+		return result, fmt.Errorf("Error updating run: %v", err)
+	}
+
+	events := runEvents(previous, run, ctr.User.ID)
+	run, err = ctr.db.UpdateRun(run, events...)
+	if err != nil {
+		return run, fmt.Errorf("Error updating run in database: %v", err)
+	}
+	ctr.publish(events...)
+
+	return run, nil
+}
+
+// Lists the runs of the workspace matching the filter, most recent first
+func (ctr *Controller) ListRuns(workspaceID string, filter model.RunFilter) ([]model.Run, error) {
+
+	var runs []model.Run
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return runs, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ViewableBy(ctr.principals()...) && !ctr.isTrustedRuntime() {
+		return runs, errors.New("User does not have permission to view workspace")
+	}
+
+	filter.WorkspaceID = workspace.ID
+	runs, err = ctr.db.ListRuns(filter)
+	if err != nil {
+		return runs, fmt.Errorf("Error reading runs from database: %v", err)
+	}
+
+	return runs, nil
+}
+
+func (ctr *Controller) ReadRun(workspaceID string, id string) (model.Run, error) {
+
+	var result model.Run
+
+	workspace, run, err := ctr.getWorkspaceRun(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) && !ctr.isTrustedRuntime() {
+		return result, errors.New("User does not have permission to view workspace")
+	}
+
+	return run, nil
+}
+
+// Event of a run that just finished, if it did
+func runEvents(previous model.Run, run model.Run, principalID string) []model.Event {
+	if !run.Finished() || previous.Finished() {
+		return []model.Event{}
+	}
+	return []model.Event{ model.NewEvent(model.EventRunFinished, run.WorkspaceID, "run", run.ID, run, principalID, time.Now()) }
+}
+
+// Attaches the health derived from the runs of the integration.
+// Failing to read the runs is not an error, the integration is returned as is
+func (ctr *Controller) withHealth(integration model.Integration) model.Integration {
+
+	runs, err := ctr.db.ListRuns(model.RunFilter{ WorkspaceID: integration.WorkspaceID, IntegrationID: integration.ID })
+	if err != nil {
+		return integration
+	}
+
+	return integration.WithHealth(runs, time.Now())
+}
+
+// Reads a run, making sure it belongs to the workspace
+func (ctr *Controller) getWorkspaceRun(workspaceID string, id string) (model.Workspace, model.Run, error) {
+
+	var run model.Run
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return workspace, run, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+
+	run, err = ctr.db.GetRunByID(id)
+	if err != nil {
+		return workspace, run, fmt.Errorf("Error reading run from database: %v", err)
+	}
+	if run.WorkspaceID != workspace.ID {
+		return workspace, run, fmt.Errorf("Run %s not found in workspace %s", id, workspaceID)
+	}
+
+	return workspace, run, nil
+}
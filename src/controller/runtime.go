@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Scope the token of a runtime needs to read runtime configurations
+const RuntimeConfigScope = "read:runtime_config"
+
+// How long workers can cache a runtime config bundle
+const runtimeBundleTTL = 5 * time.Minute
+
+// Only Runtimes and Client Apps with the runtime config scope read configurations with their secrets,
+// as runtime configs or Singer configs. Super Admins are not allowed
+func (ctr *Controller) canReadRuntimeConfig() bool {
+	isRuntime := ctr.User.AppRole == "Runtime" || ctr.User.AppRole == "Client App"
+	return isRuntime && ctr.HasScope(RuntimeConfigScope)
+}
+
+// Returns the effective configuration of the integration: defaults applied, secrets in clear
+// and the metadata of its definition attached. Every secret read is audited. With an environment,
+// its overrides and variables apply
+func (ctr *Controller) ResolveRuntimeConfig(workspaceID string, id string, environment string) (model.RuntimeConfig, error) {
+
+	var result model.RuntimeConfig
+
+	if !ctr.canReadRuntimeConfig() {
+		return result, fmt.Errorf("Only Runtimes and Client Apps with the %s scope can read runtime configurations", RuntimeConfigScope)
+	}
+
+	_, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return result, fmt.Errorf("Error reading definition from database: %v", err)
+	}
+
+	var env *model.Environment
+	if len(environment) > 0 {
+		e, err := ctr.db.GetEnvironment(integration.WorkspaceID, environment)
+		if err != nil {
+			return result, fmt.Errorf("Error reading environment from database: %v", err)
+		}
+		integration.Configuration = e.Configuration(integration)
+		env = &e
+	}
+
+	config := model.NewRuntimeConfig(integration, definition, time.Now())
+	config.Environment = environment
+	config.Configuration, err = ctr.resolveVariables(integration, config.Configuration, env, "secret.read", config.ResolvedAt)
+	if err != nil {
+		return result, err
+	}
+
+	// Secrets are only released once their reads are recorded
+	entries := []model.AuditEntry{}
+	for _, path := range config.Configuration.SecretPaths(definition.ConfigurationSchema) {
+		entries = append(entries, model.NewAuditEntry(integration.WorkspaceID, "secret.read", "integration", integration.ID, path, ctr.User.ID, config.ResolvedAt))
+	}
+	if len(entries) > 0 {
+		_, err = ctr.db.InsertAuditEntries(entries)
+		if err != nil {
+			return result, fmt.Errorf("Error inserting audit entries into database: %v", err)
+		}
+	}
+
+	return config, nil
+}
+
+// Same as ResolveRuntimeConfig, encrypted and signed so workers can cache it until it expires
+func (ctr *Controller) RuntimeConfigBundle(workspaceID string, id string, environment string) (model.RuntimeConfigBundle, error) {
+
+	var bundle model.RuntimeConfigBundle
+
+	if len(ctr.Options.RuntimeBundleKey) == 0 || len(ctr.Options.RuntimeBundleEncryptionKey) == 0 {
+		return bundle, errors.New("Runtime config bundles are not enabled")
+	}
+
+	config, err := ctr.ResolveRuntimeConfig(workspaceID, id, environment)
+	if err != nil {
+		return bundle, err
+	}
+
+	bundle, err = model.SealRuntimeConfig(config, ctr.Options.RuntimeBundleKey, ctr.Options.RuntimeBundleEncryptionKey, config.ResolvedAt.Add(runtimeBundleTTL))
+	if err != nil {
+		return bundle, fmt.Errorf("Error sealing runtime config: %v", err)
+	}
+
+	return bundle, nil
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Longest window the scheduler can ask for at once
+const maxDueWindow = 24 * time.Hour
+
+// Returns the next n runs of the connection after the given time
+func (ctr *Controller) NextConnectionRuns(workspaceID string, id string, after time.Time, n int) ([]time.Time, error) {
+
+	var runs []time.Time
+
+	connection, err := ctr.ReadConnection(workspaceID, id)
+	if err != nil {
+		return runs, err
+	}
+
+	runs, err = connection.Schedule.NextRuns(after, n)
+	if err != nil {
+		return runs, fmt.Errorf("Error computing next runs: %v", err)
+	}
+
+	return runs, nil
+}
+
+// Lists the enabled connections of every workspace with a run within [from, to), sorted by run time.
+// Only for the scheduler, which runs as a Client App
+func (ctr *Controller) DueConnections(from time.Time, to time.Time) ([]model.DueConnection, error) {
+
+	due := []model.DueConnection{}
+
+	if !ctr.isTrustedRuntime() {
+		return due, errors.New("Only Runtimes, Client Apps and Super Admins can query due connections")
+	}
+	if !from.Before(to) {
+		return due, errors.New("The start of the window must be before its end")
+	}
+	if to.Sub(from) > maxDueWindow {
+		return due, fmt.Errorf("The window can't be longer than %v", maxDueWindow)
+	}
+
+	connections, err := ctr.db.ListConnections()
+	if err != nil {
+		return due, fmt.Errorf("Error reading connections from database: %v", err)
+	}
+
+	for _, connection := range connections {
+		if !connection.Enabled {
+			continue
+		}
+		run, ok, err := connection.Schedule.FirstRunBetween(from, to)
+		if err != nil || !ok {
+			continue
+		}
+		due = append(due, model.DueConnection{ ConnectionID: connection.ID, WorkspaceID: connection.WorkspaceID, RunAt: run })
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].RunAt.Before(due[j].RunAt)
+	})
+
+	return due, nil
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+)
+
+// Runtime services authenticate as Runtimes or Client Apps. They and Super Admins run connectors,
+// so they reach the integrations of every workspace. Reading secrets takes canReadRuntimeConfig
+func (ctr *Controller) isTrustedRuntime() bool {
+	switch ctr.User.AppRole {
+	case "Super Admin", "Client App", "Runtime":
+		return true
+	}
+	return false
+}
+
+// Renders an integration as a Singer config, in the environment when set. Secrets are only resolved for
+// the callers allowed to read runtime configurations, through the same audited path. Workspace members get
+// them masked, secret variables included.
+func (ctr *Controller) ExportSingerConfig(workspaceID string, id string, environment string) (model.SingerConfig, error) {
+
+	var config model.SingerConfig
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return config, err
+	}
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return config, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	if ctr.canReadRuntimeConfig() {
+		runtime, err := ctr.ResolveRuntimeConfig(workspace.ID, integration.ID, environment)
+		if err != nil {
+			return config, err
+		}
+		config, err = runtime.Configuration.SingerConfig(definition.ConfigurationSchema, true)
+		if err != nil {
+			return config, fmt.Errorf("Error exporting Singer config: %v", err)
+		}
+		return config, nil
+	}
+
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return config, errors.New("User does not have permission to view workspace")
+	}
+
+	variables, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return config, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	if len(environment) > 0 {
+		env, err := ctr.db.GetEnvironment(workspace.ID, environment)
+		if err != nil {
+			return config, fmt.Errorf("Error reading environment from database: %v", err)
+		}
+		integration.Configuration = env.Configuration(integration)
+		variables = env.EffectiveVariables(variables)
+	}
+	for idx, variable := range variables {
+		variables[idx] = variable.Masked()
+	}
+	resolved, err := integration.Configuration.ResolveVariables(variables)
+	if err != nil {
+		return config, fmt.Errorf("Error resolving variables: %v", err)
+	}
+
+	config, err = resolved.SingerConfig(definition.ConfigurationSchema, false)
+	if err != nil {
+		return config, fmt.Errorf("Error exporting Singer config: %v", err)
+	}
+
+	return config, nil
+}
+
+// Renders the stream catalog of an integration as a Singer catalog
+func (ctr *Controller) ExportSingerCatalog(workspaceID string, id string) (model.SingerCatalog, error) {
+
+	var catalog model.SingerCatalog
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return catalog, err
+	}
+	if !ctr.isTrustedRuntime() && !workspace.ViewableBy(ctr.principals()...) {
+		return catalog, errors.New("User does not have permission to view workspace")
+	}
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return catalog, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+
+	return integration.SingerCatalog(definition), nil
+}
+
+// Creates a draft definition with the schema inferred from a sample Singer config.
+// Returns the values that couldn't be typed as warnings.
+func (ctr *Controller) ImportSingerDefinition(name string, t string, sample map[string]interface{}, scope model.DefinitionScope) (model.IntegrationDefinition, []string, error) {
+
+	var definition model.IntegrationDefinition
+
+	// Authorization
+	err := ctr.canManageDefinitions(scope)
+	if err != nil {
+		return definition, nil, err
+	}
+
+	schema, warnings := model.InferSingerSchema(sample)
+	definition, err = model.NewIntegrationDefinition(name, t, schema)
+	if err != nil {
+		return definition, warnings, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+	definition, err = definition.WithScope(scope)
+	if err != nil {
+		return definition, warnings, fmt.Errorf("Error creating integration definition: %v", err)
+	}
+
+	definition, err = ctr.db.InsertIntegrationDefinition(definition)
+	if err != nil {
+		return definition, warnings, fmt.Errorf("Error inserting integration definition into database: %v", err)
+	}
+
+	return definition, warnings, nil
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+func (ctr *Controller) ReadConnectorState(workspaceID string, id string) (model.ConnectorState, error) {
+
+	var result model.ConnectorState
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) && !ctr.isTrustedRuntime() {
+		return result, errors.New("User does not have permission to view workspace")
+	}
+
+	state, err := ctr.db.GetConnectorState(integration)
+	if err != nil {
+		return result, fmt.Errorf("Error reading connector state from database: %v", err)
+	}
+
+	return state, nil
+}
+
+// Replaces the whole state, if it is still at the expected version. Only for connector runtimes
+func (ctr *Controller) WriteConnectorState(workspaceID string, id string, global map[string]interface{}, streams map[string]map[string]interface{}, expectedVersion int64) (model.ConnectorState, error) {
+	return ctr.swapConnectorState(workspaceID, id, expectedVersion, func(state model.ConnectorState) model.ConnectorState {
+		return state.WithState(global, streams)
+	})
+}
+
+// Replaces the state of a single stream, if the state is still at the expected version. Only for connector runtimes
+func (ctr *Controller) WriteStreamState(workspaceID string, id string, stream string, value map[string]interface{}, expectedVersion int64) (model.ConnectorState, error) {
+	if value == nil {
+		value = map[string]interface{}{}
+	}
+	return ctr.swapConnectorState(workspaceID, id, expectedVersion, func(state model.ConnectorState) model.ConnectorState {
+		return state.WithStream(stream, value)
+	})
+}
+
+// Clears the state of the given streams, or the whole state when none is given.
+// The replaced state is kept in the reset history
+func (ctr *Controller) ResetConnectorState(workspaceID string, id string, streams []string) (model.StateReset, error) {
+
+	var result model.StateReset
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to reset states in this workspace")
+	}
+
+	previous, err := ctr.db.GetConnectorState(integration)
+	if err != nil {
+		return result, fmt.Errorf("Error reading connector state from database: %v", err)
+	}
+
+	state := previous.Reset(streams)
+	state.UpdatedAt = time.Now()
+	state.UpdatedBy = ctr.User.ID
+	_, err = ctr.db.SwapConnectorState(state, previous.Version)
+	if err != nil {
+		return result, fmt.Errorf("Error resetting connector state: %w", err)
+	}
+
+	if streams == nil {
+		streams = []string{}
+	}
+	reset := model.StateReset{ IntegrationID: integration.ID, WorkspaceID: workspace.ID, Streams: streams, Previous: previous, ResetAt: state.UpdatedAt, ResetBy: ctr.User.ID }
+	reset, err = ctr.db.InsertStateReset(reset)
+	if err != nil {
+		return reset, fmt.Errorf("Error inserting state reset into database: %v", err)
+	}
+
+	return reset, nil
+}
+
+func (ctr *Controller) ListStateResets(workspaceID string, id string) ([]model.StateReset, error) {
+
+	var resets []model.StateReset
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return resets, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) && !ctr.isTrustedRuntime() {
+		return resets, errors.New("User does not have permission to view workspace")
+	}
+
+	resets, err = ctr.db.ListStateResets(integration.ID)
+	if err != nil {
+		return resets, fmt.Errorf("Error reading state resets from database: %v", err)
+	}
+
+	return resets, nil
+}
+
+func (ctr *Controller) swapConnectorState(workspaceID string, id string, expectedVersion int64, edit func(model.ConnectorState) model.ConnectorState) (model.ConnectorState, error) {
+
+	var result model.ConnectorState
+
+	if !ctr.isTrustedRuntime() {
+		return result, errors.New("Only Runtimes, Client Apps and Super Admins can write connector states")
+	}
+
+	_, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+
+	current, err := ctr.db.GetConnectorState(integration)
+	if err != nil {
+		return result, fmt.Errorf("Error reading connector state from database: %v", err)
+	}
+
+	state := edit(current)
+	state.UpdatedAt = time.Now()
+	state.UpdatedBy = ctr.User.ID
+	err = state.Validate(ctr.Options.StateSizeLimit)
+	if err != nil {
+		return result, fmt.Errorf("Invalid state: %v", err)
+	}
+
+	// The database checks the version again, the state may have changed since it was read
+	state, err = ctr.db.SwapConnectorState(state, expectedVersion)
+	if err != nil {
+		return result, fmt.Errorf("Error writing connector state: %w", err)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+func (ctr *Controller) ListStreamSelection(workspaceID string, id string) ([]model.StreamSelection, error) {
+
+	var selection []model.StreamSelection
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return selection, err
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return selection, errors.New("User does not have permission to view workspace")
+	}
+
+	return integration.Streams, nil
+}
+
+// Replaces the whole stream selection of the integration
+func (ctr *Controller) SetStreamSelection(workspaceID string, id string, selection []model.StreamSelection) ([]model.StreamSelection, error) {
+
+	return ctr.editStreamSelection(workspaceID, id, func(current []model.StreamSelection) ([]model.StreamSelection, error) {
+		return selection, nil
+	})
+}
+
+// Adds a stream to the selection, or replaces how it is synced if already selected
+func (ctr *Controller) SelectStream(workspaceID string, id string, stream model.StreamSelection) ([]model.StreamSelection, error) {
+
+	return ctr.editStreamSelection(workspaceID, id, func(current []model.StreamSelection) ([]model.StreamSelection, error) {
+		selection := []model.StreamSelection{}
+		replaced := false
+		for _, s := range current {
+			if s.Stream == stream.Stream {
+				s = stream
+				replaced = true
+			}
+			selection = append(selection, s)
+		}
+		if !replaced {
+			selection = append(selection, stream)
+		}
+		return selection, nil
+	})
+}
+
+// Removes a stream from the selection. Streams that aren't selected are an error, so clients can
+// tell a change from a no-op
+func (ctr *Controller) UnselectStream(workspaceID string, id string, stream string) ([]model.StreamSelection, error) {
+
+	return ctr.editStreamSelection(workspaceID, id, func(current []model.StreamSelection) ([]model.StreamSelection, error) {
+		selection := []model.StreamSelection{}
+		for _, s := range current {
+			if s.Stream != stream {
+				selection = append(selection, s)
+			}
+		}
+		if len(selection) == len(current) {
+			return nil, fmt.Errorf("Stream %s is not selected", stream)
+		}
+		return selection, nil
+	})
+}
+
+// Applies a change to the stream selection, validated against the pinned definition version
+func (ctr *Controller) editStreamSelection(workspaceID string, id string, edit func([]model.StreamSelection) ([]model.StreamSelection, error)) ([]model.StreamSelection, error) {
+
+	var selection []model.StreamSelection
+
+	workspace, integration, err := ctr.getWorkspaceIntegration(workspaceID, id)
+	if err != nil {
+		return selection, err
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return selection, errors.New("User does not have permission to edit integrations in this workspace")
+	}
+
+	definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+	if err != nil {
+		return selection, fmt.Errorf("Error reading integration definition from database: %v", err)
+	}
+	if definition.Type != "source" {
+		return selection, errors.New("Only source integrations have streams")
+	}
+
+	edited, err := edit(integration.Streams)
+	if err != nil {
+		return selection, err
+	}
+	integration, err = integration.SelectStreams(definition, edited)
+	if err != nil {
+		return selection, err
+	}
+
+	event := model.NewIntegrationEvent(model.EventIntegrationUpdated, integration, definition.ConfigurationSchema, ctr.principalID(), time.Now())
+	integration, err = ctr.db.UpdateIntegration(integration, event)
+	if err != nil {
+		return selection, fmt.Errorf("Error updating integration in database: %v", err)
+	}
+	ctr.publish(event)
+
+	return integration.Streams, nil
+}
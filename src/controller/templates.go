@@ -0,0 +1,372 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Integration to insert into a new workspace. Connections reference it by ref until it is inserted
+type pendingIntegration struct {
+	ref string
+	integration model.Integration
+	definition model.IntegrationDefinition
+}
+
+// Creates a workspace in the organization of the source workspace, with copies of its variables,
+// integrations and connections. Secrets aren't copied, secret variables are left empty and the connections
+// are disabled, until the credentials of the new workspace are filled in. Permissions aren't copied
+// either: the user owns the new workspace
+func (ctr *Controller) CloneWorkspace(id string, name string) (model.Workspace, error) {
+
+	var result model.Workspace
+
+	source, err := ctr.db.GetWorkspaceByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !source.ViewableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to view workspace")
+	}
+
+	variables, err := ctr.db.ListWorkspaceVariables(source.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	for idx, variable := range variables {
+		if variable.Secret {
+			variables[idx].Value = ""
+		}
+	}
+
+	integrations, err := ctr.db.ListIntegrationsForWorkspace(source.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+
+	// Everything is checked before the workspace is created
+	target := model.Workspace{ OrganizationID: source.OrganizationID }
+	pending := []pendingIntegration{}
+	cloned := map[string]bool{}
+	for _, integration := range integrations {
+		definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+		if err != nil {
+			return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+		}
+		if !definition.Scope.Includes(target) {
+			return result, fmt.Errorf("Can't clone integration %s: definition %s is private to the workspace", integration.Name, definition.ID)
+		}
+		pending = append(pending, pendingIntegration{ integration.ID, integration.Clone("", definition), definition })
+		cloned[integration.ID] = true
+	}
+
+	connections, err := ctr.db.ListConnectionsForWorkspace(source.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading connections from database: %v", err)
+	}
+	clones := []model.Connection{}
+	for _, connection := range connections {
+		if !cloned[connection.SourceID] || !cloned[connection.DestinationID] {
+			return result, fmt.Errorf("Can't clone connection %s: its ends are missing", connection.Name)
+		}
+		clones = append(clones, connection.Clone("", connection.SourceID, connection.DestinationID))
+	}
+
+	workspace, err := ctr.CreateWorkspace(source.OrganizationID, name, nil)
+	if err != nil {
+		return result, err
+	}
+
+	return workspace, ctr.populateWorkspace(workspace, variables, pending, clones)
+}
+
+// Templates are created by any user, or by the admins and members of an organization, for its members.
+// Their creator, the admins of their organization and Super Admins manage them
+func (ctr *Controller) CreateWorkspaceTemplate(organizationID string, name string, description string, parameters []model.TemplateParameter, integrations []model.TemplateIntegration, connections []model.TemplateConnection) (model.WorkspaceTemplate, error) {
+
+	var template model.WorkspaceTemplate
+
+	if ctr.principal() == "" {
+		return template, errors.New("Only users can create templates")
+	}
+	if organizationID != "" {
+		organization, err := ctr.db.GetOrganizationByID(organizationID)
+		if err != nil {
+			return template, fmt.Errorf("Error reading organization from database: %v", err)
+		}
+		role := organization.RoleOf(ctr.principal())
+		if role != model.OrganizationAdmin && role != model.OrganizationMemberRole && ctr.User.AppRole != "Super Admin" {
+			return template, fmt.Errorf("User does not have permission to create templates in organization %s", organization.Name)
+		}
+	}
+
+	template, err := model.NewWorkspaceTemplate(organizationID, name, description, parameters, integrations, connections, ctr.User.ID, time.Now())
+	if err != nil {
+		return template, fmt.Errorf("Error creating template: %v", err)
+	}
+	err = ctr.checkTemplateDefinitions(template)
+	if err != nil {
+		return template, err
+	}
+
+	template, err = ctr.db.InsertWorkspaceTemplate(template)
+	if err != nil {
+		return template, fmt.Errorf("Error inserting template into database: %v", err)
+	}
+
+	return template, nil
+}
+
+// Templates the user can instantiate
+func (ctr *Controller) ListWorkspaceTemplates() ([]model.WorkspaceTemplate, error) {
+
+	results := []model.WorkspaceTemplate{}
+
+	templates, err := ctr.db.ListWorkspaceTemplates()
+	if err != nil {
+		return results, fmt.Errorf("Error reading templates from database: %v", err)
+	}
+
+	for _, template := range templates {
+		if ctr.canUseTemplate(template) {
+			results = append(results, template)
+		}
+	}
+
+	return results, nil
+}
+
+func (ctr *Controller) ReadWorkspaceTemplate(id string) (model.WorkspaceTemplate, error) {
+
+	var result model.WorkspaceTemplate
+
+	template, err := ctr.db.GetWorkspaceTemplateByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading template from database: %v", err)
+	}
+	if !ctr.canUseTemplate(template) {
+		return result, errors.New("User does not have permission to view template")
+	}
+
+	return template, nil
+}
+
+// Replaces the content of the template. Templates stay in their organization
+func (ctr *Controller) UpdateWorkspaceTemplate(id string, name string, description string, parameters []model.TemplateParameter, integrations []model.TemplateIntegration, connections []model.TemplateConnection) (model.WorkspaceTemplate, error) {
+
+	var result model.WorkspaceTemplate
+
+	current, err := ctr.getManagedTemplate(id)
+	if err != nil {
+		return result, err
+	}
+
+	template, err := model.NewWorkspaceTemplate(current.OrganizationID, name, description, parameters, integrations, connections, current.CreatedBy, time.Now())
+	if err != nil {
+		return result, fmt.Errorf("Invalid template: %v", err)
+	}
+	template.ID = current.ID
+	template.CreatedAt = current.CreatedAt
+	err = ctr.checkTemplateDefinitions(template)
+	if err != nil {
+		return result, err
+	}
+
+	template, err = ctr.db.UpdateWorkspaceTemplate(template)
+	if err != nil {
+		return result, fmt.Errorf("Error updating template in database: %v", err)
+	}
+
+	return template, nil
+}
+
+func (ctr *Controller) DeleteWorkspaceTemplate(id string) (model.WorkspaceTemplate, error) {
+
+	var result model.WorkspaceTemplate
+
+	_, err := ctr.getManagedTemplate(id)
+	if err != nil {
+		return result, err
+	}
+
+	result, err = ctr.db.DeleteWorkspaceTemplateByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error deleting template from database: %v", err)
+	}
+
+	return result, nil
+}
+
+// Creates a workspace from the template, in its organization. The template is rendered with the values
+// and every integration and connection is validated before anything is created, so a missing value or
+// an invalid configuration leaves no half-populated workspace behind
+func (ctr *Controller) InstantiateWorkspaceTemplate(id string, name string, values map[string]interface{}) (model.Workspace, error) {
+
+	var result model.Workspace
+
+	template, err := ctr.ReadWorkspaceTemplate(id)
+	if err != nil {
+		return result, err
+	}
+
+	rendered, err := template.Render(values)
+	if err != nil {
+		return result, fmt.Errorf("Error rendering template: %v", err)
+	}
+
+	target := model.Workspace{ OrganizationID: template.OrganizationID }
+	pending := []pendingIntegration{}
+	ends := map[string]model.Integration{}
+	for _, item := range rendered.Integrations {
+		// Like any new integration, pinned to the latest published version of the definition
+		versions, err := ctr.db.ListIntegrationDefinitionVersions(item.DefinitionID)
+		if err != nil {
+			return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+		}
+		definition, err := model.LatestPublishedVersion(versions)
+		if err != nil {
+			return result, fmt.Errorf("Can't create integration %s: %v", item.Key, err)
+		}
+		if !definition.Scope.Includes(target) {
+			return result, fmt.Errorf("Can't create integration %s: definition %s is private to another %s", item.Key, item.DefinitionID, definition.Scope.Visibility)
+		}
+
+		config := item.Configuration.Normalize(definition.ConfigurationSchema)
+		integration, err := model.NewIntegration(item.Name, "", definition, config, nil)
+		if err != nil {
+			return result, fmt.Errorf("Error creating integration %s: %v", item.Key, err)
+		}
+		if len(item.Streams) > 0 {
+			if definition.Type != "source" {
+				return result, fmt.Errorf("Error creating integration %s: only source integrations have streams", item.Key)
+			}
+			integration, err = integration.SelectStreams(definition, item.Streams)
+			if err != nil {
+				return result, fmt.Errorf("Error creating integration %s: %v", item.Key, err)
+			}
+		}
+
+		// Identified by its key until inserted, so the connections are validated against their ends
+		integration.ID = item.Key
+		ends[item.Key] = integration
+		pending = append(pending, pendingIntegration{ item.Key, integration, definition })
+	}
+
+	connections := []model.Connection{}
+	for _, item := range rendered.Connections {
+		connection, err := model.NewConnection(item.Name, "", ends[item.Source], ends[item.Destination], item.Schedule, item.Namespace, item.Enabled, time.Now())
+		if err != nil {
+			return result, fmt.Errorf("Error creating connection %s: %v", item.Name, err)
+		}
+		connections = append(connections, connection)
+	}
+
+	workspace, err := ctr.CreateWorkspace(template.OrganizationID, name, nil)
+	if err != nil {
+		return result, err
+	}
+
+	return workspace, ctr.populateWorkspace(workspace, nil, pending, connections)
+}
+
+// Inserts the variables and the integrations into the new workspace, then the connections between them
+func (ctr *Controller) populateWorkspace(workspace model.Workspace, variables []model.WorkspaceVariable, integrations []pendingIntegration, connections []model.Connection) error {
+
+	now := time.Now()
+	ids := map[string]string{}
+
+	for _, variable := range variables {
+		variable.WorkspaceID = workspace.ID
+		variable.UpdatedAt = now
+		_, err := ctr.db.SetWorkspaceVariable(variable)
+		if err != nil {
+			return fmt.Errorf("Error inserting variable into database: %v", err)
+		}
+	}
+
+	for _, pending := range integrations {
+		integration := pending.integration
+		integration.ID = ""
+		integration.WorkspaceID = workspace.ID
+
+		event := model.NewIntegrationEvent(model.EventIntegrationCreated, integration, pending.definition.ConfigurationSchema, ctr.principalID(), now)
+		integration, err := ctr.db.InsertIntegration(integration, event)
+		if err != nil {
+			return fmt.Errorf("Error inserting integration into database: %v", err)
+		}
+		ctr.publish(event.WithResourceID(integration.ID))
+		ids[pending.ref] = integration.ID
+	}
+
+	for _, connection := range connections {
+		connection.WorkspaceID = workspace.ID
+		connection.SourceID = ids[connection.SourceID]
+		connection.DestinationID = ids[connection.DestinationID]
+
+		event := model.NewConnectionEvent(model.EventConnectionCreated, connection, ctr.principalID(), now)
+		connection, err := ctr.db.InsertConnection(connection, event)
+		if err != nil {
+			return fmt.Errorf("Error inserting connection into database: %v", err)
+		}
+		ctr.publish(event.WithResourceID(connection.ID))
+	}
+
+	return nil
+}
+
+// Definitions of the template must be available to the workspaces it creates
+func (ctr *Controller) checkTemplateDefinitions(template model.WorkspaceTemplate) error {
+
+	target := model.Workspace{ OrganizationID: template.OrganizationID }
+	for _, integration := range template.Integrations {
+		versions, err := ctr.db.ListIntegrationDefinitionVersions(integration.DefinitionID)
+		if err != nil || len(versions) == 0 {
+			return fmt.Errorf("Integration %s: definition %s not found", integration.Key, integration.DefinitionID)
+		}
+		scope := versions[0].Scope
+		if !scope.Includes(target) || !ctr.canViewDefinitions(scope) {
+			return fmt.Errorf("Integration %s: definition %s is private to another %s", integration.Key, integration.DefinitionID, scope.Visibility)
+		}
+	}
+
+	return nil
+}
+
+func (ctr *Controller) canUseTemplate(template model.WorkspaceTemplate) bool {
+
+	if ctr.User.AppRole == "Super Admin" || template.CreatedBy == ctr.User.ID {
+		return true
+	}
+	if template.OrganizationID == "" {
+		return false
+	}
+
+	organization, err := ctr.db.GetOrganizationByID(template.OrganizationID)
+	if err != nil {
+		return false
+	}
+	role := organization.RoleOf(ctr.principal())
+	return role == model.OrganizationAdmin || role == model.OrganizationMemberRole
+}
+
+func (ctr *Controller) getManagedTemplate(id string) (model.WorkspaceTemplate, error) {
+
+	var result model.WorkspaceTemplate
+
+	template, err := ctr.db.GetWorkspaceTemplateByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading template from database: %v", err)
+	}
+	if ctr.User.AppRole == "Super Admin" || template.CreatedBy == ctr.User.ID {
+		return template, nil
+	}
+	if template.OrganizationID != "" {
+		organization, err := ctr.db.GetOrganizationByID(template.OrganizationID)
+		if err == nil && organization.AdministeredBy(ctr.principal()) {
+			return template, nil
+		}
+	}
+
+	return result, errors.New("User does not have permission to manage template")
+}
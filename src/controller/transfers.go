@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Offers the ownership of the workspace to another user, given by principal or by email. The transfer
+// waits for the new owner to accept it, and replaces any pending one
+func (ctr *Controller) TransferWorkspaceOwnership(id string, principal string, email string) (model.Workspace, error) {
+
+	var result model.Workspace
+
+	workspace, err := ctr.db.GetWorkspaceByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return result, errors.New("Only owners can transfer the ownership of the workspace")
+	}
+
+	to, err := ctr.resolveUser(principal, email)
+	if err != nil {
+		return result, err
+	}
+	_, err = ctr.grantable(to, workspace.OrganizationID)
+	if err != nil {
+		return result, err
+	}
+
+	ttl := ctr.Options.TransferTTL
+	if ttl <= 0 {
+		ttl = model.DefaultTransferTTL
+	}
+	transfer, err := model.NewOwnershipTransfer(ctr.principal(), to, time.Now(), ttl)
+	if err != nil {
+		return result, fmt.Errorf("Error transferring ownership: %v", err)
+	}
+
+	workspace.Transfer = &transfer
+	workspace.UpdatedAt = transfer.CreatedAt
+	event := model.NewEvent(model.EventWorkspaceTransferStarted, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), transfer.CreatedAt)
+	workspace, err = ctr.db.UpdateWorkspace(workspace, event)
+	if err != nil {
+		return result, fmt.Errorf("Error updating workspace in database: %v", err)
+	}
+	ctr.publish(event)
+	err = ctr.auditTransfer(workspace.ID, "workspace.transfer_started", to, transfer.CreatedAt)
+	if err != nil {
+		return result, err
+	}
+
+	return ctr.withNames(workspace)[0], nil
+}
+
+// Workspaces whose ownership is offered to the user, waiting for their confirmation
+func (ctr *Controller) ListWorkspaceTransfers() ([]model.Workspace, error) {
+
+	results := []model.Workspace{}
+
+	workspaces, err := ctr.db.ListWorkspaces()
+	if err != nil {
+		return results, fmt.Errorf("Error reading workspaces from database: %v", err)
+	}
+
+	now := time.Now()
+	for _, workspace := range workspaces {
+		if workspace.Transfer != nil && workspace.Transfer.To == ctr.principal() && !workspace.Transfer.Expired(now) {
+			results = append(results, workspace)
+		}
+	}
+
+	return ctr.withNames(results...), nil
+}
+
+// Confirms the pending transfer on behalf of the new owner. The owner who started it must still own
+// the workspace
+func (ctr *Controller) AcceptWorkspaceTransfer(id string) (model.Workspace, error) {
+
+	var result model.Workspace
+
+	workspace, err := ctr.db.GetWorkspaceByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if workspace.Transfer == nil || workspace.Transfer.To != ctr.principal() {
+		return result, errors.New("No ownership transfer to the user is pending")
+	}
+
+	_, fromID, err := model.ParsePrincipal(workspace.Transfer.From)
+	if err != nil {
+		return result, fmt.Errorf("Invalid ownership transfer: %v", err)
+	}
+	from, err := ctr.root.GetUserById(fromID)
+	if err != nil || !workspace.EditableBy(ctr.principalsOf(from)...) {
+		return result, errors.New("The user who started the transfer doesn't own the workspace anymore")
+	}
+
+	now := time.Now()
+	workspace, err = workspace.AcceptTransfer(ctr.principal(), now)
+	if err != nil {
+		return result, fmt.Errorf("Error accepting ownership transfer: %v", err)
+	}
+
+	event := model.NewEvent(model.EventWorkspacePermissionsChanged, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), now)
+	workspace, err = ctr.db.UpdateWorkspace(workspace, event)
+	if err != nil {
+		return result, fmt.Errorf("Error updating workspace in database: %v", err)
+	}
+	ctr.publish(event)
+	err = ctr.auditTransfer(workspace.ID, "workspace.transfer_accepted", ctr.principal(), now)
+	if err != nil {
+		return result, err
+	}
+
+	return ctr.withNames(workspace)[0], nil
+}
+
+// Drops the pending transfer. The new owner declines it, owners cancel it
+func (ctr *Controller) CancelWorkspaceTransfer(id string) (model.Workspace, error) {
+
+	var result model.Workspace
+
+	workspace, err := ctr.db.GetWorkspaceByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if workspace.Transfer == nil {
+		return result, errors.New("No ownership transfer is pending")
+	}
+	if workspace.Transfer.To != ctr.principal() && !workspace.EditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to cancel the ownership transfer")
+	}
+
+	to := workspace.Transfer.To
+	workspace.Transfer = nil
+	workspace.UpdatedAt = time.Now()
+	event := model.NewEvent(model.EventWorkspaceTransferCancelled, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), workspace.UpdatedAt)
+	workspace, err = ctr.db.UpdateWorkspace(workspace, event)
+	if err != nil {
+		return result, fmt.Errorf("Error updating workspace in database: %v", err)
+	}
+	ctr.publish(event)
+	err = ctr.auditTransfer(workspace.ID, "workspace.transfer_cancelled", to, workspace.UpdatedAt)
+	if err != nil {
+		return result, err
+	}
+
+	return ctr.withNames(workspace)[0], nil
+}
+
+// Records a step of the transfer in the audit log of the workspace, with the new owner as detail
+func (ctr *Controller) auditTransfer(workspaceID string, action string, to string, now time.Time) error {
+	entry := model.NewAuditEntry(workspaceID, action, "workspace", workspaceID, to, ctr.User.ID, now)
+	_, err := ctr.db.InsertAuditEntries([]model.AuditEntry{ entry })
+	if err != nil {
+		return fmt.Errorf("Error inserting audit entries into database: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Variables of the workspace, secret values masked
+func (ctr *Controller) ListWorkspaceVariables(workspaceID string) ([]model.WorkspaceVariable, error) {
+
+	var variables []model.WorkspaceVariable
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return variables, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ViewableBy(ctr.principals()...) {
+		return variables, errors.New("User does not have permission to view workspace")
+	}
+
+	variables, err = ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return variables, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+
+	for idx, variable := range variables {
+		variables[idx] = variable.Masked()
+	}
+
+	return variables, nil
+}
+
+// Creates or replaces the variable. Every integration referencing it is validated with the new value
+// first, so the change can't leave an integration invalid
+func (ctr *Controller) SetWorkspaceVariable(workspaceID string, name string, value string, secret bool) (model.WorkspaceVariable, error) {
+
+	var result model.WorkspaceVariable
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to edit variables in this workspace")
+	}
+
+	variable, err := model.NewWorkspaceVariable(workspace.ID, name, value, secret, time.Now())
+	if err != nil {
+		return result, fmt.Errorf("Invalid variable: %v", err)
+	}
+
+	err = ctr.checkInheritingEnvironments(workspace, func(environment model.Environment) bool {
+		return environment.InheritsVariable(name)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	current, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	variables := []model.WorkspaceVariable{ variable }
+	for _, v := range current {
+		if v.Name != name {
+			variables = append(variables, v)
+		}
+	}
+
+	integrations, err := ctr.db.ListIntegrationsForWorkspace(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+	for _, integration := range integrations {
+		if !integration.Configuration.References(name) {
+			continue
+		}
+		definition, err := ctr.db.GetIntegrationDefinition(integration.DefinitionID, integration.DefinitionVersion)
+		if err != nil {
+			return result, fmt.Errorf("Error reading integration definition from database: %v", err)
+		}
+		err = integration.ValidateWithVariables(definition, variables)
+		if err != nil {
+			return result, fmt.Errorf("Integration %s would be invalid: %v", integration.Name, err)
+		}
+	}
+	err = ctr.checkEnvironments(workspace.ID, integrations, variables)
+	if err != nil {
+		return result, err
+	}
+
+	variable, err = ctr.db.SetWorkspaceVariable(variable)
+	if err != nil {
+		return result, fmt.Errorf("Error updating variable in database: %v", err)
+	}
+
+	return variable.Masked(), nil
+}
+
+// Variables referenced by integrations, or by their overrides in environments, can't be deleted
+func (ctr *Controller) DeleteWorkspaceVariable(workspaceID string, name string) (model.WorkspaceVariable, error) {
+
+	var result model.WorkspaceVariable
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ContentEditableBy(ctr.principals()...) {
+		return result, errors.New("User does not have permission to edit variables in this workspace")
+	}
+
+	err = ctr.checkInheritingEnvironments(workspace, func(environment model.Environment) bool {
+		return environment.InheritsVariable(name)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	integrations, err := ctr.db.ListIntegrationsForWorkspace(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading integrations from database: %v", err)
+	}
+	for _, integration := range integrations {
+		if integration.Configuration.References(name) {
+			return result, fmt.Errorf("Variable %s is referenced by integration %s", name, integration.Name)
+		}
+	}
+
+	// Overrides of environments may reference it too, unless they define their own
+	current, err := ctr.db.ListWorkspaceVariables(workspace.ID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	variables := []model.WorkspaceVariable{}
+	for _, v := range current {
+		if v.Name != name {
+			variables = append(variables, v)
+		}
+	}
+	err = ctr.checkEnvironments(workspace.ID, integrations, variables)
+	if err != nil {
+		return result, err
+	}
+
+	result, err = ctr.db.DeleteWorkspaceVariable(workspace.ID, name)
+	if err != nil {
+		return result, fmt.Errorf("Error deleting variable from database: %v", err)
+	}
+
+	return result.Masked(), nil
+}
+
+// Configuration of the integration with its references resolved, for runtimes and checkers. Reads of
+// secret variables are audited like reads of secret fields, with the given action. In an environment,
+// its variables replace those of the workspace
+func (ctr *Controller) resolveVariables(integration model.Integration, config model.IntegrationConfig, environment *model.Environment, action string, now time.Time) (model.IntegrationConfig, error) {
+
+	references := config.VariableReferences()
+	if len(references) == 0 {
+		return config, nil
+	}
+
+	variables, err := ctr.db.ListWorkspaceVariables(integration.WorkspaceID)
+	if err != nil {
+		return config, fmt.Errorf("Error reading variables from database: %v", err)
+	}
+	if environment != nil {
+		variables = environment.EffectiveVariables(variables)
+	}
+	resolved, err := config.ResolveVariables(variables)
+	if err != nil {
+		return config, fmt.Errorf("Error resolving variables: %v", err)
+	}
+
+	secret := map[string]bool{}
+	for _, variable := range variables {
+		secret[variable.Name] = variable.Secret
+	}
+	entries := []model.AuditEntry{}
+	for _, name := range references {
+		if secret[name] {
+			entries = append(entries, model.NewAuditEntry(integration.WorkspaceID, action, "integration", integration.ID, "var." + name, ctr.User.ID, now))
+		}
+	}
+	if len(entries) > 0 {
+		_, err = ctr.db.InsertAuditEntries(entries)
+		if err != nil {
+			return config, fmt.Errorf("Error inserting audit entries into database: %v", err)
+		}
+	}
+
+	return resolved, nil
+}
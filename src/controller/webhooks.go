@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"smartgrowth-connectors/configapi/webhook"
+	"time"
+)
+
+// Events and deliveries handled per run of the background jobs
+const webhookBatchSize = 100
+
+// Subscriptions of a workspace are managed by its admins. An empty workspace ID stands for
+// the global subscriptions, managed by Super Admins
+func (ctr *Controller) CreateWebhookSubscription(workspaceID string, url string, events []string) (model.WebhookSubscription, error) {
+
+	var subscription model.WebhookSubscription
+
+	err := ctr.canManageWebhooks(workspaceID)
+	if err != nil {
+		return subscription, err
+	}
+
+	subscription, err = model.NewWebhookSubscription(workspaceID, url, events, ctr.User.ID, time.Now(), ctr.webhookClient().Insecure)
+	if err != nil {
+		return subscription, err
+	}
+
+	// The secret is only returned here
+	subscription, err = ctr.db.InsertWebhookSubscription(subscription)
+	if err != nil {
+		return subscription, fmt.Errorf("Error inserting webhook subscription into database: %v", err)
+	}
+
+	return subscription, nil
+}
+
+func (ctr *Controller) ListWebhookSubscriptions(workspaceID string) ([]model.WebhookSubscription, error) {
+
+	var subscriptions []model.WebhookSubscription
+
+	err := ctr.canManageWebhooks(workspaceID)
+	if err != nil {
+		return subscriptions, err
+	}
+
+	subscriptions, err = ctr.db.ListWebhookSubscriptionsForWorkspace(workspaceID)
+	if err != nil {
+		return subscriptions, fmt.Errorf("Error reading webhook subscriptions from database: %v", err)
+	}
+
+	for idx, subscription := range subscriptions {
+		subscriptions[idx] = subscription.WithoutSecret()
+	}
+
+	return subscriptions, nil
+}
+
+func (ctr *Controller) ReadWebhookSubscription(workspaceID string, id string) (model.WebhookSubscription, error) {
+
+	subscription, err := ctr.getWebhookSubscription(workspaceID, id)
+	if err != nil {
+		return subscription, err
+	}
+
+	return subscription.WithoutSecret(), nil
+}
+
+// Inactive subscriptions keep their log but receive no new deliveries
+func (ctr *Controller) UpdateWebhookSubscription(workspaceID string, id string, url string, events []string, active bool) (model.WebhookSubscription, error) {
+
+	var result model.WebhookSubscription
+
+	subscription, err := ctr.getWebhookSubscription(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+
+	subscription.URL = url
+	subscription.Events = events
+	subscription.Active = active
+	err = subscription.Validate(ctr.webhookClient().Insecure)
+	if err != nil {
+		return result, fmt.Errorf("Invalid webhook subscription: %v", err)
+	}
+
+	subscription, err = ctr.db.UpdateWebhookSubscription(subscription)
+	if err != nil {
+		return result, fmt.Errorf("Error updating webhook subscription in database: %v", err)
+	}
+
+	return subscription.WithoutSecret(), nil
+}
+
+// Pending deliveries of deleted subscriptions are abandoned. The log is kept
+func (ctr *Controller) DeleteWebhookSubscription(workspaceID string, id string) (model.WebhookSubscription, error) {
+
+	var result model.WebhookSubscription
+
+	_, err := ctr.getWebhookSubscription(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+
+	deleted, err := ctr.db.DeleteWebhookSubscriptionByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error deleting webhook subscription from database: %v", err)
+	}
+
+	return deleted.WithoutSecret(), nil
+}
+
+// Delivery log of the subscription, most recent first
+func (ctr *Controller) ListWebhookDeliveries(workspaceID string, id string, offset int, limit int) ([]model.WebhookDelivery, error) {
+
+	var deliveries []model.WebhookDelivery
+
+	subscription, err := ctr.getWebhookSubscription(workspaceID, id)
+	if err != nil {
+		return deliveries, err
+	}
+
+	deliveries, err = ctr.db.ListWebhookDeliveries(subscription.ID, offset, limit)
+	if err != nil {
+		return deliveries, fmt.Errorf("Error reading webhook deliveries from database: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+// Delivers the event of a past delivery again, as a new delivery sent by the next run of DeliverWebhooks
+func (ctr *Controller) ReplayWebhookDelivery(workspaceID string, id string, deliveryID string) (model.WebhookDelivery, error) {
+
+	var result model.WebhookDelivery
+
+	subscription, err := ctr.getWebhookSubscription(workspaceID, id)
+	if err != nil {
+		return result, err
+	}
+
+	delivery, err := ctr.db.GetWebhookDeliveryByID(deliveryID)
+	if err != nil {
+		return result, fmt.Errorf("Error reading webhook delivery from database: %v", err)
+	}
+	if delivery.SubscriptionID != subscription.ID {
+		return result, fmt.Errorf("Delivery %s not found for subscription %s", deliveryID, id)
+	}
+	if delivery.Status == "pending" {
+		return result, errors.New("Delivery is still pending")
+	}
+
+	result, err = ctr.db.InsertWebhookDelivery(delivery.Replay(time.Now()))
+	if err != nil {
+		return result, fmt.Errorf("Error inserting webhook delivery into database: %v", err)
+	}
+
+	return result, nil
+}
+
+// Creates the deliveries of the events waiting in the outbox. Meant to run periodically in the background.
+// Failures don't stop the other events, they are returned together.
+func (ctr *Controller) DispatchEvents(now time.Time) (int, []error) {
+
+	dispatched := 0
+	errs := []error{}
+
+	events, err := ctr.db.ListPendingEvents(webhookBatchSize)
+	if err != nil {
+		return dispatched, append(errs, fmt.Errorf("Error reading events from database: %v", err))
+	}
+	if len(events) == 0 {
+		return dispatched, errs
+	}
+
+	subscriptions, err := ctr.db.ListWebhookSubscriptions()
+	if err != nil {
+		return dispatched, append(errs, fmt.Errorf("Error reading webhook subscriptions from database: %v", err))
+	}
+
+	// Organizations of the subscribed workspaces, for the events of organization resources.
+	// Workspaces in the trash aren't found
+	organizations := map[string]string{}
+	for _, subscription := range subscriptions {
+		if _, ok := organizations[subscription.WorkspaceID]; ok || subscription.WorkspaceID == "" {
+			continue
+		}
+		workspace, err := ctr.db.GetWorkspaceByID(subscription.WorkspaceID)
+		if err == nil {
+			organizations[subscription.WorkspaceID] = workspace.OrganizationID
+		}
+	}
+
+	for _, event := range events {
+		deliveries := []model.WebhookDelivery{}
+		for _, subscription := range subscriptions {
+			// Subscriptions of workspaces in the trash only get the events of their workspace, e.g. its deletion
+			organizationID, live := organizations[subscription.WorkspaceID]
+			if subscription.WorkspaceID != "" && !live && event.WorkspaceID != subscription.WorkspaceID {
+				continue
+			}
+			if subscription.Matches(event, organizationID) {
+				deliveries = append(deliveries, model.NewWebhookDelivery(subscription, event, now))
+			}
+		}
+
+		_, err := ctr.db.DispatchEvent(event.ID, deliveries)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error dispatching event %s: %v", event.ID, err))
+			continue
+		}
+		dispatched++
+	}
+
+	return dispatched, errs
+}
+
+// Attempts the deliveries that are due. Meant to run periodically in the background, after DispatchEvents.
+// Returns the number of successful deliveries. Failed attempts are recorded on the deliveries, not returned
+func (ctr *Controller) DeliverWebhooks(now time.Time) (int, []error) {
+
+	delivered := 0
+	errs := []error{}
+
+	deliveries, err := ctr.db.ListDueWebhookDeliveries(now, webhookBatchSize)
+	if err != nil {
+		return delivered, append(errs, fmt.Errorf("Error reading webhook deliveries from database: %v", err))
+	}
+
+	for _, delivery := range deliveries {
+		delivery, err = ctr.deliverWebhook(delivery, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error delivering %s: %v", delivery.ID, err))
+			continue
+		}
+		if delivery.Status == "succeeded" {
+			delivered++
+		}
+	}
+
+	return delivered, errs
+}
+
+func (ctr *Controller) deliverWebhook(delivery model.WebhookDelivery, now time.Time) (model.WebhookDelivery, error) {
+
+	subscription, err := ctr.db.GetWebhookSubscriptionByID(delivery.SubscriptionID)
+	if err != nil {
+		delivery = delivery.Abandon("Subscription was deleted")
+	} else if !subscription.Active {
+		delivery = delivery.Abandon("Subscription is inactive")
+	} else {
+		event, err := ctr.db.GetEventByID(delivery.EventID)
+		if err != nil {
+			return delivery, fmt.Errorf("Error reading event from database: %v", err)
+		}
+		status, err := ctr.webhookClient().Deliver(subscription, delivery, event, now)
+		delivery = delivery.Record(status, err, now)
+	}
+
+	delivery, err = ctr.db.UpdateWebhookDelivery(delivery)
+	if err != nil {
+		return delivery, fmt.Errorf("Error updating webhook delivery in database: %v", err)
+	}
+
+	return delivery, nil
+}
+
+// Reads a subscription, making sure it belongs to the workspace and the user can manage it
+func (ctr *Controller) getWebhookSubscription(workspaceID string, id string) (model.WebhookSubscription, error) {
+
+	var subscription model.WebhookSubscription
+
+	err := ctr.canManageWebhooks(workspaceID)
+	if err != nil {
+		return subscription, err
+	}
+
+	subscription, err = ctr.db.GetWebhookSubscriptionByID(id)
+	if err != nil {
+		return subscription, fmt.Errorf("Error reading webhook subscription from database: %v", err)
+	}
+	if subscription.WorkspaceID != workspaceID {
+		return subscription, fmt.Errorf("Webhook subscription %s not found", id)
+	}
+
+	return subscription, nil
+}
+
+func (ctr *Controller) canManageWebhooks(workspaceID string) error {
+
+	if ctr.User.AppRole == "Super Admin" {
+		return nil
+	}
+	if workspaceID == "" {
+		return errors.New("Only Super Admins can manage global webhook subscriptions")
+	}
+
+	workspace, err := ctr.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return errors.New("User does not have permission to manage the webhooks of this workspace")
+	}
+
+	return nil
+}
+
+func (ctr *Controller) webhookClient() *webhook.Client {
+	if ctr.Options.Webhooks != nil {
+		return ctr.Options.Webhooks
+	}
+	return webhook.NewClient(10 * time.Second)
+}
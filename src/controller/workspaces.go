@@ -6,22 +6,38 @@ import (
 	"smartgrowth-connectors/configapi/model"
 )
 
-func (ctr *Controller) CreateWorkspace(name string, permissions []model.WorkspacePermission)  (model.Workspace, error) {
+// Workspaces of organizations are created by their admins and members. An empty organization ID creates
+// a workspace outside organizations
+func (ctr *Controller) CreateWorkspace(organizationID string, name string, permissions []model.WorkspacePermission)  (model.Workspace, error) {
 	
 	var workspace model.Workspace
 
+	if organizationID != "" {
+		organization, err := ctr.db.GetOrganizationByID(organizationID)
+		if err != nil {
+			return workspace, fmt.Errorf("Error reading organization from database: %v", err)
+		}
+		role := organization.RoleOf(ctr.principal())
+		if role != model.OrganizationAdmin && role != model.OrganizationMemberRole && ctr.User.AppRole != "Super Admin" {
+			return workspace, fmt.Errorf("User does not have permission to create workspaces in organization %s", organization.Name)
+		}
+	}
+
 	// User is owner
-	basePermission, err  := model.NewWorkspacePermission(ctr.User.Email, "owner")
+	basePermission, err  := model.NewWorkspacePermission(ctr.principal(), "owner")
 	if err != nil {
 		return workspace, fmt.Errorf("Error creating base permissions: %v", err)
 	}
 
 
-	permissions = append(permissions, basePermission)
+	permissions, err = ctr.resolvePrincipals(append(permissions, basePermission), nil, organizationID)
+	if err != nil {
+		return workspace, err
+	}
 	permissions = dedupePermissions(permissions)
 
 	// Create the workspace and insert it into the database
-	workspace, err = model.NewWorkspace(name, permissions)
+	workspace, err = model.NewWorkspace(organizationID, name, permissions)
 	if err != nil {
 		return workspace, fmt.Errorf("Error creating workspace: %v", err) 
 	}
@@ -31,7 +47,7 @@ func (ctr *Controller) CreateWorkspace(name string, permissions []model.Workspac
 		return workspace, fmt.Errorf("Error inserting workspace into database: %v", err)
 	}
 
-	return workspace, nil
+	return ctr.withNames(workspace)[0], nil
 }
 
 
@@ -52,16 +68,258 @@ func dedupePermissions(perms []model.WorkspacePermission) ([]model.WorkspacePerm
 	return v
 }
 
+// Resolves the principals of the permissions, which only keep their principal IDs. Users may be given
+// by email, resolved to the user registered with it. Access can only be granted directly to registered
+// users, others must be invited, and to the groups of the user. In workspaces of organizations, only
+// to the members and groups of the organization. Principals already granted are left alone
+func (ctr *Controller) resolvePrincipals(permissions []model.WorkspacePermission, current []model.WorkspacePermission, organizationID string) ([]model.WorkspacePermission, error) {
+
+	granted := map[string]bool{ ctr.principal(): true }
+	for _, perm := range current {
+		granted[perm.Principal] = true
+	}
+
+	resolved := []model.WorkspacePermission{}
+	for _, perm := range permissions {
+		if perm.Principal == "" {
+			if perm.Email == "" {
+				return resolved, fmt.Errorf("Permission with role %s has no principal", perm.Role)
+			}
+			user, err := ctr.db.GetUserByEmail(perm.Email)
+			if err != nil {
+				return resolved, fmt.Errorf("%s is not a registered user, invite them instead", perm.Email)
+			}
+			perm.Principal = user.Principal()
+		}
+		if !granted[perm.Principal] {
+			err := ctr.canGrant(perm.Principal, organizationID)
+			if err != nil {
+				return resolved, err
+			}
+		}
+		resolved = append(resolved, model.WorkspacePermission{ Principal: perm.Principal, Role: perm.Role })
+	}
+
+	return resolved, nil
+}
+
+// Users can be granted access when they exist, groups when the user belongs to them. In workspaces of
+// organizations, users must be members of the organization and groups must belong to it
+func (ctr *Controller) canGrant(principal string, organizationID string) error {
+
+	group, err := ctr.grantable(principal, organizationID)
+	if err != nil {
+		return err
+	}
+	if group != nil && !group.HasMember(ctr.principal()) && ctr.User.AppRole != "Super Admin" {
+		return fmt.Errorf("Only members of group %s can grant it access", group.Name)
+	}
+
+	return nil
+}
+
+// Checks the principal can hold permissions in workspaces of the organization, whoever grants them.
+// Returns the group of group principals
+func (ctr *Controller) grantable(principal string, organizationID string) (*model.Group, error) {
+
+	kind, id, err := model.ParsePrincipal(principal)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case model.PrincipalGroup:
+		group, err := ctr.db.GetGroupByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("Principal %s not found", principal)
+		}
+		if group.OrganizationID != organizationID {
+			return nil, fmt.Errorf("Group %s belongs to another organization", group.Name)
+		}
+		return &group, nil
+	case model.PrincipalUser:
+		email, err := ctr.principalName(principal)
+		if err != nil {
+			return nil, err
+		}
+		if organizationID != "" {
+			organization, err := ctr.db.GetOrganizationByID(organizationID)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading organization from database: %v", err)
+			}
+			if organization.RoleOf(principal) == "" {
+				return nil, fmt.Errorf("%s is not a member of organization %s, invite them instead", email, organization.Name)
+			}
+		}
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("Principal %s can't be granted access, its access comes with its role", principal)
+}
+
+// Display name of the principal: the email of a user, the name of a group
+func (ctr *Controller) principalName(principal string) (string, error) {
+
+	kind, id, err := model.ParsePrincipal(principal)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case model.PrincipalGroup:
+		group, err := ctr.db.GetGroupByID(id)
+		if err != nil {
+			return "", fmt.Errorf("Principal %s not found", principal)
+		}
+		return group.Name, nil
+	case model.PrincipalUser:
+		user, err := ctr.db.GetUserById(id)
+		if err != nil {
+			return "", fmt.Errorf("Principal %s not found", principal)
+		}
+		return user.Email, nil
+	}
+
+	// Roles name themselves
+	return principal, nil
+}
+
+// Copy of the workspaces with the current emails of their users and names of their groups, for display,
+// along with the email of the new owner of their pending transfer. Principals that don't exist anymore have none
+func (ctr *Controller) withNames(workspaces ...model.Workspace) []model.Workspace {
+
+	names := map[string]string{}
+	for idx, workspace := range workspaces {
+		permissions := []model.WorkspacePermission{}
+		for _, perm := range workspace.Permissions {
+			permissions = append(permissions, ctr.withName(perm, names))
+		}
+		workspaces[idx].Permissions = permissions
+
+		if workspace.Transfer != nil {
+			transfer := *workspace.Transfer
+			transfer.Email = ctr.withName(model.WorkspacePermission{ Principal: transfer.To }, names).Email
+			workspaces[idx].Transfer = &transfer
+		}
+	}
+
+	return workspaces
+}
+
+// Callers share the names read, by principal
+func (ctr *Controller) withName(perm model.WorkspacePermission, names map[string]string) model.WorkspacePermission {
+
+	name, ok := names[perm.Principal]
+	if !ok {
+		name, _ = ctr.principalName(perm.Principal)
+		names[perm.Principal] = name
+	}
+
+	kind, _, _ := model.ParsePrincipal(perm.Principal)
+	switch kind {
+	case model.PrincipalGroup:
+		perm.GroupName = name
+	case model.PrincipalUser:
+		perm.Email = name
+	}
+
+	return perm
+}
+
+// Reports the access of a user to the workspace and the permission giving it, directly or through
+// one of their groups. Users read their own access, owners the access of any user. An empty principal
+// stands for the user
+func (ctr *Controller) ReadWorkspaceAccess(id string, principal string) (model.WorkspaceAccess, error) {
+
+	var result model.WorkspaceAccess
+
+	workspace, err := ctr.db.GetWorkspaceByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.ViewableBy(ctr.principals()...) && ctr.User.AppRole != "Super Admin" {
+		return result, fmt.Errorf("User does not have permission to view workspace")
+	}
+
+	user := *ctr.User
+	if principal != "" && principal != ctr.principal() {
+		if !workspace.EditableBy(ctr.principals()...) && ctr.User.AppRole != "Super Admin" {
+			return result, fmt.Errorf("Only owners can read the access of other users")
+		}
+		kind, userID, err := model.ParsePrincipal(principal)
+		if err != nil || kind != model.PrincipalUser {
+			return result, fmt.Errorf("Invalid user principal %s", principal)
+		}
+		user, err = ctr.db.GetUserById(userID)
+		if err != nil {
+			return result, fmt.Errorf("Principal %s not found", principal)
+		}
+	}
+
+	principals := ctr.principalsOf(user)
+	if len(principals) == 0 {
+		return result, fmt.Errorf("User has no identity")
+	}
+
+	result = workspace.Access(principals[0], principals[1:])
+	if result.Grant != nil {
+		grant := ctr.withName(*result.Grant, map[string]string{})
+		result.Grant = &grant
+	}
+
+	return result, nil
+}
+
+// Converts the permissions stored before principal IDs, keyed on the email of users, to the IDs of the
+// users currently registered with those emails. Permissions of unregistered emails are dropped and their
+// emails returned. Meant to run once, at startup: it's a no-op once every workspace is migrated
+func (ctr *Controller) MigrateWorkspacePermissions() (int, []string, error) {
+
+	migrated := 0
+	dropped := []string{}
+
+	workspaces, err := ctr.db.ListWorkspaces()
+	if err != nil {
+		return migrated, dropped, fmt.Errorf("Error reading workspaces from database: %v", err)
+	}
+
+	resolve := func(email string) (string, bool) {
+		user, err := ctr.db.GetUserByEmail(email)
+		return user.Principal(), err == nil && user.HasIdentity()
+	}
+
+	for _, workspace := range workspaces {
+		permissions, unresolved := model.MigratePermissions(workspace.Permissions, resolve)
+		if model.SamePermissions(workspace.Permissions, permissions) {
+			continue
+		}
+
+		workspace.Permissions = dedupePermissions(permissions)
+		workspace.UpdatedAt = time.Now()
+		_, err := ctr.db.UpdateWorkspace(workspace)
+		if err != nil {
+			return migrated, dropped, fmt.Errorf("Error updating workspace %s in database: %v", workspace.ID, err)
+		}
+
+		migrated++
+		for _, email := range unresolved {
+			dropped = append(dropped, fmt.Sprintf("%s in workspace %s", email, workspace.ID))
+		}
+	}
+
+	return migrated, dropped, nil
+}
+
 func (ctr *Controller) ListWorkspaces(offset int, limit int) ([]model.Workspace, error){
 
 	// TODO: Super Admins should be able to read all workspaces
 	
-	workspaces, err := ctr.db.ListWorkspacesForPrincipal(ctr.User.Email)
+	workspaces, err := ctr.db.ListWorkspacesForPrincipal(ctr.principals()...)
 	if err != nil {
 		return workspaces, fmt.Errorf("Error reading workspaces from database: %v", err)
 	}
 
-	return workspaces,  nil
+	return ctr.withNames(workspaces...),  nil
 }
 
 func (ctr *Controller) ReadWorkspace(id string) (model.Workspace, error) {
@@ -73,11 +331,11 @@ func (ctr *Controller) ReadWorkspace(id string) (model.Workspace, error) {
 	}
 
 	// Check dedupePermissions
-	if !workspace.ViewableBy(ctr.User.Email) {
+	if !workspace.ViewableBy(ctr.principals()...) {
 		return result, fmt.Errorf("User does not have permission to view workspace")
 	}
 
-	return workspace, nil
+	return ctr.withNames(workspace)[0], nil
 }
 
 func (ctr *Controller) UpdateWorkspace(id string, name string, permissions []model.WorkspacePermission)  (model.Workspace, error) {
@@ -91,32 +349,53 @@ func (ctr *Controller) UpdateWorkspace(id string, name string, permissions []mod
 	if err != nil {
 		return workspace, fmt.Errorf("Error reading workspace from database: %v", err)
 	}
-	if !workspace.EditableBy(ctr.User.Email) {
+	if !workspace.EditableBy(ctr.principals()...) {
 		return workspace, fmt.Errorf("User can't edit this workspace")
 	}
 
 	// User is owner
-	basePermission, err  := model.NewWorkspacePermission(ctr.User.Email, "owner")
+	basePermission, err  := model.NewWorkspacePermission(ctr.principal(), "owner")
 	if err != nil {
 		return workspace, fmt.Errorf("Error creating base permissions: %v", err)
 	}
 
 
-	permissions = append(permissions, basePermission)
+	permissions, err = ctr.resolvePrincipals(append(permissions, basePermission), workspace.Permissions, workspace.OrganizationID)
+	if err != nil {
+		return workspace, err
+	}
 	permissions = dedupePermissions(permissions)
 
+	// Owners are added through confirmed ownership transfers. Owners through a group or their
+	// organization keep their own grant
+	for _, principal := range workspace.NewOwners(permissions) {
+		if principal != ctr.principal() {
+			return workspace, fmt.Errorf("Can't make %s an owner here, transfer the ownership of the workspace instead", principal)
+		}
+	}
+
+	changed := !model.SamePermissions(workspace.Permissions, permissions)
+
 	// Create the workspace and insert it into the database
 	workspace.Permissions = permissions
 	workspace.UpdatedAt = time.Now()
 
-	workspace, err = ctr.db.UpdateWorkspace(workspace)
+	events := []model.Event{}
+	if changed {
+		events = append(events, model.NewEvent(model.EventWorkspacePermissionsChanged, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), workspace.UpdatedAt))
+	}
+
+	workspace, err = ctr.db.UpdateWorkspace(workspace, events...)
 	if err != nil {
 		return workspace, fmt.Errorf("Error inserting workspace into database: %v", err)
 	}
+	ctr.publish(events...)
 
-	return workspace, nil
+	return ctr.withNames(workspace)[0], nil
 }
 
+// Moves the workspace to the trash with its integrations and connections. They stop being reachable,
+// and can be restored until the retention is over
 func (ctr *Controller) DeleteWorkspace(id string) (model.Workspace, error) {
 	
 	// Read the workspace, check if it is existing
@@ -126,14 +405,114 @@ func (ctr *Controller) DeleteWorkspace(id string) (model.Workspace, error) {
 	}
 
 	// Check permissions
-	if !workspace.EditableBy(ctr.User.Email) {
+	if !workspace.EditableBy(ctr.principals()...) {
 		return workspace, fmt.Errorf("User does not have permission to delete workspace")
 	}
 
-	deletedWorkspace, err := ctr.db.DeleteWorkspaceByID(id)
+	workspace, err = workspace.Trash(time.Now())
+	if err != nil {
+		return workspace, fmt.Errorf("Error deleting workspace: %v", err)
+	}
+
+	event := model.NewEvent(model.EventWorkspaceDeleted, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), *workspace.DeletedAt)
+	deletedWorkspace, err := ctr.db.TrashWorkspace(workspace, event)
 	if err != nil {
 		return deletedWorkspace, fmt.Errorf("Error deleting workspace from database: %v", err)
 	}
+	ctr.publish(event)
+
+	return ctr.withNames(deletedWorkspace)[0], nil
+}
+
+// Workspaces in the trash the user owns
+func (ctr *Controller) ListTrashedWorkspaces() ([]model.Workspace, error) {
 
-	return deletedWorkspace, nil
+	results := []model.Workspace{}
+
+	workspaces, err := ctr.db.ListTrashedWorkspaces()
+	if err != nil {
+		return results, fmt.Errorf("Error reading workspaces from database: %v", err)
+	}
+
+	principals := ctr.principals()
+	for _, workspace := range workspaces {
+		if workspace.EditableBy(principals...) {
+			results = append(results, workspace)
+		}
+	}
+
+	return ctr.withNames(results...), nil
+}
+
+// Takes the workspace out of the trash with its integrations and connections. Permissions that couldn't be
+// granted anymore, e.g. of users who left the organization or of deleted groups, are dropped
+func (ctr *Controller) RestoreWorkspace(id string) (model.Workspace, error) {
+
+	var result model.Workspace
+
+	workspace, err := ctr.db.GetTrashedWorkspaceByID(id)
+	if err != nil {
+		return result, fmt.Errorf("Error reading workspace from database: %v", err)
+	}
+	if !workspace.EditableBy(ctr.principals()...) {
+		return result, fmt.Errorf("User does not have permission to restore workspace")
+	}
+
+	workspace, err = workspace.Restore(time.Now(), ctr.trashRetention())
+	if err != nil {
+		return result, fmt.Errorf("Error restoring workspace: %v", err)
+	}
+
+	permissions := []model.WorkspacePermission{}
+	for _, perm := range workspace.Permissions {
+		if _, err := ctr.grantable(perm.Principal, workspace.OrganizationID); err == nil {
+			permissions = append(permissions, perm)
+		}
+	}
+	workspace.Permissions = permissions
+
+	event := model.NewEvent(model.EventWorkspaceRestored, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), workspace.UpdatedAt)
+	workspace, err = ctr.db.RestoreWorkspace(workspace, event)
+	if err != nil {
+		return result, fmt.Errorf("Error restoring workspace in database: %v", err)
+	}
+	ctr.publish(event)
+
+	return ctr.withNames(workspace)[0], nil
+}
+
+// Permanently deletes the workspaces that spent the whole retention in the trash, with everything they
+// hold. Returns the number of workspaces purged
+func (ctr *Controller) PurgeWorkspaces(now time.Time) (int, []error) {
+
+	purged := 0
+	errs := []error{}
+
+	workspaces, err := ctr.db.ListTrashedWorkspaces()
+	if err != nil {
+		return purged, append(errs, fmt.Errorf("Error reading workspaces from database: %v", err))
+	}
+
+	for _, workspace := range workspaces {
+		if !workspace.Purgeable(now, ctr.trashRetention()) {
+			continue
+		}
+
+		event := model.NewEvent(model.EventWorkspacePurged, workspace.ID, "workspace", workspace.ID, workspace, ctr.principalID(), now)
+		_, err := ctr.db.DeleteWorkspaceByID(workspace.ID, event)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error purging workspace %s: %v", workspace.ID, err))
+			continue
+		}
+		purged++
+	}
+
+	return purged, errs
+}
+
+func (ctr *Controller) trashRetention() time.Duration {
+	if ctr.Options.TrashRetention > 0 {
+		return ctr.Options.TrashRetention
+	}
+	return model.DefaultTrashRetention
 }
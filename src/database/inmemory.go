@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"smartgrowth-connectors/configapi/model"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,17 +14,57 @@ import (
 type inMemoryDB struct {
 	users map[string]model.User
 	workspaces map[string]model.Workspace
+	definitions map[string]map[string]model.IntegrationDefinition // [id] => [version] => definition
+	integrations map[string]model.Integration
+	connections map[string]model.Connection
+	runs map[string]model.Run
+	states map[string]model.ConnectorState // [integration id] => state
+	stateResets map[string]model.StateReset
+	audit []model.AuditEntry // In insertion order
+	oauthSessions map[string]model.OAuthSession // [state] => session
+	invitations map[string]model.Invitation
+	variables map[string]map[string]model.WorkspaceVariable // [workspace id] => [name] => variable
+	environments map[string]map[string]model.Environment // [workspace id] => [name] => environment
+	organizations map[string]model.Organization
+	organizationMemberships map[string]map[string]bool // [member principal] => [organization id]
+	groups map[string]model.Group
+	memberships map[string]map[string]bool // [member principal] => [group id]
+	templates map[string]model.WorkspaceTemplate
+	outbox []model.Event // In insertion order
+	subscriptions map[string]model.WebhookSubscription
+	deliveries []model.WebhookDelivery // In insertion order
+	lock sync.RWMutex // Requests and background jobs share the database
 }
 
 func NewInMemoryDB() (Database, error) {
 	return &inMemoryDB {
 		users: map[string]model.User{},
 		workspaces: map[string]model.Workspace{},
+		definitions: map[string]map[string]model.IntegrationDefinition{},
+		integrations: map[string]model.Integration{},
+		connections: map[string]model.Connection{},
+		runs: map[string]model.Run{},
+		states: map[string]model.ConnectorState{},
+		stateResets: map[string]model.StateReset{},
+		oauthSessions: map[string]model.OAuthSession{},
+		invitations: map[string]model.Invitation{},
+		variables: map[string]map[string]model.WorkspaceVariable{},
+		environments: map[string]map[string]model.Environment{},
+		organizations: map[string]model.Organization{},
+		organizationMemberships: map[string]map[string]bool{},
+		groups: map[string]model.Group{},
+		memberships: map[string]map[string]bool{},
+		templates: map[string]model.WorkspaceTemplate{},
+		subscriptions: map[string]model.WebhookSubscription{},
 	}, nil
 }
 
 // User
 func (db *inMemoryDB) GetUserBySub(sub string) (model.User, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
 	for _, val := range db.users {
 		if val.Sub == sub {
 			return val, nil
@@ -33,6 +75,10 @@ func (db *inMemoryDB) GetUserBySub(sub string) (model.User, error) {
 }
 
 func (db *inMemoryDB) GetUserById(id string) (model.User, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
 	if val, ok := db.users[id]; ok {
 		return val, nil
 	}
@@ -40,8 +86,25 @@ func (db *inMemoryDB) GetUserById(id string) (model.User, error) {
 	return result, fmt.Errorf("User with id %s not found", id)
 }
 
+func (db *inMemoryDB) GetUserByEmail(email string) (model.User, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	for _, val := range db.users {
+		if val.Email == email {
+			return val, nil
+		}
+	}
+	var result model.User
+	return result, fmt.Errorf("User with email %s not found", email)
+}
+
 func (db *inMemoryDB) InsertUser(u model.User) (model.User, error) {
 
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
 	var result model.User
 
 	// User should not be identified
@@ -60,6 +123,9 @@ func (db *inMemoryDB) InsertUser(u model.User) (model.User, error) {
 }
 
 func (db *inMemoryDB) ListUsers(offset int, limit int) ([]model.User, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
 	
 	var result []model.User
 	for _, value := range db.users {
@@ -70,6 +136,9 @@ func (db *inMemoryDB) ListUsers(offset int, limit int) ([]model.User, error) {
 }
 
 func (db *inMemoryDB) UpdateUser(id string, u model.User) (model.User, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
 	
 	var result model.User
 
@@ -86,6 +155,9 @@ func (db *inMemoryDB) UpdateUser(id string, u model.User) (model.User, error) {
 }
 
 func (db *inMemoryDB) DeleteUserById(id string) (model.User, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
 	
 	var result model.User
 
@@ -102,6 +174,9 @@ func (db *inMemoryDB) DeleteUserById(id string) (model.User, error) {
 // Workspaces
 func (db *inMemoryDB) InsertWorkspace(w model.Workspace) (model.Workspace, error) {
 
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
 	var idW model.Workspace
 
 	// Workspace should not be identified
@@ -117,12 +192,30 @@ func (db *inMemoryDB) InsertWorkspace(w model.Workspace) (model.Workspace, error
 	return w, nil
 } 
 
-func (db *inMemoryDB) ListWorkspacesForPrincipal(principal string) ([]model.Workspace, error) {
+func (db *inMemoryDB) ListWorkspaces() ([]model.Workspace, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Workspace{}
+	for _, val := range db.workspaces {
+		if val.DeletedAt == nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListWorkspacesForPrincipal(principals ...string) ([]model.Workspace, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
 
 	results := []model.Workspace{}
 
 	for _, val := range db.workspaces {
-		if val.ViewableBy(principal) {
+		if val.DeletedAt == nil && val.ViewableBy(principals...) {
 			results = append(results, val)
 		}
 	}
@@ -132,11 +225,20 @@ func (db *inMemoryDB) ListWorkspacesForPrincipal(principal string) ([]model.Work
 
 func (db  *inMemoryDB) GetWorkspaceByID(id string) (model.Workspace, error) {
 	
-	workspace := db.workspaces[id]
-	return workspace, nil
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.workspaces[id]; ok && val.DeletedAt == nil {
+		return val, nil
+	}
+	var result model.Workspace
+	return result, fmt.Errorf("Workspace with id %s not found", id)
 }
 
-func (db *inMemoryDB) UpdateWorkspace(w model.Workspace) (model.Workspace, error) {
+func (db *inMemoryDB) UpdateWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
 
 	var upW model.Workspace
 
@@ -144,25 +246,1701 @@ func (db *inMemoryDB) UpdateWorkspace(w model.Workspace) (model.Workspace, error
 	if w.ID == "" {
 		return upW, errors.New("Workspace should be identified")
 	}
+	err := checkEvents(events)
+	if err != nil {
+		return upW, err
+	}
 
-	// Workpace should exist
-	_, ok := db.workspaces[w.ID]
-	if !ok {
+	// Workpace should exist, out of the trash
+	if !db.liveWorkspace(w.ID) {
 		return upW, fmt.Errorf("Workspace with id %s does not exist", w.ID) 
 	}
+	if w.DeletedAt != nil {
+		return upW, errors.New("Workspace should be trashed with TrashWorkspace")
+	}
 
 	db.workspaces[w.ID] = w
+	db.appendEvents(events, w.ID)
 
 	return w, nil
 } 
 
-func (db *inMemoryDB) DeleteWorkspaceByID(id string) (model.Workspace, error) {
+// Purges the workspace with everything it holds: integrations and their states, connections, runs,
+// invitations, variables, environments, OAuth sessions, its private definitions, its audit entries and
+// webhook subscriptions with their deliveries. The outbox is kept
+func (db *inMemoryDB) DeleteWorkspaceByID(id string, events ...model.Event) (model.Workspace, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
 	//  Should exists
 	deleteResult, ok := db.workspaces[id]
 	if !ok {
 		return deleteResult, errors.New("Workspace does not exists")
 	}
+	err := checkEvents(events)
+	if err != nil {
+		return deleteResult, err
+	}
+
+	for integrationID, integration := range db.integrations {
+		if integration.WorkspaceID != id {
+			continue
+		}
+		delete(db.states, integrationID)
+		for resetID, reset := range db.stateResets {
+			if reset.IntegrationID == integrationID {
+				delete(db.stateResets, resetID)
+			}
+		}
+		delete(db.integrations, integrationID)
+	}
+	for connectionID, connection := range db.connections {
+		if connection.WorkspaceID == id {
+			delete(db.connections, connectionID)
+		}
+	}
+	for runID, run := range db.runs {
+		if run.WorkspaceID == id {
+			delete(db.runs, runID)
+		}
+	}
+	for invitationID, invitation := range db.invitations {
+		if invitation.WorkspaceID == id {
+			delete(db.invitations, invitationID)
+		}
+	}
+	for state, session := range db.oauthSessions {
+		if session.WorkspaceID == id {
+			delete(db.oauthSessions, state)
+		}
+	}
+	delete(db.variables, id)
+	delete(db.environments, id)
+	for definitionID, versions := range db.definitions {
+		for _, definition := range versions {
+			if definition.Scope.WorkspaceID == id {
+				delete(db.definitions, definitionID)
+			}
+			break
+		}
+	}
+	audit := []model.AuditEntry{}
+	for _, entry := range db.audit {
+		if entry.WorkspaceID != id {
+			audit = append(audit, entry)
+		}
+	}
+	db.audit = audit
+	purged := map[string]bool{}
+	for subscriptionID, subscription := range db.subscriptions {
+		if subscription.WorkspaceID == id {
+			purged[subscriptionID] = true
+			delete(db.subscriptions, subscriptionID)
+		}
+	}
+	deliveries := []model.WebhookDelivery{}
+	for _, delivery := range db.deliveries {
+		if !purged[delivery.SubscriptionID] {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	db.deliveries = deliveries
 
 	delete(db.workspaces, id)
+	db.appendEvents(events, id)
 	return deleteResult, nil
 }
+
+// Stores the trashed workspace, and marks its integrations and connections deleted at the same time
+func (db *inMemoryDB) TrashWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Workspace
+
+	if w.DeletedAt == nil {
+		return result, errors.New("Workspace should be trashed")
+	}
+	if !db.liveWorkspace(w.ID) {
+		return result, fmt.Errorf("Workspace with id %s does not exist", w.ID)
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	db.setWorkspaceDeletedAt(w.ID, w.DeletedAt)
+	db.workspaces[w.ID] = w
+	db.appendEvents(events, w.ID)
+	return w, nil
+}
+
+// Stores the restored workspace, and takes its integrations and connections out of the trash with it
+func (db *inMemoryDB) RestoreWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Workspace
+
+	if w.DeletedAt != nil {
+		return result, errors.New("Workspace should be restored")
+	}
+	if stored, ok := db.workspaces[w.ID]; !ok || stored.DeletedAt == nil {
+		return result, fmt.Errorf("Workspace with id %s is not in the trash", w.ID)
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	db.setWorkspaceDeletedAt(w.ID, nil)
+	db.workspaces[w.ID] = w
+	db.appendEvents(events, w.ID)
+	return w, nil
+}
+
+func (db *inMemoryDB) GetTrashedWorkspaceByID(id string) (model.Workspace, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.workspaces[id]; ok && val.DeletedAt != nil {
+		return val, nil
+	}
+	var result model.Workspace
+	return result, fmt.Errorf("Workspace with id %s not found in the trash", id)
+}
+
+func (db *inMemoryDB) ListTrashedWorkspaces() ([]model.Workspace, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Workspace{}
+	for _, val := range db.workspaces {
+		if val.DeletedAt != nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+// Whether the workspace exists out of the trash. Callers must hold the lock
+func (db *inMemoryDB) liveWorkspace(id string) bool {
+	workspace, ok := db.workspaces[id]
+	return ok && workspace.DeletedAt == nil
+}
+
+// Marks the integrations and connections of the workspace deleted, or not. Callers must hold the lock
+func (db *inMemoryDB) setWorkspaceDeletedAt(id string, deletedAt *time.Time) {
+	for integrationID, integration := range db.integrations {
+		if integration.WorkspaceID == id {
+			integration.DeletedAt = deletedAt
+			db.integrations[integrationID] = integration
+		}
+	}
+	for connectionID, connection := range db.connections {
+		if connection.WorkspaceID == id {
+			connection.DeletedAt = deletedAt
+			db.connections[connectionID] = connection
+		}
+	}
+}
+
+// Workspace variables
+func (db *inMemoryDB) ListWorkspaceVariables(workspaceID string) ([]model.WorkspaceVariable, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.WorkspaceVariable{}
+	for _, val := range db.variables[workspaceID] {
+		results = append(results, val)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	return results, nil
+}
+
+func (db *inMemoryDB) SetWorkspaceVariable(v model.WorkspaceVariable) (model.WorkspaceVariable, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WorkspaceVariable
+
+	if !db.liveWorkspace(v.WorkspaceID) {
+		return result, fmt.Errorf("Workspace with id %s does not exist", v.WorkspaceID)
+	}
+
+	variables := db.variables[v.WorkspaceID]
+	if variables == nil {
+		variables = map[string]model.WorkspaceVariable{}
+		db.variables[v.WorkspaceID] = variables
+	}
+	variables[v.Name] = v
+	return v, nil
+}
+
+func (db *inMemoryDB) DeleteWorkspaceVariable(workspaceID string, name string) (model.WorkspaceVariable, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	result, ok := db.variables[workspaceID][name]
+	if !ok {
+		return result, fmt.Errorf("Variable %s does not exist", name)
+	}
+
+	delete(db.variables[workspaceID], name)
+	return result, nil
+}
+
+// Environments
+func (db *inMemoryDB) InsertEnvironment(e model.Environment) (model.Environment, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Environment
+
+	if !db.liveWorkspace(e.WorkspaceID) {
+		return result, fmt.Errorf("Workspace with id %s does not exist", e.WorkspaceID)
+	}
+	if _, ok := db.environments[e.WorkspaceID][e.Name]; ok {
+		return result, fmt.Errorf("Environment %s already exists", e.Name)
+	}
+
+	environments := db.environments[e.WorkspaceID]
+	if environments == nil {
+		environments = map[string]model.Environment{}
+		db.environments[e.WorkspaceID] = environments
+	}
+	environments[e.Name] = e
+	return e, nil
+}
+
+func (db *inMemoryDB) GetEnvironment(workspaceID string, name string) (model.Environment, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.environments[workspaceID][name]; ok {
+		return val, nil
+	}
+	var result model.Environment
+	return result, fmt.Errorf("Environment %s not found", name)
+}
+
+func (db *inMemoryDB) ListEnvironments(workspaceID string) ([]model.Environment, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Environment{}
+	for _, val := range db.environments[workspaceID] {
+		results = append(results, val)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateEnvironment(e model.Environment) (model.Environment, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Environment
+
+	if _, ok := db.environments[e.WorkspaceID][e.Name]; !ok {
+		return result, fmt.Errorf("Environment %s does not exist", e.Name)
+	}
+
+	db.environments[e.WorkspaceID][e.Name] = e
+	return e, nil
+}
+
+func (db *inMemoryDB) DeleteEnvironment(workspaceID string, name string) (model.Environment, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	result, ok := db.environments[workspaceID][name]
+	if !ok {
+		return result, fmt.Errorf("Environment %s does not exist", name)
+	}
+
+	delete(db.environments[workspaceID], name)
+	return result, nil
+}
+
+// Invitations
+func (db *inMemoryDB) InsertInvitation(i model.Invitation) (model.Invitation, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Invitation
+
+	// Invitation should not be identified
+	if i.ID != "" {
+		return result, errors.New("Invitation should not be identified")
+	}
+
+	i.ID = uuid.NewString()
+	db.invitations[i.ID] = i
+	return i, nil
+}
+
+func (db *inMemoryDB) GetInvitationByID(id string) (model.Invitation, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.invitations[id]; ok {
+		return val, nil
+	}
+	var result model.Invitation
+	return result, fmt.Errorf("Invitation with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListInvitations(workspaceID string) ([]model.Invitation, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Invitation{}
+	for _, val := range db.invitations {
+		if val.WorkspaceID == workspaceID {
+			results = append(results, val)
+		}
+	}
+	sort.Slice(results, func(a, b int) bool {
+		return results[a].CreatedAt.After(results[b].CreatedAt)
+	})
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateInvitation(i model.Invitation) (model.Invitation, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Invitation
+
+	// Invitation should be identified
+	if i.ID == "" {
+		return result, errors.New("Invitation should be identified")
+	}
+
+	// Invitation should exist
+	if _, ok := db.invitations[i.ID]; !ok {
+		return result, fmt.Errorf("Invitation with id %s does not exist", i.ID)
+	}
+
+	db.invitations[i.ID] = i
+	return i, nil
+}
+
+func (db *inMemoryDB) AcceptInvitation(i model.Invitation, w model.Workspace, o *model.Organization, events ...model.Event) (model.Workspace, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Workspace
+
+	// Invitation should still be pending, so it is accepted once
+	stored, ok := db.invitations[i.ID]
+	if !ok {
+		return result, fmt.Errorf("Invitation with id %s does not exist", i.ID)
+	}
+	if stored.Status != "pending" {
+		return result, fmt.Errorf("Invitation was already %s", stored.Status)
+	}
+
+	// Workspace should exist
+	if !db.liveWorkspace(w.ID) || w.ID != i.WorkspaceID {
+		return result, fmt.Errorf("Workspace with id %s does not exist", i.WorkspaceID)
+	}
+	if o != nil {
+		if _, ok := db.organizations[o.ID]; !ok || o.ID != w.OrganizationID {
+			return result, fmt.Errorf("Organization with id %s does not exist", w.OrganizationID)
+		}
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	db.invitations[i.ID] = i
+	db.workspaces[w.ID] = w
+	if o != nil {
+		db.indexOrganizationMembers(db.organizations[o.ID], false)
+		db.organizations[o.ID] = *o
+		db.indexOrganizationMembers(*o, true)
+	}
+	db.appendEvents(events, w.ID)
+	return w, nil
+}
+
+// Organizations
+func (db *inMemoryDB) InsertOrganization(o model.Organization) (model.Organization, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Organization
+
+	// Organization should not be identified
+	if o.ID != "" {
+		return result, errors.New("Organization should not be identified")
+	}
+
+	o.ID = uuid.NewString()
+	db.organizations[o.ID] = o
+	db.indexOrganizationMembers(o, true)
+	return o, nil
+}
+
+func (db *inMemoryDB) GetOrganizationByID(id string) (model.Organization, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.organizations[id]; ok {
+		return val, nil
+	}
+	var result model.Organization
+	return result, fmt.Errorf("Organization with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListOrganizations() ([]model.Organization, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Organization{}
+	for _, val := range db.organizations {
+		results = append(results, val)
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListOrganizationsForMember(principal string) ([]model.Organization, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Organization{}
+	for id := range db.organizationMemberships[principal] {
+		results = append(results, db.organizations[id])
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateOrganization(o model.Organization) (model.Organization, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Organization
+
+	current, ok := db.organizations[o.ID]
+	if !ok {
+		return result, fmt.Errorf("Organization with id %s does not exist", o.ID)
+	}
+
+	db.indexOrganizationMembers(current, false)
+	db.organizations[o.ID] = o
+	db.indexOrganizationMembers(o, true)
+	return o, nil
+}
+
+// Organizations still owning workspaces or groups can't be deleted
+func (db *inMemoryDB) DeleteOrganizationByID(id string) (model.Organization, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Organization
+
+	result, ok := db.organizations[id]
+	if !ok {
+		return result, fmt.Errorf("Organization with id %s does not exist", id)
+	}
+	for _, workspace := range db.workspaces {
+		if workspace.OrganizationID == id {
+			return result, fmt.Errorf("Organization with id %s still owns workspace %s", id, workspace.ID)
+		}
+	}
+	for _, group := range db.groups {
+		if group.OrganizationID == id {
+			return result, fmt.Errorf("Organization with id %s still owns group %s", id, group.ID)
+		}
+	}
+	for _, template := range db.templates {
+		if template.OrganizationID == id {
+			return result, fmt.Errorf("Organization with id %s still owns template %s", id, template.ID)
+		}
+	}
+
+	db.indexOrganizationMembers(result, false)
+	delete(db.organizations, id)
+	return result, nil
+}
+
+// Adds or removes the memberships of the organization to the index. Callers must hold the lock
+func (db *inMemoryDB) indexOrganizationMembers(o model.Organization, add bool) {
+	for _, member := range o.Members {
+		organizations := db.organizationMemberships[member.Principal]
+		if add {
+			if organizations == nil {
+				organizations = map[string]bool{}
+				db.organizationMemberships[member.Principal] = organizations
+			}
+			organizations[o.ID] = true
+		} else {
+			delete(organizations, o.ID)
+			if len(organizations) == 0 {
+				delete(db.organizationMemberships, member.Principal)
+			}
+		}
+	}
+}
+
+// Groups
+func (db *inMemoryDB) InsertGroup(g model.Group) (model.Group, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Group
+
+	// Group should not be identified
+	if g.ID != "" {
+		return result, errors.New("Group should not be identified")
+	}
+
+	g.ID = uuid.NewString()
+	db.groups[g.ID] = g
+	db.indexMembers(g, true)
+	return g, nil
+}
+
+func (db *inMemoryDB) GetGroupByID(id string) (model.Group, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.groups[id]; ok {
+		return val, nil
+	}
+	var result model.Group
+	return result, fmt.Errorf("Group with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListGroups() ([]model.Group, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Group{}
+	for _, val := range db.groups {
+		results = append(results, val)
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListGroupsForMember(principal string) ([]model.Group, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Group{}
+	for id := range db.memberships[principal] {
+		results = append(results, db.groups[id])
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateGroup(g model.Group) (model.Group, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Group
+
+	current, ok := db.groups[g.ID]
+	if !ok {
+		return result, fmt.Errorf("Group with id %s does not exist", g.ID)
+	}
+
+	db.indexMembers(current, false)
+	db.groups[g.ID] = g
+	db.indexMembers(g, true)
+	return g, nil
+}
+
+func (db *inMemoryDB) DeleteGroupByID(id string) (model.Group, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Group
+
+	result, ok := db.groups[id]
+	if !ok {
+		return result, fmt.Errorf("Group with id %s does not exist", id)
+	}
+
+	db.indexMembers(result, false)
+	delete(db.groups, id)
+	return result, nil
+}
+
+// Adds or removes the memberships of the group to the index. Callers must hold the lock
+func (db *inMemoryDB) indexMembers(g model.Group, add bool) {
+	for _, member := range g.Members {
+		groups := db.memberships[member.Principal]
+		if add {
+			if groups == nil {
+				groups = map[string]bool{}
+				db.memberships[member.Principal] = groups
+			}
+			groups[g.ID] = true
+		} else {
+			delete(groups, g.ID)
+			if len(groups) == 0 {
+				delete(db.memberships, member.Principal)
+			}
+		}
+	}
+}
+
+// Workspace templates
+func (db *inMemoryDB) InsertWorkspaceTemplate(t model.WorkspaceTemplate) (model.WorkspaceTemplate, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WorkspaceTemplate
+
+	// Template should not be identified
+	if t.ID != "" {
+		return result, errors.New("Template should not be identified")
+	}
+
+	t.ID = uuid.NewString()
+	db.templates[t.ID] = t
+	return t, nil
+}
+
+func (db *inMemoryDB) GetWorkspaceTemplateByID(id string) (model.WorkspaceTemplate, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.templates[id]; ok {
+		return val, nil
+	}
+	var result model.WorkspaceTemplate
+	return result, fmt.Errorf("Template with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListWorkspaceTemplates() ([]model.WorkspaceTemplate, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.WorkspaceTemplate{}
+	for _, val := range db.templates {
+		results = append(results, val)
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateWorkspaceTemplate(t model.WorkspaceTemplate) (model.WorkspaceTemplate, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WorkspaceTemplate
+
+	if _, ok := db.templates[t.ID]; !ok {
+		return result, fmt.Errorf("Template with id %s does not exist", t.ID)
+	}
+
+	db.templates[t.ID] = t
+	return t, nil
+}
+
+func (db *inMemoryDB) DeleteWorkspaceTemplateByID(id string) (model.WorkspaceTemplate, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WorkspaceTemplate
+
+	result, ok := db.templates[id]
+	if !ok {
+		return result, fmt.Errorf("Template with id %s does not exist", id)
+	}
+
+	delete(db.templates, id)
+	return result, nil
+}
+
+// Integration Definitions
+func (db *inMemoryDB) InsertIntegrationDefinition(d model.IntegrationDefinition) (model.IntegrationDefinition, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.IntegrationDefinition
+
+	// Definition should not be identified
+	if d.ID != "" {
+		return result, errors.New("Integration definition should not be identified")
+	}
+
+	id := uuid.NewString()
+	d.ID = id
+
+	db.definitions[id] = map[string]model.IntegrationDefinition{ d.Version: d }
+	return d, nil
+}
+
+func (db *inMemoryDB) InsertIntegrationDefinitionVersion(d model.IntegrationDefinition) (model.IntegrationDefinition, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.IntegrationDefinition
+
+	// Definition should exist
+	versions, ok := db.definitions[d.ID]
+	if !ok {
+		return result, fmt.Errorf("Integration definition with id %s not found", d.ID)
+	}
+
+	// Versions are immutable
+	if _, ok := versions[d.Version]; ok {
+		return result, fmt.Errorf("Version %s of integration definition %s already exists", d.Version, d.ID)
+	}
+
+	versions[d.Version] = d
+	return d, nil
+}
+
+func (db *inMemoryDB) GetIntegrationDefinition(id string, version string) (model.IntegrationDefinition, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var result model.IntegrationDefinition
+
+	versions, ok := db.definitions[id]
+	if !ok {
+		return result, fmt.Errorf("Integration definition with id %s not found", id)
+	}
+
+	if version == "" {
+		return latestDefinitionVersion(versions), nil
+	}
+
+	result, ok = versions[version]
+	if !ok {
+		return result, fmt.Errorf("Version %s of integration definition %s not found", version, id)
+	}
+
+	return result, nil
+}
+
+func (db *inMemoryDB) ListIntegrationDefinitions() ([]model.IntegrationDefinition, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.IntegrationDefinition{}
+	for _, versions := range db.definitions {
+		results = append(results, latestDefinitionVersion(versions))
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListIntegrationDefinitionVersions(id string) ([]model.IntegrationDefinition, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.definitionVersions(id)
+}
+
+// Callers must hold the lock
+func (db *inMemoryDB) definitionVersions(id string) ([]model.IntegrationDefinition, error) {
+
+	results := []model.IntegrationDefinition{}
+
+	versions, ok := db.definitions[id]
+	if !ok {
+		return results, fmt.Errorf("Integration definition with id %s not found", id)
+	}
+
+	for _, def := range versions {
+		results = append(results, def)
+	}
+	model.SortDefinitionVersions(results)
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateIntegrationDefinition(d model.IntegrationDefinition, events ...model.Event) (model.IntegrationDefinition, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.IntegrationDefinition
+
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	// Version should exist
+	versions, ok := db.definitions[d.ID]
+	if !ok {
+		return result, fmt.Errorf("Integration definition with id %s not found", d.ID)
+	}
+	if _, ok := versions[d.Version]; !ok {
+		return result, fmt.Errorf("Version %s of integration definition %s not found", d.Version, d.ID)
+	}
+
+	versions[d.Version] = d
+	db.appendEvents(events, d.ID)
+	return d, nil
+}
+
+func (db *inMemoryDB) DeleteIntegrationDefinitionByID(id string) ([]model.IntegrationDefinition, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Deletes every version
+	deleted, err := db.definitionVersions(id)
+	if err != nil {
+		return deleted, err
+	}
+
+	delete(db.definitions, id)
+	return deleted, nil
+}
+
+func latestDefinitionVersion(versions map[string]model.IntegrationDefinition) model.IntegrationDefinition {
+
+	sorted := []model.IntegrationDefinition{}
+	for _, def := range versions {
+		sorted = append(sorted, def)
+	}
+	model.SortDefinitionVersions(sorted)
+
+	return sorted[len(sorted) - 1]
+}
+
+// Integrations
+func (db *inMemoryDB) InsertIntegration(i model.Integration, events ...model.Event) (model.Integration, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Integration
+
+	// Integration should not be identified
+	if i.ID != "" {
+		return result, errors.New("Integration should not be identified")
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	id := uuid.NewString()
+	i.ID = id
+
+	db.integrations[id] = i
+	db.appendEvents(events, id)
+	return i, nil
+}
+
+func (db *inMemoryDB) GetIntegrationByID(id string) (model.Integration, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.integrations[id]; ok && val.DeletedAt == nil {
+		return val, nil
+	}
+	var result model.Integration
+	return result, fmt.Errorf("Integration with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListIntegrationsForWorkspace(workspaceID string) ([]model.Integration, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Integration{}
+	for _, val := range db.integrations {
+		if val.WorkspaceID == workspaceID && val.DeletedAt == nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListIntegrationsForDefinition(definitionID string) ([]model.Integration, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Integration{}
+	for _, val := range db.integrations {
+		if val.DefinitionID == definitionID && val.DeletedAt == nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListTrashedIntegrationsForDefinition(definitionID string) ([]model.Integration, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Integration{}
+	for _, val := range db.integrations {
+		if val.DefinitionID == definitionID && val.DeletedAt != nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateIntegration(i model.Integration, events ...model.Event) (model.Integration, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Integration
+
+	// Integration should be identified
+	if i.ID == "" {
+		return result, errors.New("Integration should be identified")
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	// Integration should exist, out of the trash
+	if stored, ok := db.integrations[i.ID]; !ok || stored.DeletedAt != nil {
+		return result, fmt.Errorf("Integration with id %s does not exist", i.ID)
+	}
+
+	db.integrations[i.ID] = i
+	db.appendEvents(events, i.ID)
+	return i, nil
+}
+
+func (db *inMemoryDB) DeleteIntegrationByID(id string, events ...model.Event) (model.Integration, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Should exist, out of the trash
+	result, ok := db.integrations[id]
+	if !ok || result.DeletedAt != nil {
+		return result, fmt.Errorf("Integration with id %s does not exist", id)
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	delete(db.integrations, id)
+	db.appendEvents(events, id)
+	return result, nil
+}
+
+// Connections
+func (db *inMemoryDB) InsertConnection(c model.Connection, events ...model.Event) (model.Connection, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Connection
+
+	// Connection should not be identified
+	if c.ID != "" {
+		return result, errors.New("Connection should not be identified")
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	id := uuid.NewString()
+	c.ID = id
+
+	db.connections[id] = c
+	db.appendEvents(events, id)
+	return c, nil
+}
+
+func (db *inMemoryDB) GetConnectionByID(id string) (model.Connection, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.connections[id]; ok && val.DeletedAt == nil {
+		return val, nil
+	}
+	var result model.Connection
+	return result, fmt.Errorf("Connection with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListConnections() ([]model.Connection, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Connection{}
+	for _, val := range db.connections {
+		if val.DeletedAt == nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListConnectionsForWorkspace(workspaceID string) ([]model.Connection, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Connection{}
+	for _, val := range db.connections {
+		if val.WorkspaceID == workspaceID && val.DeletedAt == nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListConnectionsForIntegration(integrationID string) ([]model.Connection, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Connection{}
+	for _, val := range db.connections {
+		if (val.SourceID == integrationID || val.DestinationID == integrationID) && val.DeletedAt == nil {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateConnection(c model.Connection, events ...model.Event) (model.Connection, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Connection
+
+	// Connection should be identified
+	if c.ID == "" {
+		return result, errors.New("Connection should be identified")
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	// Connection should exist, out of the trash
+	if stored, ok := db.connections[c.ID]; !ok || stored.DeletedAt != nil {
+		return result, fmt.Errorf("Connection with id %s does not exist", c.ID)
+	}
+
+	db.connections[c.ID] = c
+	db.appendEvents(events, c.ID)
+	return c, nil
+}
+
+func (db *inMemoryDB) DeleteConnectionByID(id string, events ...model.Event) (model.Connection, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Should exist, out of the trash
+	result, ok := db.connections[id]
+	if !ok || result.DeletedAt != nil {
+		return result, fmt.Errorf("Connection with id %s does not exist", id)
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	delete(db.connections, id)
+	db.appendEvents(events, id)
+	return result, nil
+}
+
+// Runs
+func (db *inMemoryDB) InsertRun(r model.Run, events ...model.Event) (model.Run, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Run
+
+	// Run should not be identified
+	if r.ID != "" {
+		return result, errors.New("Run should not be identified")
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	id := uuid.NewString()
+	r.ID = id
+
+	db.runs[id] = r
+	db.appendEvents(events, id)
+	return r, nil
+}
+
+func (db *inMemoryDB) GetRunByID(id string) (model.Run, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.runs[id]; ok {
+		return val, nil
+	}
+	var result model.Run
+	return result, fmt.Errorf("Run with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListRuns(filter model.RunFilter) ([]model.Run, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Run{}
+	for _, val := range db.runs {
+		if filter.Matches(val) {
+			results = append(results, val)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].StartedAt.Equal(results[j].StartedAt) {
+			return results[i].ID < results[j].ID
+		}
+		return results[i].StartedAt.After(results[j].StartedAt)
+	})
+	if filter.Limit > 0 && len(results) > filter.Limit {
+		results = results[:filter.Limit]
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateRun(r model.Run, events ...model.Event) (model.Run, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.Run
+
+	// Run should be identified
+	if r.ID == "" {
+		return result, errors.New("Run should be identified")
+	}
+	err := checkEvents(events)
+	if err != nil {
+		return result, err
+	}
+
+	// Run should exist
+	if _, ok := db.runs[r.ID]; !ok {
+		return result, fmt.Errorf("Run with id %s does not exist", r.ID)
+	}
+
+	db.runs[r.ID] = r
+	db.appendEvents(events, r.ID)
+	return r, nil
+}
+
+// Connector states
+func (db *inMemoryDB) GetConnectorState(integration model.Integration) (model.ConnectorState, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.states[integration.ID]; ok {
+		return val, nil
+	}
+	return model.NewConnectorState(integration), nil
+}
+
+func (db *inMemoryDB) SwapConnectorState(state model.ConnectorState, expectedVersion int64) (model.ConnectorState, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.ConnectorState
+
+	// State should be identified by its integration
+	if state.IntegrationID == "" {
+		return result, errors.New("Connector state should be identified")
+	}
+
+	current := db.states[state.IntegrationID].Version
+	if current != expectedVersion {
+		return result, &model.StateConflictError{ IntegrationID: state.IntegrationID, Expected: expectedVersion, Current: current }
+	}
+
+	state.Version = current + 1
+	db.states[state.IntegrationID] = state
+	return state, nil
+}
+
+func (db *inMemoryDB) DeleteConnectorState(integrationID string) error {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	delete(db.states, integrationID)
+	return nil
+}
+
+func (db *inMemoryDB) InsertStateReset(r model.StateReset) (model.StateReset, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.StateReset
+
+	// Reset should not be identified
+	if r.ID != "" {
+		return result, errors.New("State reset should not be identified")
+	}
+
+	id := uuid.NewString()
+	r.ID = id
+
+	db.stateResets[id] = r
+	return r, nil
+}
+
+func (db *inMemoryDB) ListStateResets(integrationID string) ([]model.StateReset, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.StateReset{}
+	for _, val := range db.stateResets {
+		if val.IntegrationID == integrationID {
+			results = append(results, val)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ResetAt.After(results[j].ResetAt)
+	})
+
+	return results, nil
+}
+
+// Audit log
+func (db *inMemoryDB) InsertAuditEntries(entries []model.AuditEntry) ([]model.AuditEntry, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	results := []model.AuditEntry{}
+
+	// Entries should not be identified
+	for _, entry := range entries {
+		if entry.ID != "" {
+			return results, errors.New("Audit entry should not be identified")
+		}
+	}
+
+	for _, entry := range entries {
+		entry.ID = uuid.NewString()
+		results = append(results, entry)
+	}
+
+	db.audit = append(db.audit, results...)
+	return results, nil
+}
+
+func (db *inMemoryDB) ListAuditEntries(workspaceID string, offset int, limit int) ([]model.AuditEntry, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.AuditEntry{}
+	for idx := len(db.audit) - 1; idx >= 0; idx-- {
+		if db.audit[idx].WorkspaceID == workspaceID {
+			results = append(results, db.audit[idx])
+		}
+	}
+
+	if offset >= len(results) {
+		return []model.AuditEntry{}, nil
+	}
+	results = results[offset:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// OAuth sessions
+func (db *inMemoryDB) InsertOAuthSession(session model.OAuthSession) error {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Session should be identified by its state
+	if session.State == "" {
+		return errors.New("OAuth session should be identified")
+	}
+	if _, ok := db.oauthSessions[session.State]; ok {
+		return errors.New("OAuth session already exists")
+	}
+
+	db.oauthSessions[session.State] = session
+	return nil
+}
+
+func (db *inMemoryDB) TakeOAuthSession(state string) (model.OAuthSession, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	result, ok := db.oauthSessions[state]
+	if !ok {
+		return result, errors.New("OAuth session not found")
+	}
+
+	delete(db.oauthSessions, state)
+	return result, nil
+}
+
+// Event outbox
+// Events should not be identified. Checked before changing anything, so changes are written with their events or not at all
+func checkEvents(events []model.Event) error {
+	for _, event := range events {
+		if event.ID != "" {
+			return errors.New("Event should not be identified")
+		}
+	}
+	return nil
+}
+
+// Events created before their resource was inserted get its ID. Callers must hold the lock
+func (db *inMemoryDB) appendEvents(events []model.Event, resourceID string) {
+	for _, event := range events {
+		event.ID = uuid.NewString()
+		if event.ResourceID == "" {
+			event = event.WithResourceID(resourceID)
+		}
+		db.outbox = append(db.outbox, event)
+	}
+}
+
+func (db *inMemoryDB) ListPendingEvents(limit int) ([]model.Event, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.Event{}
+	for _, event := range db.outbox {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		if !event.Dispatched {
+			results = append(results, event)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) GetEventByID(id string) (model.Event, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	for _, event := range db.outbox {
+		if event.ID == id {
+			return event, nil
+		}
+	}
+	var result model.Event
+	return result, fmt.Errorf("Event with id %s not found", id)
+}
+
+func (db *inMemoryDB) DispatchEvent(id string, deliveries []model.WebhookDelivery) ([]model.WebhookDelivery, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	results := []model.WebhookDelivery{}
+
+	// Event should be pending
+	idx := -1
+	for i, event := range db.outbox {
+		if event.ID == id {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return results, fmt.Errorf("Event with id %s not found", id)
+	}
+	if db.outbox[idx].Dispatched {
+		return results, fmt.Errorf("Event with id %s already dispatched", id)
+	}
+
+	// Deliveries should not be identified
+	for _, delivery := range deliveries {
+		if delivery.ID != "" {
+			return results, errors.New("Webhook delivery should not be identified")
+		}
+	}
+
+	for _, delivery := range deliveries {
+		delivery.ID = uuid.NewString()
+		results = append(results, delivery)
+	}
+	db.deliveries = append(db.deliveries, results...)
+	db.outbox[idx].Dispatched = true
+
+	return results, nil
+}
+
+// Webhooks
+func (db *inMemoryDB) InsertWebhookSubscription(s model.WebhookSubscription) (model.WebhookSubscription, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WebhookSubscription
+
+	// Subscription should not be identified
+	if s.ID != "" {
+		return result, errors.New("Webhook subscription should not be identified")
+	}
+
+	s.ID = uuid.NewString()
+	db.subscriptions[s.ID] = s
+	return s, nil
+}
+
+func (db *inMemoryDB) GetWebhookSubscriptionByID(id string) (model.WebhookSubscription, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if val, ok := db.subscriptions[id]; ok {
+		return val, nil
+	}
+	var result model.WebhookSubscription
+	return result, fmt.Errorf("Webhook subscription with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListWebhookSubscriptions() ([]model.WebhookSubscription, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.WebhookSubscription{}
+	for _, val := range db.subscriptions {
+		results = append(results, val)
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListWebhookSubscriptionsForWorkspace(workspaceID string) ([]model.WebhookSubscription, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.WebhookSubscription{}
+	for _, val := range db.subscriptions {
+		if val.WorkspaceID == workspaceID {
+			results = append(results, val)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateWebhookSubscription(s model.WebhookSubscription) (model.WebhookSubscription, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WebhookSubscription
+
+	// Subscription should be identified
+	if s.ID == "" {
+		return result, errors.New("Webhook subscription should be identified")
+	}
+
+	// Subscription should exist
+	if _, ok := db.subscriptions[s.ID]; !ok {
+		return result, fmt.Errorf("Webhook subscription with id %s does not exist", s.ID)
+	}
+
+	db.subscriptions[s.ID] = s
+	return s, nil
+}
+
+func (db *inMemoryDB) DeleteWebhookSubscriptionByID(id string) (model.WebhookSubscription, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// Should exist
+	result, ok := db.subscriptions[id]
+	if !ok {
+		return result, fmt.Errorf("Webhook subscription with id %s does not exist", id)
+	}
+
+	delete(db.subscriptions, id)
+	return result, nil
+}
+
+func (db *inMemoryDB) InsertWebhookDelivery(d model.WebhookDelivery) (model.WebhookDelivery, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WebhookDelivery
+
+	// Delivery should not be identified
+	if d.ID != "" {
+		return result, errors.New("Webhook delivery should not be identified")
+	}
+
+	d.ID = uuid.NewString()
+	db.deliveries = append(db.deliveries, d)
+	return d, nil
+}
+
+func (db *inMemoryDB) GetWebhookDeliveryByID(id string) (model.WebhookDelivery, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	for _, delivery := range db.deliveries {
+		if delivery.ID == id {
+			return delivery, nil
+		}
+	}
+	var result model.WebhookDelivery
+	return result, fmt.Errorf("Webhook delivery with id %s not found", id)
+}
+
+func (db *inMemoryDB) ListWebhookDeliveries(subscriptionID string, offset int, limit int) ([]model.WebhookDelivery, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.WebhookDelivery{}
+	for idx := len(db.deliveries) - 1; idx >= 0; idx-- {
+		if db.deliveries[idx].SubscriptionID == subscriptionID {
+			results = append(results, db.deliveries[idx])
+		}
+	}
+
+	if offset >= len(results) {
+		return []model.WebhookDelivery{}, nil
+	}
+	results = results[offset:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) ListDueWebhookDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	results := []model.WebhookDelivery{}
+	for _, delivery := range db.deliveries {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		if delivery.Due(now) {
+			results = append(results, delivery)
+		}
+	}
+
+	return results, nil
+}
+
+func (db *inMemoryDB) UpdateWebhookDelivery(d model.WebhookDelivery) (model.WebhookDelivery, error) {
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	var result model.WebhookDelivery
+
+	// Delivery should be identified
+	if d.ID == "" {
+		return result, errors.New("Webhook delivery should be identified")
+	}
+
+	for idx, delivery := range db.deliveries {
+		if delivery.ID == d.ID {
+			db.deliveries[idx] = d
+			return d, nil
+		}
+	}
+	return result, fmt.Errorf("Webhook delivery with id %s does not exist", d.ID)
+}
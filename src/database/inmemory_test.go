@@ -0,0 +1,70 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"smartgrowth-connectors/configapi/model"
+)
+
+func TestPurgeWorkspace(t *testing.T) {
+
+	db, _ := NewInMemoryDB()
+	purged := insertTenant(t, db, "purged")
+	kept := insertTenant(t, db, "kept")
+
+	private, err := model.NewIntegrationDefinition("private", "source", model.ConfigurationSchema{ { Label: "host", Type: "string" } })
+	if err != nil {
+		t.Fatalf("Invalid definition: %v", err)
+	}
+	private.Scope = model.DefinitionScope{ Visibility: model.DefinitionWorkspace, WorkspaceID: purged.workspace.ID }
+	private, err = db.InsertIntegrationDefinition(private)
+	if err != nil {
+		t.Fatalf("Error inserting definition: %v", err)
+	}
+	_, err = db.InsertAuditEntries([]model.AuditEntry{
+		model.NewAuditEntry(purged.workspace.ID, "secret.read", "integration", purged.integration.ID, "password", "user:purged", time.Now()),
+		model.NewAuditEntry(kept.workspace.ID, "secret.read", "integration", kept.integration.ID, "password", "user:kept", time.Now()),
+	})
+	if err != nil {
+		t.Fatalf("Error inserting audit entries: %v", err)
+	}
+
+	_, err = db.DeleteWorkspaceByID(purged.workspace.ID)
+	if err != nil {
+		t.Fatalf("Error purging workspace: %v", err)
+	}
+
+	if _, err := db.GetIntegrationDefinition(private.ID, private.Version); err == nil {
+		t.Errorf("Expected the private definitions of the workspace to be purged")
+	}
+	if _, err := db.GetIntegrationDefinition(purged.definition.ID, purged.definition.Version); err != nil {
+		t.Errorf("Expected the definitions of the organization to be kept, got %v", err)
+	}
+	if entries, _ := db.ListAuditEntries(purged.workspace.ID, 0, 10); len(entries) != 0 {
+		t.Errorf("Expected the audit entries of the workspace to be purged, got %+v", entries)
+	}
+	if entries, _ := db.ListAuditEntries(kept.workspace.ID, 0, 10); len(entries) != 1 {
+		t.Errorf("Expected the audit entries of other workspaces to be kept, got %+v", entries)
+	}
+}
+
+func TestTrashedIntegrationsForDefinition(t *testing.T) {
+
+	db, _ := NewInMemoryDB()
+	trashed := insertTenant(t, db, "trashed")
+
+	now := time.Now()
+	trashed.workspace.DeletedAt = &now
+	_, err := db.TrashWorkspace(trashed.workspace)
+	if err != nil {
+		t.Fatalf("Error trashing workspace: %v", err)
+	}
+
+	if live, _ := db.ListIntegrationsForDefinition(trashed.definition.ID); len(live) != 0 {
+		t.Errorf("Expected the integrations in the trash not to be listed with the live ones, got %+v", live)
+	}
+	integrations, err := db.ListTrashedIntegrationsForDefinition(trashed.definition.ID)
+	if err != nil || len(integrations) != 1 || integrations[0].ID != trashed.integration.ID {
+		t.Errorf("Expected the integration in the trash, got %+v, %v", integrations, err)
+	}
+}
@@ -2,21 +2,154 @@ package database
 
 import (
 	"smartgrowth-connectors/configapi/model"
+	"time"
 )
 
+// Mutations taking events write them to the outbox atomically with the change,
+// so no event is lost nor sent for a change that failed.
 type Database interface {
 	// Users
 	GetUserBySub(sub string) (model.User, error)
 	GetUserById(id string) (model.User, error)
+	GetUserByEmail(email string) (model.User, error)
 	InsertUser(model.User) (model.User, error)
 	ListUsers(offset int, limit int) ([]model.User, error)
 	UpdateUser(id string, user model.User) (model.User, error)
 	DeleteUserById(id string) (model.User, error)
 
 	// Workspace
+	// Trashing marks the workspace, its integrations and its connections deleted at once, and restoring
+	// takes them out of the trash together. Trashed records are hidden from every other query.
+	// Deleting purges the workspace, trashed or not, with everything it holds
 	InsertWorkspace(model.Workspace) (model.Workspace, error)
-	ListWorkspacesForPrincipal(string) ([]model.Workspace, error)
+	ListWorkspaces() ([]model.Workspace, error)
+	ListWorkspacesForPrincipal(principals ...string) ([]model.Workspace, error) // Workspaces granting any of the principals, e.g. a user and their groups
 	GetWorkspaceByID(string) (model.Workspace, error)
-	UpdateWorkspace(model.Workspace) (model.Workspace, error)
-	DeleteWorkspaceByID(id string)  (model.Workspace, error)
+	UpdateWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error)
+	DeleteWorkspaceByID(id string, events ...model.Event) (model.Workspace, error)
+	TrashWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error)
+	RestoreWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error)
+	GetTrashedWorkspaceByID(id string) (model.Workspace, error)
+	ListTrashedWorkspaces() ([]model.Workspace, error)
+
+	// Workspace variables
+	// Variables are identified by their name within their workspace. Setting one inserts or replaces it
+	ListWorkspaceVariables(workspaceID string) ([]model.WorkspaceVariable, error) // Sorted by name
+	SetWorkspaceVariable(model.WorkspaceVariable) (model.WorkspaceVariable, error)
+	DeleteWorkspaceVariable(workspaceID string, name string) (model.WorkspaceVariable, error)
+
+	// Environments
+	// Environments are identified by their name within their workspace
+	InsertEnvironment(model.Environment) (model.Environment, error)
+	GetEnvironment(workspaceID string, name string) (model.Environment, error)
+	ListEnvironments(workspaceID string) ([]model.Environment, error) // Sorted by name
+	UpdateEnvironment(model.Environment) (model.Environment, error)
+	DeleteEnvironment(workspaceID string, name string) (model.Environment, error)
+
+	// Invitations
+	// Accepting stores the accepted invitation, the workspace granting its permission and the organization
+	// of the workspace, when the invitee joins it, at once, only if the stored invitation is still pending
+	InsertInvitation(model.Invitation) (model.Invitation, error)
+	GetInvitationByID(id string) (model.Invitation, error)
+	ListInvitations(workspaceID string) ([]model.Invitation, error) // Most recent first
+	UpdateInvitation(model.Invitation) (model.Invitation, error)
+	AcceptInvitation(i model.Invitation, w model.Workspace, o *model.Organization, events ...model.Event) (model.Workspace, error)
+
+	// Organizations
+	// Organizations are indexed by member, like groups
+	InsertOrganization(model.Organization) (model.Organization, error)
+	GetOrganizationByID(id string) (model.Organization, error)
+	ListOrganizations() ([]model.Organization, error)
+	ListOrganizationsForMember(principal string) ([]model.Organization, error)
+	UpdateOrganization(model.Organization) (model.Organization, error)
+	DeleteOrganizationByID(id string) (model.Organization, error)
+
+	// Groups
+	// Groups are indexed by member, so the groups of a user are read without scanning them all
+	InsertGroup(model.Group) (model.Group, error)
+	GetGroupByID(id string) (model.Group, error)
+	ListGroups() ([]model.Group, error)
+	ListGroupsForMember(principal string) ([]model.Group, error)
+	UpdateGroup(model.Group) (model.Group, error)
+	DeleteGroupByID(id string) (model.Group, error)
+
+	// Workspace templates
+	InsertWorkspaceTemplate(model.WorkspaceTemplate) (model.WorkspaceTemplate, error)
+	GetWorkspaceTemplateByID(id string) (model.WorkspaceTemplate, error)
+	ListWorkspaceTemplates() ([]model.WorkspaceTemplate, error)
+	UpdateWorkspaceTemplate(model.WorkspaceTemplate) (model.WorkspaceTemplate, error)
+	DeleteWorkspaceTemplateByID(id string) (model.WorkspaceTemplate, error)
+
+	// Integration Definitions
+	// Definitions are stored per version. An empty version means the latest one.
+	InsertIntegrationDefinition(model.IntegrationDefinition) (model.IntegrationDefinition, error)
+	InsertIntegrationDefinitionVersion(model.IntegrationDefinition) (model.IntegrationDefinition, error)
+	GetIntegrationDefinition(id string, version string) (model.IntegrationDefinition, error)
+	ListIntegrationDefinitions() ([]model.IntegrationDefinition, error)
+	ListIntegrationDefinitionVersions(id string) ([]model.IntegrationDefinition, error)
+	UpdateIntegrationDefinition(d model.IntegrationDefinition, events ...model.Event) (model.IntegrationDefinition, error)
+	DeleteIntegrationDefinitionByID(id string) ([]model.IntegrationDefinition, error)
+
+	// Integrations
+	InsertIntegration(i model.Integration, events ...model.Event) (model.Integration, error)
+	GetIntegrationByID(id string) (model.Integration, error)
+	ListIntegrationsForWorkspace(workspaceID string) ([]model.Integration, error)
+	ListIntegrationsForDefinition(definitionID string) ([]model.Integration, error)
+	ListTrashedIntegrationsForDefinition(definitionID string) ([]model.Integration, error) // Integrations of workspaces in the trash
+	UpdateIntegration(i model.Integration, events ...model.Event) (model.Integration, error)
+	DeleteIntegrationByID(id string, events ...model.Event) (model.Integration, error)
+
+	// Connections
+	InsertConnection(c model.Connection, events ...model.Event) (model.Connection, error)
+	GetConnectionByID(id string) (model.Connection, error)
+	ListConnections() ([]model.Connection, error)
+	ListConnectionsForWorkspace(workspaceID string) ([]model.Connection, error)
+	ListConnectionsForIntegration(integrationID string) ([]model.Connection, error) // As source or destination
+	UpdateConnection(c model.Connection, events ...model.Event) (model.Connection, error)
+	DeleteConnectionByID(id string, events ...model.Event) (model.Connection, error)
+
+	// Runs
+	InsertRun(r model.Run, events ...model.Event) (model.Run, error)
+	GetRunByID(id string) (model.Run, error)
+	ListRuns(filter model.RunFilter) ([]model.Run, error) // Most recent first
+	UpdateRun(r model.Run, events ...model.Event) (model.Run, error)
+
+	// Connector states
+	// States that were never written are returned empty, at version 0.
+	// Swapping stores the state at the next version, only if the current one is the expected version,
+	// and returns a *model.StateConflictError otherwise
+	GetConnectorState(integration model.Integration) (model.ConnectorState, error)
+	SwapConnectorState(state model.ConnectorState, expectedVersion int64) (model.ConnectorState, error)
+	DeleteConnectorState(integrationID string) error
+	InsertStateReset(model.StateReset) (model.StateReset, error)
+	ListStateResets(integrationID string) ([]model.StateReset, error) // Most recent first
+
+	// Audit log
+	InsertAuditEntries([]model.AuditEntry) ([]model.AuditEntry, error)
+	ListAuditEntries(workspaceID string, offset int, limit int) ([]model.AuditEntry, error) // Most recent first
+
+	// OAuth sessions
+	// Sessions are identified by their state. Taking a session deletes it, so callbacks can't be replayed
+	InsertOAuthSession(model.OAuthSession) error
+	TakeOAuthSession(state string) (model.OAuthSession, error)
+
+	// Event outbox
+	// Dispatching inserts the deliveries of an event and marks it dispatched at once,
+	// so events are dispatched exactly once even if the process crashes in between
+	ListPendingEvents(limit int) ([]model.Event, error) // Oldest first
+	GetEventByID(id string) (model.Event, error)
+	DispatchEvent(id string, deliveries []model.WebhookDelivery) ([]model.WebhookDelivery, error)
+
+	// Webhooks
+	InsertWebhookSubscription(model.WebhookSubscription) (model.WebhookSubscription, error)
+	GetWebhookSubscriptionByID(id string) (model.WebhookSubscription, error)
+	ListWebhookSubscriptions() ([]model.WebhookSubscription, error)
+	ListWebhookSubscriptionsForWorkspace(workspaceID string) ([]model.WebhookSubscription, error) // Empty for global subscriptions
+	UpdateWebhookSubscription(model.WebhookSubscription) (model.WebhookSubscription, error)
+	DeleteWebhookSubscriptionByID(id string) (model.WebhookSubscription, error)
+	InsertWebhookDelivery(model.WebhookDelivery) (model.WebhookDelivery, error)
+	GetWebhookDeliveryByID(id string) (model.WebhookDelivery, error)
+	ListWebhookDeliveries(subscriptionID string, offset int, limit int) ([]model.WebhookDelivery, error) // Most recent first
+	ListDueWebhookDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) // Oldest first
+	UpdateWebhookDelivery(model.WebhookDelivery) (model.WebhookDelivery, error)
 }
@@ -0,0 +1,1029 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"time"
+)
+
+// Database restricted to the organizations of a user. Resources of other organizations are reported
+// not found, and can't be written. Resources outside organizations, e.g. workspaces created before them
+// or users who joined none, belong to no tenant and stay reachable, as before organizations.
+// Every method is implemented rather than embedding the database, so new queries have to decide
+// how they are isolated
+type tenantDB struct {
+	db Database
+	user string // ID of the user
+	organizations map[string]bool
+}
+
+func ForTenant(db Database, userID string, organizationIDs []string) Database {
+
+	organizations := map[string]bool{}
+	for _, id := range organizationIDs {
+		organizations[id] = true
+	}
+
+	return &tenantDB{ db, userID, organizations }
+}
+
+var errOutsideTenant = errors.New("Resource belongs to another organization")
+
+func notFound(resource string, id string) error {
+	return fmt.Errorf("%s with id %s not found", resource, id)
+}
+
+func (t *tenantDB) inTenant(organizationID string) bool {
+	return organizationID == "" || t.organizations[organizationID]
+}
+
+func (t *tenantDB) checkWorkspace(workspaceID string) error {
+
+	workspace, err := t.db.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return err
+	}
+	if !t.inTenant(workspace.OrganizationID) {
+		return notFound("Workspace", workspaceID)
+	}
+
+	return nil
+}
+
+// Checks the workspaces of a listing once each
+func (t *tenantDB) workspaceChecker() func(workspaceID string) bool {
+	visible := map[string]bool{}
+	return func(workspaceID string) bool {
+		ok, checked := visible[workspaceID]
+		if !checked {
+			ok = t.checkWorkspace(workspaceID) == nil
+			visible[workspaceID] = ok
+		}
+		return ok
+	}
+}
+
+// Users are reachable by themselves, by the members of their organizations, and by everyone when they
+// belong to none
+func (t *tenantDB) userVisible(user model.User) bool {
+
+	if user.ID == t.user {
+		return true
+	}
+
+	organizations, err := t.db.ListOrganizationsForMember(user.Principal())
+	if err != nil {
+		return false
+	}
+	if len(organizations) == 0 {
+		return true
+	}
+	for _, organization := range organizations {
+		if t.organizations[organization.ID] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Users
+func (t *tenantDB) GetUserBySub(sub string) (model.User, error) {
+	user, err := t.db.GetUserBySub(sub)
+	if err == nil && !t.userVisible(user) {
+		return model.User{}, fmt.Errorf("User with sub %s not found", sub)
+	}
+	return user, err
+}
+
+func (t *tenantDB) GetUserById(id string) (model.User, error) {
+	user, err := t.db.GetUserById(id)
+	if err == nil && !t.userVisible(user) {
+		return model.User{}, notFound("User", id)
+	}
+	return user, err
+}
+
+func (t *tenantDB) GetUserByEmail(email string) (model.User, error) {
+	user, err := t.db.GetUserByEmail(email)
+	if err == nil && !t.userVisible(user) {
+		return model.User{}, fmt.Errorf("User with email %s not found", email)
+	}
+	return user, err
+}
+
+// New users belong to no organization yet
+func (t *tenantDB) InsertUser(u model.User) (model.User, error) {
+	return t.db.InsertUser(u)
+}
+
+func (t *tenantDB) ListUsers(offset int, limit int) ([]model.User, error) {
+
+	users, err := t.db.ListUsers(offset, limit)
+	if err != nil {
+		return users, err
+	}
+
+	results := []model.User{}
+	for _, user := range users {
+		if t.userVisible(user) {
+			results = append(results, user)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) UpdateUser(id string, user model.User) (model.User, error) {
+	_, err := t.GetUserById(id)
+	if err != nil {
+		return model.User{}, err
+	}
+	return t.db.UpdateUser(id, user)
+}
+
+func (t *tenantDB) DeleteUserById(id string) (model.User, error) {
+	_, err := t.GetUserById(id)
+	if err != nil {
+		return model.User{}, err
+	}
+	return t.db.DeleteUserById(id)
+}
+
+// Workspaces
+func (t *tenantDB) InsertWorkspace(w model.Workspace) (model.Workspace, error) {
+	if !t.inTenant(w.OrganizationID) {
+		return model.Workspace{}, errOutsideTenant
+	}
+	return t.db.InsertWorkspace(w)
+}
+
+func (t *tenantDB) ListWorkspaces() ([]model.Workspace, error) {
+	workspaces, err := t.db.ListWorkspaces()
+	return t.filterWorkspaces(workspaces), err
+}
+
+func (t *tenantDB) ListWorkspacesForPrincipal(principals ...string) ([]model.Workspace, error) {
+	workspaces, err := t.db.ListWorkspacesForPrincipal(principals...)
+	return t.filterWorkspaces(workspaces), err
+}
+
+func (t *tenantDB) filterWorkspaces(workspaces []model.Workspace) []model.Workspace {
+	results := []model.Workspace{}
+	for _, workspace := range workspaces {
+		if t.inTenant(workspace.OrganizationID) {
+			results = append(results, workspace)
+		}
+	}
+	return results
+}
+
+func (t *tenantDB) GetWorkspaceByID(id string) (model.Workspace, error) {
+	workspace, err := t.db.GetWorkspaceByID(id)
+	if err == nil && !t.inTenant(workspace.OrganizationID) {
+		return model.Workspace{}, notFound("Workspace", id)
+	}
+	return workspace, err
+}
+
+func (t *tenantDB) UpdateWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error) {
+	err := t.checkWorkspace(w.ID)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if !t.inTenant(w.OrganizationID) {
+		return model.Workspace{}, errOutsideTenant
+	}
+	return t.db.UpdateWorkspace(w, events...)
+}
+
+func (t *tenantDB) DeleteWorkspaceByID(id string, events ...model.Event) (model.Workspace, error) {
+	err := t.checkWorkspace(id)
+	if err != nil {
+		_, err = t.GetTrashedWorkspaceByID(id)
+	}
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	return t.db.DeleteWorkspaceByID(id, events...)
+}
+
+func (t *tenantDB) TrashWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error) {
+	err := t.checkWorkspace(w.ID)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if !t.inTenant(w.OrganizationID) {
+		return model.Workspace{}, errOutsideTenant
+	}
+	return t.db.TrashWorkspace(w, events...)
+}
+
+func (t *tenantDB) RestoreWorkspace(w model.Workspace, events ...model.Event) (model.Workspace, error) {
+	_, err := t.GetTrashedWorkspaceByID(w.ID)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if !t.inTenant(w.OrganizationID) {
+		return model.Workspace{}, errOutsideTenant
+	}
+	return t.db.RestoreWorkspace(w, events...)
+}
+
+func (t *tenantDB) GetTrashedWorkspaceByID(id string) (model.Workspace, error) {
+	workspace, err := t.db.GetTrashedWorkspaceByID(id)
+	if err == nil && !t.inTenant(workspace.OrganizationID) {
+		return model.Workspace{}, notFound("Workspace", id)
+	}
+	return workspace, err
+}
+
+func (t *tenantDB) ListTrashedWorkspaces() ([]model.Workspace, error) {
+	workspaces, err := t.db.ListTrashedWorkspaces()
+	return t.filterWorkspaces(workspaces), err
+}
+
+// Workspace variables
+func (t *tenantDB) ListWorkspaceVariables(workspaceID string) ([]model.WorkspaceVariable, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return []model.WorkspaceVariable{}, err
+	}
+	return t.db.ListWorkspaceVariables(workspaceID)
+}
+
+func (t *tenantDB) SetWorkspaceVariable(v model.WorkspaceVariable) (model.WorkspaceVariable, error) {
+	err := t.checkWorkspace(v.WorkspaceID)
+	if err != nil {
+		return model.WorkspaceVariable{}, err
+	}
+	return t.db.SetWorkspaceVariable(v)
+}
+
+func (t *tenantDB) DeleteWorkspaceVariable(workspaceID string, name string) (model.WorkspaceVariable, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return model.WorkspaceVariable{}, err
+	}
+	return t.db.DeleteWorkspaceVariable(workspaceID, name)
+}
+
+// Environments
+func (t *tenantDB) InsertEnvironment(e model.Environment) (model.Environment, error) {
+	err := t.checkWorkspace(e.WorkspaceID)
+	if err != nil {
+		return model.Environment{}, err
+	}
+	return t.db.InsertEnvironment(e)
+}
+
+func (t *tenantDB) GetEnvironment(workspaceID string, name string) (model.Environment, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return model.Environment{}, err
+	}
+	return t.db.GetEnvironment(workspaceID, name)
+}
+
+func (t *tenantDB) ListEnvironments(workspaceID string) ([]model.Environment, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return []model.Environment{}, err
+	}
+	return t.db.ListEnvironments(workspaceID)
+}
+
+func (t *tenantDB) UpdateEnvironment(e model.Environment) (model.Environment, error) {
+	err := t.checkWorkspace(e.WorkspaceID)
+	if err != nil {
+		return model.Environment{}, err
+	}
+	return t.db.UpdateEnvironment(e)
+}
+
+func (t *tenantDB) DeleteEnvironment(workspaceID string, name string) (model.Environment, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return model.Environment{}, err
+	}
+	return t.db.DeleteEnvironment(workspaceID, name)
+}
+
+// Invitations
+func (t *tenantDB) InsertInvitation(i model.Invitation) (model.Invitation, error) {
+	err := t.checkWorkspace(i.WorkspaceID)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	return t.db.InsertInvitation(i)
+}
+
+func (t *tenantDB) GetInvitationByID(id string) (model.Invitation, error) {
+	invitation, err := t.db.GetInvitationByID(id)
+	if err == nil && t.checkWorkspace(invitation.WorkspaceID) != nil {
+		return model.Invitation{}, notFound("Invitation", id)
+	}
+	return invitation, err
+}
+
+func (t *tenantDB) ListInvitations(workspaceID string) ([]model.Invitation, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return []model.Invitation{}, err
+	}
+	return t.db.ListInvitations(workspaceID)
+}
+
+func (t *tenantDB) UpdateInvitation(i model.Invitation) (model.Invitation, error) {
+	_, err := t.GetInvitationByID(i.ID)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	return t.db.UpdateInvitation(i)
+}
+
+func (t *tenantDB) AcceptInvitation(i model.Invitation, w model.Workspace, o *model.Organization, events ...model.Event) (model.Workspace, error) {
+	err := t.checkWorkspace(w.ID)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	return t.db.AcceptInvitation(i, w, o, events...)
+}
+
+// Organizations
+func (t *tenantDB) InsertOrganization(o model.Organization) (model.Organization, error) {
+	return model.Organization{}, errOutsideTenant
+}
+
+func (t *tenantDB) GetOrganizationByID(id string) (model.Organization, error) {
+	if !t.organizations[id] {
+		return model.Organization{}, notFound("Organization", id)
+	}
+	return t.db.GetOrganizationByID(id)
+}
+
+func (t *tenantDB) ListOrganizations() ([]model.Organization, error) {
+	organizations, err := t.db.ListOrganizations()
+	return t.filterOrganizations(organizations), err
+}
+
+func (t *tenantDB) ListOrganizationsForMember(principal string) ([]model.Organization, error) {
+	organizations, err := t.db.ListOrganizationsForMember(principal)
+	return t.filterOrganizations(organizations), err
+}
+
+func (t *tenantDB) filterOrganizations(organizations []model.Organization) []model.Organization {
+	results := []model.Organization{}
+	for _, organization := range organizations {
+		if t.organizations[organization.ID] {
+			results = append(results, organization)
+		}
+	}
+	return results
+}
+
+func (t *tenantDB) UpdateOrganization(o model.Organization) (model.Organization, error) {
+	if !t.organizations[o.ID] {
+		return model.Organization{}, notFound("Organization", o.ID)
+	}
+	return t.db.UpdateOrganization(o)
+}
+
+func (t *tenantDB) DeleteOrganizationByID(id string) (model.Organization, error) {
+	if !t.organizations[id] {
+		return model.Organization{}, notFound("Organization", id)
+	}
+	return t.db.DeleteOrganizationByID(id)
+}
+
+// Groups
+func (t *tenantDB) InsertGroup(g model.Group) (model.Group, error) {
+	if !t.inTenant(g.OrganizationID) {
+		return model.Group{}, errOutsideTenant
+	}
+	return t.db.InsertGroup(g)
+}
+
+func (t *tenantDB) GetGroupByID(id string) (model.Group, error) {
+	group, err := t.db.GetGroupByID(id)
+	if err == nil && !t.inTenant(group.OrganizationID) {
+		return model.Group{}, notFound("Group", id)
+	}
+	return group, err
+}
+
+func (t *tenantDB) ListGroups() ([]model.Group, error) {
+	groups, err := t.db.ListGroups()
+	return t.filterGroups(groups), err
+}
+
+func (t *tenantDB) ListGroupsForMember(principal string) ([]model.Group, error) {
+	groups, err := t.db.ListGroupsForMember(principal)
+	return t.filterGroups(groups), err
+}
+
+func (t *tenantDB) filterGroups(groups []model.Group) []model.Group {
+	results := []model.Group{}
+	for _, group := range groups {
+		if t.inTenant(group.OrganizationID) {
+			results = append(results, group)
+		}
+	}
+	return results
+}
+
+func (t *tenantDB) UpdateGroup(g model.Group) (model.Group, error) {
+	_, err := t.GetGroupByID(g.ID)
+	if err != nil {
+		return model.Group{}, err
+	}
+	if !t.inTenant(g.OrganizationID) {
+		return model.Group{}, errOutsideTenant
+	}
+	return t.db.UpdateGroup(g)
+}
+
+func (t *tenantDB) DeleteGroupByID(id string) (model.Group, error) {
+	_, err := t.GetGroupByID(id)
+	if err != nil {
+		return model.Group{}, err
+	}
+	return t.db.DeleteGroupByID(id)
+}
+
+// Workspace templates
+func (t *tenantDB) InsertWorkspaceTemplate(w model.WorkspaceTemplate) (model.WorkspaceTemplate, error) {
+	if !t.inTenant(w.OrganizationID) {
+		return model.WorkspaceTemplate{}, errOutsideTenant
+	}
+	return t.db.InsertWorkspaceTemplate(w)
+}
+
+func (t *tenantDB) GetWorkspaceTemplateByID(id string) (model.WorkspaceTemplate, error) {
+	template, err := t.db.GetWorkspaceTemplateByID(id)
+	if err == nil && !t.inTenant(template.OrganizationID) {
+		return model.WorkspaceTemplate{}, notFound("Template", id)
+	}
+	return template, err
+}
+
+func (t *tenantDB) ListWorkspaceTemplates() ([]model.WorkspaceTemplate, error) {
+	templates, err := t.db.ListWorkspaceTemplates()
+	results := []model.WorkspaceTemplate{}
+	for _, template := range templates {
+		if t.inTenant(template.OrganizationID) {
+			results = append(results, template)
+		}
+	}
+	return results, err
+}
+
+func (t *tenantDB) UpdateWorkspaceTemplate(w model.WorkspaceTemplate) (model.WorkspaceTemplate, error) {
+	_, err := t.GetWorkspaceTemplateByID(w.ID)
+	if err != nil {
+		return model.WorkspaceTemplate{}, err
+	}
+	if !t.inTenant(w.OrganizationID) {
+		return model.WorkspaceTemplate{}, errOutsideTenant
+	}
+	return t.db.UpdateWorkspaceTemplate(w)
+}
+
+func (t *tenantDB) DeleteWorkspaceTemplateByID(id string) (model.WorkspaceTemplate, error) {
+	_, err := t.GetWorkspaceTemplateByID(id)
+	if err != nil {
+		return model.WorkspaceTemplate{}, err
+	}
+	return t.db.DeleteWorkspaceTemplateByID(id)
+}
+
+// Integration Definitions. Public definitions are shared by every organization, private ones are only
+// reachable in the tenant of their organization or workspace
+func (t *tenantDB) definitionInTenant(scope model.DefinitionScope) bool {
+	if !t.inTenant(scope.OrganizationID) {
+		return false
+	}
+	return scope.WorkspaceID == "" || t.checkWorkspace(scope.WorkspaceID) == nil
+}
+
+func (t *tenantDB) checkDefinition(id string) error {
+
+	definition, err := t.db.GetIntegrationDefinition(id, "")
+	if err != nil {
+		return err
+	}
+	if !t.definitionInTenant(definition.Scope) {
+		return notFound("Integration definition", id)
+	}
+
+	return nil
+}
+
+func (t *tenantDB) InsertIntegrationDefinition(d model.IntegrationDefinition) (model.IntegrationDefinition, error) {
+	if !t.definitionInTenant(d.Scope) {
+		return model.IntegrationDefinition{}, errOutsideTenant
+	}
+	return t.db.InsertIntegrationDefinition(d)
+}
+
+func (t *tenantDB) InsertIntegrationDefinitionVersion(d model.IntegrationDefinition) (model.IntegrationDefinition, error) {
+	err := t.checkDefinition(d.ID)
+	if err != nil {
+		return model.IntegrationDefinition{}, err
+	}
+	if !t.definitionInTenant(d.Scope) {
+		return model.IntegrationDefinition{}, errOutsideTenant
+	}
+	return t.db.InsertIntegrationDefinitionVersion(d)
+}
+
+func (t *tenantDB) GetIntegrationDefinition(id string, version string) (model.IntegrationDefinition, error) {
+	definition, err := t.db.GetIntegrationDefinition(id, version)
+	if err == nil && !t.definitionInTenant(definition.Scope) {
+		return model.IntegrationDefinition{}, notFound("Integration definition", id)
+	}
+	return definition, err
+}
+
+func (t *tenantDB) ListIntegrationDefinitions() ([]model.IntegrationDefinition, error) {
+
+	definitions, err := t.db.ListIntegrationDefinitions()
+	if err != nil {
+		return definitions, err
+	}
+
+	results := []model.IntegrationDefinition{}
+	for _, definition := range definitions {
+		if t.definitionInTenant(definition.Scope) {
+			results = append(results, definition)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) ListIntegrationDefinitionVersions(id string) ([]model.IntegrationDefinition, error) {
+	err := t.checkDefinition(id)
+	if err != nil {
+		return []model.IntegrationDefinition{}, err
+	}
+	return t.db.ListIntegrationDefinitionVersions(id)
+}
+
+func (t *tenantDB) UpdateIntegrationDefinition(d model.IntegrationDefinition, events ...model.Event) (model.IntegrationDefinition, error) {
+	err := t.checkDefinition(d.ID)
+	if err != nil {
+		return model.IntegrationDefinition{}, err
+	}
+	if !t.definitionInTenant(d.Scope) {
+		return model.IntegrationDefinition{}, errOutsideTenant
+	}
+	return t.db.UpdateIntegrationDefinition(d, events...)
+}
+
+func (t *tenantDB) DeleteIntegrationDefinitionByID(id string) ([]model.IntegrationDefinition, error) {
+	err := t.checkDefinition(id)
+	if err != nil {
+		return []model.IntegrationDefinition{}, err
+	}
+	return t.db.DeleteIntegrationDefinitionByID(id)
+}
+
+// Integrations
+func (t *tenantDB) InsertIntegration(i model.Integration, events ...model.Event) (model.Integration, error) {
+	err := t.checkWorkspace(i.WorkspaceID)
+	if err != nil {
+		return model.Integration{}, err
+	}
+	return t.db.InsertIntegration(i, events...)
+}
+
+func (t *tenantDB) GetIntegrationByID(id string) (model.Integration, error) {
+	integration, err := t.db.GetIntegrationByID(id)
+	if err == nil && t.checkWorkspace(integration.WorkspaceID) != nil {
+		return model.Integration{}, notFound("Integration", id)
+	}
+	return integration, err
+}
+
+func (t *tenantDB) ListIntegrationsForWorkspace(workspaceID string) ([]model.Integration, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return []model.Integration{}, err
+	}
+	return t.db.ListIntegrationsForWorkspace(workspaceID)
+}
+
+func (t *tenantDB) ListIntegrationsForDefinition(definitionID string) ([]model.Integration, error) {
+
+	integrations, err := t.db.ListIntegrationsForDefinition(definitionID)
+	if err != nil {
+		return integrations, err
+	}
+
+	visible := t.workspaceChecker()
+	results := []model.Integration{}
+	for _, integration := range integrations {
+		if visible(integration.WorkspaceID) {
+			results = append(results, integration)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) ListTrashedIntegrationsForDefinition(definitionID string) ([]model.Integration, error) {
+
+	integrations, err := t.db.ListTrashedIntegrationsForDefinition(definitionID)
+	if err != nil {
+		return integrations, err
+	}
+
+	visible := map[string]bool{}
+	results := []model.Integration{}
+	for _, integration := range integrations {
+		ok, checked := visible[integration.WorkspaceID]
+		if !checked {
+			_, err := t.GetTrashedWorkspaceByID(integration.WorkspaceID)
+			ok = err == nil
+			visible[integration.WorkspaceID] = ok
+		}
+		if ok {
+			results = append(results, integration)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) UpdateIntegration(i model.Integration, events ...model.Event) (model.Integration, error) {
+	_, err := t.GetIntegrationByID(i.ID)
+	if err != nil {
+		return model.Integration{}, err
+	}
+	err = t.checkWorkspace(i.WorkspaceID)
+	if err != nil {
+		return model.Integration{}, err
+	}
+	return t.db.UpdateIntegration(i, events...)
+}
+
+func (t *tenantDB) DeleteIntegrationByID(id string, events ...model.Event) (model.Integration, error) {
+	_, err := t.GetIntegrationByID(id)
+	if err != nil {
+		return model.Integration{}, err
+	}
+	return t.db.DeleteIntegrationByID(id, events...)
+}
+
+// Connections
+func (t *tenantDB) InsertConnection(c model.Connection, events ...model.Event) (model.Connection, error) {
+	err := t.checkWorkspace(c.WorkspaceID)
+	if err != nil {
+		return model.Connection{}, err
+	}
+	return t.db.InsertConnection(c, events...)
+}
+
+func (t *tenantDB) GetConnectionByID(id string) (model.Connection, error) {
+	connection, err := t.db.GetConnectionByID(id)
+	if err == nil && t.checkWorkspace(connection.WorkspaceID) != nil {
+		return model.Connection{}, notFound("Connection", id)
+	}
+	return connection, err
+}
+
+func (t *tenantDB) ListConnections() ([]model.Connection, error) {
+	connections, err := t.db.ListConnections()
+	return t.filterConnections(connections), err
+}
+
+func (t *tenantDB) ListConnectionsForWorkspace(workspaceID string) ([]model.Connection, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return []model.Connection{}, err
+	}
+	return t.db.ListConnectionsForWorkspace(workspaceID)
+}
+
+func (t *tenantDB) ListConnectionsForIntegration(integrationID string) ([]model.Connection, error) {
+	connections, err := t.db.ListConnectionsForIntegration(integrationID)
+	return t.filterConnections(connections), err
+}
+
+func (t *tenantDB) filterConnections(connections []model.Connection) []model.Connection {
+	visible := t.workspaceChecker()
+	results := []model.Connection{}
+	for _, connection := range connections {
+		if visible(connection.WorkspaceID) {
+			results = append(results, connection)
+		}
+	}
+	return results
+}
+
+func (t *tenantDB) UpdateConnection(c model.Connection, events ...model.Event) (model.Connection, error) {
+	_, err := t.GetConnectionByID(c.ID)
+	if err != nil {
+		return model.Connection{}, err
+	}
+	err = t.checkWorkspace(c.WorkspaceID)
+	if err != nil {
+		return model.Connection{}, err
+	}
+	return t.db.UpdateConnection(c, events...)
+}
+
+func (t *tenantDB) DeleteConnectionByID(id string, events ...model.Event) (model.Connection, error) {
+	_, err := t.GetConnectionByID(id)
+	if err != nil {
+		return model.Connection{}, err
+	}
+	return t.db.DeleteConnectionByID(id, events...)
+}
+
+// Runs
+func (t *tenantDB) InsertRun(r model.Run, events ...model.Event) (model.Run, error) {
+	err := t.checkWorkspace(r.WorkspaceID)
+	if err != nil {
+		return model.Run{}, err
+	}
+	return t.db.InsertRun(r, events...)
+}
+
+func (t *tenantDB) GetRunByID(id string) (model.Run, error) {
+	run, err := t.db.GetRunByID(id)
+	if err == nil && t.checkWorkspace(run.WorkspaceID) != nil {
+		return model.Run{}, notFound("Run", id)
+	}
+	return run, err
+}
+
+func (t *tenantDB) ListRuns(filter model.RunFilter) ([]model.Run, error) {
+
+	runs, err := t.db.ListRuns(filter)
+	if err != nil {
+		return runs, err
+	}
+
+	visible := t.workspaceChecker()
+	results := []model.Run{}
+	for _, run := range runs {
+		if visible(run.WorkspaceID) {
+			results = append(results, run)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) UpdateRun(r model.Run, events ...model.Event) (model.Run, error) {
+	_, err := t.GetRunByID(r.ID)
+	if err != nil {
+		return model.Run{}, err
+	}
+	err = t.checkWorkspace(r.WorkspaceID)
+	if err != nil {
+		return model.Run{}, err
+	}
+	return t.db.UpdateRun(r, events...)
+}
+
+// Connector states. Checked through their stored integration
+func (t *tenantDB) GetConnectorState(integration model.Integration) (model.ConnectorState, error) {
+	_, err := t.GetIntegrationByID(integration.ID)
+	if err != nil {
+		return model.ConnectorState{}, err
+	}
+	return t.db.GetConnectorState(integration)
+}
+
+func (t *tenantDB) SwapConnectorState(state model.ConnectorState, expectedVersion int64) (model.ConnectorState, error) {
+	_, err := t.GetIntegrationByID(state.IntegrationID)
+	if err != nil {
+		return model.ConnectorState{}, err
+	}
+	return t.db.SwapConnectorState(state, expectedVersion)
+}
+
+func (t *tenantDB) DeleteConnectorState(integrationID string) error {
+	_, err := t.GetIntegrationByID(integrationID)
+	if err != nil {
+		return err
+	}
+	return t.db.DeleteConnectorState(integrationID)
+}
+
+func (t *tenantDB) InsertStateReset(r model.StateReset) (model.StateReset, error) {
+	_, err := t.GetIntegrationByID(r.IntegrationID)
+	if err != nil {
+		return model.StateReset{}, err
+	}
+	return t.db.InsertStateReset(r)
+}
+
+func (t *tenantDB) ListStateResets(integrationID string) ([]model.StateReset, error) {
+	_, err := t.GetIntegrationByID(integrationID)
+	if err != nil {
+		return []model.StateReset{}, err
+	}
+	return t.db.ListStateResets(integrationID)
+}
+
+// Audit log
+func (t *tenantDB) InsertAuditEntries(entries []model.AuditEntry) ([]model.AuditEntry, error) {
+	visible := t.workspaceChecker()
+	for _, entry := range entries {
+		if !visible(entry.WorkspaceID) {
+			return []model.AuditEntry{}, notFound("Workspace", entry.WorkspaceID)
+		}
+	}
+	return t.db.InsertAuditEntries(entries)
+}
+
+func (t *tenantDB) ListAuditEntries(workspaceID string, offset int, limit int) ([]model.AuditEntry, error) {
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return []model.AuditEntry{}, err
+	}
+	return t.db.ListAuditEntries(workspaceID, offset, limit)
+}
+
+// OAuth sessions
+func (t *tenantDB) InsertOAuthSession(s model.OAuthSession) error {
+	err := t.checkWorkspace(s.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	return t.db.InsertOAuthSession(s)
+}
+
+// Sessions of other organizations are taken all the same, so their state can't be tried again
+func (t *tenantDB) TakeOAuthSession(state string) (model.OAuthSession, error) {
+	session, err := t.db.TakeOAuthSession(state)
+	if err == nil && t.checkWorkspace(session.WorkspaceID) != nil {
+		return model.OAuthSession{}, errors.New("OAuth session not found")
+	}
+	return session, err
+}
+
+// Event outbox. Events without workspace, e.g. of definitions, are global
+func (t *tenantDB) ListPendingEvents(limit int) ([]model.Event, error) {
+
+	events, err := t.db.ListPendingEvents(limit)
+	if err != nil {
+		return events, err
+	}
+
+	visible := t.workspaceChecker()
+	results := []model.Event{}
+	for _, event := range events {
+		if event.WorkspaceID == "" || visible(event.WorkspaceID) {
+			results = append(results, event)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) GetEventByID(id string) (model.Event, error) {
+	event, err := t.db.GetEventByID(id)
+	if err == nil && event.WorkspaceID != "" && t.checkWorkspace(event.WorkspaceID) != nil {
+		return model.Event{}, notFound("Event", id)
+	}
+	return event, err
+}
+
+func (t *tenantDB) DispatchEvent(id string, deliveries []model.WebhookDelivery) ([]model.WebhookDelivery, error) {
+	_, err := t.GetEventByID(id)
+	if err != nil {
+		return []model.WebhookDelivery{}, err
+	}
+	return t.db.DispatchEvent(id, deliveries)
+}
+
+// Webhooks. Global subscriptions belong to the platform, not to any organization
+func (t *tenantDB) subscriptionVisible(s model.WebhookSubscription) bool {
+	return s.WorkspaceID != "" && t.checkWorkspace(s.WorkspaceID) == nil
+}
+
+func (t *tenantDB) InsertWebhookSubscription(s model.WebhookSubscription) (model.WebhookSubscription, error) {
+	if !t.subscriptionVisible(s) {
+		return model.WebhookSubscription{}, errOutsideTenant
+	}
+	return t.db.InsertWebhookSubscription(s)
+}
+
+func (t *tenantDB) GetWebhookSubscriptionByID(id string) (model.WebhookSubscription, error) {
+	subscription, err := t.db.GetWebhookSubscriptionByID(id)
+	if err == nil && !t.subscriptionVisible(subscription) {
+		return model.WebhookSubscription{}, notFound("Webhook subscription", id)
+	}
+	return subscription, err
+}
+
+func (t *tenantDB) ListWebhookSubscriptions() ([]model.WebhookSubscription, error) {
+
+	subscriptions, err := t.db.ListWebhookSubscriptions()
+	if err != nil {
+		return subscriptions, err
+	}
+
+	results := []model.WebhookSubscription{}
+	for _, subscription := range subscriptions {
+		if t.subscriptionVisible(subscription) {
+			results = append(results, subscription)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) ListWebhookSubscriptionsForWorkspace(workspaceID string) ([]model.WebhookSubscription, error) {
+	if workspaceID == "" {
+		return []model.WebhookSubscription{}, errOutsideTenant
+	}
+	err := t.checkWorkspace(workspaceID)
+	if err != nil {
+		return []model.WebhookSubscription{}, err
+	}
+	return t.db.ListWebhookSubscriptionsForWorkspace(workspaceID)
+}
+
+func (t *tenantDB) UpdateWebhookSubscription(s model.WebhookSubscription) (model.WebhookSubscription, error) {
+	_, err := t.GetWebhookSubscriptionByID(s.ID)
+	if err != nil {
+		return model.WebhookSubscription{}, err
+	}
+	if !t.subscriptionVisible(s) {
+		return model.WebhookSubscription{}, errOutsideTenant
+	}
+	return t.db.UpdateWebhookSubscription(s)
+}
+
+func (t *tenantDB) DeleteWebhookSubscriptionByID(id string) (model.WebhookSubscription, error) {
+	_, err := t.GetWebhookSubscriptionByID(id)
+	if err != nil {
+		return model.WebhookSubscription{}, err
+	}
+	return t.db.DeleteWebhookSubscriptionByID(id)
+}
+
+func (t *tenantDB) InsertWebhookDelivery(d model.WebhookDelivery) (model.WebhookDelivery, error) {
+	_, err := t.GetWebhookSubscriptionByID(d.SubscriptionID)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return t.db.InsertWebhookDelivery(d)
+}
+
+func (t *tenantDB) GetWebhookDeliveryByID(id string) (model.WebhookDelivery, error) {
+	delivery, err := t.db.GetWebhookDeliveryByID(id)
+	if err == nil {
+		if _, err := t.GetWebhookSubscriptionByID(delivery.SubscriptionID); err != nil {
+			return model.WebhookDelivery{}, notFound("Webhook delivery", id)
+		}
+	}
+	return delivery, err
+}
+
+func (t *tenantDB) ListWebhookDeliveries(subscriptionID string, offset int, limit int) ([]model.WebhookDelivery, error) {
+	_, err := t.GetWebhookSubscriptionByID(subscriptionID)
+	if err != nil {
+		return []model.WebhookDelivery{}, err
+	}
+	return t.db.ListWebhookDeliveries(subscriptionID, offset, limit)
+}
+
+func (t *tenantDB) ListDueWebhookDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+
+	deliveries, err := t.db.ListDueWebhookDeliveries(now, limit)
+	if err != nil {
+		return deliveries, err
+	}
+
+	visible := map[string]bool{}
+	results := []model.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		ok, checked := visible[delivery.SubscriptionID]
+		if !checked {
+			_, err := t.GetWebhookSubscriptionByID(delivery.SubscriptionID)
+			ok = err == nil
+			visible[delivery.SubscriptionID] = ok
+		}
+		if ok {
+			results = append(results, delivery)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *tenantDB) UpdateWebhookDelivery(d model.WebhookDelivery) (model.WebhookDelivery, error) {
+	_, err := t.GetWebhookDeliveryByID(d.ID)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return t.db.UpdateWebhookDelivery(d)
+}
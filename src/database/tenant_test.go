@@ -0,0 +1,159 @@
+package database
+
+import (
+	"testing"
+	"time"
+	"smartgrowth-connectors/configapi/model"
+)
+
+// Resources of an organization, inserted without tenant restrictions
+type tenantFixture struct {
+	organization model.Organization
+	workspace model.Workspace
+	integration model.Integration
+	definition model.IntegrationDefinition
+	subscription model.WebhookSubscription
+	run model.Run
+}
+
+func insertTenant(t *testing.T, db Database, name string) tenantFixture {
+
+	var fixture tenantFixture
+	now := time.Now()
+
+	organization, err := model.NewOrganization(name, []model.OrganizationMember{ { Principal: "user:" + name, Role: model.OrganizationAdmin } }, name, now)
+	if err != nil {
+		t.Fatalf("Invalid organization: %v", err)
+	}
+	fixture.organization, err = db.InsertOrganization(organization)
+	if err != nil {
+		t.Fatalf("Error inserting organization: %v", err)
+	}
+
+	fixture.workspace, err = db.InsertWorkspace(model.Workspace{ OrganizationID: fixture.organization.ID, Name: name, Permissions: []model.WorkspacePermission{} })
+	if err != nil {
+		t.Fatalf("Error inserting workspace: %v", err)
+	}
+
+	definition, err := model.NewIntegrationDefinition(name, "source", model.ConfigurationSchema{ { Label: "host", Type: "string" } })
+	if err != nil {
+		t.Fatalf("Invalid definition: %v", err)
+	}
+	definition.Scope = model.DefinitionScope{ Visibility: model.DefinitionOrganization, OrganizationID: fixture.organization.ID }
+	fixture.definition, err = db.InsertIntegrationDefinition(definition)
+	if err != nil {
+		t.Fatalf("Error inserting definition: %v", err)
+	}
+
+	fixture.integration, err = db.InsertIntegration(model.Integration{ Name: name, WorkspaceID: fixture.workspace.ID, DefinitionID: fixture.definition.ID, DefinitionVersion: fixture.definition.Version, Configuration: model.IntegrationConfig{ "host": "db" } })
+	if err != nil {
+		t.Fatalf("Error inserting integration: %v", err)
+	}
+
+	fixture.subscription, err = db.InsertWebhookSubscription(model.WebhookSubscription{ WorkspaceID: fixture.workspace.ID, URL: "https://example.com/" + name, Events: []string{ model.EventIntegrationCreated }, Active: true })
+	if err != nil {
+		t.Fatalf("Error inserting webhook subscription: %v", err)
+	}
+
+	fixture.run, err = db.InsertRun(model.Run{ WorkspaceID: fixture.workspace.ID, IntegrationIDs: []string{ fixture.integration.ID }, Status: "running", StartedAt: now })
+	if err != nil {
+		t.Fatalf("Error inserting run: %v", err)
+	}
+
+	return fixture
+}
+
+func TestTenantIsolation(t *testing.T) {
+
+	root, err := NewInMemoryDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := insertTenant(t, root, "a")
+	b := insertTenant(t, root, "b")
+	db := ForTenant(root, "a", []string{ a.organization.ID })
+
+	// Resources of its own organization are reachable
+	if _, err := db.GetWorkspaceByID(a.workspace.ID); err != nil {
+		t.Errorf("Expected own workspace to be found, got %v", err)
+	}
+	if _, err := db.GetIntegrationByID(a.integration.ID); err != nil {
+		t.Errorf("Expected own integration to be found, got %v", err)
+	}
+	if _, err := db.GetIntegrationDefinition(a.definition.ID, ""); err != nil {
+		t.Errorf("Expected own definition to be found, got %v", err)
+	}
+	if _, err := db.GetWebhookSubscriptionByID(a.subscription.ID); err != nil {
+		t.Errorf("Expected own webhook subscription to be found, got %v", err)
+	}
+	if _, err := db.GetRunByID(a.run.ID); err != nil {
+		t.Errorf("Expected own run to be found, got %v", err)
+	}
+
+	// Resources of the other organization are not found
+	expectNotFound := func(resource string, err error) {
+		if err == nil {
+			t.Errorf("Expected %s of another organization not to be found", resource)
+		}
+	}
+	_, err = db.GetOrganizationByID(b.organization.ID)
+	expectNotFound("organization", err)
+	_, err = db.GetWorkspaceByID(b.workspace.ID)
+	expectNotFound("workspace", err)
+	_, err = db.GetIntegrationByID(b.integration.ID)
+	expectNotFound("integration", err)
+	_, err = db.ListIntegrationsForWorkspace(b.workspace.ID)
+	expectNotFound("integrations", err)
+	_, err = db.GetIntegrationDefinition(b.definition.ID, "")
+	expectNotFound("definition", err)
+	_, err = db.GetWebhookSubscriptionByID(b.subscription.ID)
+	expectNotFound("webhook subscription", err)
+	_, err = db.ListWebhookSubscriptionsForWorkspace(b.workspace.ID)
+	expectNotFound("webhook subscriptions", err)
+	_, err = db.GetRunByID(b.run.ID)
+	expectNotFound("run", err)
+
+	// Nor listed
+	workspaces, _ := db.ListWorkspaces()
+	for _, workspace := range workspaces {
+		if workspace.ID == b.workspace.ID {
+			t.Errorf("Expected workspaces of another organization not to be listed")
+		}
+	}
+	definitions, _ := db.ListIntegrationDefinitions()
+	for _, definition := range definitions {
+		if definition.ID == b.definition.ID {
+			t.Errorf("Expected definitions of another organization not to be listed")
+		}
+	}
+	subscriptions, _ := db.ListWebhookSubscriptions()
+	for _, subscription := range subscriptions {
+		if subscription.ID == b.subscription.ID {
+			t.Errorf("Expected webhook subscriptions of another organization not to be listed")
+		}
+	}
+	runs, _ := db.ListRuns(model.RunFilter{ WorkspaceID: b.workspace.ID })
+	if len(runs) != 0 {
+		t.Errorf("Expected runs of another organization not to be listed, got %v", runs)
+	}
+
+	// Nor written
+	if _, err := db.UpdateWorkspace(b.workspace); err == nil {
+		t.Errorf("Expected error updating a workspace of another organization")
+	}
+	if _, err := db.InsertIntegration(model.Integration{ Name: "x", WorkspaceID: b.workspace.ID, DefinitionID: a.definition.ID, DefinitionVersion: a.definition.Version }); err == nil {
+		t.Errorf("Expected error inserting an integration into a workspace of another organization")
+	}
+	if _, err := db.DeleteIntegrationByID(b.integration.ID); err == nil {
+		t.Errorf("Expected error deleting an integration of another organization")
+	}
+	if _, err := db.DeleteWebhookSubscriptionByID(b.subscription.ID); err == nil {
+		t.Errorf("Expected error deleting a webhook subscription of another organization")
+	}
+	if _, err := db.UpdateRun(b.run); err == nil {
+		t.Errorf("Expected error updating a run of another organization")
+	}
+	if _, err := root.GetIntegrationByID(b.integration.ID); err != nil {
+		t.Errorf("Expected integration of another organization to be left untouched, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package eventbus
+
+import (
+	"fmt"
+	"smartgrowth-connectors/configapi/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Why a subscription was closed by the bus
+const (
+	ClosedRevoked = "revoked" // The subscriber lost access to the workspace
+	ClosedLagging = "lagging" // The subscriber didn't keep up. It should reconnect with its last event ID
+	ClosedShutdown = "shutdown"
+)
+
+// Buffered messages per subscriber, on top of the replayed ones
+const subscriberBuffer = 64
+
+// In process bus carrying the events of committed changes to the live streams of the workspaces.
+// The most recent events are kept, so subscribers reconnecting with the ID of the last event they
+// received get the events they missed.
+type Bus struct {
+	lock sync.Mutex
+	boot string // Prefix of the IDs, so IDs of a previous process are recognized
+	seq int64
+	history []Message // Most recent last
+	historySize int
+	subscribers map[*Subscription]bool
+}
+
+type Message struct {
+	ID string // "<boot>-<sequence>"
+	Event model.Event
+}
+
+type Subscription struct {
+	WorkspaceID string
+	OrganizationID string // Of the workspace, so it receives the events of the organization's resources
+	Principals []string // Principal IDs of the subscriber and of its groups when it subscribed, checked again when the permissions of the workspace change
+	C <-chan Message // Closed when the subscription is
+	Reset bool // The missed events are not known anymore, the subscriber should reload the workspace
+	messages chan Message
+	reason string
+	bus *Bus
+}
+
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		boot: strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize: historySize,
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+// Sends the event to the subscribers of its workspace. Events without a workspace, e.g. of definitions,
+// go to every subscriber, or to the subscribers of the workspaces of their organization. When the permissions of a workspace change, subscribers who can't view
+// it anymore are closed, as are the subscribers of deleted workspaces.
+func (b *Bus) Publish(event model.Event) Message {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.seq++
+	id := fmt.Sprintf("%s-%d", b.boot, b.seq)
+	// Events are published after being written to the outbox, without its ID
+	if event.ID == "" {
+		event.ID = id
+	}
+	message := Message{ id, event }
+	b.history = append(b.history, message)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history) - b.historySize:]
+	}
+
+	workspace, revoking := event.Data.(model.Workspace)
+	revoking = revoking && event.Type == model.EventWorkspacePermissionsChanged
+	deleted := event.Type == model.EventWorkspaceDeleted
+
+	for subscription := range b.subscribers {
+		if !event.Reaches(subscription.WorkspaceID, subscription.OrganizationID) {
+			continue
+		}
+		if deleted || (revoking && !workspace.ViewableBy(subscription.Principals...)) {
+			b.close(subscription, ClosedRevoked)
+			continue
+		}
+		select {
+		case subscription.messages <- message:
+		default:
+			b.close(subscription, ClosedLagging)
+		}
+	}
+
+	return message
+}
+
+// Subscribes to the events of the workspace, a workspace of the organization, on behalf of the principals, the subscriber's first. Subscribers resuming after lastEventID first receive the events
+// they missed, or are flagged to reset when those events aren't kept anymore. An empty lastEventID starts live
+func (b *Bus) Subscribe(workspaceID string, organizationID string, principals []string, lastEventID string) *Subscription {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	missed := []Message{}
+	reset := false
+	if lastEventID != "" {
+		missed, reset = b.since(lastEventID)
+	}
+
+	replay := []Message{}
+	for _, message := range missed {
+		if message.Event.Reaches(workspaceID, organizationID) {
+			replay = append(replay, message)
+		}
+	}
+
+	messages := make(chan Message, len(replay) + subscriberBuffer)
+	for _, message := range replay {
+		messages <- message
+	}
+
+	subscription := &Subscription{ workspaceID, organizationID, principals, messages, reset, messages, "", b }
+	b.subscribers[subscription] = true
+	return subscription
+}
+
+// Closes the subscriptions of the principal to the workspace, e.g. when it lost access through a group
+func (b *Bus) Revoke(workspaceID string, principal string) {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for subscription := range b.subscribers {
+		if subscription.WorkspaceID == workspaceID && len(subscription.Principals) > 0 && subscription.Principals[0] == principal {
+			b.close(subscription, ClosedRevoked)
+		}
+	}
+}
+
+// Closes every subscription, e.g. when the server stops
+func (b *Bus) Shutdown() {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for subscription := range b.subscribers {
+		b.close(subscription, ClosedShutdown)
+	}
+}
+
+// Messages after the one identified, and whether some of them are unknown. Callers must hold the lock
+func (b *Bus) since(lastEventID string) ([]Message, bool) {
+
+	boot, value, _ := strings.Cut(lastEventID, "-")
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || boot != b.boot || seq > b.seq {
+		return []Message{}, true
+	}
+
+	missed := []Message{}
+	for _, message := range b.history {
+		_, value, _ := strings.Cut(message.ID, "-")
+		n, _ := strconv.ParseInt(value, 10, 64)
+		if n > seq {
+			missed = append(missed, message)
+		}
+	}
+
+	// Some events between the last one received and the oldest one kept are lost
+	return missed, int64(len(missed)) < b.seq - seq
+}
+
+// Callers must hold the lock
+func (b *Bus) close(subscription *Subscription, reason string) {
+	if !b.subscribers[subscription] {
+		return
+	}
+	delete(b.subscribers, subscription)
+	subscription.reason = reason
+	close(subscription.messages)
+}
+
+// Unsubscribes. Safe to call after the bus closed the subscription
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	s.bus.close(s, "")
+}
+
+// Why the bus closed the subscription. Empty while open or when closed by the subscriber
+func (s *Subscription) Reason() string {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	return s.reason
+}
@@ -0,0 +1,158 @@
+package eventbus
+
+import (
+	"smartgrowth-connectors/configapi/model"
+	"testing"
+	"time"
+)
+
+func event(t string, workspaceID string, data interface{}) model.Event {
+	return model.NewEvent(t, workspaceID, "integration", "integration", data, "user", time.Now())
+}
+
+// Messages waiting on the subscription, without blocking
+func pending(subscription *Subscription) []Message {
+	messages := []Message{}
+	for {
+		select {
+		case message, ok := <-subscription.C:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+
+	bus := NewBus(10)
+	subscription := bus.Subscribe("workspace", "", []string{ "user:a" }, "")
+	other := bus.Subscribe("other", "", []string{ "user:a" }, "")
+
+	bus.Publish(event(model.EventIntegrationCreated, "workspace", nil))
+	bus.Publish(event(model.EventIntegrationCreated, "other", nil))
+	bus.Publish(model.NewEvent(model.EventDefinitionPublished, "", "definition", "definition", nil, "user", time.Now()))
+
+	received := pending(subscription)
+	if len(received) != 2 || received[0].Event.WorkspaceID != "workspace" || received[1].Event.Type != model.EventDefinitionPublished {
+		t.Errorf("Expected the events of the workspace and the global ones, got %+v", received)
+	}
+	if len(pending(other)) != 2 {
+		t.Errorf("Expected the other workspace to receive its events")
+	}
+
+	subscription.Close()
+	subscription.Close()
+	bus.Publish(event(model.EventIntegrationUpdated, "workspace", nil))
+	if _, ok := <-subscription.C; ok || subscription.Reason() != "" {
+		t.Errorf("Expected closed subscription without reason")
+	}
+}
+
+func TestResume(t *testing.T) {
+
+	bus := NewBus(3)
+	first := bus.Publish(event(model.EventIntegrationCreated, "workspace", nil))
+	bus.Publish(event(model.EventIntegrationUpdated, "other", nil))
+	bus.Publish(event(model.EventIntegrationUpdated, "workspace", nil))
+
+	subscription := bus.Subscribe("workspace", "", []string{ "user:a" }, first.ID)
+	received := pending(subscription)
+	if subscription.Reset || len(received) != 1 || received[0].Event.Type != model.EventIntegrationUpdated {
+		t.Errorf("Expected the missed event of the workspace, got %+v", received)
+	}
+
+	// The first event is not kept anymore
+	bus.Publish(event(model.EventIntegrationDeleted, "workspace", nil))
+	bus.Publish(event(model.EventIntegrationDeleted, "workspace", nil))
+	late := bus.Subscribe("workspace", "", []string{ "user:a" }, first.ID)
+	if !late.Reset || len(pending(late)) != 3 {
+		t.Errorf("Expected a reset with the events still kept")
+	}
+
+	// IDs of a previous process
+	previous := NewBus(3)
+	previous.boot = "previous"
+	old := previous.Publish(event(model.EventIntegrationCreated, "workspace", nil))
+	if !bus.Subscribe("workspace", "", []string{ "user:a" }, old.ID).Reset {
+		t.Errorf("Expected a reset for the ID of another process")
+	}
+	if !bus.Subscribe("workspace", "", []string{ "user:a" }, "garbage").Reset {
+		t.Errorf("Expected a reset for an invalid ID")
+	}
+}
+
+func TestRevoke(t *testing.T) {
+
+	bus := NewBus(10)
+	kept := bus.Subscribe("workspace", "", []string{ "user:a" }, "")
+	revoked := bus.Subscribe("workspace", "", []string{ "user:b" }, "")
+	elsewhere := bus.Subscribe("other", "", []string{ "user:b" }, "")
+	member := bus.Subscribe("workspace", "", []string{ "user:c", "group:g" }, "")
+
+	workspace := model.Workspace{ ID: "workspace", Permissions: []model.WorkspacePermission{ { Principal: "user:a", Role: "owner" }, { Principal: "group:g", Role: "viewer" } } }
+	bus.Publish(event(model.EventWorkspacePermissionsChanged, "workspace", workspace))
+
+	if len(pending(kept)) != 1 || kept.Reason() != "" {
+		t.Errorf("Expected subscriber still allowed to receive the change")
+	}
+	if len(pending(revoked)) != 0 || revoked.Reason() != ClosedRevoked {
+		t.Errorf("Expected revoked subscriber to be closed, got %s", revoked.Reason())
+	}
+	if elsewhere.Reason() != "" {
+		t.Errorf("Expected subscribers of other workspaces to be left open")
+	}
+	if len(pending(member)) != 1 || member.Reason() != "" {
+		t.Errorf("Expected subscriber allowed through a group to receive the change")
+	}
+
+	// Members removed from a group are revoked directly
+	bus.Revoke("workspace", "user:c")
+	if member.Reason() != ClosedRevoked || kept.Reason() != "" {
+		t.Errorf("Expected only the revoked principal to be closed, got %s", member.Reason())
+	}
+
+	bus.Publish(event(model.EventWorkspaceDeleted, "workspace", workspace))
+	if kept.Reason() != ClosedRevoked {
+		t.Errorf("Expected subscribers of deleted workspaces to be closed, got %s", kept.Reason())
+	}
+}
+
+func TestLagging(t *testing.T) {
+
+	bus := NewBus(1000)
+	subscription := bus.Subscribe("workspace", "", []string{ "user:a" }, "")
+	for i := 0; i <= subscriberBuffer; i++ {
+		bus.Publish(event(model.EventIntegrationUpdated, "workspace", nil))
+	}
+
+	if len(pending(subscription)) != subscriberBuffer || subscription.Reason() != ClosedLagging {
+		t.Errorf("Expected lagging subscriber to be closed after %d events, got %s", subscriberBuffer, subscription.Reason())
+	}
+}
+
+func TestOrganizationEvents(t *testing.T) {
+
+	bus := NewBus(10)
+	member := bus.Subscribe("workspace", "organization", []string{ "user:a" }, "")
+	outsider := bus.Subscribe("other", "other", []string{ "user:b" }, "")
+
+	first := bus.Publish(event(model.EventIntegrationCreated, "third", nil))
+	definition := model.IntegrationDefinition{ ID: "definition", Scope: model.DefinitionScope{ Visibility: model.DefinitionOrganization, OrganizationID: "organization" } }
+	bus.Publish(model.NewDefinitionEvent(model.EventDefinitionPublished, definition, "user", time.Now()))
+
+	if len(pending(member)) != 1 {
+		t.Errorf("Expected the workspaces of the organization to receive its events")
+	}
+	if len(pending(outsider)) != 0 {
+		t.Errorf("Expected other organizations not to receive the events")
+	}
+
+	bus.Publish(event(model.EventIntegrationCreated, "other", nil))
+	if replayed := pending(bus.Subscribe("other", "other", []string{ "user:b" }, first.ID)); len(replayed) != 1 || replayed[0].Event.WorkspaceID != "other" {
+		t.Errorf("Expected only the events of the workspace to be replayed, got %+v", replayed)
+	}
+}
@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Plain text email
+type Message struct {
+	To string
+	Subject string
+	Body string
+}
+
+// Sends notifications, e.g. invitations
+type Mailer interface {
+	Send(Message) error
+}
+
+func (m Message) Validate() error {
+	address, err := mail.ParseAddress(m.To)
+	if err != nil || address.Address != m.To {
+		return fmt.Errorf("Invalid recipient %s", m.To)
+	}
+	// Headers can't be injected through the subject
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return errors.New("Subject can't span lines")
+	}
+	return nil
+}
+
+// RFC 5322 message
+func (m Message) format(from string, at time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + m.To + "\r\n")
+	b.WriteString("Subject: " + m.Subject + "\r\n")
+	b.WriteString("Date: " + at.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// Sends through an SMTP server. STARTTLS is used when the server offers it
+type SMTP struct {
+	Addr string // "host:port"
+	From string
+	Auth smtp.Auth // Optional
+}
+
+// Authenticates with PLAIN when a username is set
+func NewSMTP(addr string, from string, username string, password string) *SMTP {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTP{ addr, from, auth }
+}
+
+func (s *SMTP) Send(m Message) error {
+	err := m.Validate()
+	if err != nil {
+		return err
+	}
+	err = smtp.SendMail(s.Addr, s.Auth, s.From, []string{ m.To }, m.format(s.From, time.Now()))
+	if err != nil {
+		return fmt.Errorf("Error sending email: %v", err)
+	}
+	return nil
+}
+
+// Writes messages instead of sending them, for local use. Messages are appended to a file, or written to the log
+type Writer struct {
+	From string
+	out io.Writer
+	lock sync.Mutex
+}
+
+// Appends messages to the file, creating it if needed
+func NewFile(path string, from string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening mail file: %v", err)
+	}
+	return &Writer{ From: from, out: file }, nil
+}
+
+func NewLog(from string) *Writer {
+	return &Writer{ From: from, out: log.Writer() }
+}
+
+func (w *Writer) Send(m Message) error {
+	err := m.Validate()
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err = fmt.Fprintf(w.out, "%s\r\n\r\n", m.format(w.From, time.Now()))
+	if err != nil {
+		return fmt.Errorf("Error writing email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "mail.log")
+	mailer, err := NewFile(path, "noreply@example.com")
+	if err != nil {
+		t.Fatalf("Expected mailer, got %v", err)
+	}
+
+	err = mailer.Send(Message{ To: "ana@example.com", Subject: "Invitation", Body: "Hello\nAccept: https://app.example.com" })
+	if err != nil {
+		t.Fatalf("Expected message to be written, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, expected := range []string{ "From: noreply@example.com\r\n", "To: ana@example.com\r\n", "Subject: Invitation\r\n", "\r\n\r\nHello\r\nAccept: https://app.example.com" } {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in %q", expected, data)
+		}
+	}
+
+	invalid := []Message{
+		{ To: "ana", Subject: "Invitation" },
+		{ To: "ana@example.com\r\nBcc: eve@example.com", Subject: "Invitation" },
+		{ To: "ana@example.com", Subject: "Invitation\r\nBcc: eve@example.com" },
+	}
+	for idx, message := range invalid {
+		if err := mailer.Send(message); err == nil {
+			t.Errorf("Expected error for message at index %d, got nil", idx)
+		}
+	}
+}
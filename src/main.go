@@ -1,10 +1,16 @@
 package main
 
 import (
+	"encoding/hex"
 	"os"
 	"log"
+	"strconv"
+	"time"
 	"smartgrowth-connectors/configapi/controller"
 	"smartgrowth-connectors/configapi/database"
+	"smartgrowth-connectors/configapi/eventbus"
+	"smartgrowth-connectors/configapi/mailer"
+	"smartgrowth-connectors/configapi/model"
 	"smartgrowth-connectors/configapi/server"
 	"smartgrowth-connectors/configapi/scripts"
 )
@@ -17,6 +23,15 @@ Using enviroment variables to initialize the database, configure the controller,
 
 func main(){
 
+	// Subcommands run offline and exit
+	if len(os.Args) > 1 {
+		err := scripts.RunCommand(os.Args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initialize database
 	// If in testing mode, use in memory database
 	var db database.Database
@@ -44,6 +59,120 @@ func main(){
 	if err != nil {
 		log.Fatalf("Failed to initialize controller: %v", err)
 	}
+	if limit := os.Getenv("STATE_SIZE_LIMIT"); limit != "" {
+		controller.Options.StateSizeLimit, err = strconv.Atoi(limit)
+		if err != nil {
+			log.Fatalf("Invalid STATE_SIZE_LIMIT: %v", err)
+		}
+	}
+	controller.Options.RuntimeBundleKey = []byte(os.Getenv("RUNTIME_BUNDLE_KEY"))
+	// Bundles are encrypted with a separate hex encoded key, so secrets stay out of the worker caches
+	if key := os.Getenv("RUNTIME_BUNDLE_ENCRYPTION_KEY"); key != "" {
+		controller.Options.RuntimeBundleEncryptionKey, err = hex.DecodeString(key)
+		if err != nil || len(controller.Options.RuntimeBundleEncryptionKey) != model.RuntimeBundleEncryptionKeySize {
+			log.Fatalf("Invalid RUNTIME_BUNDLE_ENCRYPTION_KEY: %d hex encoded bytes expected", model.RuntimeBundleEncryptionKeySize)
+		}
+	}
+
+	// OAuth client credentials are read from OAUTH_<NAME>_CLIENT_ID and OAUTH_<NAME>_CLIENT_SECRET
+	controller.Options.OAuthRedirectURL = os.Getenv("OAUTH_REDIRECT_URL")
+	controller.Options.OAuthClients = func(name string) (model.OAuthClient, bool) {
+		id := os.Getenv("OAUTH_" + name + "_CLIENT_ID")
+		return model.OAuthClient{ ID: id, Secret: os.Getenv("OAUTH_" + name + "_CLIENT_SECRET") }, id != ""
+	}
+
+	// Invitations are mailed through SMTP, or written to a file or to the log for local use
+	from := os.Getenv("MAIL_FROM")
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		controller.Options.Mailer = mailer.NewSMTP(os.Getenv("SMTP_ADDR"), from, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	case "file":
+		controller.Options.Mailer, err = mailer.NewFile(os.Getenv("MAILER_FILE"), from)
+		if err != nil {
+			log.Fatalf("Failed to initialize mailer: %v", err)
+		}
+	case "log":
+		controller.Options.Mailer = mailer.NewLog(from)
+	}
+	controller.Options.InvitationKey = []byte(os.Getenv("INVITATION_KEY"))
+	controller.Options.InvitationURL = os.Getenv("INVITATION_URL")
+	if ttl := os.Getenv("INVITATION_TTL"); ttl != "" {
+		controller.Options.InvitationTTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("Invalid INVITATION_TTL: %v", err)
+		}
+	}
+
+	// Ownership transfers wait for the new owner, deleted workspaces stay in the trash before being purged
+	if ttl := os.Getenv("TRANSFER_TTL"); ttl != "" {
+		controller.Options.TransferTTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("Invalid TRANSFER_TTL: %v", err)
+		}
+	}
+	if retention := os.Getenv("TRASH_RETENTION"); retention != "" {
+		controller.Options.TrashRetention, err = time.ParseDuration(retention)
+		if err != nil {
+			log.Fatalf("Invalid TRASH_RETENTION: %v", err)
+		}
+	}
+
+	// Live event streams keep the last 1000 events for reconnecting clients
+	controller.Options.Events = eventbus.NewBus(1000)
+
+	// Options are complete: the background jobs below and the server share them without locking
+
+	// Permissions stored before principal IDs are keyed on emails, convert them before serving
+	migrated, dropped, err := controller.MigrateWorkspacePermissions()
+	if err != nil {
+		log.Fatalf("Failed to migrate workspace permissions: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Migrated the permissions of %d workspace(s)", migrated)
+	}
+	for _, permission := range dropped {
+		log.Printf("Dropped the permission of unregistered %s", permission)
+	}
+
+	// Refresh OAuth tokens in the background before they expire
+	go func() {
+		for range time.Tick(time.Minute) {
+			refreshed, errs := controller.RefreshOAuthTokens(time.Now())
+			for _, err := range errs {
+				log.Println(err)
+			}
+			if refreshed > 0 {
+				log.Printf("Refreshed %d OAuth token(s)", refreshed)
+			}
+		}
+	}()
+
+	// Purge the workspaces deleted for longer than the trash retention
+	go func() {
+		for range time.Tick(time.Hour) {
+			purged, errs := controller.PurgeWorkspaces(time.Now())
+			for _, err := range errs {
+				log.Println(err)
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted workspace(s)", purged)
+			}
+		}
+	}()
+
+	// Send the events of the outbox to the webhook subscriptions
+	go func() {
+		for range time.Tick(10 * time.Second) {
+			_, errs := controller.DispatchEvents(time.Now())
+			for _, err := range errs {
+				log.Println(err)
+			}
+			_, errs = controller.DeliverWebhooks(time.Now())
+			for _, err := range errs {
+				log.Println(err)
+			}
+		}
+	}()
 	
 	server, err := server.NewServer(controller, os.Getenv("AUTH0_DOMAIN"), os.Getenv("AUTH0_IDENTIFIER"))
 	if err != nil {
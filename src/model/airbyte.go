@@ -0,0 +1,436 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Connector specification published by Airbyte connectors through the spec command
+// https://docs.airbyte.com/understanding-airbyte/airbyte-protocol#spec
+type AirbyteSpec struct {
+	DocumentationURL string `json:"documentationUrl"`
+	ConnectionSpecification JSONSchema `json:"connectionSpecification"`
+}
+
+// Parses a spec file. Accepts both the SPEC message written by the connector
+// ({"type": "SPEC", "spec": {...}}) and the bare spec object.
+func ParseAirbyteSpec(data []byte) (AirbyteSpec, error) {
+
+	var spec AirbyteSpec
+
+	var message struct {
+		Type string `json:"type"`
+		Spec *AirbyteSpec `json:"spec"`
+	}
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		return spec, fmt.Errorf("Invalid spec file: %v", err)
+	}
+
+	if message.Spec != nil {
+		if message.Type != "SPEC" {
+			return spec, fmt.Errorf("Expected a SPEC message, got %s", message.Type)
+		}
+		spec = *message.Spec
+	} else {
+		err = json.Unmarshal(data, &spec)
+		if err != nil {
+			return spec, fmt.Errorf("Invalid spec file: %v", err)
+		}
+	}
+
+	if spec.ConnectionSpecification == nil {
+		return spec, errors.New("Spec is missing the connectionSpecification")
+	}
+
+	return spec, nil
+}
+
+// Airbyte keywords used only to render forms. They are dropped silently
+var airbyteUIKeywords = map[string]bool{
+	"$schema": true,
+	"title": true,
+	"order": true,
+	"examples": true,
+	"airbyte_hidden": true,
+	"always_show": true,
+	"multiline": true,
+	"group": true,
+	"groups": true,
+	"display_type": true,
+	"pattern_descriptor": true,
+	"additionalProperties": true,
+	"x-speakeasy-param-sensitive": true,
+}
+
+// Converts the spec into a draft integration definition of the given type ("source" or "destination").
+// If name is empty, the title of the connection specification is used.
+//
+// airbyte_secret, enum, default, oneOf and nested objects are mapped onto the schema.
+// Validation keywords the schema can't express (pattern, minimum, ...) are dropped, and reported
+// back as warnings so the author knows they won't be enforced.
+func (s AirbyteSpec) Definition(name string, t string) (IntegrationDefinition, []string, error) {
+
+	warnings := []string{}
+
+	if name == "" {
+		name, _ = s.ConnectionSpecification["title"].(string)
+	}
+	if name == "" {
+		return IntegrationDefinition{}, warnings, errors.New("Name is required when the spec has no title")
+	}
+
+	fields, err := airbyteObject(s.ConnectionSpecification, "connectionSpecification", &warnings)
+	if err != nil {
+		return IntegrationDefinition{}, warnings, err
+	}
+
+	def, err := NewIntegrationDefinition(name, t, fields)
+	if err != nil {
+		return def, warnings, err
+	}
+
+	return def, warnings, nil
+}
+
+func airbyteObject(schema JSONSchema, path string, warnings *[]string) (ConfigurationSchema, error) {
+
+	properties := map[string]interface{}{}
+	if raw, ok := schema["properties"]; ok {
+		properties, ok = raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s/properties must be an object", path)
+		}
+	}
+
+	required, err := stringList(schema["required"], path + "/required")
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields follow the "order" keyword, then the name
+	labels := []string{}
+	for label := range properties {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		a, aHasOrder := airbyteOrder(properties[labels[i]])
+		b, bHasOrder := airbyteOrder(properties[labels[j]])
+		if aHasOrder != bHasOrder {
+			return aHasOrder
+		}
+		if a != b {
+			return a < b
+		}
+		return labels[i] < labels[j]
+	})
+
+	fields := ConfigurationSchema{}
+	for _, label := range labels {
+		property, ok := asJSONSchema(properties[label])
+		if !ok {
+			return nil, fmt.Errorf("%s/properties/%s must be an object", path, label)
+		}
+		field, err := airbyteField(label, property, containsString(required, label), path + "/properties/" + label, warnings)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+
+	for _, label := range required {
+		if _, ok := properties[label]; !ok {
+			*warnings = append(*warnings, fmt.Sprintf("%s/required references unknown property %s", path, label))
+		}
+	}
+
+	return fields, nil
+}
+
+func airbyteField(label string, schema JSONSchema, required bool, path string, warnings *[]string) (SchemaField, error) {
+
+	field := SchemaField{Label: label, Required: required}
+	if description, ok := schema["description"].(string); ok {
+		field.Description = description
+	}
+
+	t, err := airbyteType(schema, path)
+	if err != nil {
+		return field, err
+	}
+
+	handled := map[string]bool{"type": true, "description": true}
+
+	switch t {
+	case "string":
+		field.Type = "string"
+	case "integer":
+		field.Type = "int"
+	case "number":
+		field.Type = "float"
+	case "boolean":
+		field.Type = "boolean"
+	case "object":
+		field.Type = "object"
+		handled["properties"] = true
+		handled["required"] = true
+		fields, err := airbyteObject(schema, path, warnings)
+		if err != nil {
+			return field, err
+		}
+		field.Fields = fields
+
+		if raw, ok := schema["oneOf"]; ok {
+			handled["oneOf"] = true
+			err := field.airbyteVariants(raw, path + "/oneOf", warnings)
+			if err != nil {
+				return field, err
+			}
+		}
+	case "array":
+		handled["items"] = true
+		items, ok := asJSONSchema(schema["items"])
+		if !ok {
+			*warnings = append(*warnings, fmt.Sprintf("%s has no items schema, assuming strings", path))
+			items = JSONSchema{"type": "string"}
+		}
+		item, err := airbyteField(label, items, false, path + "/items", warnings)
+		if err != nil {
+			return field, err
+		}
+		if item.Array {
+			return field, fmt.Errorf("%s: nested arrays are not supported", path)
+		}
+		item.Required = required
+		item.Array = true
+		if field.Description != "" {
+			item.Description = field.Description
+		}
+		field = item
+
+		// Only a minimum of one item can be expressed, through required
+		if minItems, ok := schema["minItems"]; ok {
+			handled["minItems"] = true
+			if n, _ := toFloat(minItems); n > 1 || (n == 1 && !required) {
+				*warnings = append(*warnings, fmt.Sprintf("%s/minItems %v is not enforced", path, minItems))
+			}
+		}
+	}
+
+	if secret, ok := schema["airbyte_secret"]; ok {
+		handled["airbyte_secret"] = true
+		if secret == true {
+			if field.Type == "string" {
+				field.Secret = true
+			} else {
+				*warnings = append(*warnings, fmt.Sprintf("%s is a secret %s, only string secrets are supported", path, field.Type))
+			}
+		}
+	}
+
+	if raw, ok := schema["enum"]; ok && t != "array" {
+		handled["enum"] = true
+		enum, ok := raw.([]interface{})
+		if !ok {
+			return field, fmt.Errorf("%s/enum must be an array", path)
+		}
+		field.Enum = enum
+	}
+
+	if value, ok := schema["const"]; ok && t != "array" {
+		handled["const"] = true
+		field.Enum = []interface{}{value}
+	}
+
+	if value, ok := schema["default"]; ok {
+		handled["default"] = true
+		field.Default = value
+
+		// Some connectors have defaults that don't match their own schema
+		err := field.Validate(3)
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("%s/default %v was dropped: %v", path, value, err))
+			field.Default = nil
+		}
+	}
+
+	ignored := []string{}
+	for keyword := range schema {
+		if handled[keyword] || airbyteUIKeywords[keyword] {
+			continue
+		}
+		ignored = append(ignored, keyword)
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		*warnings = append(*warnings, fmt.Sprintf("%s: %s not enforced", path, strings.Join(ignored, ", ")))
+	}
+
+	return field, nil
+}
+
+// Resolves the type of a property. Nullable types are treated as the non null type,
+// and properties without type are inferred from their oneOf or enum.
+func airbyteType(schema JSONSchema, path string) (string, error) {
+
+	switch t := schema["type"].(type) {
+	case string:
+		return t, airbyteCheckType(t, path)
+	case []interface{}:
+		types := []string{}
+		for _, raw := range t {
+			if s, ok := raw.(string); ok && s != "null" {
+				types = append(types, s)
+			}
+		}
+		if len(types) != 1 {
+			return "", fmt.Errorf("%s/type %v is not supported", path, t)
+		}
+		return types[0], airbyteCheckType(types[0], path)
+	case nil:
+		if _, ok := schema["oneOf"]; ok {
+			return "object", nil
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+			switch enum[0].(type) {
+			case string:
+				return "string", nil
+			case float64:
+				return "number", nil
+			case bool:
+				return "boolean", nil
+			}
+		}
+		if _, ok := schema["const"].(string); ok {
+			return "string", nil
+		}
+		return "", fmt.Errorf("%s/type is required", path)
+	}
+
+	return "", fmt.Errorf("%s/type %v is not supported", path, schema["type"])
+}
+
+func airbyteCheckType(t string, path string) error {
+	switch t {
+	case "string", "integer", "number", "boolean", "object", "array":
+		return nil
+	}
+	return fmt.Errorf("%s/type %s is not supported", path, t)
+}
+
+// Variants are told apart by a property with a single allowed value, declared with
+// const or with a one value enum. It must have the same name in every variant.
+func (f *SchemaField) airbyteVariants(raw interface{}, path string, warnings *[]string) error {
+
+	variants, ok := raw.([]interface{})
+	if !ok || len(variants) == 0 {
+		return fmt.Errorf("%s must be a non empty array", path)
+	}
+
+	// Candidates shared by every variant
+	var shared []string
+	schemas := []JSONSchema{}
+	for idx, raw := range variants {
+		variant, ok := asJSONSchema(raw)
+		if !ok {
+			return fmt.Errorf("%s/%d must be an object", path, idx)
+		}
+		schemas = append(schemas, variant)
+
+		candidates := []string{}
+		properties, _ := variant["properties"].(map[string]interface{})
+		for label, property := range properties {
+			if _, ok := airbyteTag(property); ok {
+				candidates = append(candidates, label)
+			}
+		}
+
+		if shared == nil {
+			shared = candidates
+			continue
+		}
+		common := []string{}
+		for _, label := range shared {
+			if containsString(candidates, label) {
+				common = append(common, label)
+			}
+		}
+		shared = common
+	}
+
+	if len(shared) == 0 {
+		return fmt.Errorf("%s: can't find a discriminator shared by every variant", path)
+	}
+	sort.Strings(shared)
+	f.Discriminator = shared[0]
+
+	for idx, variant := range schemas {
+		variantPath := fmt.Sprintf("%s/%d", path, idx)
+		properties, _ := variant["properties"].(map[string]interface{})
+		tag, _ := airbyteTag(properties[f.Discriminator])
+
+		rest := JSONSchema{}
+		for key, value := range variant {
+			rest[key] = value
+		}
+		restProperties := map[string]interface{}{}
+		for label, property := range properties {
+			if label != f.Discriminator {
+				restProperties[label] = property
+			}
+		}
+		rest["properties"] = restProperties
+		required, err := stringList(variant["required"], variantPath + "/required")
+		if err != nil {
+			return err
+		}
+		restRequired := []interface{}{}
+		for _, label := range required {
+			if label != f.Discriminator {
+				restRequired = append(restRequired, label)
+			}
+		}
+		rest["required"] = restRequired
+
+		fields, err := airbyteObject(rest, variantPath, warnings)
+		if err != nil {
+			return err
+		}
+
+		title, _ := variant["title"].(string)
+		f.OneOf = append(f.OneOf, SchemaVariant{ tag, title, fields })
+	}
+
+	return nil
+}
+
+// Reads the single allowed value of a property, if it has one
+func airbyteTag(raw interface{}) (string, bool) {
+
+	property, ok := asJSONSchema(raw)
+	if !ok {
+		return "", false
+	}
+
+	if value, ok := property["const"].(string); ok {
+		return value, true
+	}
+	if enum, ok := property["enum"].([]interface{}); ok && len(enum) == 1 {
+		if value, ok := enum[0].(string); ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+func airbyteOrder(raw interface{}) (float64, bool) {
+	property, ok := asJSONSchema(raw)
+	if !ok {
+		return 0, false
+	}
+	return toFloat(property["order"])
+}
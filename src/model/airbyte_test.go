@@ -0,0 +1,324 @@
+package model
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadAirbyteSpec(t *testing.T, name string) (IntegrationDefinition, []string) {
+
+	data, err := os.ReadFile("testdata/airbyte/" + name + ".json")
+	if err != nil {
+		t.Fatalf("Error reading spec %s: %v", name, err)
+	}
+
+	spec, err := ParseAirbyteSpec(data)
+	if err != nil {
+		t.Fatalf("Error parsing spec %s: %v", name, err)
+	}
+
+	def, warnings, err := spec.Definition("", "source")
+	if err != nil {
+		t.Fatalf("Error converting spec %s: %v", name, err)
+	}
+
+	return def, warnings
+}
+
+// Looks up a field by path, entering variants by their tag
+func lookupField(t *testing.T, schema ConfigurationSchema, path ...string) SchemaField {
+
+	var field SchemaField
+	for idx := 0; idx < len(path); idx++ {
+		var ok bool
+		field, ok = schema.Field(path[idx])
+		if !ok {
+			t.Fatalf("Field %s not found", strings.Join(path[:idx+1], "/"))
+		}
+		schema = field.Fields
+
+		if len(field.OneOf) > 0 && idx+1 < len(path) {
+			idx++
+			found := false
+			for _, variant := range field.OneOf {
+				if variant.Tag == path[idx] {
+					schema = append(append(ConfigurationSchema{}, field.Fields...), variant.Fields...)
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("Variant %s not found", strings.Join(path[:idx+1], "/"))
+			}
+		}
+	}
+
+	return field
+}
+
+func hasWarning(warnings []string, substring string) bool {
+	for _, warning := range warnings {
+		if strings.Contains(warning, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAirbyteSpecs(t *testing.T) {
+
+	names := []string{"source-postgres", "source-hubspot", "destination-bigquery", "source-stripe", "source-facebook-marketing"}
+	for _, name := range names {
+		def, _ := loadAirbyteSpec(t, name)
+		if def.Status != "draft" {
+			t.Errorf("Expected %s to be imported as a draft, got %s", name, def.Status)
+		}
+		err := def.Validate()
+		if err != nil {
+			t.Errorf("Expected %s to be valid, got %v", name, err)
+		}
+	}
+}
+
+func TestAirbytePostgres(t *testing.T) {
+
+	def, warnings := loadAirbyteSpec(t, "source-postgres")
+	schema := def.ConfigurationSchema
+
+	if def.Name != "Postgres Source Spec" {
+		t.Errorf("Expected name from title, got %s", def.Name)
+	}
+
+	// Fields follow the order keyword
+	labels := []string{}
+	for _, field := range schema {
+		labels = append(labels, field.Label)
+	}
+	expected := []string{"host", "port", "database", "schemas", "username", "password", "jdbc_url_params", "ssl_mode", "replication_method", "tunnel_method"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, labels)
+	}
+
+	port := lookupField(t, schema, "port")
+	if port.Type != "int" || !port.Required || port.Default != float64(5432) {
+		t.Errorf("Expected required int port defaulting to 5432, got %+v", port)
+	}
+	if !lookupField(t, schema, "password").Secret {
+		t.Errorf("Expected password to be secret")
+	}
+	schemas := lookupField(t, schema, "schemas")
+	if schemas.Type != "string" || !schemas.Array || !reflect.DeepEqual(schemas.Default, []interface{}{"public"}) {
+		t.Errorf("Expected string array defaulting to public, got %+v", schemas)
+	}
+
+	ssl := lookupField(t, schema, "ssl_mode")
+	if ssl.Discriminator != "mode" || len(ssl.OneOf) != 3 || ssl.OneOf[2].Tag != "verify-ca" {
+		t.Errorf("Expected ssl_mode variants discriminated by mode, got %+v", ssl)
+	}
+	if !lookupField(t, schema, "ssl_mode", "verify-ca", "ca_certificate").Secret {
+		t.Errorf("Expected CA certificate to be secret")
+	}
+	if !lookupField(t, schema, "tunnel_method", "SSH_KEY_AUTH", "ssh_key").Required {
+		t.Errorf("Expected SSH key to be required")
+	}
+
+	plugin := lookupField(t, schema, "replication_method", "CDC", "plugin")
+	if !reflect.DeepEqual(plugin.Enum, []interface{}{"pgoutput"}) || plugin.Default != "pgoutput" {
+		t.Errorf("Expected plugin enum with default, got %+v", plugin)
+	}
+
+	// The object default "CDC" names a variant, it doesn't match the schema
+	if lookupField(t, schema, "replication_method").Default != nil {
+		t.Errorf("Expected invalid default to be dropped")
+	}
+	if !hasWarning(warnings, "replication_method/default") {
+		t.Errorf("Expected a warning for the dropped default, got %v", warnings)
+	}
+	if !hasWarning(warnings, "properties/port: maximum, minimum not enforced") {
+		t.Errorf("Expected a warning for port bounds, got %v", warnings)
+	}
+	if hasWarning(warnings, "order") || hasWarning(warnings, "group") || hasWarning(warnings, "examples") {
+		t.Errorf("Expected UI keywords to be ignored, got %v", warnings)
+	}
+
+	// Configurations written for the connector are accepted
+	config := IntegrationConfig{
+		"host": "localhost",
+		"port": float64(5432),
+		"database": "app",
+		"username": "reader",
+		"password": "secret",
+		"ssl_mode": map[string]interface{}{"mode": "require"},
+		"replication_method": map[string]interface{}{"method": "CDC", "replication_slot": "slot", "publication": "pub"},
+		"tunnel_method": map[string]interface{}{"tunnel_method": "NO_TUNNEL"},
+	}
+	err := config.Normalize(schema).Validate(schema)
+	if err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	config["replication_method"] = map[string]interface{}{"method": "CDC", "replication_slot": "slot", "publication": "pub", "plugin": "wal2json"}
+	err = config.Normalize(schema).Validate(schema)
+	if err == nil {
+		t.Errorf("Expected error for value outside the enum, got nil")
+	}
+}
+
+func TestAirbyteHubspot(t *testing.T) {
+
+	def, _ := loadAirbyteSpec(t, "source-hubspot")
+	schema := def.ConfigurationSchema
+
+	credentials := lookupField(t, schema, "credentials")
+	if !credentials.Required || credentials.Discriminator != "credentials_title" {
+		t.Fatalf("Expected required credentials discriminated by credentials_title, got %+v", credentials)
+	}
+	tags := []string{}
+	titles := []string{}
+	for _, variant := range credentials.OneOf {
+		tags = append(tags, variant.Tag)
+		titles = append(titles, variant.Title)
+	}
+	if !reflect.DeepEqual(tags, []string{"OAuth Credentials", "Private App Credentials"}) || !reflect.DeepEqual(titles, []string{"OAuth", "Private App"}) {
+		t.Errorf("Unexpected variants %v %v", tags, titles)
+	}
+	if !lookupField(t, schema, "credentials", "OAuth Credentials", "refresh_token").Secret {
+		t.Errorf("Expected refresh token to be secret")
+	}
+	if lookupField(t, schema, "credentials", "OAuth Credentials", "client_id").Secret {
+		t.Errorf("Expected client ID not to be secret")
+	}
+
+	experimental := lookupField(t, schema, "enable_experimental_streams")
+	if experimental.Type != "boolean" || experimental.Default != false {
+		t.Errorf("Expected boolean defaulting to false, got %+v", experimental)
+	}
+}
+
+func TestAirbyteBigQuery(t *testing.T) {
+
+	def, _ := loadAirbyteSpec(t, "destination-bigquery")
+	schema := def.ConfigurationSchema
+
+	location := lookupField(t, schema, "dataset_location")
+	if len(location.Enum) != 11 || !location.Required {
+		t.Errorf("Expected required dataset location with 11 values, got %+v", location)
+	}
+
+	// Variant nested in a variant
+	credential := lookupField(t, schema, "loading_method", "GCS Staging", "credential")
+	if credential.Discriminator != "credential_type" || len(credential.OneOf) != 1 || credential.OneOf[0].Tag != "HMAC_KEY" {
+		t.Errorf("Expected nested credential variants, got %+v", credential)
+	}
+	if !lookupField(t, schema, "loading_method", "GCS Staging", "credential", "HMAC_KEY", "hmac_key_secret").Secret {
+		t.Errorf("Expected HMAC secret to be secret")
+	}
+
+	config := IntegrationConfig{
+		"project_id": "project",
+		"dataset_location": "EU",
+		"dataset_id": "dataset",
+		"loading_method": map[string]interface{}{
+			"method": "GCS Staging",
+			"gcs_bucket_name": "bucket",
+			"gcs_bucket_path": "path",
+			"credential": map[string]interface{}{"credential_type": "HMAC_KEY", "hmac_key_access_id": "id", "hmac_key_secret": "secret"},
+		},
+	}
+	err := config.Normalize(schema).Validate(schema)
+	if err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	config["dataset_location"] = "mars"
+	err = config.Normalize(schema).Validate(schema)
+	if err == nil {
+		t.Errorf("Expected error for unknown location, got nil")
+	}
+}
+
+func TestAirbyteStripe(t *testing.T) {
+
+	def, warnings := loadAirbyteSpec(t, "source-stripe")
+	schema := def.ConfigurationSchema
+
+	if !lookupField(t, schema, "client_secret").Secret {
+		t.Errorf("Expected client secret to be secret")
+	}
+	lookback := lookupField(t, schema, "lookback_window_days")
+	if lookback.Type != "int" || lookback.Default != float64(0) {
+		t.Errorf("Expected int defaulting to 0, got %+v", lookback)
+	}
+	if !hasWarning(warnings, "start_date: format, pattern not enforced") {
+		t.Errorf("Expected a warning for the date pattern, got %v", warnings)
+	}
+}
+
+func TestAirbyteFacebookMarketing(t *testing.T) {
+
+	def, warnings := loadAirbyteSpec(t, "source-facebook-marketing")
+	schema := def.ConfigurationSchema
+
+	accounts := lookupField(t, schema, "account_ids")
+	if accounts.Type != "string" || !accounts.Array || !accounts.Required {
+		t.Errorf("Expected required string array, got %+v", accounts)
+	}
+
+	// Array of objects, with enum items without type
+	insights := lookupField(t, schema, "custom_insights")
+	if insights.Type != "object" || !insights.Array {
+		t.Errorf("Expected object array, got %+v", insights)
+	}
+	fields := lookupField(t, schema, "custom_insights", "fields")
+	if fields.Type != "string" || !fields.Array || len(fields.Enum) != 16 {
+		t.Errorf("Expected string array with enum items, got %+v", fields)
+	}
+	if !lookupField(t, schema, "custom_insights", "name").Required {
+		t.Errorf("Expected insight name to be required")
+	}
+	if !hasWarning(warnings, "account_ids/items: pattern not enforced") {
+		t.Errorf("Expected a warning for the item pattern, got %v", warnings)
+	}
+
+	config := IntegrationConfig{
+		"account_ids": []interface{}{"111"},
+		"access_token": "token",
+		"custom_insights": []interface{}{
+			map[string]interface{}{"name": "spend", "fields": []interface{}{"spend", "clicks"}, "time_increment": float64(7)},
+		},
+	}
+	err := config.Normalize(schema).Validate(schema)
+	if err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	config["custom_insights"] = []interface{}{map[string]interface{}{"name": "spend", "fields": []interface{}{"likes"}}}
+	err = config.Normalize(schema).Validate(schema)
+	if err == nil {
+		t.Errorf("Expected error for unknown insight field, got nil")
+	}
+}
+
+func TestAirbyteInvalidSpecs(t *testing.T) {
+
+	invalid := []string{
+		`not json`,
+		`{"type": "LOG", "spec": {"connectionSpecification": {"type": "object"}}}`,
+		`{"documentationUrl": "https://example.com"}`,
+		`{"connectionSpecification": {"type": "object", "properties": {"a": {"type": "null"}}}}`,
+		`{"connectionSpecification": {"type": "object", "properties": {"a": {"type": "string"}}}}`,
+		`{"connectionSpecification": {"title": "T", "type": "object", "properties": {"a": {"type": "object", "oneOf": [{"properties": {"b": {"type": "string"}}}]}}}}`,
+		`{"connectionSpecification": {"title": "T", "type": "object", "properties": {"a": {"type": "array", "items": {"type": "array", "items": {"type": "string"}}}}}}`,
+	}
+
+	for idx, raw := range invalid {
+		spec, err := ParseAirbyteSpec([]byte(raw))
+		if err == nil {
+			_, _, err = spec.Definition("", "source")
+		}
+		if err == nil {
+			t.Errorf("Expected error for spec at index %d, got nil", idx)
+		}
+	}
+}
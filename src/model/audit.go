@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// Record of a sensitive operation, e.g. a runtime reading a secret
+type AuditEntry struct {
+	ID string `json:"id" firestore:"id"`
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	Action string `json:"action" firestore:"action"` // e.g. "secret.read"
+	ResourceType string `json:"resource_type" firestore:"resource_type"` // e.g. "integration"
+	ResourceID string `json:"resource_id" firestore:"resource_id"`
+	Detail string `json:"detail,omitempty" firestore:"detail,omitempty"` // e.g. the path of the secret
+	PrincipalID string `json:"principal_id" firestore:"principal_id"` // ID of the user performing the operation
+	At time.Time `json:"at" firestore:"at"`
+}
+
+func NewAuditEntry(workspaceID string, action string, resourceType string, resourceID string, detail string, principalID string, at time.Time) AuditEntry {
+	return AuditEntry{ "", workspaceID, action, resourceType, resourceID, detail, principalID, at }
+}
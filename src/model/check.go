@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Webhook checking the configurations of a definition, e.g. by connecting with the credentials.
+// It receives a CheckRequest as JSON and answers with a CheckResponse
+type CheckHook struct {
+	URL string `json:"url" firestore:"url"`
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" firestore:"timeout_seconds,omitempty"` // Defaults to DefaultCheckTimeout
+}
+
+const DefaultCheckTimeout = 30 * time.Second
+const MaxCheckTimeout = 5 * time.Minute
+
+// Sent to checkers. Secrets are in clear and defaults are applied
+type CheckRequest struct {
+	DefinitionID string `json:"definition_id"`
+	DefinitionName string `json:"definition_name"`
+	DefinitionVersion string `json:"definition_version"`
+	Type string `json:"type"`
+	IntegrationID string `json:"integration_id,omitempty"` // Empty for unsaved configurations
+	Configuration IntegrationConfig `json:"configuration"`
+}
+
+// What checkers answer
+type CheckResponse struct {
+	Status string `json:"status"` // "succeeded" or "failed"
+	Message string `json:"message"`
+}
+
+// Outcome of a check. "error" means the checker itself failed or timed out,
+// so nothing is known about the configuration
+type CheckResult struct {
+	Status string `json:"status" firestore:"status"` // "succeeded", "failed" or "error"
+	Message string `json:"message,omitempty" firestore:"message,omitempty"`
+	CheckedAt time.Time `json:"checked_at" firestore:"checked_at"`
+	DurationMS int64 `json:"duration_ms" firestore:"duration_ms"`
+}
+
+func (h CheckHook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("Invalid URL %s", h.URL)
+	}
+	if h.TimeoutSeconds < 0 || time.Duration(h.TimeoutSeconds) * time.Second > MaxCheckTimeout {
+		return fmt.Errorf("Timeout must be between 0 and %d seconds", int(MaxCheckTimeout / time.Second))
+	}
+	return nil
+}
+
+func (h CheckHook) Timeout() time.Duration {
+	if h.TimeoutSeconds == 0 {
+		return DefaultCheckTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+func (r CheckResponse) Validate() error {
+	if r.Status != "succeeded" && r.Status != "failed" {
+		return fmt.Errorf("Invalid status %s. Valid statuses are \"succeeded\" and \"failed\"", r.Status)
+	}
+	if r.Status == "failed" && r.Message == "" {
+		return errors.New("Failed checks require a message")
+	}
+	return nil
+}
+
+// Sets the check hook of the definition. Nil removes it
+func (d IntegrationDefinition) WithCheck(hook *CheckHook) (IntegrationDefinition, error) {
+	d.Check = hook
+	err := d.Validate()
+	if err != nil {
+		return d, fmt.Errorf("Invalid definition: %v", err)
+	}
+	return d, nil
+}
+
+// Request to check the configuration against the definition. Integrations not saved yet have no ID
+func NewCheckRequest(def IntegrationDefinition, integrationID string, config IntegrationConfig) CheckRequest {
+	return CheckRequest{ def.ID, def.Name, def.Version, def.Type, integrationID, config.WithDefaults(def.ConfigurationSchema) }
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckHook(t *testing.T) {
+
+	hook := CheckHook{URL: "https://checks.example.com/postgres"}
+	if err := hook.Validate(); err != nil {
+		t.Fatalf("Expected hook to be valid, got %v", err)
+	}
+	if hook.Timeout() != DefaultCheckTimeout {
+		t.Errorf("Expected default timeout, got %v", hook.Timeout())
+	}
+	hook.TimeoutSeconds = 5
+	if hook.Timeout() != 5 * time.Second {
+		t.Errorf("Expected 5 seconds timeout, got %v", hook.Timeout())
+	}
+
+	invalid := []CheckHook{
+		{URL: "checks.example.com"},
+		{URL: "ftp://checks.example.com"},
+		{URL: "https://checks.example.com", TimeoutSeconds: -1},
+		{URL: "https://checks.example.com", TimeoutSeconds: 301},
+	}
+	for idx, hook := range invalid {
+		if err := hook.Validate(); err == nil {
+			t.Errorf("Expected error for hook at index %d, got nil", idx)
+		}
+	}
+
+	def, err := NewIntegrationDefinition("postgres", "source", runtimeSchema())
+	if err != nil {
+		t.Fatalf("Expected definition to be valid, got %v", err)
+	}
+	if _, err := def.WithCheck(&invalid[0]); err == nil {
+		t.Errorf("Expected error for definition with an invalid hook, got nil")
+	}
+	private, err := def.WithScope(DefinitionScope{ DefinitionWorkspace, "", "client" })
+	if err != nil {
+		t.Fatalf("Expected private definition to be valid, got %v", err)
+	}
+	if _, err := private.WithCheck(&hook); err == nil {
+		t.Errorf("Expected error for a private definition with a check hook, got nil")
+	}
+	def, err = def.WithCheck(&hook)
+	if err != nil {
+		t.Fatalf("Expected hook to be accepted, got %v", err)
+	}
+	next, err := def.NewVersion("1.1.0", runtimeSchema(), nil)
+	if err != nil || next.Check == nil {
+		t.Errorf("Expected new versions to keep the hook, got %+v, %v", next.Check, err)
+	}
+}
+
+func TestCheckRequest(t *testing.T) {
+
+	def := IntegrationDefinition{ID: "postgres", Name: "Postgres", Type: "source", Version: "1.0.0", ConfigurationSchema: runtimeSchema()}
+	request := NewCheckRequest(def, "", IntegrationConfig{ "host": "db", "password": "hunter2" })
+	if request.Configuration["port"] != 5432 || request.Configuration["password"] != "hunter2" || request.IntegrationID != "" {
+		t.Errorf("Expected defaults applied and secrets in clear, got %+v", request)
+	}
+
+	valid := []CheckResponse{
+		{ Status: "succeeded" },
+		{ Status: "failed", Message: "Authentication failed" },
+	}
+	for idx, response := range valid {
+		if err := response.Validate(); err != nil {
+			t.Errorf("Expected response at index %d to be valid, got %v", idx, err)
+		}
+	}
+	invalid := []CheckResponse{
+		{ Status: "ok" },
+		{ Status: "failed" },
+	}
+	for idx, response := range invalid {
+		if err := response.Validate(); err == nil {
+			t.Errorf("Expected error for response at index %d, got nil", idx)
+		}
+	}
+}
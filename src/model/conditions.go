@@ -0,0 +1,214 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Rule attached to a field. When the field equals the value, the listed fields of the
+// same level are required or allowed.
+// A field listed in any rule is conditional: it is only allowed when a matching rule lists it.
+// If several fields drive the same conditional field, it is enough for one of them to enable it.
+type FieldRule struct {
+	Equals interface{} `json:"equals" firestore:"equals"`
+	Require []string `json:"require,omitempty" firestore:"require,omitempty"`
+	Allow []string `json:"allow,omitempty" firestore:"allow,omitempty"`
+}
+
+// One of the shapes a tagged union object can take.
+// The variant is selected by the value of the discriminator field of the object.
+type SchemaVariant struct {
+	Tag string `json:"tag" firestore:"tag"`
+	Title string `json:"title,omitempty" firestore:"title,omitempty"`
+	Fields ConfigurationSchema `json:"fields" firestore:"fields"`
+}
+
+func (s ConfigurationSchema) validateRules(f SchemaField) error {
+
+	if len(f.Rules) == 0 {
+		return nil
+	}
+
+	// Rules are driven by scalar values
+	if f.Type == "object" || f.Array {
+		return errors.New("Only scalar fields can have rules")
+	}
+
+	for idx, rule := range f.Rules {
+		if rule.Equals == nil {
+			return fmt.Errorf("Rule at index %d is missing a value", idx)
+		}
+		if len(rule.Require) == 0 && len(rule.Allow) == 0 {
+			return fmt.Errorf("Rule at index %d doesn't require or allow any field", idx)
+		}
+
+		for _, label := range append(append([]string{}, rule.Require...), rule.Allow...) {
+			if label == f.Label {
+				return fmt.Errorf("Rule at index %d references its own field", idx)
+			}
+			if _, ok := s.Field(label); !ok {
+				return fmt.Errorf("Rule at index %d references unknown field %s", idx, label)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f SchemaField) validateVariants(remainingDepth int) error {
+
+	if len(f.OneOf) == 0 {
+		if f.Discriminator != "" {
+			return errors.New("Discriminator is set but there are no variants")
+		}
+		return nil
+	}
+
+	if f.Discriminator == "" {
+		return errors.New("Variants require a discriminator")
+	}
+	if _, ok := f.Fields.Field(f.Discriminator); ok {
+		return fmt.Errorf("Discriminator %s can't be a common field", f.Discriminator)
+	}
+
+	tags := map[string]bool{}
+	for _, variant := range f.OneOf {
+		if variant.Tag == "" {
+			return errors.New("Variant tag is required")
+		}
+		if tags[variant.Tag] {
+			return fmt.Errorf("Variant tag %s is duplicated", variant.Tag)
+		}
+		tags[variant.Tag] = true
+
+		// Variant fields share the object with the common fields
+		merged := append(append(ConfigurationSchema{}, f.Fields...), variant.Fields...)
+		err := merged.Validate(remainingDepth - 1)
+		if err != nil {
+			return fmt.Errorf("Invalid variant %s: %v", variant.Tag, err)
+		}
+		if _, ok := variant.Fields.Field(f.Discriminator); ok {
+			return fmt.Errorf("Variant %s redefines the discriminator %s", variant.Tag, f.Discriminator)
+		}
+	}
+
+	return nil
+}
+
+// Returns the field with the given label
+func (s ConfigurationSchema) Field(label string) (SchemaField, bool) {
+	for _, field := range s {
+		if field.Label == label {
+			return field, true
+		}
+	}
+	var result SchemaField
+	return result, false
+}
+
+// Returns the fields that apply to an object value. For tagged unions, this is
+// the common fields plus the fields of the variant selected by the discriminator,
+// which is itself included as a required string.
+func (f SchemaField) ObjectSchema(value IntegrationConfig) (ConfigurationSchema, error) {
+
+	if len(f.OneOf) == 0 {
+		return f.Fields, nil
+	}
+
+	tags := []string{}
+	for _, variant := range f.OneOf {
+		tags = append(tags, variant.Tag)
+	}
+
+	tag, ok := value[f.Discriminator]
+	if !ok {
+		return nil, fmt.Errorf("Field %s is required. Valid values are %s", f.Discriminator, strings.Join(tags, ", "))
+	}
+
+	for _, variant := range f.OneOf {
+		if tag == variant.Tag {
+			discriminator := SchemaField{Label: f.Discriminator, Type: "string", Required: true}
+			fields := append(ConfigurationSchema{discriminator}, f.Fields...)
+			return append(fields, variant.Fields...), nil
+		}
+	}
+
+	return nil, fmt.Errorf("Invalid value %v for field %s. Valid values are %s", tag, f.Discriminator, strings.Join(tags, ", "))
+}
+
+const (
+	hiddenField = iota
+	allowedField
+	requiredField
+)
+
+type fieldCondition struct {
+	state int
+	reason string
+}
+
+// Resolves the state of every conditional field of this level for the given configuration
+func (s ConfigurationSchema) conditionalFields(c IntegrationConfig) map[string]fieldCondition {
+
+	conditions := map[string]fieldCondition{}
+
+	for _, field := range s {
+		value, present := c[field.Label]
+
+		for _, rule := range field.Rules {
+			matches := present && valuesEqual(value, rule.Equals)
+			reason := fmt.Sprintf("when %s is %v", field.Label, value)
+			if !present {
+				reason = fmt.Sprintf("when %s is not set", field.Label)
+			}
+
+			update := func(label string, state int) {
+				current, ok := conditions[label]
+				if !ok {
+					current = fieldCondition{ hiddenField, reason }
+				}
+				if matches && state > current.state {
+					current = fieldCondition{ state, fmt.Sprintf("when %s is %v", field.Label, value) }
+				}
+				conditions[label] = current
+			}
+
+			for _, label := range rule.Allow {
+				update(label, allowedField)
+			}
+			for _, label := range rule.Require {
+				update(label, requiredField)
+			}
+		}
+	}
+
+	return conditions
+}
+
+// Compares configuration values. Numbers are compared by value regardless of their type,
+// since rules decoded from JSON hold float64 while normalized configs hold int.
+func valuesEqual(a interface{}, b interface{}) bool {
+	fa, aIsNumber := toFloat(a)
+	fb, bIsNumber := toFloat(b)
+	if aIsNumber && bIsNumber {
+		return fa == fb
+	}
+
+	// Only scalars are compared, other values could be uncomparable
+	switch a.(type) {
+	case string, bool:
+		return a == b
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"testing"
+)
+
+func authSchema() ConfigurationSchema {
+	return ConfigurationSchema{
+		SchemaField{Label: "auth_type", Type: "string", Required: true, Rules: []FieldRule{
+			{Equals: "oauth", Require: []string{"client_id", "client_secret"}, Allow: []string{"scopes"}},
+			{Equals: "api_key", Require: []string{"api_key"}},
+		}},
+		SchemaField{Label: "client_id", Type: "string"},
+		SchemaField{Label: "client_secret", Type: "string"},
+		SchemaField{Label: "scopes", Type: "string", Array: true},
+		SchemaField{Label: "api_key", Type: "string"},
+		SchemaField{Label: "account_id", Type: "string"},
+	}
+}
+
+func TestConditionalSchemaValidation(t *testing.T) {
+
+	_, err := NewIntegrationDefinition("name", "source", authSchema())
+	if err != nil {
+		t.Errorf("Expected schema with rules to be valid, got %v", err)
+	}
+
+	invalid := []ConfigurationSchema{
+		// Unknown field
+		{
+			SchemaField{Label: "auth_type", Type: "string", Rules: []FieldRule{{Equals: "oauth", Require: []string{"missing"}}}},
+		},
+		// References itself
+		{
+			SchemaField{Label: "auth_type", Type: "string", Rules: []FieldRule{{Equals: "oauth", Require: []string{"auth_type"}}}},
+		},
+		// Missing value
+		{
+			SchemaField{Label: "auth_type", Type: "string", Rules: []FieldRule{{Require: []string{"key"}}}},
+			SchemaField{Label: "key", Type: "string"},
+		},
+		// Rule on an object
+		{
+			SchemaField{Label: "auth", Type: "object", Fields: ConfigurationSchema{}, Rules: []FieldRule{{Equals: "oauth", Require: []string{"key"}}}},
+			SchemaField{Label: "key", Type: "string"},
+		},
+	}
+	for idx, schema := range invalid {
+		_, err := NewIntegrationDefinition("name", "source", schema)
+		if err == nil {
+			t.Errorf("Expected error for schema at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestConditionalRequiredFields(t *testing.T) {
+
+	schema := authSchema()
+
+	valid := []IntegrationConfig{
+		{"auth_type": "oauth", "client_id": "id", "client_secret": "secret"},
+		{"auth_type": "oauth", "client_id": "id", "client_secret": "secret", "scopes": []interface{}{"read"}},
+		{"auth_type": "api_key", "api_key": "key"},
+		{"auth_type": "api_key", "api_key": "key", "account_id": "123"},
+	}
+	for idx, config := range valid {
+		err := config.Validate(schema)
+		if err != nil {
+			t.Errorf("Expected config at index %d to be valid, got %v", idx, err)
+		}
+	}
+
+	invalid := []IntegrationConfig{
+		// Missing required fields
+		{"auth_type": "oauth", "client_id": "id"},
+		{"auth_type": "api_key"},
+		// Fields of another mode
+		{"auth_type": "api_key", "api_key": "key", "client_id": "id"},
+		{"auth_type": "oauth", "client_id": "id", "client_secret": "secret", "api_key": "key"},
+		{"auth_type": "api_key", "api_key": "key", "scopes": []interface{}{"read"}},
+		// Unknown mode hides every conditional field
+		{"auth_type": "basic", "api_key": "key"},
+	}
+	for idx, config := range invalid {
+		err := config.Validate(schema)
+		if err == nil {
+			t.Errorf("Expected error for config at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestConditionalNumericRule(t *testing.T) {
+
+	// Rules decoded from JSON hold float64
+	schema := ConfigurationSchema{
+		SchemaField{Label: "version", Type: "int", Required: true, Rules: []FieldRule{
+			{Equals: float64(2), Require: []string{"region"}},
+		}},
+		SchemaField{Label: "region", Type: "string"},
+	}
+
+	err := IntegrationConfig{"version": 2, "region": "us"}.Validate(schema)
+	if err != nil {
+		t.Errorf("Expected valid configuration, got %v", err)
+	}
+	err = IntegrationConfig{"version": 2}.Validate(schema)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	err = IntegrationConfig{"version": 1, "region": "us"}.Validate(schema)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func credentialsSchema() ConfigurationSchema {
+	return ConfigurationSchema{
+		SchemaField{Label: "credentials", Type: "object", Required: true, Discriminator: "method",
+			Fields: ConfigurationSchema{
+				SchemaField{Label: "user_agent", Type: "string"},
+			},
+			OneOf: []SchemaVariant{
+				{Tag: "oauth", Fields: ConfigurationSchema{
+					SchemaField{Label: "client_id", Type: "string", Required: true},
+					SchemaField{Label: "refresh_token", Type: "string", Required: true},
+				}},
+				{Tag: "service_account", Fields: ConfigurationSchema{
+					SchemaField{Label: "key_json", Type: "string", Required: true},
+				}},
+			},
+		},
+	}
+}
+
+func TestOneOfSchemaValidation(t *testing.T) {
+
+	_, err := NewIntegrationDefinition("name", "source", credentialsSchema())
+	if err != nil {
+		t.Errorf("Expected schema with variants to be valid, got %v", err)
+	}
+
+	variant := []SchemaVariant{{Tag: "a", Fields: ConfigurationSchema{}}}
+	invalid := []SchemaField{
+		// Missing discriminator
+		{Label: "credentials", Type: "object", OneOf: variant},
+		// Not an object
+		{Label: "credentials", Type: "string", Discriminator: "method", OneOf: variant},
+		// Duplicated tags
+		{Label: "credentials", Type: "object", Discriminator: "method", OneOf: append(variant, variant...)},
+		// Discriminator collides with a common field
+		{Label: "credentials", Type: "object", Discriminator: "method", OneOf: variant, Fields: ConfigurationSchema{
+			SchemaField{Label: "method", Type: "string"},
+		}},
+		// Variant field collides with a common field
+		{Label: "credentials", Type: "object", Discriminator: "method", Fields: ConfigurationSchema{
+			SchemaField{Label: "key", Type: "string"},
+		}, OneOf: []SchemaVariant{{Tag: "a", Fields: ConfigurationSchema{
+			SchemaField{Label: "key", Type: "int"},
+		}}}},
+	}
+	for idx, field := range invalid {
+		_, err := NewIntegrationDefinition("name", "source", ConfigurationSchema{field})
+		if err == nil {
+			t.Errorf("Expected error for field at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestOneOfConfig(t *testing.T) {
+
+	schema := credentialsSchema()
+
+	valid := []IntegrationConfig{
+		{"credentials": IntegrationConfig{"method": "oauth", "client_id": "id", "refresh_token": "token"}},
+		{"credentials": IntegrationConfig{"method": "service_account", "key_json": "{}", "user_agent": "agent"}},
+	}
+	for idx, config := range valid {
+		err := config.Validate(schema)
+		if err != nil {
+			t.Errorf("Expected config at index %d to be valid, got %v", idx, err)
+		}
+	}
+
+	invalid := []IntegrationConfig{
+		// Missing discriminator
+		{"credentials": IntegrationConfig{"client_id": "id", "refresh_token": "token"}},
+		// Unknown variant
+		{"credentials": IntegrationConfig{"method": "basic"}},
+		// Missing variant field
+		{"credentials": IntegrationConfig{"method": "oauth", "client_id": "id"}},
+		// Field of another variant has the wrong type
+		{"credentials": IntegrationConfig{"method": "service_account", "key_json": 1}},
+	}
+	for idx, config := range invalid {
+		err := config.Validate(schema)
+		if err == nil {
+			t.Errorf("Expected error for config at index %d, got nil", idx)
+		}
+	}
+
+	// Variant fields are normalized too
+	decoded := IntegrationConfig{"credentials": map[string]interface{}{"method": "oauth", "client_id": "id", "refresh_token": "token"}}
+	err := decoded.Normalize(schema).Validate(schema)
+	if err != nil {
+		t.Errorf("Expected normalized config to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Connection struct {
+	/*
+		Syncs the streams of a source integration into a destination integration.
+		Both ends belong to the workspace of the connection.
+	*/
+	ID string `json:"id" firestore:"id"`
+	Name string `json:"name" firestore:"name"`
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	SourceID string `json:"source_id" firestore:"source_id"`
+	DestinationID string `json:"destination_id" firestore:"destination_id"`
+	Schedule Schedule `json:"schedule" firestore:"schedule"`
+	Namespace NamespaceMapping `json:"namespace" firestore:"namespace"`
+	Enabled bool `json:"enabled" firestore:"enabled"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" firestore:"deleted_at,omitempty"` // Set while its workspace is in the trash
+}
+
+// Where the streams of the source are written in the destination
+type NamespaceMapping struct {
+	Mode string `json:"mode" firestore:"mode"` // "source", "destination" or "custom"
+	Format string `json:"format,omitempty" firestore:"format,omitempty"` // Only for "custom". May reference ${SOURCE_NAMESPACE}
+	Prefix string `json:"prefix,omitempty" firestore:"prefix,omitempty"` // Prepended to the name of destination tables
+}
+
+const sourceNamespacePlaceholder = "${SOURCE_NAMESPACE}"
+
+// Connections without schedule are manual, and write to the default namespace of the destination
+func NewConnection(name string, workspaceID string, source Integration, destination Integration, schedule Schedule, namespace NamespaceMapping, enabled bool, now time.Time) (Connection, error) {
+	// Constructor be ignorant in respect to the state of the database
+	if schedule.Type == "" {
+		schedule.Type = "manual"
+	}
+	if namespace.Mode == "" {
+		namespace.Mode = "destination"
+	}
+	connection := Connection{ "", name, workspaceID, source.ID, destination.ID, schedule, namespace, enabled, nil }
+	err := connection.Validate(source, destination, now)
+	if err != nil {
+		return connection, fmt.Errorf("Invalid connection: %v", err)
+	}
+
+	return connection, nil
+}
+
+// Copy of the connection for another workspace, between the copies of its ends. Copies are disabled,
+// as their ends may lack secrets
+func (c Connection) Clone(workspaceID string, sourceID string, destinationID string) Connection {
+	return Connection{ "", c.Name, workspaceID, sourceID, destinationID, c.Schedule, c.Namespace, false, nil }
+}
+
+// Checks the connection against its ends. The integrations must have their definition attached
+func (c Connection) Validate(source Integration, destination Integration, now time.Time) error {
+
+	if c.Name == "" {
+		return errors.New("Name is required")
+	}
+
+	ends := []struct {
+		role string
+		integration Integration
+		id string
+	}{
+		{ "source", source, c.SourceID },
+		{ "destination", destination, c.DestinationID },
+	}
+	for _, end := range ends {
+		if end.integration.ID == "" || end.integration.ID != end.id {
+			return fmt.Errorf("The %s must be an existing integration", end.role)
+		}
+		if end.integration.WorkspaceID != c.WorkspaceID {
+			return fmt.Errorf("The %s %s belongs to another workspace", end.role, end.integration.ID)
+		}
+		if t := end.integration.definition.Type; t != end.role {
+			return fmt.Errorf("Integration %s is a %s, expected a %s", end.integration.ID, t, end.role)
+		}
+	}
+
+	err := c.Schedule.Validate(now)
+	if err != nil {
+		return fmt.Errorf("Invalid schedule: %v", err)
+	}
+
+	err = c.Namespace.Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid namespace: %v", err)
+	}
+
+	return nil
+}
+
+func (n NamespaceMapping) Validate() error {
+	switch n.Mode {
+	case "source", "destination":
+		if n.Format != "" {
+			return errors.New("Format is only allowed for custom namespaces")
+		}
+	case "custom":
+		if n.Format == "" {
+			return errors.New("Custom namespaces require a format")
+		}
+	default:
+		return fmt.Errorf("Invalid namespace mode %s. Valid modes are \"source\", \"destination\" and \"custom\"", n.Mode)
+	}
+	return nil
+}
+
+// Resolves where a source stream is written. An empty namespace means the default
+// namespace of the destination.
+func (n NamespaceMapping) Destination(sourceNamespace string, stream string) (string, string) {
+
+	namespace := ""
+	switch n.Mode {
+	case "source":
+		namespace = sourceNamespace
+	case "custom":
+		namespace = strings.ReplaceAll(n.Format, sourceNamespacePlaceholder, sourceNamespace)
+	}
+
+	return namespace, n.Prefix + stream
+}
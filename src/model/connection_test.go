@@ -0,0 +1,78 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func connectionEnds() (Integration, Integration) {
+	source := Integration{ID: "source", WorkspaceID: "workspace"}.WithDefinition(IntegrationDefinition{Type: "source"})
+	destination := Integration{ID: "destination", WorkspaceID: "workspace"}.WithDefinition(IntegrationDefinition{Type: "destination"})
+	return source, destination
+}
+
+func TestNewConnection(t *testing.T) {
+
+	source, destination := connectionEnds()
+
+	connection, err := NewConnection("sync", "workspace", source, destination, Schedule{}, NamespaceMapping{}, true, time.Now())
+	if err != nil {
+		t.Fatalf("Expected connection to be valid, got %v", err)
+	}
+	if connection.Schedule.Type != "manual" || connection.Namespace.Mode != "destination" {
+		t.Errorf("Expected manual schedule and destination namespace by default, got %+v", connection)
+	}
+
+	other := Integration{ID: "other", WorkspaceID: "other"}.WithDefinition(IntegrationDefinition{Type: "destination"})
+	invalid := []struct {
+		name string
+		source Integration
+		destination Integration
+		schedule Schedule
+		namespace NamespaceMapping
+	}{
+		{ "", source, destination, Schedule{}, NamespaceMapping{} },
+		// Wrong types
+		{ "sync", destination, source, Schedule{}, NamespaceMapping{} },
+		{ "sync", source, source, Schedule{}, NamespaceMapping{} },
+		// Another workspace
+		{ "sync", source, other, Schedule{}, NamespaceMapping{} },
+		// Missing end
+		{ "sync", source, Integration{}, Schedule{}, NamespaceMapping{} },
+		// Invalid schedules
+		{ "sync", source, destination, Schedule{Type: "interval"}, NamespaceMapping{} },
+		{ "sync", source, destination, Schedule{Type: "manual", IntervalMinutes: 5}, NamespaceMapping{} },
+		{ "sync", source, destination, Schedule{Type: "hourly"}, NamespaceMapping{} },
+		// Invalid namespaces
+		{ "sync", source, destination, Schedule{}, NamespaceMapping{Mode: "custom"} },
+		{ "sync", source, destination, Schedule{}, NamespaceMapping{Mode: "source", Format: "raw"} },
+		{ "sync", source, destination, Schedule{}, NamespaceMapping{Mode: "table"} },
+	}
+	for idx, test := range invalid {
+		_, err := NewConnection(test.name, "workspace", test.source, test.destination, test.schedule, test.namespace, true, time.Now())
+		if err == nil {
+			t.Errorf("Expected error for connection at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestNamespaceMapping(t *testing.T) {
+
+	tests := []struct {
+		mapping NamespaceMapping
+		namespace string
+		table string
+	}{
+		{ NamespaceMapping{Mode: "destination"}, "", "users" },
+		{ NamespaceMapping{Mode: "source", Prefix: "crm_"}, "public", "crm_users" },
+		{ NamespaceMapping{Mode: "custom", Format: "raw_${SOURCE_NAMESPACE}"}, "raw_public", "users" },
+		{ NamespaceMapping{Mode: "custom", Format: "staging"}, "staging", "users" },
+	}
+
+	for idx, test := range tests {
+		namespace, table := test.mapping.Destination("public", "users")
+		if namespace != test.namespace || table != test.table {
+			t.Errorf("Expected %s.%s at index %d, got %s.%s", test.namespace, test.table, idx, namespace, table)
+		}
+	}
+}
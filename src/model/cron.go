@@ -0,0 +1,283 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Timezones don't depend on the host
+)
+
+// Parsed five field cron expression: minute, hour, day of month, month and day of week.
+// Supports lists, ranges, steps, month and day names, and the @hourly, @daily,
+// @weekly, @monthly and @yearly macros.
+type CronExpression struct {
+	minutes []int
+	hours []int
+	days map[int]bool
+	months map[int]bool
+	weekdays map[int]bool
+	// As in Vixie cron, when both day fields are restricted a day matching either one runs
+	anyDay bool
+	anyWeekday bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly": "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly": "0 0 * * 0",
+	"@daily": "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly": "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronWeekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+func ParseCronExpression(expression string) (CronExpression, error) {
+
+	var cron CronExpression
+
+	expression = strings.TrimSpace(expression)
+	if macro, ok := cronMacros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != 5 {
+		return cron, fmt.Errorf("Expected 5 fields, got %d", len(parts))
+	}
+
+	minutes, _, err := parseCronField(parts[0], 0, 59, nil)
+	if err != nil {
+		return cron, fmt.Errorf("Invalid minute field: %v", err)
+	}
+	hours, _, err := parseCronField(parts[1], 0, 23, nil)
+	if err != nil {
+		return cron, fmt.Errorf("Invalid hour field: %v", err)
+	}
+	days, anyDay, err := parseCronField(parts[2], 1, 31, nil)
+	if err != nil {
+		return cron, fmt.Errorf("Invalid day of month field: %v", err)
+	}
+	months, _, err := parseCronField(parts[3], 1, 12, cronMonthNames)
+	if err != nil {
+		return cron, fmt.Errorf("Invalid month field: %v", err)
+	}
+	// 7 is Sunday too
+	weekdays, anyWeekday, err := parseCronField(parts[4], 0, 7, cronWeekdayNames)
+	if err != nil {
+		return cron, fmt.Errorf("Invalid day of week field: %v", err)
+	}
+
+	cron = CronExpression{ minutes, hours, map[int]bool{}, map[int]bool{}, map[int]bool{}, anyDay, anyWeekday }
+	for _, day := range days {
+		cron.days[day] = true
+	}
+	for _, month := range months {
+		cron.months[month] = true
+	}
+	for _, weekday := range weekdays {
+		cron.weekdays[weekday % 7] = true
+	}
+
+	return cron, nil
+}
+
+// Parses a comma separated list of values, ranges (a-b) and steps (*/n or a-b/n).
+// Returns the sorted values and whether the field starts with a wildcard, like */n, which as in
+// Vixie cron doesn't restrict the day for the day of month and day of week rule.
+func parseCronField(field string, min int, max int, names map[string]int) ([]int, bool, error) {
+
+	values := map[int]bool{}
+
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			rangePart = item[:idx]
+			n, err := strconv.Atoi(item[idx+1:])
+			if err != nil || n <= 0 {
+				return nil, false, fmt.Errorf("Invalid step in %s", item)
+			}
+			step = n
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			start, err = parseCronValue(bounds[0], min, max, names)
+			if err != nil {
+				return nil, false, err
+			}
+			end, err = parseCronValue(bounds[1], min, max, names)
+			if err != nil {
+				return nil, false, err
+			}
+			if start > end {
+				return nil, false, fmt.Errorf("Invalid range %s", rangePart)
+			}
+		default:
+			value, err := parseCronValue(rangePart, min, max, names)
+			if err != nil {
+				return nil, false, err
+			}
+			start, end = value, value
+			// a/n means from a to the end
+			if step > 1 {
+				end = max
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+
+	sorted := []int{}
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Ints(sorted)
+
+	return sorted, strings.HasPrefix(field, "*"), nil
+}
+
+func parseCronValue(value string, min int, max int, names map[string]int) (int, error) {
+
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid value %s", value)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("Value %d out of range %d-%d", n, min, max)
+	}
+
+	return n, nil
+}
+
+func (c CronExpression) matchesDay(year int, month time.Month, day int) bool {
+
+	if !c.months[int(month)] {
+		return false
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	dayMatches := c.days[day]
+	weekdayMatches := c.weekdays[int(date.Weekday())]
+
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekdayMatches
+	case c.anyWeekday:
+		return dayMatches
+	}
+	return dayMatches || weekdayMatches
+}
+
+// Runs further away than this are considered never to happen
+const cronSearchLimit = 5 * 366
+
+// Returns the first run strictly after the given time, with the expression evaluated on
+// the wall clock of the location.
+//
+// Daylight saving time transitions are handled like this:
+//   - Wall clock times skipped when clocks move forward run at the moment of the transition
+//   - Wall clock times repeated when clocks move back run once, on their first occurrence
+func (c CronExpression) Next(after time.Time, loc *time.Location) (time.Time, error) {
+
+	local := after.In(loc)
+	year, month, day := local.Date()
+
+	for offset := 0; offset < cronSearchLimit; offset++ {
+		date := time.Date(year, month, day + offset, 0, 0, 0, 0, time.UTC)
+		if !c.matchesDay(date.Year(), date.Month(), date.Day()) {
+			continue
+		}
+
+		for _, hour := range c.hours {
+			for _, minute := range c.minutes {
+				run, ok := wallClockInstant(date.Year(), date.Month(), date.Day(), hour, minute, loc)
+				if ok && run.After(after) {
+					return run, nil
+				}
+			}
+		}
+	}
+
+	return time.Time{}, errors.New("Expression doesn't run in the next 5 years")
+}
+
+// Converts a wall clock time of the location to an instant. Repeated wall clock times
+// resolve to their first occurrence, and skipped ones to the end of the gap.
+func wallClockInstant(year int, month time.Month, day int, hour int, minute int, loc *time.Location) (time.Time, bool) {
+
+	civil := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+
+	// The offsets in effect around that wall clock time. Offsets never change twice within two days
+	_, before := civil.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := civil.Add(24 * time.Hour).In(loc).Zone()
+
+	var first time.Time
+	found := false
+	for _, offset := range []int{before, after} {
+		instant := civil.Add(-time.Duration(offset) * time.Second)
+		if sameWallClock(instant.In(loc), civil) && (!found || instant.Before(first)) {
+			first = instant
+			found = true
+		}
+	}
+	if found {
+		return first, true
+	}
+
+	// Skipped by a transition. Find the first second past that wall clock time
+	low := civil.Unix() - int64(before)
+	high := civil.Unix() - int64(after)
+	if high < low {
+		low, high = high, low
+	}
+	if !wallClockAfter(time.Unix(high, 0).In(loc), civil) {
+		return time.Time{}, false
+	}
+	for high - low > 1 {
+		middle := low + (high - low) / 2
+		if wallClockAfter(time.Unix(middle, 0).In(loc), civil) {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+
+	return time.Unix(high, 0), true
+}
+
+func sameWallClock(t time.Time, civil time.Time) bool {
+	y, m, d := t.Date()
+	cy, cm, cd := civil.Date()
+	return y == cy && m == cm && d == cd && t.Hour() == civil.Hour() && t.Minute() == civil.Minute()
+}
+
+// Whether the wall clock time of t is past the civil time
+func wallClockAfter(t time.Time, civil time.Time) bool {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return wall.After(civil)
+}
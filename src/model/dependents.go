@@ -0,0 +1,39 @@
+package model
+
+import (
+	"fmt"
+)
+
+// A resource that references another one and would be left orphaned if it was removed
+type Dependent struct {
+	Type string `json:"type"` // e.g "integration"
+	ID string `json:"id"`
+	Name string `json:"name"`
+	WorkspaceID string `json:"workspace_id"`
+}
+
+// Returned when an operation is refused because other resources still depend on the target
+type InUseError struct {
+	Resource string
+	Dependents []Dependent
+}
+
+func (e *InUseError) Error() string {
+	return fmt.Sprintf("%s is still used by %d resource(s)", e.Resource, len(e.Dependents))
+}
+
+func IntegrationDependents(integrations []Integration) []Dependent {
+	dependents := []Dependent{}
+	for _, i := range integrations {
+		dependents = append(dependents, Dependent{ "integration", i.ID, i.Name, i.WorkspaceID })
+	}
+	return dependents
+}
+
+func ConnectionDependents(connections []Connection) []Dependent {
+	dependents := []Dependent{}
+	for _, c := range connections {
+		dependents = append(dependents, Dependent{ "connection", c.ID, c.Name, c.WorkspaceID })
+	}
+	return dependents
+}
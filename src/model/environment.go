@@ -0,0 +1,337 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Named stage of a workspace, e.g. "dev", "staging" or "prod". Environments override fields of the
+// configurations of the integrations of the workspace and its variables, so a configuration tested in a
+// sandbox is promoted to production accounts without editing it by hand
+type Environment struct {
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	Name string `json:"name" firestore:"name"`
+	Permissions []WorkspacePermission `json:"permissions" firestore:"permissions"` // Empty to follow the workspace
+	Overrides map[string]IntegrationConfig `json:"overrides" firestore:"overrides"` // [integration id] => fields replacing those of its configuration. Null fields remove them
+	Variables []WorkspaceVariable `json:"variables" firestore:"variables"` // Replace the variables of the workspace with the same name
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+func NewEnvironment(workspaceID string, name string, permissions []WorkspacePermission, now time.Time) (Environment, error) {
+
+	if permissions == nil {
+		permissions = []WorkspacePermission{}
+	}
+	environment := Environment{ workspaceID, name, permissions, map[string]IntegrationConfig{}, []WorkspaceVariable{}, now, now }
+
+	err := environment.Validate()
+	if err != nil {
+		return environment, err
+	}
+
+	return environment, nil
+}
+
+func (e Environment) Validate() error {
+
+	if !parameterName.MatchString(e.Name) {
+		return fmt.Errorf("Invalid environment name %q. Names are made of letters, digits and underscores", e.Name)
+	}
+	for idx, perm := range e.Permissions {
+		err := perm.Validate()
+		if err != nil {
+			return fmt.Errorf("Invalid permission at index %d: %v", idx, err)
+		}
+	}
+
+	return nil
+}
+
+// Role of the principals in the environment. Environments with their own permissions narrow the roles of
+// the workspace: principals keep the lowest of both, and principals they don't list are viewers.
+// Owners of the workspace keep administering every environment
+func (e Environment) Role(w Workspace, principals ...string) string {
+
+	grant, ok := w.Grant(principals...)
+	if !ok {
+		return ""
+	}
+	if len(e.Permissions) == 0 || grant.Role == "owner" {
+		return grant.Role
+	}
+
+	scoped := Workspace{ ID: w.ID, OrganizationID: w.OrganizationID, Permissions: e.Permissions }
+	narrowed, ok := scoped.Grant(principals...)
+	if !ok {
+		return "viewer"
+	}
+	if roleRanks[narrowed.Role] < roleRanks[grant.Role] {
+		return narrowed.Role
+	}
+	return grant.Role
+}
+
+// Editors of the environment change its overrides and variables, and promote configurations into it
+func (e Environment) EditableBy(w Workspace, principals ...string) bool {
+	role := e.Role(w, principals...)
+	return role == "editor" || role == "owner"
+}
+
+// Configuration of the integration in the environment: its own with the overridden fields replaced
+func (e Environment) Configuration(i Integration) IntegrationConfig {
+
+	config := IntegrationConfig{}
+	for key, value := range i.Configuration {
+		config[key] = value
+	}
+	for key, value := range e.Overrides[i.ID] {
+		if value == nil {
+			delete(config, key)
+		} else {
+			config[key] = value
+		}
+	}
+
+	return config
+}
+
+// Variables of the workspace with those of the environment replacing them
+func (e Environment) EffectiveVariables(variables []WorkspaceVariable) []WorkspaceVariable {
+
+	overridden := map[string]bool{}
+	for _, variable := range e.Variables {
+		overridden[variable.Name] = true
+	}
+
+	effective := []WorkspaceVariable{}
+	for _, variable := range variables {
+		if !overridden[variable.Name] {
+			effective = append(effective, variable)
+		}
+	}
+	effective = append(effective, e.Variables...)
+	sort.Slice(effective, func(i, j int) bool {
+		return effective[i].Name < effective[j].Name
+	})
+
+	return effective
+}
+
+// Sets the fields of the override of the integration that make its configuration the given one.
+// An override that leaves the configuration unchanged is dropped
+func (e Environment) WithConfiguration(i Integration, config IntegrationConfig) Environment {
+
+	override := IntegrationConfig{}
+	for key, value := range config {
+		if !reflect.DeepEqual(value, i.Configuration[key]) {
+			override[key] = value
+		}
+	}
+	for key := range i.Configuration {
+		if _, ok := config[key]; !ok {
+			override[key] = nil
+		}
+	}
+
+	overrides := map[string]IntegrationConfig{}
+	for id, o := range e.Overrides {
+		overrides[id] = o
+	}
+	if len(override) > 0 {
+		overrides[i.ID] = override
+	} else {
+		delete(overrides, i.ID)
+	}
+	e.Overrides = overrides
+
+	return e
+}
+
+// Adds the variable to the environment, or replaces the one with the same name
+func (e Environment) WithVariable(variable WorkspaceVariable) Environment {
+
+	variables := []WorkspaceVariable{}
+	for _, v := range e.Variables {
+		if v.Name != variable.Name {
+			variables = append(variables, v)
+		}
+	}
+	e.Variables = append(variables, variable)
+
+	return e
+}
+
+func (e Environment) WithoutVariable(name string) Environment {
+
+	variables := []WorkspaceVariable{}
+	for _, v := range e.Variables {
+		if v.Name != name {
+			variables = append(variables, v)
+		}
+	}
+	e.Variables = variables
+
+	return e
+}
+
+// Applies the migrations of an upgrade of the integration between the versions to its overrides, so renamed
+// and dropped fields follow. Overrides only hold some fields: defaults set by the migrations are left to
+// the configuration of the integration
+func (e Environment) MigrateOverrides(integrationID string, history []IntegrationDefinition, current string, target string) (Environment, error) {
+
+	override, ok := e.Overrides[integrationID]
+	if !ok {
+		return e, nil
+	}
+
+	definitionID := ""
+	if len(history) > 0 {
+		definitionID = history[0].ID
+	}
+	path, err := upgradePath(history, definitionID, current, target)
+	if err != nil {
+		return e, err
+	}
+
+	for _, def := range path {
+		steps := []MigrationStep{}
+		for _, step := range def.Migrations {
+			if step.Operation != "set_default" {
+				steps = append(steps, step)
+			}
+		}
+		override, err = override.Migrate(steps)
+		if err != nil {
+			return e, fmt.Errorf("Migration of the overrides of environment %s to version %s failed: %v", e.Name, def.Version, err)
+		}
+	}
+
+	overrides := map[string]IntegrationConfig{}
+	for id, o := range e.Overrides {
+		overrides[id] = o
+	}
+	overrides[integrationID] = override
+	e.Overrides = overrides
+
+	return e, nil
+}
+
+// Whether the environment takes the variable of the workspace, not defining its own
+func (e Environment) InheritsVariable(name string) bool {
+	for _, v := range e.Variables {
+		if v.Name == name {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether the change of the configuration of the integration reaches the environment, through the fields
+// it doesn't override
+func (e Environment) InheritsChange(before Integration, after Integration) bool {
+	return !reflect.DeepEqual(e.Configuration(before), e.Configuration(after))
+}
+
+// Copy of the environment for display, with secret variables masked and the overrides masked with the
+// schemas of their integrations, by integration id. Overrides of other integrations are left out
+func (e Environment) Masked(schemas map[string]ConfigurationSchema) Environment {
+
+	overrides := map[string]IntegrationConfig{}
+	for id, override := range e.Overrides {
+		if schema, ok := schemas[id]; ok {
+			overrides[id] = override.MaskSecrets(schema)
+		}
+	}
+	e.Overrides = overrides
+
+	variables := []WorkspaceVariable{}
+	for _, variable := range e.Variables {
+		variables = append(variables, variable.Masked())
+	}
+	e.Variables = variables
+
+	return e
+}
+
+// Configuration promoted from an environment to another: the source configuration, with the secrets of
+// the target so the credentials of an environment never leak into another one. Secrets of arrays of
+// objects can't be matched between environments, and are left out
+func PromotedConfiguration(source IntegrationConfig, target IntegrationConfig, schema ConfigurationSchema) IntegrationConfig {
+
+	promoted := source.StripSecrets(schema)
+
+	for _, field := range schema {
+		if field.Secret {
+			if value, ok := target[field.Label]; ok {
+				promoted[field.Label] = value
+			}
+			continue
+		}
+		if field.Type != "object" || field.Array {
+			continue
+		}
+		sourceObject, ok := asConfig(promoted[field.Label])
+		if !ok {
+			continue
+		}
+		targetObject, ok := asConfig(target[field.Label])
+		if !ok {
+			continue
+		}
+		fields, err := field.ObjectSchema(sourceObject)
+		if err != nil {
+			fields = field.Fields
+		}
+		promoted[field.Label] = PromotedConfiguration(sourceObject, targetObject, fields)
+	}
+
+	return promoted
+}
+
+// Change of a top level field of a configuration. Secrets are masked
+type ConfigChange struct {
+	Field string `json:"field"`
+	Before interface{} `json:"before"` // Null when the field is added
+	After interface{} `json:"after"` // Null when the field is removed
+}
+
+// Changes between two configurations of the same schema, sorted by field
+func DiffConfigurations(before IntegrationConfig, after IntegrationConfig, schema ConfigurationSchema) []ConfigChange {
+
+	maskedBefore := before.MaskSecrets(schema)
+	maskedAfter := after.MaskSecrets(schema)
+
+	fields := map[string]bool{}
+	for key := range before {
+		fields[key] = true
+	}
+	for key := range after {
+		fields[key] = true
+	}
+	keys := []string{}
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changes := []ConfigChange{}
+	for _, key := range keys {
+		if !reflect.DeepEqual(before[key], after[key]) {
+			changes = append(changes, ConfigChange{ key, maskedBefore[key], maskedAfter[key] })
+		}
+	}
+
+	return changes
+}
+
+// Promotion of the configuration of an integration from an environment to another
+type Promotion struct {
+	IntegrationID string `json:"integration_id"`
+	From string `json:"from"`
+	To string `json:"to"`
+	Changes []ConfigChange `json:"changes"` // Changes to the configuration in the target environment
+	Applied bool `json:"applied"` // False for previews
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnvironmentRole(t *testing.T) {
+
+	workspace := Workspace{ ID: "workspace", Permissions: []WorkspacePermission{
+		{ Principal: "user:owner", Role: "owner" },
+		{ Principal: "user:editor", Role: "editor" },
+		{ Principal: "user:viewer", Role: "viewer" },
+		{ Principal: "user:lead", Role: "editor" },
+	} }
+
+	dev, err := NewEnvironment(workspace.ID, "dev", nil, time.Now())
+	if err != nil {
+		t.Fatalf("Expected environment to be valid, got %v", err)
+	}
+	if !dev.EditableBy(workspace, "user:editor") || dev.EditableBy(workspace, "user:viewer") {
+		t.Errorf("Expected environments without permissions to follow the workspace")
+	}
+
+	prod, err := NewEnvironment(workspace.ID, "prod", []WorkspacePermission{
+		{ Principal: "user:lead", Role: "editor" },
+		{ Principal: "user:viewer", Role: "owner" },
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("Expected environment to be valid, got %v", err)
+	}
+	for principal, role := range map[string]string{
+		"user:owner": "owner", // Owners of the workspace administer every environment
+		"user:editor": "viewer", // Not listed
+		"user:viewer": "viewer", // Environments don't grant more than the workspace
+		"user:lead": "editor",
+		"user:other": "",
+	} {
+		if r := prod.Role(workspace, principal); r != role {
+			t.Errorf("Expected role %q for %s, got %q", role, principal, r)
+		}
+	}
+
+	if _, err := NewEnvironment(workspace.ID, "pre-prod", nil, time.Now()); err == nil {
+		t.Errorf("Expected error for environment name with a dash")
+	}
+}
+
+func TestEnvironmentConfiguration(t *testing.T) {
+
+	integration := Integration{ ID: "integration", Configuration: IntegrationConfig{ "host": "db", "port": 5432, "ssl": true } }
+	environment := Environment{ Overrides: map[string]IntegrationConfig{} }
+
+	environment = environment.WithConfiguration(integration, IntegrationConfig{ "host": "prod-db", "port": 5432 })
+	if !reflect.DeepEqual(environment.Overrides["integration"], IntegrationConfig{ "host": "prod-db", "ssl": nil }) {
+		t.Errorf("Expected only the changed fields to be overridden, got %v", environment.Overrides["integration"])
+	}
+	if config := environment.Configuration(integration); !reflect.DeepEqual(config, IntegrationConfig{ "host": "prod-db", "port": 5432 }) {
+		t.Errorf("Unexpected configuration in environment %v", config)
+	}
+	if integration.Configuration["host"] != "db" {
+		t.Errorf("Expected the configuration of the integration not to be modified")
+	}
+
+	environment = environment.WithConfiguration(integration, integration.Configuration)
+	if _, ok := environment.Overrides["integration"]; ok {
+		t.Errorf("Expected override without changes to be dropped")
+	}
+
+	variables := []WorkspaceVariable{ { Name: "project", Value: "analytics" }, { Name: "region", Value: "eu" } }
+	environment = environment.WithVariable(WorkspaceVariable{ Name: "project", Value: "analytics-prod" })
+	effective := environment.EffectiveVariables(variables)
+	expected := []WorkspaceVariable{ { Name: "project", Value: "analytics-prod" }, { Name: "region", Value: "eu" } }
+	if !reflect.DeepEqual(effective, expected) {
+		t.Errorf("Expected variables of the environment to replace those of the workspace, got %v", effective)
+	}
+	if environment = environment.WithoutVariable("project"); len(environment.Variables) != 0 {
+		t.Errorf("Expected variable to be removed, got %v", environment.Variables)
+	}
+}
+
+func TestEnvironmentInherits(t *testing.T) {
+
+	before := Integration{ ID: "integration", Configuration: IntegrationConfig{ "host": "db", "port": 5432 } }
+	environment := Environment{ Overrides: map[string]IntegrationConfig{ "integration": { "host": "prod-db" } }, Variables: []WorkspaceVariable{ { Name: "project", Value: "analytics-prod" } } }
+
+	overridden := before
+	overridden.Configuration = IntegrationConfig{ "host": "other-db", "port": 5432 }
+	if environment.InheritsChange(before, overridden) {
+		t.Errorf("Expected changes of overridden fields not to reach the environment")
+	}
+	inherited := before
+	inherited.Configuration = IntegrationConfig{ "host": "db", "port": 6432 }
+	if !environment.InheritsChange(before, inherited) {
+		t.Errorf("Expected changes of inherited fields to reach the environment")
+	}
+
+	if environment.InheritsVariable("project") || !environment.InheritsVariable("region") {
+		t.Errorf("Expected the environment to inherit only the variables it doesn't define")
+	}
+}
+
+func TestPromotedConfiguration(t *testing.T) {
+
+	schema := ConfigurationSchema{
+		{ Label: "host", Type: "string" },
+		{ Label: "password", Type: "string", Secret: true },
+		{ Label: "ssh", Type: "object", Fields: ConfigurationSchema{
+			{ Label: "user", Type: "string" },
+			{ Label: "key", Type: "string", Secret: true },
+		} },
+	}
+	source := IntegrationConfig{
+		"host": "staging-db",
+		"password": "staging",
+		"ssh": map[string]interface{}{ "user": "deploy", "key": "staging-key" },
+	}
+	target := IntegrationConfig{
+		"host": "prod-db",
+		"password": "prod",
+		"ssh": map[string]interface{}{ "user": "root", "key": "prod-key" },
+	}
+
+	promoted := PromotedConfiguration(source, target, schema)
+	expected := IntegrationConfig{
+		"host": "staging-db",
+		"password": "prod",
+		"ssh": IntegrationConfig{ "user": "deploy", "key": "prod-key" },
+	}
+	if !reflect.DeepEqual(promoted, expected) {
+		t.Errorf("Expected the target to keep its secrets, got %v", promoted)
+	}
+
+	changes := DiffConfigurations(IntegrationConfig{ "host": "prod-db", "password": "prod", "port": 5432 }, IntegrationConfig{ "host": "staging-db", "password": "other" }, schema)
+	expectedChanges := []ConfigChange{
+		{ "host", "prod-db", "staging-db" },
+		{ "password", SecretMask, SecretMask },
+		{ "port", 5432, nil },
+	}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+}
+
+func TestMigrateOverrides(t *testing.T) {
+
+	history := upgradeHistory(t)
+	environment := Environment{ Name: "prod", Overrides: map[string]IntegrationConfig{
+		"integration": { "token": "prod", "legacy": nil },
+		"other": { "host": "prod-db" },
+	} }
+
+	migrated, err := environment.MigrateOverrides("integration", history, "1.0.0", "2.0.0")
+	if err != nil {
+		t.Fatalf("Expected overrides to be migrated, got %v", err)
+	}
+	if !reflect.DeepEqual(migrated.Overrides["integration"], IntegrationConfig{ "api_key": "prod" }) {
+		t.Errorf("Expected renames and drops without defaults, got %v", migrated.Overrides["integration"])
+	}
+	if !reflect.DeepEqual(migrated.Overrides["other"], environment.Overrides["other"]) {
+		t.Errorf("Expected the overrides of other integrations to be kept, got %v", migrated.Overrides["other"])
+	}
+	if _, ok := environment.Overrides["integration"]["token"]; !ok {
+		t.Errorf("Expected the environment not to be modified")
+	}
+
+	environment.Overrides["integration"]["api_key"] = "conflict"
+	if _, err := environment.MigrateOverrides("integration", history, "1.0.0", "1.1.0"); err == nil {
+		t.Errorf("Expected error when a rename collides with an override, got nil")
+	}
+}
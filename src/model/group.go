@@ -0,0 +1,124 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Group of users, granted access to workspaces as a single principal, "group:<group id>".
+// Groups only have users as members, they don't nest
+type Group struct {
+	ID string `json:"id" firestore:"id"`
+	OrganizationID string `json:"organization_id,omitempty" firestore:"organization_id,omitempty"` // Groups of organizations only have its members, and are only granted its workspaces
+	Name string `json:"name" firestore:"name"`
+	Members []GroupMember `json:"members" firestore:"members"`
+	CreatedBy string `json:"created_by" firestore:"created_by"` // ID of the user who created the group
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+type GroupMember struct {
+	Principal string `json:"principal" firestore:"principal"` // Principal ID of the user
+	Role string `json:"role" firestore:"role"` // "manager" or "member". Managers manage the group and its members
+	Email string `json:"user,omitempty" firestore:"-"` // For display only, filled on reads
+}
+
+func NewGroup(organizationID string, name string, members []GroupMember, createdBy string, now time.Time) (Group, error) {
+
+	group := Group{ "", organizationID, name, members, createdBy, now, now }
+
+	err := group.Validate()
+	if err != nil {
+		return group, err
+	}
+
+	return group, nil
+}
+
+func (g Group) Validate() error {
+
+	if strings.TrimSpace(g.Name) == "" {
+		return errors.New("Name is required")
+	}
+
+	seen := map[string]bool{}
+	for idx, member := range g.Members {
+		kind, _, err := ParsePrincipal(member.Principal)
+		if err != nil {
+			return fmt.Errorf("Invalid member at index %d: %v", idx, err)
+		}
+		if kind != PrincipalUser {
+			return fmt.Errorf("Invalid member at index %d: only users can be members of groups", idx)
+		}
+		if member.Role != "manager" && member.Role != "member" {
+			return fmt.Errorf("Invalid role %s of member at index %d. Valid roles are \"manager\" and \"member\"", member.Role, idx)
+		}
+		if seen[member.Principal] {
+			return fmt.Errorf("Member %s is listed more than once", member.Principal)
+		}
+		seen[member.Principal] = true
+	}
+
+	return nil
+}
+
+func (g Group) Principal() string {
+	return GroupPrincipal(g.ID)
+}
+
+func (g Group) HasMember(principal string) bool {
+	for _, member := range g.Members {
+		if member.Principal == principal {
+			return true
+		}
+	}
+	return false
+}
+
+func (g Group) ManagedBy(principal string) bool {
+	for _, member := range g.Members {
+		if member.Principal == principal {
+			return member.Role == "manager"
+		}
+	}
+	return false
+}
+
+// Returns a copy of the group with the member, in place of any other role of its principal
+func (g Group) WithMember(member GroupMember) Group {
+
+	members := []GroupMember{}
+	for _, m := range g.Members {
+		if m.Principal != member.Principal {
+			members = append(members, m)
+		}
+	}
+	g.Members = append(members, GroupMember{ member.Principal, member.Role, "" })
+
+	return g
+}
+
+func (g Group) WithoutMember(principal string) Group {
+
+	members := []GroupMember{}
+	for _, m := range g.Members {
+		if m.Principal != principal {
+			members = append(members, m)
+		}
+	}
+	g.Members = members
+
+	return g
+}
+
+// Whether the group has at least one manager left, so it can still be managed
+func (g Group) Managed() bool {
+	for _, member := range g.Members {
+		if member.Role == "manager" {
+			return true
+		}
+	}
+	return false
+}
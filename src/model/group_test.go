@@ -0,0 +1,45 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+
+	now := time.Now()
+	group, err := NewGroup("", "Analysts", []GroupMember{ { "user:ana", "manager", "" }, { "user:bob", "member", "" } }, "ana", now)
+	if err != nil {
+		t.Fatalf("Expected group to be valid, got %v", err)
+	}
+
+	invalid := [][]GroupMember{
+		{ { "ana@example.com", "member", "" } },
+		{ { "group:other", "member", "" } },
+		{ { "user:ana", "owner", "" } },
+		{ { "user:ana", "manager", "" }, { "user:ana", "member", "" } },
+	}
+	for idx, members := range invalid {
+		if _, err := NewGroup("", "Analysts", members, "ana", now); err == nil {
+			t.Errorf("Expected error for members at index %d, got nil", idx)
+		}
+	}
+	if _, err := NewGroup("", " ", nil, "ana", now); err == nil {
+		t.Errorf("Expected error for a group without name, got nil")
+	}
+
+	if !group.ManagedBy("user:ana") || group.ManagedBy("user:bob") || !group.HasMember("user:bob") || group.HasMember("user:eve") {
+		t.Errorf("Unexpected members %+v", group.Members)
+	}
+
+	promoted := group.WithMember(GroupMember{ "user:bob", "manager", "bob@example.com" })
+	if len(promoted.Members) != 2 || !promoted.ManagedBy("user:bob") || promoted.Members[1].Email != "" {
+		t.Errorf("Expected member to be promoted, got %+v", promoted.Members)
+	}
+	if group.WithoutMember("user:ana").Managed() || !promoted.WithoutMember("user:ana").Managed() {
+		t.Errorf("Expected groups to be managed as long as they have a manager")
+	}
+	if group.Principal() != "group:" + group.ID {
+		t.Errorf("Unexpected principal %s", group.Principal())
+	}
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"time"
 )
 
 type Integration struct {
@@ -17,14 +18,25 @@ type Integration struct {
 	Name string `json:"name" firestore:"name"`
 	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
 	DefinitionID string `json:"definition_id" firestore:"definition_id"`
+	DefinitionVersion string `json:"definition_version" firestore:"definition_version"` // Integrations are pinned to a definition version
 	definition IntegrationDefinition // Definition denormalization
 	Configuration IntegrationConfig `json:"configuration" firestore:"configuration"`
+	Streams []StreamSelection `json:"streams" firestore:"streams"` // Streams to sync. Only for sources
+	LastCheck *CheckResult `json:"last_check,omitempty" firestore:"last_check,omitempty"` // Cleared when the configuration changes
+	Warnings []string `json:"warnings,omitempty" firestore:"-"` // Derived from the definition, not stored
+	Health *IntegrationHealth `json:"health,omitempty" firestore:"-"` // Derived from the runs, not stored
+	DeletedAt *time.Time `json:"deleted_at,omitempty" firestore:"deleted_at,omitempty"` // Set while its workspace is in the trash
 }
 
-func NewIntegration(name string, workspaceID string, definition IntegrationDefinition, configuration IntegrationConfig) (Integration, error) {
+// The configuration is validated with its variable references resolved against the variables of the workspace
+func NewIntegration(name string, workspaceID string, definition IntegrationDefinition, configuration IntegrationConfig, variables []WorkspaceVariable) (Integration, error) {
 	// Constructor be ignorant in respect to the state of the database
-	integration := Integration{ "", name, workspaceID, definition.ID, definition, configuration }
-	err := integration.Validate(integration.definition)
+	integration := Integration{ "", name, workspaceID, definition.ID, definition.Version, definition, configuration, []StreamSelection{}, nil, nil, nil, nil }
+	if !definition.AcceptsNewIntegrations() {
+		return integration, fmt.Errorf("Definition %s version %s is %s. Only published definitions accept new integrations", definition.ID, definition.Version, definition.Status)
+	}
+
+	err := integration.ValidateWithVariables(integration.definition, variables)
 	if err != nil {
 		return integration, fmt.Errorf("Invalid integration: %v", err)
 	}
@@ -32,15 +44,162 @@ func NewIntegration(name string, workspaceID string, definition IntegrationDefin
 	return integration, nil
 }
 
+// Upgrades the integration to the target version of its definition.
+// History should hold every known version of the definition. The migrations of each version
+// after the current one, up to the target, are applied in order and the resulting configuration
+// is validated against the target schema, with the references to the variables resolved.
+func (i Integration) Upgrade(history []IntegrationDefinition, targetVersion string, variables []WorkspaceVariable) (Integration, error) {
+
+	upgraded := i
+
+	cmp, err := CompareVersions(targetVersion, i.DefinitionVersion)
+	if err != nil {
+		return upgraded, fmt.Errorf("Invalid version: %v", err)
+	}
+	if cmp < 0 {
+		return upgraded, fmt.Errorf("Can't downgrade from version %s to %s", i.DefinitionVersion, targetVersion)
+	}
+
+	path, err := upgradePath(history, i.DefinitionID, i.DefinitionVersion, targetVersion)
+	if err != nil {
+		return upgraded, err
+	}
+
+	var target *IntegrationDefinition
+	config := i.Configuration
+	for idx, def := range path {
+		config, err = config.Migrate(def.Migrations)
+		if err != nil {
+			return upgraded, fmt.Errorf("Migration to version %s failed: %v", def.Version, err)
+		}
+		if cmp, _ := CompareVersions(def.Version, targetVersion); cmp == 0 {
+			target = &path[idx]
+		}
+	}
+
+	if target == nil {
+		if cmp == 0 {
+			return upgraded, nil
+		}
+		return upgraded, fmt.Errorf("Version %s not found for definition %s", targetVersion, i.DefinitionID)
+	}
+	if !target.AcceptsNewIntegrations() {
+		return upgraded, fmt.Errorf("Can't upgrade to version %s: version is %s", targetVersion, target.Status)
+	}
+
+	config = config.Normalize(target.ConfigurationSchema)
+	err = config.ValidateWithVariables(target.ConfigurationSchema, variables)
+	if err != nil {
+		return upgraded, fmt.Errorf("Configuration is not valid for version %s: %v", targetVersion, err)
+	}
+
+	// Selected streams must still be in the catalog
+	streams, err := target.ResolveStreamSelection(i.Streams)
+	if err != nil {
+		return upgraded, fmt.Errorf("Stream selection is not valid for version %s: %v", targetVersion, err)
+	}
+
+	upgraded.DefinitionVersion = target.Version
+	upgraded.Configuration = config
+	upgraded.Streams = streams
+	// The check was of the previous version
+	upgraded.LastCheck = nil
+	return upgraded.WithDefinition(*target), nil
+}
+
+// Versions of the definition after the current one, up to the target, from oldest to newest
+func upgradePath(history []IntegrationDefinition, definitionID string, current string, target string) ([]IntegrationDefinition, error) {
+
+	versions := make([]IntegrationDefinition, len(history))
+	copy(versions, history)
+	SortDefinitionVersions(versions)
+
+	path := []IntegrationDefinition{}
+	for _, def := range versions {
+		if def.ID != definitionID {
+			return path, fmt.Errorf("Version %s belongs to definition %s", def.Version, def.ID)
+		}
+
+		afterCurrent, err := CompareVersions(def.Version, current)
+		if err != nil {
+			return path, fmt.Errorf("Invalid version in history: %v", err)
+		}
+		untilTarget, _ := CompareVersions(def.Version, target)
+		if afterCurrent > 0 && untilTarget <= 0 {
+			path = append(path, def)
+		}
+	}
+
+	return path, nil
+}
+
+// Attaches the pinned definition version, deriving the warnings shown in API responses
+func (i Integration) WithDefinition(def IntegrationDefinition) Integration {
+	i.definition = def
+	i.Warnings = def.Warnings()
+	if len(i.Warnings) == 0 {
+		i.Warnings = nil
+	}
+	return i
+}
+
+// Copy of the integration for user facing views, its secrets masked with the schema of its attached
+// definition. Without a definition secrets can't be told apart, so the configuration is left out
+func (i Integration) Masked() Integration {
+	if i.definition.ID == "" {
+		i.Configuration = IntegrationConfig{}
+		return i
+	}
+	i.Configuration = i.Configuration.MaskSecrets(i.definition.ConfigurationSchema)
+	return i
+}
+
+// Copy of the integration for another workspace, pinned to the same definition version but without
+// its secrets nor its last check. The copy may lack required secrets until they are filled in again
+func (i Integration) Clone(workspaceID string, def IntegrationDefinition) Integration {
+	streams := make([]StreamSelection, len(i.Streams))
+	copy(streams, i.Streams)
+	clone := Integration{ "", i.Name, workspaceID, i.DefinitionID, i.DefinitionVersion, def, i.Configuration.StripSecrets(def.ConfigurationSchema), streams, nil, nil, nil, nil }
+	return clone.WithDefinition(def)
+}
+
+// Derives the health shown in API responses from the runs of the integration.
+// Runs of other integrations are ignored
+func (i Integration) WithHealth(runs []Run, now time.Time) Integration {
+	own := []Run{}
+	for _, run := range runs {
+		if run.HasIntegration(i.ID) {
+			own = append(own, run)
+		}
+	}
+	i.Health = HealthFromRuns(own, now, DefaultStaleAfter)
+	return i
+}
+
 func  (i Integration) Validate(def IntegrationDefinition) error {
 	// Checks if the configuration matches the definition
 	err := i.Configuration.Validate(def.ConfigurationSchema)
 	if err != nil {
 		return fmt.Errorf("Invalid configuration: %v", err)
 	}
+	_, err = def.ResolveStreamSelection(i.Streams)
+	if err != nil {
+		return fmt.Errorf("Invalid stream selection: %v", err)
+	}
 	return nil
 }
 
+// Replaces the stream selection. Cursor fields and primary keys left empty
+// are filled in from the catalog of the definition
+func (i Integration) SelectStreams(def IntegrationDefinition, selection []StreamSelection) (Integration, error) {
+	streams, err := def.ResolveStreamSelection(selection)
+	if err != nil {
+		return i, fmt.Errorf("Invalid stream selection: %v", err)
+	}
+	i.Streams = streams
+	return i, nil
+}
+
 // Holds the concrete values for the configuration field
 // Mapped as [field_name] => value
 type IntegrationConfig map[string]interface{}
@@ -57,12 +216,94 @@ func NewIntegrationConfig(args map[string]interface{}, schema ConfigurationSchem
 	return config, nil
 }
 
+// Converts values decoded from JSON into the types expected by the schema.
+// Numbers are decoded as float64 and objects as maps, while validation expects
+// int and IntegrationConfig. Values that can't be converted are left as they are.
+func (c IntegrationConfig) Normalize(schema ConfigurationSchema) IntegrationConfig {
+
+	normalized := IntegrationConfig{}
+	for key, value := range c {
+		normalized[key] = value
+	}
+
+	for _, field := range schema {
+		value, ok := normalized[field.Label]
+		if !ok {
+			continue
+		}
+		if field.Array {
+			items, ok := value.([]interface{})
+			if !ok {
+				continue
+			}
+			normalizedItems := make([]interface{}, len(items))
+			for idx, item := range items {
+				normalizedItems[idx] = normalizeValue(field, item)
+			}
+			normalized[field.Label] = normalizedItems
+		} else {
+			normalized[field.Label] = normalizeValue(field, value)
+		}
+	}
+
+	return normalized
+}
+
+func normalizeValue(f SchemaField, value interface{}) interface{} {
+	switch f.Type {
+	case "int":
+		if number, ok := value.(float64); ok && number == float64(int(number)) {
+			return int(number)
+		}
+	case "float", "decimal":
+		if number, ok := value.(int); ok {
+			return float64(number)
+		}
+	case "object":
+		var object IntegrationConfig
+		switch v := value.(type) {
+		case map[string]interface{}:
+			object = IntegrationConfig(v)
+		case IntegrationConfig:
+			object = v
+		default:
+			return value
+		}
+		fields, err := f.ObjectSchema(object)
+		if err != nil {
+			fields = f.Fields
+		}
+		return object.Normalize(fields)
+	}
+	return value
+}
+
 func (c IntegrationConfig) Validate(def ConfigurationSchema) error {
 	// Checks if the configuration matches the schema
+
+	// Fields driven by conditional rules may be required or not allowed at all
+	conditions := def.conditionalFields(c)
+
 	for _, field := range def {
+		required := field.Required
 		value, ok := c[field.Label]
+
+		if condition, isConditional := conditions[field.Label]; isConditional {
+			switch condition.state {
+			case hiddenField:
+				if ok {
+					return fmt.Errorf("Field %s is not allowed %s", field.Label, condition.reason)
+				}
+				continue
+			case requiredField:
+				if !ok {
+					return fmt.Errorf("Field %s is required %s", field.Label, condition.reason)
+				}
+			}
+		}
+
 		if !ok {
-			if field.Required {
+			if required {
 				return fmt.Errorf("Field %s is required", field.Label)
 			}
 		} else {
@@ -113,7 +354,11 @@ func (c IntegrationConfig) ValidateValue(f SchemaField, value interface{}) error
 			if !ok {
 				return fmt.Errorf("Expected object, got %T", value)
 			}
-			err := config.Validate(f.Fields)
+			fields, err := f.ObjectSchema(config)
+			if err != nil {
+				return fmt.Errorf("Invalid object: %v", err)
+			}
+			err = config.Validate(fields)
 			if err != nil {
 				return fmt.Errorf("Invalid object: %v", err)
 			}
@@ -121,6 +366,18 @@ func (c IntegrationConfig) ValidateValue(f SchemaField, value interface{}) error
 			return fmt.Errorf("Invalid type %s", f.Type)
 		}
 
+		if len(f.Enum) > 0 {
+			allowed := false
+			for _, option := range f.Enum {
+				if valuesEqual(value, option) {
+					allowed = true
+				}
+			}
+			if !allowed {
+				return fmt.Errorf("Value %v is not one of %v", value, f.Enum)
+			}
+		}
+
 	} else {
 
 		// Check it is an Array
@@ -135,7 +392,9 @@ func (c IntegrationConfig) ValidateValue(f SchemaField, value interface{}) error
 		}
 
 		// Validate each element
-		itemFields := SchemaField{f.Label, f.Type, false, false, f.Fields}
+		itemFields := f
+		itemFields.Required = false
+		itemFields.Array = false
 		for _, v := range value.([]interface{}) {
 			err := c.ValidateValue(itemFields, v)
 			if err != nil {
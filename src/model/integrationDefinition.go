@@ -9,16 +9,49 @@ type IntegrationDefinition struct {
 	ID string `json:"id" firestore:"id"`
 	Name string `json:"name" firestore:"name"`
 	Type string `json:"type" firestore:"type"` // "source" or "destination"
+	Version string `json:"version" firestore:"version"` // Semantic version, e.g "1.2.0"
+	Status string `json:"status" firestore:"status"` // "draft", "published", "deprecated" or "retired"
 	ConfigurationSchema ConfigurationSchema `json:"configuration_schema"  firestore:"configuration_schema"`
+	Migrations []MigrationStep `json:"migrations" firestore:"migrations"` // Steps to migrate configs from the previous version
+	Streams []Stream `json:"streams,omitempty" firestore:"streams,omitempty"` // Catalog of the streams a source can read
+	OAuth *OAuthProvider `json:"oauth,omitempty" firestore:"oauth,omitempty"` // Set when integrations authorize through OAuth2
+	Check *CheckHook `json:"check,omitempty" firestore:"check,omitempty"` // Webhook checking configurations
+	Scope DefinitionScope `json:"scope" firestore:"scope"` // Shared by every version of the definition
 }
 
+const InitialDefinitionVersion = "1.0.0"
+
 func NewIntegrationDefinition(name string, t string, schema ConfigurationSchema) (IntegrationDefinition, error) {
-	def := IntegrationDefinition{ "", name, t, schema }
+	def := IntegrationDefinition{ "", name, t, InitialDefinitionVersion, "draft", schema, []MigrationStep{}, nil, nil, nil, DefinitionScope{ DefinitionPublic, "", "" } }
+	err := def.Validate()
+	if err != nil {
+		return def, fmt.Errorf("Invalid definition: %v", err)
+	}
+
+	return def, nil
+}
+
+// Creates the next version of a definition. The new version keeps the identity, name, type,
+// stream catalog, OAuth provider, check hook and scope of the current one and must have a higher version number. It starts as a draft.
+func (d IntegrationDefinition) NewVersion(version string, schema ConfigurationSchema, migrations []MigrationStep) (IntegrationDefinition, error) {
+
+	if migrations == nil {
+		migrations = []MigrationStep{}
+	}
+	def := IntegrationDefinition{ d.ID, d.Name, d.Type, version, "draft", schema, migrations, d.Streams, d.OAuth, d.Check, d.Scope }
 	err := def.Validate()
 	if err != nil {
 		return def, fmt.Errorf("Invalid definition: %v", err)
 	}
 
+	cmp, err := CompareVersions(version, d.Version)
+	if err != nil {
+		return def, fmt.Errorf("Invalid current version: %v", err)
+	}
+	if cmp <= 0 {
+		return def, fmt.Errorf("Version %s must be greater than the current version %s", version, d.Version)
+	}
+
 	return def, nil
 }
 
@@ -29,14 +62,204 @@ func (d IntegrationDefinition) Validate() error {
 		return fmt.Errorf("Invalid type %s. Valid types are \"source\" and \"destination\"", d.Type)
 	}
 
-	err := d.ConfigurationSchema.Validate(3)
+	_, err := ParseSemanticVersion(d.Version)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := definitionStatusTransitions[d.Status]; !ok {
+		return fmt.Errorf("Invalid status %s. Valid statuses are \"draft\", \"published\", \"deprecated\" and \"retired\"", d.Status)
+	}
+
+	err = d.ConfigurationSchema.Validate(3)
 	if err != nil {
 		return fmt.Errorf("Invalid configuration schema: %v", err)
 	}
 
+	for idx, step := range d.Migrations {
+		err := step.Validate()
+		if err != nil {
+			return fmt.Errorf("Invalid migration step at index %d: %v", idx, err)
+		}
+	}
+
+	// Only sources read streams
+	if len(d.Streams) > 0 && d.Type != "source" {
+		return errors.New("Only source definitions can have a stream catalog")
+	}
+	err = validateCatalog(d.Streams)
+	if err != nil {
+		return fmt.Errorf("Invalid stream catalog: %v", err)
+	}
+
+	if d.OAuth != nil {
+		err = d.OAuth.Validate(d.ConfigurationSchema)
+		if err != nil {
+			return fmt.Errorf("Invalid OAuth provider: %v", err)
+		}
+	}
+
+	if d.Check != nil {
+		err = d.Check.Validate()
+		if err != nil {
+			return fmt.Errorf("Invalid check hook: %v", err)
+		}
+	}
+
+	err = d.Scope.Validate()
+	if err != nil {
+		return fmt.Errorf("Invalid scope: %v", err)
+	}
+
+	// OAuth providers get the client secrets of the deployment posted to their token URL, and check hooks
+	// get configurations with their secrets. Only public definitions, managed by Super Admins, can have them
+	if !d.Scope.Public() && d.OAuth != nil {
+		return errors.New("Only public definitions can use OAuth")
+	}
+	if !d.Scope.Public() && d.Check != nil {
+		return errors.New("Only public definitions can have a check hook")
+	}
+
 	return nil
 }
 
+// Replaces the stream catalog of the definition
+func (d IntegrationDefinition) WithStreams(streams []Stream) (IntegrationDefinition, error) {
+	d.Streams = streams
+	err := d.Validate()
+	if err != nil {
+		return d, fmt.Errorf("Invalid definition: %v", err)
+	}
+	return d, nil
+}
+
+// Replaces the scope of the definition. An empty visibility means public
+func (d IntegrationDefinition) WithScope(scope DefinitionScope) (IntegrationDefinition, error) {
+	if scope.Visibility == "" {
+		scope.Visibility = DefinitionPublic
+	}
+	d.Scope = scope
+	err := d.Validate()
+	if err != nil {
+		return d, fmt.Errorf("Invalid definition: %v", err)
+	}
+	return d, nil
+}
+
+// Visibility of definitions. Public definitions make the catalog shared by every organization,
+// private ones are custom connectors only visible and usable in their organization or workspace
+const (
+	DefinitionPublic = "public"
+	DefinitionOrganization = "organization"
+	DefinitionWorkspace = "workspace"
+)
+
+type DefinitionScope struct {
+	Visibility string `json:"visibility" firestore:"visibility"` // "public", "organization" or "workspace". Empty for definitions stored before scopes, which are public
+	OrganizationID string `json:"organization_id,omitempty" firestore:"organization_id,omitempty"` // Only for organization definitions
+	WorkspaceID string `json:"workspace_id,omitempty" firestore:"workspace_id,omitempty"` // Only for workspace definitions
+}
+
+func (s DefinitionScope) Validate() error {
+
+	switch s.Visibility {
+	case "", DefinitionPublic:
+		if s.OrganizationID != "" || s.WorkspaceID != "" {
+			return errors.New("Public definitions can't belong to an organization or a workspace")
+		}
+	case DefinitionOrganization:
+		if s.OrganizationID == "" || s.WorkspaceID != "" {
+			return errors.New("Organization definitions need an organization, and no workspace")
+		}
+	case DefinitionWorkspace:
+		if s.WorkspaceID == "" || s.OrganizationID != "" {
+			return errors.New("Workspace definitions need a workspace, and no organization")
+		}
+	default:
+		return fmt.Errorf("Invalid visibility %s. Valid visibilities are \"public\", \"organization\" and \"workspace\"", s.Visibility)
+	}
+
+	return nil
+}
+
+func (s DefinitionScope) Public() bool {
+	return s.Visibility == "" || s.Visibility == DefinitionPublic
+}
+
+// Whether integrations of the workspace can use definitions of the scope
+func (s DefinitionScope) Includes(w Workspace) bool {
+	switch s.Visibility {
+	case DefinitionOrganization:
+		return w.OrganizationID == s.OrganizationID
+	case DefinitionWorkspace:
+		return w.ID == s.WorkspaceID
+	}
+	return s.Public()
+}
+
+// Allowed status changes. Retired is final.
+var definitionStatusTransitions = map[string][]string{
+	"draft": {"published"},
+	"published": {"deprecated", "retired"},
+	"deprecated": {"published", "retired"},
+	"retired": {},
+}
+
+// Changes the lifecycle status of the definition version.
+// Checking that no integration depends on a retired version is up to the caller.
+func (d IntegrationDefinition) Transition(status string) (IntegrationDefinition, error) {
+
+	allowed, ok := definitionStatusTransitions[d.Status]
+	if !ok {
+		return d, fmt.Errorf("Invalid current status %s", d.Status)
+	}
+
+	for _, s := range allowed {
+		if s == status {
+			d.Status = status
+			return d, nil
+		}
+	}
+
+	return d, fmt.Errorf("Can't change status from %s to %s", d.Status, status)
+}
+
+// Only published definitions accept new integrations
+func (d IntegrationDefinition) AcceptsNewIntegrations() bool {
+	return d.Status == "published"
+}
+
+// Warnings to show on the integrations pinned to this definition version
+func (d IntegrationDefinition) Warnings() []string {
+
+	warnings := []string{}
+	switch d.Status {
+	case "deprecated":
+		warnings = append(warnings, fmt.Sprintf("Definition %s version %s is deprecated. Upgrade to a newer version", d.Name, d.Version))
+	case "retired":
+		warnings = append(warnings, fmt.Sprintf("Definition %s version %s is retired", d.Name, d.Version))
+	}
+
+	return warnings
+}
+
+// Returns the most recent published version. Versions can be unordered.
+func LatestPublishedVersion(versions []IntegrationDefinition) (IntegrationDefinition, error) {
+
+	sorted := make([]IntegrationDefinition, len(versions))
+	copy(sorted, versions)
+	SortDefinitionVersions(sorted)
+
+	for idx := len(sorted) - 1; idx >= 0; idx-- {
+		if sorted[idx].AcceptsNewIntegrations() {
+			return sorted[idx], nil
+		}
+	}
+
+	var result IntegrationDefinition
+	return result, errors.New("Definition has no published version")
+}
+
 type ConfigurationSchema []SchemaField
 
 func (s ConfigurationSchema) Validate(remainingDepth  int) error {
@@ -60,6 +283,14 @@ func (s ConfigurationSchema) Validate(remainingDepth  int) error {
 			return fmt.Errorf("Invalid field: %v", err)
 		}
 	}
+
+	// Conditional rules can only reference fields of the same level
+	for _, field := range s {
+		err := s.validateRules(field)
+		if err != nil {
+			return fmt.Errorf("Invalid rules for field %s: %v", field.Label, err)
+		}
+	}
 	
 	return nil
 }
@@ -70,6 +301,13 @@ type SchemaField struct {
 	Required bool  `json:"required" firestore:"required"`
 	Array bool `json:"array" firesotre:"array"`
 	Fields ConfigurationSchema `json:"fields" firestore:"fields"` // Only for "object" types
+	Rules []FieldRule `json:"rules,omitempty" firestore:"rules,omitempty"` // Conditional rules driven by the value of this field
+	Discriminator string `json:"discriminator,omitempty" firestore:"discriminator,omitempty"` // Only for "object" types with variants
+	OneOf []SchemaVariant `json:"one_of,omitempty" firestore:"one_of,omitempty"` // Only for "object" types. Tagged union variants
+	Description string `json:"description,omitempty" firestore:"description,omitempty"`
+	Secret bool `json:"secret,omitempty" firestore:"secret,omitempty"` // Only for "string" types. Hidden from user facing views
+	Enum []interface{} `json:"enum,omitempty" firestore:"enum,omitempty"` // Allowed values. Not for "object" types
+	Default interface{} `json:"default,omitempty" firestore:"default,omitempty"` // Value used when the field is missing
 }
 
 func (f SchemaField) Validate(remainingDepth int)  error {
@@ -93,6 +331,40 @@ func (f SchemaField) Validate(remainingDepth int)  error {
 		if err != nil {
 			return fmt.Errorf("Invalid object: %v", err)
 		}
+
+		err = f.validateVariants(remainingDepth)
+		if err != nil {
+			return fmt.Errorf("Invalid variants for object %s: %v", f.Label, err)
+		}
+	} else if len(f.OneOf) > 0 || f.Discriminator != "" {
+		return fmt.Errorf("Field %s has variants but is not an object", f.Label)
+	}
+
+	if f.Secret && f.Type != "string" {
+		return fmt.Errorf("Field %s is secret but is not a string", f.Label)
+	}
+
+	// Enum values and defaults must be valid values for the field
+	if len(f.Enum) > 0 {
+		if f.Type == "object" {
+			return fmt.Errorf("Field %s is an object and can't have an enum", f.Label)
+		}
+		item := f
+		item.Array = false
+		item.Enum = nil
+		for _, value := range f.Enum {
+			err := IntegrationConfig{}.ValidateValue(item, normalizeValue(item, value))
+			if err != nil {
+				return fmt.Errorf("Invalid enum value %v for field %s: %v", value, f.Label, err)
+			}
+		}
+	}
+	if f.Default != nil {
+		value := IntegrationConfig{ f.Label: f.Default }.Normalize(ConfigurationSchema{f})[f.Label]
+		err := IntegrationConfig{}.ValidateValue(f, value)
+		if err != nil {
+			return fmt.Errorf("Invalid default for field %s: %v", f.Label, err)
+		}
 	}
 
 	return nil
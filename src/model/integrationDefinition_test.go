@@ -30,8 +30,8 @@ func TestInvalidType(t *testing.T) {
 func TestNameCollision(t *testing.T) {
 	
 	schema := ConfigurationSchema{
-		SchemaField{"field1", "string", false, false, nil},
-		SchemaField{"field1", "int", false, false, nil},
+		SchemaField{Label: "field1", Type: "string", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "field1", Type: "int", Required: false, Array: false, Fields: nil},
 	}
 
 	_, err := NewIntegrationDefinition("name", "source", schema)
@@ -43,11 +43,11 @@ func TestNameCollision(t *testing.T) {
 func TestValidFlatSchema(t *testing.T) {
 
 	schema := ConfigurationSchema{
-		SchemaField{"field1", "string", false, false, nil},
-		SchemaField{"field2", "int", false, false, nil},
-		SchemaField{"field3", "float", false, false, nil},
-		SchemaField{"field4", "decimal", false, false, nil},
-		SchemaField{"field5", "boolean", false, false, nil},
+		SchemaField{Label: "field1", Type: "string", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "field2", Type: "int", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "field3", Type: "float", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "field4", Type: "decimal", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "field5", Type: "boolean", Required: false, Array: false, Fields: nil},
 	}
 
 	_, err := NewIntegrationDefinition("name", "source", schema)
@@ -58,7 +58,7 @@ func TestValidFlatSchema(t *testing.T) {
 
 func TestInvalidFieldType(t *testing.T) {
 	schema := ConfigurationSchema{
-		SchemaField{"field1", "this type is not valid", false, false, nil},
+		SchemaField{Label: "field1", Type: "this type is not valid", Required: false, Array: false, Fields: nil},
 	}
 
 	_, err := NewIntegrationDefinition("name", "source", schema)
@@ -69,12 +69,12 @@ func TestInvalidFieldType(t *testing.T) {
 
 func TestValidNestedObject(t *testing.T) {
 	schema := ConfigurationSchema{
-		SchemaField{"field1", "object", false, false, ConfigurationSchema{
-			SchemaField{"field1.1", "string", false, false, nil},
-			SchemaField{"field1.2", "int", false, false, nil},
-			SchemaField{"field1.3", "float", false, false, nil},
-			SchemaField{"field1.4", "decimal", false, false, nil},
-			SchemaField{"field1.5", "boolean", false, false, nil},
+		SchemaField{Label: "field1", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{
+			SchemaField{Label: "field1.1", Type: "string", Required: false, Array: false, Fields: nil},
+			SchemaField{Label: "field1.2", Type: "int", Required: false, Array: false, Fields: nil},
+			SchemaField{Label: "field1.3", Type: "float", Required: false, Array: false, Fields: nil},
+			SchemaField{Label: "field1.4", Type: "decimal", Required: false, Array: false, Fields: nil},
+			SchemaField{Label: "field1.5", Type: "boolean", Required: false, Array: false, Fields: nil},
 		}},
 	}
 
@@ -86,10 +86,10 @@ func TestValidNestedObject(t *testing.T) {
 
 func TestInvalidNestedObject(t *testing.T) {
 	schema := ConfigurationSchema{
-		SchemaField{"field1", "object", false, false, ConfigurationSchema{
-			SchemaField{"field1.1", "string", false, false, nil},
-			SchemaField{"field1.2", "int", false, false, nil},
-			SchemaField{"field1.3", "this type is invalid", false, false, nil},
+		SchemaField{Label: "field1", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{
+			SchemaField{Label: "field1.1", Type: "string", Required: false, Array: false, Fields: nil},
+			SchemaField{Label: "field1.2", Type: "int", Required: false, Array: false, Fields: nil},
+			SchemaField{Label: "field1.3", Type: "this type is invalid", Required: false, Array: false, Fields: nil},
 		}},
 	}
 
@@ -102,9 +102,9 @@ func TestInvalidNestedObject(t *testing.T) {
 func TestValidMaxDepth(t *testing.T) {
 
 	schema := ConfigurationSchema{
-		SchemaField{"level1", "object", false, false, ConfigurationSchema{
-			SchemaField{"level2", "object", false, false, ConfigurationSchema{
-				SchemaField{"level3", "object", false, false, ConfigurationSchema{}},
+		SchemaField{Label: "level1", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{
+			SchemaField{Label: "level2", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{
+				SchemaField{Label: "level3", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{}},
 			}},
 		}},
 	}
@@ -118,10 +118,10 @@ func TestValidMaxDepth(t *testing.T) {
 func TestInvalidMaxDepth(t *testing.T) {
 	
 	schema := ConfigurationSchema{
-		SchemaField{"level1", "object", false, false, ConfigurationSchema{
-			SchemaField{"level2", "object", false, false, ConfigurationSchema{
-				SchemaField{"level3", "object", false, false, ConfigurationSchema{
-					SchemaField{"level4", "object", false, false, ConfigurationSchema{}},
+		SchemaField{Label: "level1", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{
+			SchemaField{Label: "level2", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{
+				SchemaField{Label: "level3", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{
+					SchemaField{Label: "level4", Type: "object", Required: false, Array: false, Fields: ConfigurationSchema{}},
 				}},
 			}},
 		}},
@@ -132,3 +132,122 @@ func TestInvalidMaxDepth(t *testing.T) {
 		t.Errorf("Expected error, got nil")
 	}
 }
+
+func TestDefinitionLifecycle(t *testing.T) {
+
+	def, _ := NewIntegrationDefinition("name", "source", ConfigurationSchema{})
+	if def.Status != "draft" {
+		t.Errorf("Expected new definitions to be drafts, got %s", def.Status)
+	}
+
+	// Drafts don't accept integrations
+	_, err := NewIntegration("integration", "workspace", def, IntegrationConfig{}, nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+
+	// Drafts can't be deprecated or retired
+	_, err = def.Transition("deprecated")
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+
+	def, err = def.Transition("published")
+	if err != nil {
+		t.Fatalf("Expected draft to be published, got %v", err)
+	}
+	_, err = NewIntegration("integration", "workspace", def, IntegrationConfig{}, nil)
+	if err != nil {
+		t.Errorf("Expected published definition to accept integrations, got %v", err)
+	}
+
+	def, err = def.Transition("deprecated")
+	if err != nil {
+		t.Fatalf("Expected published definition to be deprecated, got %v", err)
+	}
+	_, err = NewIntegration("integration", "workspace", def, IntegrationConfig{}, nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	if len(def.Warnings()) == 0 {
+		t.Errorf("Expected deprecated definition to have warnings")
+	}
+
+	def, err = def.Transition("retired")
+	if err != nil {
+		t.Fatalf("Expected deprecated definition to be retired, got %v", err)
+	}
+
+	// Retired is final
+	for _, status := range []string{"draft", "published", "deprecated"} {
+		_, err = def.Transition(status)
+		if err == nil {
+			t.Errorf("Expected error moving retired definition to %s, got nil", status)
+		}
+	}
+}
+
+func TestLatestPublishedVersion(t *testing.T) {
+
+	v1, _ := NewIntegrationDefinition("name", "source", ConfigurationSchema{})
+	_, err := LatestPublishedVersion([]IntegrationDefinition{v1})
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+
+	v1, _ = v1.Transition("published")
+	v2, _ := v1.NewVersion("1.1.0", ConfigurationSchema{}, nil)
+	v3, _ := v2.NewVersion("1.2.0", ConfigurationSchema{}, nil)
+	v2, _ = v2.Transition("published")
+
+	// v3 is still a draft
+	latest, err := LatestPublishedVersion([]IntegrationDefinition{v3, v1, v2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if latest.Version != "1.1.0" {
+		t.Errorf("Expected version 1.1.0, got %s", latest.Version)
+	}
+}
+
+func TestDefinitionScope(t *testing.T) {
+
+	def, err := NewIntegrationDefinition("name", "source", ConfigurationSchema{})
+	if err != nil {
+		t.Fatalf("Error creating integration definition: %v", err)
+	}
+	agency := Workspace{ ID: "client", OrganizationID: "agency" }
+	if !def.Scope.Public() || !def.Scope.Includes(agency) || !(DefinitionScope{}).Public() {
+		t.Errorf("Expected definitions to be public by default")
+	}
+
+	private, err := def.WithScope(DefinitionScope{ DefinitionWorkspace, "", "client" })
+	if err != nil {
+		t.Fatalf("Error scoping integration definition: %v", err)
+	}
+	next, err := private.NewVersion("1.1.0", ConfigurationSchema{}, nil)
+	if err != nil || next.Scope != private.Scope {
+		t.Errorf("Expected new versions to keep the scope, got %+v, %v", next.Scope, err)
+	}
+	if !private.Scope.Includes(agency) || private.Scope.Includes(Workspace{ ID: "other", OrganizationID: "agency" }) {
+		t.Errorf("Expected workspace definitions to only be usable in their workspace")
+	}
+
+	organization := DefinitionScope{ DefinitionOrganization, "agency", "" }
+	if !organization.Includes(agency) || organization.Includes(Workspace{ ID: "client" }) {
+		t.Errorf("Expected organization definitions to only be usable in their organization")
+	}
+
+	invalid := []DefinitionScope{
+		{ DefinitionPublic, "agency", "" },
+		{ DefinitionOrganization, "", "" },
+		{ DefinitionOrganization, "agency", "client" },
+		{ DefinitionWorkspace, "agency", "client" },
+		{ "secret", "", "" },
+	}
+	for idx, scope := range invalid {
+		if _, err := def.WithScope(scope); err == nil {
+			t.Errorf("Expected error for scope at index %d, got nil", idx)
+		}
+	}
+}
@@ -24,10 +24,10 @@ func TestValidFlatConfig(t *testing.T){
 		"key4": true,
 	}
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "string", false, false, nil},
-		SchemaField{"key2", "int", false, false, nil},
-		SchemaField{"key3", "float", false, false, nil},
-		SchemaField{"key4", "boolean", false, false, nil},
+		SchemaField{Label: "key1", Type: "string", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "key2", Type: "int", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "key3", Type: "float", Required: false, Array: false, Fields: nil},
+		SchemaField{Label: "key4", Type: "boolean", Required: false, Array: false, Fields: nil},
 	}
 	err := config.Validate(schema)
 	if err != nil {
@@ -40,7 +40,7 @@ func TestInvalidString(t *testing.T){
 		"key1": 1,
 	}
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "string", false, false, nil},
+		SchemaField{Label: "key1", Type: "string", Required: false, Array: false, Fields: nil},
 	}
 	err := config.Validate(schema)
 	if err == nil {
@@ -53,7 +53,7 @@ func TestInvalidInt(t *testing.T){
 		"key1": "not an int",
 	}
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "int", false, false, nil},
+		SchemaField{Label: "key1", Type: "int", Required: false, Array: false, Fields: nil},
 	}
 	err := config.Validate(schema)
 	if err == nil {
@@ -66,7 +66,7 @@ func TestInvalidFloat(t *testing.T){
 		"key1": "not a float",
 	}
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "float", false, false, nil},
+		SchemaField{Label: "key1", Type: "float", Required: false, Array: false, Fields: nil},
 	}
 	err := config.Validate(schema)
 	if err == nil {
@@ -79,7 +79,7 @@ func TestInvalidBoolean(t *testing.T){
 		"key1": "not a boolean",
 	}
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "boolean", false, false, nil},
+		SchemaField{Label: "key1", Type: "boolean", Required: false, Array: false, Fields: nil},
 	}
 	err := config.Validate(schema)
 	if err == nil {
@@ -89,8 +89,8 @@ func TestInvalidBoolean(t *testing.T){
 
 func TestRequiredField(t *testing.T){
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "string", true, false, nil},
-		SchemaField{"key2", "int", false, false, nil},
+		SchemaField{Label: "key1", Type: "string", Required: true, Array: false, Fields: nil},
+		SchemaField{Label: "key2", Type: "int", Required: false, Array: false, Fields: nil},
 	}
 	config := IntegrationConfig{
 		"key1": "value1",
@@ -111,7 +111,7 @@ func TestRequiredField(t *testing.T){
 
 func TestArrayField(t *testing.T){
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "string", false, true, nil},
+		SchemaField{Label: "key1", Type: "string", Required: false, Array: true, Fields: nil},
 	}
 
 	// Missing field pases (field not required)
@@ -161,7 +161,7 @@ func TestArrayField(t *testing.T){
 func TestRequiredArrayField(t *testing.T){
 	
 	schema := ConfigurationSchema{
-		SchemaField{"key1", "string", true, true, nil},
+		SchemaField{Label: "key1", Type: "string", Required: true, Array: true, Fields: nil},
 	}
 
 	// Array of valid values passes
@@ -195,18 +195,18 @@ func TestNestedConfig(t *testing.T){
 	// A somewhat complex schema to test the nesting features
 	// A 2D Matrix of objects
 	schema := ConfigurationSchema{
-		SchemaField{"x", "object", true, true, ConfigurationSchema{
-			SchemaField{"y", "object", true, true, ConfigurationSchema{
+		SchemaField{Label: "x", Type: "object", Required: true, Array: true, Fields: ConfigurationSchema{
+			SchemaField{Label: "y", Type: "object", Required: true, Array: true, Fields: ConfigurationSchema{
 				// Basic Optional values
-				SchemaField{"key1", "string", false, false, nil},
-				SchemaField{"key2", "int", false, false, nil},
-				SchemaField{"key3", "float", false, false, nil},
-				SchemaField{"key4", "boolean", false, false, nil},
+				SchemaField{Label: "key1", Type: "string", Required: false, Array: false, Fields: nil},
+				SchemaField{Label: "key2", Type: "int", Required: false, Array: false, Fields: nil},
+				SchemaField{Label: "key3", Type: "float", Required: false, Array: false, Fields: nil},
+				SchemaField{Label: "key4", Type: "boolean", Required: false, Array: false, Fields: nil},
 				// Required value
-				SchemaField{"key5", "int", true, false, nil},
+				SchemaField{Label: "key5", Type: "int", Required: true, Array: false, Fields: nil},
 				// Arrays
-				SchemaField{"key6", "int", true, true, nil},
-				SchemaField{"key7", "int", false, true, nil},
+				SchemaField{Label: "key6", Type: "int", Required: true, Array: true, Fields: nil},
+				SchemaField{Label: "key7", Type: "int", Required: false, Array: true, Fields: nil},
 			}},
 		}},
 	}
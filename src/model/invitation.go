@@ -0,0 +1,124 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Invitation to join a workspace, sent by email. The token of the invitation is only sent to the
+// invitee, and accepting it grants the role to the user who accepts, once
+type Invitation struct {
+	ID string `json:"id" firestore:"id"`
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	Email string `json:"email" firestore:"email"`
+	Role string `json:"role" firestore:"role"`
+	Status string `json:"status" firestore:"status"` // "pending", "accepted" or "revoked". Pending invitations past their expiration are shown "expired"
+	InvitedBy string `json:"invited_by" firestore:"invited_by"` // ID of the user who invited
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" firestore:"expires_at"`
+	AcceptedBy string `json:"accepted_by,omitempty" firestore:"accepted_by,omitempty"` // ID of the user who accepted
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" firestore:"accepted_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" firestore:"revoked_at,omitempty"`
+}
+
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+func NewInvitation(workspaceID string, email string, role string, invitedBy string, now time.Time, ttl time.Duration) (Invitation, error) {
+
+	invitation := Invitation{ "", workspaceID, email, role, "pending", invitedBy, now, now.Add(ttl), "", nil, nil }
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return invitation, fmt.Errorf("Invalid email %s", email)
+	}
+	err = validateRole(role)
+	if err != nil {
+		return invitation, fmt.Errorf("Invalid permission: %v", err)
+	}
+
+	return invitation, nil
+}
+
+func (i Invitation) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+// Copy of the invitation with the status callers should see
+func (i Invitation) WithState(now time.Time) Invitation {
+	if i.Status == "pending" && i.Expired(now) {
+		i.Status = "expired"
+	}
+	return i
+}
+
+// Accepts the invitation on behalf of the user, who must be the invitee
+func (i Invitation) Accept(user User, now time.Time) (Invitation, error) {
+
+	if i.Status != "pending" {
+		return i, fmt.Errorf("Invitation was already %s", i.Status)
+	}
+	if i.Expired(now) {
+		return i, errors.New("Invitation expired")
+	}
+	if !strings.EqualFold(user.Email, i.Email) {
+		return i, errors.New("Invitation was sent to another email")
+	}
+
+	i.Status = "accepted"
+	i.AcceptedBy = user.ID
+	i.AcceptedAt = &now
+	return i, nil
+}
+
+func (i Invitation) Revoke(now time.Time) (Invitation, error) {
+
+	if i.Status != "pending" {
+		return i, fmt.Errorf("Invitation was already %s", i.Status)
+	}
+
+	i.Status = "revoked"
+	i.RevokedAt = &now
+	return i, nil
+}
+
+// Permission granted to the user who accepted the invitation
+func (i Invitation) Permission(user User) WorkspacePermission {
+	return WorkspacePermission{ user.Principal(), i.Role, "", "" }
+}
+
+// Token sent to the invitee: "<invitation id>.<hex HMAC-SHA256 of the id>".
+// Tokens don't expire by themselves, the invitation they identify does
+func SignInvitationToken(invitation Invitation, key []byte) (string, error) {
+	if len(key) == 0 {
+		return "", errors.New("Signing key is required")
+	}
+	return invitation.ID + "." + hex.EncodeToString(invitationMAC(invitation.ID, key)), nil
+}
+
+// Checks the signature of the token and returns the ID of its invitation
+func ParseInvitationToken(token string, key []byte) (string, error) {
+
+	if len(key) == 0 {
+		return "", errors.New("Signing key is required")
+	}
+
+	id, signature, _ := strings.Cut(token, ".")
+	mac, err := hex.DecodeString(signature)
+	if err != nil || id == "" || !hmac.Equal(mac, invitationMAC(id, key)) {
+		return "", errors.New("Invalid invitation token")
+	}
+
+	return id, nil
+}
+
+func invitationMAC(id string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("invitation." + id))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvitation(t *testing.T) {
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	invitation, err := NewInvitation("workspace", "ana@example.com", "editor", "owner", now, DefaultInvitationTTL)
+	if err != nil {
+		t.Fatalf("Expected invitation to be valid, got %v", err)
+	}
+
+	invalid := []struct{ email, role string }{
+		{ "ana", "editor" },
+		{ "Ana <ana@example.com>", "editor" },
+		{ "ana@example.com", "admin" },
+	}
+	for idx, test := range invalid {
+		if _, err := NewInvitation("workspace", test.email, test.role, "owner", now, DefaultInvitationTTL); err == nil {
+			t.Errorf("Expected error for invitation at index %d, got nil", idx)
+		}
+	}
+
+	later := now.Add(DefaultInvitationTTL)
+	if invitation.WithState(later.Add(-time.Second)).Status != "pending" || invitation.WithState(later).Status != "expired" {
+		t.Errorf("Expected invitation to expire after %v", DefaultInvitationTTL)
+	}
+	if _, err := invitation.Accept(User{ ID: "ana", Email: "ana@example.com" }, later); err == nil {
+		t.Errorf("Expected error accepting an expired invitation, got nil")
+	}
+	if _, err := invitation.Accept(User{ ID: "bob", Email: "bob@example.com" }, now); err == nil {
+		t.Errorf("Expected error accepting the invitation of someone else, got nil")
+	}
+
+	user := User{ ID: "ana", Email: "Ana@Example.com" }
+	accepted, err := invitation.Accept(user, now)
+	if err != nil || accepted.Status != "accepted" || accepted.AcceptedBy != "ana" {
+		t.Fatalf("Expected invitation to be accepted, got %+v, %v", accepted, err)
+	}
+	if _, err := accepted.Accept(user, now); err == nil {
+		t.Errorf("Expected error accepting twice, got nil")
+	}
+	if _, err := accepted.Revoke(now); err == nil {
+		t.Errorf("Expected error revoking an accepted invitation, got nil")
+	}
+
+	// The role replaces any lower role of the user
+	workspace := Workspace{ Permissions: []WorkspacePermission{ { "user:owner", "owner", "", "" }, { "user:ana", "viewer", "", "" } } }
+	workspace = workspace.WithPermission(accepted.Permission(user))
+	if len(workspace.Permissions) != 2 || workspace.Permissions[1] != (WorkspacePermission{ "user:ana", "editor", "", "" }) {
+		t.Errorf("Unexpected permissions %+v", workspace.Permissions)
+	}
+	if !workspace.EditableBy("user:owner") || workspace.EditableBy("user:ana") {
+		t.Errorf("Expected only owners to administer the workspace")
+	}
+
+	// Users made owners since they were invited keep owning the workspace
+	owned := Workspace{ Permissions: []WorkspacePermission{ { "user:ana", "owner", "", "" } } }
+	owned = owned.WithPermission(accepted.Permission(user))
+	if len(owned.Permissions) != 1 || !owned.EditableBy("user:ana") {
+		t.Errorf("Expected the old invitation not to demote the only owner, got %+v", owned.Permissions)
+	}
+}
+
+func TestInvitationToken(t *testing.T) {
+
+	key := []byte("key")
+	invitation := Invitation{ ID: "invitation" }
+
+	token, err := SignInvitationToken(invitation, key)
+	if err != nil {
+		t.Fatalf("Expected token, got %v", err)
+	}
+	id, err := ParseInvitationToken(token, key)
+	if err != nil || id != "invitation" {
+		t.Errorf("Expected token of the invitation, got %s, %v", id, err)
+	}
+
+	forged, _ := SignInvitationToken(Invitation{ ID: "other" }, []byte("other key"))
+	invalid := []string{ "", "invitation", "invitation.abc", forged, "other" + token[len("invitation"):] }
+	for idx, token := range invalid {
+		if _, err := ParseInvitationToken(token, key); err == nil {
+			t.Errorf("Expected error for token at index %d, got nil", idx)
+		}
+	}
+	if _, err := SignInvitationToken(invitation, nil); err == nil {
+		t.Errorf("Expected error signing without a key, got nil")
+	}
+}
@@ -0,0 +1,711 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSON Schema document, as decoded from JSON
+type JSONSchema map[string]interface{}
+
+// Exports the configuration schema of the definition as a JSON Schema (draft 2020-12)
+func (d IntegrationDefinition) JSONSchema() JSONSchema {
+	schema := d.ConfigurationSchema.ToJSONSchema()
+	schema["title"] = d.Name
+	schema["$comment"] = fmt.Sprintf("%s definition %s version %s", d.Type, d.ID, d.Version)
+	return schema
+}
+
+// Exports the configuration schema as a JSON Schema (draft 2020-12) object.
+//
+// Required arrays are exported with minItems 1. Decimals are numbers with the "decimal" format.
+// Secrets are exported as writeOnly strings.
+// Conditional rules are exported as if/then clauses inside allOf: one clause per rule requiring
+// fields, and one clause per conditional field restricting the values that allow it.
+// Tagged unions are exported as oneOf, with the discriminator as a const property of each variant.
+func (s ConfigurationSchema) ToJSONSchema() JSONSchema {
+	schema := s.toJSONSchemaObject()
+	schema["$schema"] = JSONSchemaDialect
+	return schema
+}
+
+func (s ConfigurationSchema) toJSONSchemaObject() JSONSchema {
+
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, field := range s {
+		properties[field.Label] = field.toJSONSchema()
+		if field.Required {
+			required = append(required, field.Label)
+		}
+	}
+
+	schema := JSONSchema{
+		"type": "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	clauses := s.rulesToJSONSchema()
+	if len(clauses) > 0 {
+		schema["allOf"] = clauses
+	}
+
+	return schema
+}
+
+func (f SchemaField) toJSONSchema() JSONSchema {
+
+	var schema JSONSchema
+	switch f.Type {
+	case "string":
+		schema = JSONSchema{"type": "string"}
+	case "int":
+		schema = JSONSchema{"type": "integer"}
+	case "float":
+		schema = JSONSchema{"type": "number"}
+	case "decimal":
+		schema = JSONSchema{"type": "number", "format": "decimal"}
+	case "boolean":
+		schema = JSONSchema{"type": "boolean"}
+	case "object":
+		schema = f.Fields.toJSONSchemaObject()
+		if len(f.OneOf) > 0 {
+			variants := []interface{}{}
+			for _, variant := range f.OneOf {
+				object := variant.Fields.toJSONSchemaObject()
+				properties := object["properties"].(map[string]interface{})
+				properties[f.Discriminator] = JSONSchema{"type": "string", "const": variant.Tag}
+				required, _ := object["required"].([]string)
+				object["required"] = append([]string{f.Discriminator}, required...)
+				delete(object, "type")
+				if variant.Title != "" {
+					object["title"] = variant.Title
+				}
+				variants = append(variants, object)
+			}
+			schema["oneOf"] = variants
+		}
+	}
+
+	if len(f.Enum) > 0 {
+		schema["enum"] = f.Enum
+	}
+	if f.Secret {
+		schema["writeOnly"] = true
+	}
+
+	if f.Array {
+		schema = JSONSchema{"type": "array", "items": schema}
+		if f.Required {
+			schema["minItems"] = 1
+		}
+	}
+
+	if f.Description != "" {
+		schema["description"] = f.Description
+	}
+	if f.Default != nil {
+		schema["default"] = f.Default
+	}
+	return schema
+}
+
+func (s ConfigurationSchema) rulesToJSONSchema() []interface{} {
+
+	clauses := []interface{}{}
+
+	// Values of each driving field that allow each conditional field
+	allowedBy := map[string]map[string][]interface{}{} // [conditional] => [driver] => values
+	conditionals := []string{}
+
+	for _, field := range s {
+		for _, rule := range field.Rules {
+			condition := JSONSchema{
+				"properties": map[string]interface{}{ field.Label: JSONSchema{"const": rule.Equals} },
+				"required": []string{field.Label},
+			}
+			if len(rule.Require) > 0 {
+				clauses = append(clauses, JSONSchema{
+					"if": condition,
+					"then": JSONSchema{"required": rule.Require},
+				})
+			}
+
+			for _, label := range append(append([]string{}, rule.Require...), rule.Allow...) {
+				if _, ok := allowedBy[label]; !ok {
+					allowedBy[label] = map[string][]interface{}{}
+					conditionals = append(conditionals, label)
+				}
+				allowedBy[label][field.Label] = append(allowedBy[label][field.Label], rule.Equals)
+			}
+		}
+	}
+
+	for _, label := range conditionals {
+		drivers := []string{}
+		for driver := range allowedBy[label] {
+			drivers = append(drivers, driver)
+		}
+		sort.Strings(drivers)
+
+		options := []interface{}{}
+		for _, driver := range drivers {
+			options = append(options, JSONSchema{
+				"properties": map[string]interface{}{ driver: JSONSchema{"enum": allowedBy[label][driver]} },
+				"required": []string{driver},
+			})
+		}
+
+		var then JSONSchema
+		if len(options) == 1 {
+			then = options[0].(JSONSchema)
+		} else {
+			then = JSONSchema{"anyOf": options}
+		}
+		clauses = append(clauses, JSONSchema{
+			"if": JSONSchema{"required": []string{label}},
+			"then": then,
+		})
+	}
+
+	return clauses
+}
+
+// Keywords that carry no validation and are accepted anywhere
+var jsonSchemaAnnotations = map[string]bool{
+	"$schema": true,
+	"$id": true,
+	"$comment": true,
+	"title": true,
+	"description": true,
+	"examples": true,
+}
+
+// Imports a JSON Schema (draft 2020-12) object as a configuration schema.
+// Only the subset produced by ToJSONSchema is supported, any other keyword is an error.
+// Properties are sorted by name, since JSON objects have no order.
+func FromJSONSchema(schema JSONSchema) (ConfigurationSchema, error) {
+
+	if dialect, ok := schema["$schema"]; ok && dialect != JSONSchemaDialect {
+		return nil, fmt.Errorf("Unsupported dialect %v. Only %s is supported", dialect, JSONSchemaDialect)
+	}
+
+	if t, ok := schema["type"]; ok && t != "object" {
+		return nil, fmt.Errorf("Root schema must be an object, got %v", t)
+	}
+
+	fields, err := objectFromJSONSchema(schema, "#")
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func objectFromJSONSchema(schema JSONSchema, path string) (ConfigurationSchema, error) {
+
+	err := checkKeywords(schema, path, "type", "properties", "required", "allOf", "default")
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]interface{}{}
+	if raw, ok := schema["properties"]; ok {
+		properties, ok = raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s/properties must be an object", path)
+		}
+	}
+
+	required, err := stringList(schema["required"], path + "/required")
+	if err != nil {
+		return nil, err
+	}
+	requiredSet := map[string]bool{}
+	for _, label := range required {
+		if _, ok := properties[label]; !ok {
+			return nil, fmt.Errorf("%s/required references unknown property %s", path, label)
+		}
+		requiredSet[label] = true
+	}
+
+	labels := []string{}
+	for label := range properties {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	fields := ConfigurationSchema{}
+	for _, label := range labels {
+		property, ok := asJSONSchema(properties[label])
+		if !ok {
+			return nil, fmt.Errorf("%s/properties/%s must be an object", path, label)
+		}
+		field, err := fieldFromJSONSchema(label, property, requiredSet[label], path + "/properties/" + label)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+
+	if raw, ok := schema["allOf"]; ok {
+		fields, err = rulesFromJSONSchema(fields, raw, path + "/allOf")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
+}
+
+func fieldFromJSONSchema(label string, schema JSONSchema, required bool, path string) (SchemaField, error) {
+
+	field := SchemaField{Label: label, Required: required, Default: schema["default"]}
+	if description, ok := schema["description"].(string); ok {
+		field.Description = description
+	}
+
+	if schema["type"] == "array" {
+		err := checkKeywords(schema, path, "type", "items", "minItems", "default")
+		if err != nil {
+			return field, err
+		}
+
+		if minItems, ok := schema["minItems"]; ok {
+			n, isNumber := toFloat(minItems)
+			if !isNumber || (n != 0 && n != 1) {
+				return field, fmt.Errorf("%s/minItems only supports 0 or 1, got %v", path, minItems)
+			}
+		}
+
+		items, ok := asJSONSchema(schema["items"])
+		if !ok {
+			return field, fmt.Errorf("%s/items must be an object", path)
+		}
+		if items["type"] == "array" {
+			return field, fmt.Errorf("%s/items: nested arrays are not supported", path)
+		}
+
+		item, err := fieldFromJSONSchema(label, items, false, path + "/items")
+		if err != nil {
+			return field, err
+		}
+		item.Required = required
+		item.Array = true
+		item.Description = field.Description
+		item.Default = field.Default
+		return item, nil
+	}
+
+	switch schema["type"] {
+	case "string":
+		field.Type = "string"
+	case "integer":
+		field.Type = "int"
+	case "number":
+		field.Type = "float"
+		if format, ok := schema["format"]; ok {
+			if format != "decimal" {
+				return field, fmt.Errorf("%s/format %v is not supported", path, format)
+			}
+			field.Type = "decimal"
+		}
+	case "boolean":
+		field.Type = "boolean"
+	case "object":
+		// Variants are parsed apart from the common fields
+		common := JSONSchema{}
+		for key, value := range schema {
+			if key != "oneOf" {
+				common[key] = value
+			}
+		}
+		fields, err := objectFromJSONSchema(common, path)
+		if err != nil {
+			return field, err
+		}
+		field.Type = "object"
+		field.Fields = fields
+
+		if raw, ok := schema["oneOf"]; ok {
+			err := field.variantsFromJSONSchema(raw, path + "/oneOf")
+			if err != nil {
+				return field, err
+			}
+		}
+		return field, nil
+	case nil:
+		return field, fmt.Errorf("%s/type is required", path)
+	default:
+		return field, fmt.Errorf("%s/type %v is not supported", path, schema["type"])
+	}
+
+	allowed := []string{"type", "enum", "default", "writeOnly"}
+	if field.Type == "decimal" {
+		allowed = append(allowed, "format")
+	}
+	err := checkKeywords(schema, path, allowed...)
+	if err != nil {
+		return field, err
+	}
+
+	if raw, ok := schema["enum"]; ok {
+		field.Enum, ok = raw.([]interface{})
+		if !ok {
+			return field, fmt.Errorf("%s/enum must be an array", path)
+		}
+	}
+	if writeOnly, ok := schema["writeOnly"]; ok {
+		if writeOnly != true || field.Type != "string" {
+			return field, fmt.Errorf("%s/writeOnly is only supported as true on strings", path)
+		}
+		field.Secret = true
+	}
+
+	return field, nil
+}
+
+func (f *SchemaField) variantsFromJSONSchema(raw interface{}, path string) error {
+
+	variants, ok := raw.([]interface{})
+	if !ok || len(variants) == 0 {
+		return fmt.Errorf("%s must be a non empty array", path)
+	}
+
+	for idx, raw := range variants {
+		variantPath := fmt.Sprintf("%s/%d", path, idx)
+		variant, ok := asJSONSchema(raw)
+		if !ok {
+			return fmt.Errorf("%s must be an object", variantPath)
+		}
+		if t, ok := variant["type"]; ok && t != "object" {
+			return fmt.Errorf("%s/type must be object, got %v", variantPath, t)
+		}
+
+		properties, _ := variant["properties"].(map[string]interface{})
+
+		// The discriminator is the only property with a const value
+		discriminator := ""
+		for label, property := range properties {
+			if p, ok := asJSONSchema(property); ok {
+				if _, ok := p["const"]; ok {
+					if discriminator != "" {
+						return fmt.Errorf("%s has more than one const property", variantPath)
+					}
+					discriminator = label
+				}
+			}
+		}
+		if discriminator == "" {
+			return fmt.Errorf("%s has no const property to use as discriminator", variantPath)
+		}
+		if f.Discriminator != "" && f.Discriminator != discriminator {
+			return fmt.Errorf("%s uses discriminator %s, expected %s", variantPath, discriminator, f.Discriminator)
+		}
+		f.Discriminator = discriminator
+
+		tagSchema, _ := asJSONSchema(properties[discriminator])
+		err := checkKeywords(tagSchema, variantPath + "/properties/" + discriminator, "type", "const")
+		if err != nil {
+			return err
+		}
+		tag, ok := tagSchema["const"].(string)
+		if !ok {
+			return fmt.Errorf("%s/properties/%s/const must be a string", variantPath, discriminator)
+		}
+
+		// Parse the variant without its discriminator
+		rest := JSONSchema{}
+		for key, value := range variant {
+			rest[key] = value
+		}
+		delete(rest, "title")
+		restProperties := map[string]interface{}{}
+		for label, property := range properties {
+			if label != discriminator {
+				restProperties[label] = property
+			}
+		}
+		rest["properties"] = restProperties
+		required, err := stringList(variant["required"], variantPath + "/required")
+		if err != nil {
+			return err
+		}
+		restRequired := []interface{}{}
+		for _, label := range required {
+			if label != discriminator {
+				restRequired = append(restRequired, label)
+			}
+		}
+		rest["required"] = restRequired
+
+		fields, err := objectFromJSONSchema(rest, variantPath)
+		if err != nil {
+			return err
+		}
+
+		title, _ := variant["title"].(string)
+		f.OneOf = append(f.OneOf, SchemaVariant{ tag, title, fields })
+	}
+
+	return nil
+}
+
+// Reads the if/then clauses produced by ToJSONSchema back into field rules
+func rulesFromJSONSchema(fields ConfigurationSchema, raw interface{}, path string) (ConfigurationSchema, error) {
+
+	clauses, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array", path)
+	}
+
+	index := map[string]int{}
+	for idx, field := range fields {
+		index[field.Label] = idx
+	}
+
+	addRule := func(driver string, value interface{}, require []string, allow []string, clausePath string) error {
+		idx, ok := index[driver]
+		if !ok {
+			return fmt.Errorf("%s references unknown property %s", clausePath, driver)
+		}
+		for _, label := range append(append([]string{}, require...), allow...) {
+			if _, ok := index[label]; !ok {
+				return fmt.Errorf("%s references unknown property %s", clausePath, label)
+			}
+		}
+
+		rules := fields[idx].Rules
+		for ruleIdx, rule := range rules {
+			if valuesEqual(rule.Equals, value) {
+				rules[ruleIdx].Require = appendMissing(rule.Require, require...)
+				for _, label := range allow {
+					if !containsString(rules[ruleIdx].Require, label) {
+						rules[ruleIdx].Allow = appendMissing(rules[ruleIdx].Allow, label)
+					}
+				}
+				return nil
+			}
+		}
+		fields[idx].Rules = append(rules, FieldRule{ value, require, allow })
+		return nil
+	}
+
+	// Requirements first, so the allowed values don't duplicate required fields
+	ordered := []JSONSchema{}
+	paths := []string{}
+	for _, pass := range []bool{true, false} {
+		for idx, raw := range clauses {
+			clause, ok := asJSONSchema(raw)
+			if !ok {
+				return nil, fmt.Errorf("%s/%d must be an object", path, idx)
+			}
+			condition, _ := asJSONSchema(clause["if"])
+			_, isRequirement := condition["properties"]
+			if isRequirement == pass {
+				ordered = append(ordered, clause)
+				paths = append(paths, fmt.Sprintf("%s/%d", path, idx))
+			}
+		}
+	}
+
+	for idx, clause := range ordered {
+		clausePath := paths[idx]
+		err := checkKeywords(clause, clausePath, "if", "then")
+		if err != nil {
+			return nil, err
+		}
+		condition, ok := asJSONSchema(clause["if"])
+		if !ok {
+			return nil, fmt.Errorf("%s/if must be an object", clausePath)
+		}
+		then, ok := asJSONSchema(clause["then"])
+		if !ok {
+			return nil, fmt.Errorf("%s/then must be an object", clausePath)
+		}
+
+		// {"if": {"properties": {driver: {"const": value}}, "required": [driver]}, "then": {"required": [...]}}
+		if _, ok := condition["properties"]; ok {
+			driver, constraint, err := singlePropertyCondition(condition, clausePath + "/if")
+			if err != nil {
+				return nil, err
+			}
+			err = checkKeywords(constraint, clausePath + "/if/properties/" + driver, "const")
+			if err != nil {
+				return nil, err
+			}
+			err = checkKeywords(then, clausePath + "/then", "required")
+			if err != nil {
+				return nil, err
+			}
+			require, err := stringList(then["required"], clausePath + "/then/required")
+			if err != nil {
+				return nil, err
+			}
+			err = addRule(driver, constraint["const"], require, nil, clausePath)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// {"if": {"required": [label]}, "then": {"properties": {driver: {"enum": [...]}}, "required": [driver]}}
+		err = checkKeywords(condition, clausePath + "/if", "required")
+		if err != nil {
+			return nil, err
+		}
+		labels, err := stringList(condition["required"], clausePath + "/if/required")
+		if err != nil {
+			return nil, err
+		}
+		if len(labels) != 1 {
+			return nil, fmt.Errorf("%s/if/required must have a single property", clausePath)
+		}
+
+		options := []interface{}{then}
+		if anyOf, ok := then["anyOf"]; ok {
+			err = checkKeywords(then, clausePath + "/then", "anyOf")
+			if err != nil {
+				return nil, err
+			}
+			options, ok = anyOf.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s/then/anyOf must be an array", clausePath)
+			}
+		}
+		for optionIdx, raw := range options {
+			optionPath := clausePath + "/then"
+			if len(options) > 1 {
+				optionPath = fmt.Sprintf("%s/then/anyOf/%d", clausePath, optionIdx)
+			}
+			option, ok := asJSONSchema(raw)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an object", optionPath)
+			}
+			driver, constraint, err := singlePropertyCondition(option, optionPath)
+			if err != nil {
+				return nil, err
+			}
+			err = checkKeywords(constraint, optionPath + "/properties/" + driver, "enum")
+			if err != nil {
+				return nil, err
+			}
+			values, ok := constraint["enum"].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s/properties/%s/enum must be an array", optionPath, driver)
+			}
+			for _, value := range values {
+				err = addRule(driver, value, nil, labels, clausePath)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// Reads {"properties": {driver: constraint}, "required": [driver]}
+func singlePropertyCondition(schema JSONSchema, path string) (string, JSONSchema, error) {
+
+	err := checkKeywords(schema, path, "properties", "required")
+	if err != nil {
+		return "", nil, err
+	}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok || len(properties) != 1 {
+		return "", nil, fmt.Errorf("%s/properties must have a single property", path)
+	}
+
+	for driver, raw := range properties {
+		constraint, ok := asJSONSchema(raw)
+		if !ok {
+			return "", nil, fmt.Errorf("%s/properties/%s must be an object", path, driver)
+		}
+		required, err := stringList(schema["required"], path + "/required")
+		if err != nil {
+			return "", nil, err
+		}
+		if len(required) != 1 || required[0] != driver {
+			return "", nil, fmt.Errorf("%s/required must only have %s", path, driver)
+		}
+		return driver, constraint, nil
+	}
+
+	return "", nil, errors.New("Unreachable")
+}
+
+func checkKeywords(schema JSONSchema, path string, allowed ...string) error {
+
+	unsupported := []string{}
+	for keyword := range schema {
+		if jsonSchemaAnnotations[keyword] || containsString(allowed, keyword) {
+			continue
+		}
+		unsupported = append(unsupported, keyword)
+	}
+
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("%s: unsupported keyword(s) %s", path, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+func asJSONSchema(raw interface{}) (JSONSchema, bool) {
+	switch schema := raw.(type) {
+	case JSONSchema:
+		return schema, true
+	case map[string]interface{}:
+		return JSONSchema(schema), true
+	}
+	return nil, false
+}
+
+func stringList(raw interface{}, path string) ([]string, error) {
+
+	switch list := raw.(type) {
+	case nil:
+		return []string{}, nil
+	case []string:
+		return list, nil
+	case []interface{}:
+		result := []string{}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array of strings", path)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("%s must be an array of strings", path)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		if !containsString(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
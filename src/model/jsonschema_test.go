@@ -0,0 +1,206 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Exports a schema, serializes it and imports it back
+func roundTripJSONSchema(t *testing.T, schema ConfigurationSchema) ConfigurationSchema {
+
+	data, err := json.Marshal(schema.ToJSONSchema())
+	if err != nil {
+		t.Fatalf("Error serializing JSON Schema: %v", err)
+	}
+
+	var decoded JSONSchema
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Error decoding JSON Schema: %v", err)
+	}
+
+	imported, err := FromJSONSchema(decoded)
+	if err != nil {
+		t.Fatalf("Error importing JSON Schema %s: %v", data, err)
+	}
+
+	return imported
+}
+
+func TestJSONSchemaExport(t *testing.T) {
+
+	schema := ConfigurationSchema{
+		SchemaField{Label: "name", Type: "string", Required: true},
+		SchemaField{Label: "ids", Type: "int", Required: true, Array: true},
+		SchemaField{Label: "amount", Type: "decimal"},
+	}
+
+	exported := schema.ToJSONSchema()
+	if exported["$schema"] != JSONSchemaDialect {
+		t.Errorf("Expected dialect %s, got %v", JSONSchemaDialect, exported["$schema"])
+	}
+	if exported["type"] != "object" {
+		t.Errorf("Expected object, got %v", exported["type"])
+	}
+
+	properties := exported["properties"].(map[string]interface{})
+	ids := properties["ids"].(JSONSchema)
+	if ids["type"] != "array" || ids["minItems"] != 1 {
+		t.Errorf("Expected required array with minItems 1, got %v", ids)
+	}
+	if ids["items"].(JSONSchema)["type"] != "integer" {
+		t.Errorf("Expected integer items, got %v", ids["items"])
+	}
+	amount := properties["amount"].(JSONSchema)
+	if amount["type"] != "number" || amount["format"] != "decimal" {
+		t.Errorf("Expected decimal number, got %v", amount)
+	}
+	if !reflect.DeepEqual(exported["required"], []string{"name", "ids"}) {
+		t.Errorf("Expected name and ids to be required, got %v", exported["required"])
+	}
+}
+
+func TestJSONSchemaRoundTrip(t *testing.T) {
+
+	// Fields sorted by name, since imports are sorted
+	schemas := []ConfigurationSchema{
+		{},
+		{
+			SchemaField{Label: "a_string", Type: "string", Required: true},
+			SchemaField{Label: "b_int", Type: "int"},
+			SchemaField{Label: "c_float", Type: "float"},
+			SchemaField{Label: "d_decimal", Type: "decimal"},
+			SchemaField{Label: "e_boolean", Type: "boolean", Required: true},
+			SchemaField{Label: "f_array", Type: "string", Required: true, Array: true},
+		},
+		{
+			SchemaField{Label: "x", Type: "object", Required: true, Array: true, Fields: ConfigurationSchema{
+				SchemaField{Label: "y", Type: "object", Required: true, Fields: ConfigurationSchema{
+					SchemaField{Label: "key", Type: "int", Required: true},
+				}},
+			}},
+		},
+		authSchema().sorted(),
+		credentialsSchema(),
+		{
+			SchemaField{Label: "api_key", Type: "string", Required: true, Secret: true, Description: "Key of the API"},
+			SchemaField{Label: "levels", Type: "string", Array: true, Enum: []interface{}{"ad", "campaign"}, Default: []interface{}{"ad"}},
+			SchemaField{Label: "region", Type: "string", Enum: []interface{}{"us", "eu"}, Default: "us"},
+			SchemaField{Label: "timeout", Type: "int", Default: float64(30)},
+		},
+	}
+
+	for idx, schema := range schemas {
+		imported := roundTripJSONSchema(t, schema)
+		if !reflect.DeepEqual(normalizeForComparison(schema), normalizeForComparison(imported)) {
+			t.Errorf("Schema at index %d changed after round trip.\nExpected %#v\nGot      %#v", idx, schema, imported)
+		}
+	}
+}
+
+func TestJSONSchemaUnsupportedKeywords(t *testing.T) {
+
+	invalid := []string{
+		`{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object"}`,
+		`{"type": "array", "items": {"type": "string"}}`,
+		`{"type": "object", "properties": {"a": {"type": "string", "pattern": "^a"}}}`,
+		`{"type": "object", "properties": {"a": {"type": "string", "maxLength": 3}}}`,
+		`{"type": "object", "properties": {"a": {"type": ["string", "null"]}}}`,
+		`{"type": "object", "properties": {"a": {"type": "null"}}}`,
+		`{"type": "object", "properties": {"a": {}}}`,
+		`{"type": "object", "properties": {"a": {"type": "array", "items": {"type": "string"}, "minItems": 2}}}`,
+		`{"type": "object", "properties": {"a": {"type": "array", "items": {"type": "array", "items": {"type": "string"}}}}}`,
+		`{"type": "object", "properties": {"a": {"$ref": "#/$defs/a"}}}`,
+		`{"type": "object", "properties": {"a": {"type": "string"}}, "required": ["b"]}`,
+		`{"type": "object", "additionalProperties": false}`,
+		`{"type": "object", "properties": {"a": {"type": "object", "oneOf": [{"properties": {"b": {"type": "string"}}}]}}}`,
+		`{"type": "object", "properties": {"a": {"type": "integer", "writeOnly": true}}}`,
+		`{"type": "object", "properties": {"a": {"type": "string"}}, "allOf": [{"if": {"properties": {"a": {"minLength": 1}}, "required": ["a"]}, "then": {}}]}`,
+	}
+
+	for idx, raw := range invalid {
+		var schema JSONSchema
+		err := json.Unmarshal([]byte(raw), &schema)
+		if err != nil {
+			t.Fatalf("Invalid test data at index %d: %v", idx, err)
+		}
+		_, err = FromJSONSchema(schema)
+		if err == nil {
+			t.Errorf("Expected error for schema at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestJSONSchemaImportAnnotations(t *testing.T) {
+
+	raw := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Source",
+		"description": "A source",
+		"type": "object",
+		"properties": {
+			"host": {"type": "string", "title": "Host", "description": "Hostname", "examples": ["localhost"]}
+		},
+		"required": ["host"]
+	}`
+
+	var schema JSONSchema
+	json.Unmarshal([]byte(raw), &schema)
+	imported, err := FromJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("Expected annotations to be accepted, got %v", err)
+	}
+
+	expected := ConfigurationSchema{SchemaField{Label: "host", Type: "string", Required: true, Description: "Hostname"}}
+	if !reflect.DeepEqual(normalizeForComparison(expected), normalizeForComparison(imported)) {
+		t.Errorf("Expected %v, got %v", expected, imported)
+	}
+}
+
+// Sorts fields by label
+func (s ConfigurationSchema) sorted() ConfigurationSchema {
+	sorted := append(ConfigurationSchema{}, s...)
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			if sorted[j].Label < sorted[i].Label {
+				sorted[i], sorted[j] = sorted[j], sorted[i]
+			}
+		}
+	}
+	return sorted
+}
+
+// Serializes the schema so nil and empty slices compare equal
+func normalizeForComparison(s ConfigurationSchema) string {
+	data, _ := json.Marshal(s)
+	var decoded interface{}
+	json.Unmarshal(data, &decoded)
+	normalized, _ := json.Marshal(stripEmpty(decoded))
+	return string(normalized)
+}
+
+func stripEmpty(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range value {
+			item = stripEmpty(item)
+			if list, ok := item.([]interface{}); ok && len(list) == 0 {
+				continue
+			}
+			if item == nil {
+				continue
+			}
+			result[key] = item
+		}
+		return result
+	case []interface{}:
+		result := []interface{}{}
+		for _, item := range value {
+			result = append(result, stripEmpty(item))
+		}
+		return result
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Declarative step to migrate a configuration from the previous definition version.
+// Steps only apply to top level fields of the configuration.
+type MigrationStep struct {
+	Operation string `json:"operation" firestore:"operation"` // "rename_field", "set_default" or "drop_field"
+	Field string `json:"field" firestore:"field"`
+	To string `json:"to,omitempty" firestore:"to,omitempty"` // Only for "rename_field"
+	Value interface{} `json:"value,omitempty" firestore:"value,omitempty"` // Only for "set_default"
+}
+
+func (s MigrationStep) Validate() error {
+
+	if s.Field == "" {
+		return errors.New("Field is required")
+	}
+
+	switch s.Operation {
+	case "rename_field":
+		if s.To == "" {
+			return fmt.Errorf("Rename of field %s is missing the new name", s.Field)
+		}
+		if s.To == s.Field {
+			return fmt.Errorf("Rename of field %s has the same name as target", s.Field)
+		}
+	case "set_default":
+		if s.Value == nil {
+			return fmt.Errorf("Default for field %s is missing a value", s.Field)
+		}
+	case "drop_field":
+		// Nothing else needed
+	default:
+		return fmt.Errorf("Invalid operation %s. Valid operations are \"rename_field\", \"set_default\" and \"drop_field\"", s.Operation)
+	}
+
+	return nil
+}
+
+// Applies the migration steps in order. Returns a new configuration, the receiver is left untouched.
+func (c IntegrationConfig) Migrate(steps []MigrationStep) (IntegrationConfig, error) {
+
+	migrated := IntegrationConfig{}
+	for key, value := range c {
+		migrated[key] = value
+	}
+
+	for idx, step := range steps {
+		err := step.Validate()
+		if err != nil {
+			return migrated, fmt.Errorf("Invalid step at index %d: %v", idx, err)
+		}
+
+		value, ok := migrated[step.Field]
+		switch step.Operation {
+		case "rename_field":
+			if !ok {
+				continue
+			}
+			if _, exists := migrated[step.To]; exists {
+				return migrated, fmt.Errorf("Can't rename field %s to %s: target field already has a value", step.Field, step.To)
+			}
+			migrated[step.To] = value
+			delete(migrated, step.Field)
+		case "set_default":
+			if !ok {
+				migrated[step.Field] = step.Value
+			}
+		case "drop_field":
+			delete(migrated, step.Field)
+		}
+	}
+
+	return migrated, nil
+}
+
+// Result of upgrading every integration of a definition to a new version
+type UpgradeReport struct {
+	DefinitionID string `json:"definition_id"`
+	TargetVersion string `json:"target_version"`
+	DryRun bool `json:"dry_run"`
+	Upgraded []string `json:"upgraded"`
+	Failed []UpgradeFailure `json:"failed"`
+}
+
+// An integration that could not be upgraded automatically and needs manual changes
+type UpgradeFailure struct {
+	IntegrationID string `json:"integration_id"`
+	WorkspaceID string `json:"workspace_id"`
+	Error string `json:"error"`
+}
+
+func NewUpgradeReport(definitionID string, targetVersion string, dryRun bool) UpgradeReport {
+	return UpgradeReport{ definitionID, targetVersion, dryRun, []string{}, []UpgradeFailure{} }
+}
+
+// Sorts definition versions from oldest to newest. Invalid versions are sorted first.
+func SortDefinitionVersions(versions []IntegrationDefinition) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, errA := ParseSemanticVersion(versions[i].Version)
+		b, errB := ParseSemanticVersion(versions[j].Version)
+		switch {
+		case errA != nil && errB != nil:
+			return versions[i].Version < versions[j].Version
+		case errA != nil || errB != nil:
+			return errA != nil
+		}
+		return a.Compare(b) < 0
+	})
+}
@@ -0,0 +1,305 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+
+	config := IntegrationConfig{
+		"token": "abc",
+		"legacy": true,
+	}
+	steps := []MigrationStep{
+		{Operation: "rename_field", Field: "token", To: "api_key"},
+		{Operation: "set_default", Field: "timeout", Value: 30},
+		{Operation: "drop_field", Field: "legacy"},
+	}
+
+	migrated, err := config.Migrate(steps)
+	if err != nil {
+		t.Fatalf("Expected migration to succeed, got %v", err)
+	}
+
+	if migrated["api_key"] != "abc" {
+		t.Errorf("Expected api_key to be renamed from token, got %v", migrated["api_key"])
+	}
+	if _, ok := migrated["token"]; ok {
+		t.Errorf("Expected token to be removed after rename")
+	}
+	if migrated["timeout"] != 30 {
+		t.Errorf("Expected timeout default to be set, got %v", migrated["timeout"])
+	}
+	if _, ok := migrated["legacy"]; ok {
+		t.Errorf("Expected legacy to be dropped")
+	}
+
+	// Original config is untouched
+	if _, ok := config["token"]; !ok {
+		t.Errorf("Expected original config to be left untouched")
+	}
+
+	// Defaults don't override existing values
+	migrated, _ = IntegrationConfig{"timeout": 10}.Migrate(steps)
+	if migrated["timeout"] != 10 {
+		t.Errorf("Expected existing timeout to be kept, got %v", migrated["timeout"])
+	}
+}
+
+func TestMigrateRenameCollision(t *testing.T) {
+
+	config := IntegrationConfig{
+		"token": "abc",
+		"api_key": "def",
+	}
+	steps := []MigrationStep{
+		{Operation: "rename_field", Field: "token", To: "api_key"},
+	}
+
+	_, err := config.Migrate(steps)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestInvalidMigrationStep(t *testing.T) {
+
+	invalid := []MigrationStep{
+		{Operation: "rename_field", Field: "token"},
+		{Operation: "rename_field", Field: "token", To: "token"},
+		{Operation: "set_default", Field: "timeout"},
+		{Operation: "drop_field"},
+		{Operation: "not an operation", Field: "token"},
+	}
+
+	for _, step := range invalid {
+		err := step.Validate()
+		if err == nil {
+			t.Errorf("Expected error for step %v, got nil", step)
+		}
+	}
+}
+
+func TestNewDefinitionVersion(t *testing.T) {
+
+	def, _ := NewIntegrationDefinition("name", "source", ConfigurationSchema{})
+	if def.Version != InitialDefinitionVersion {
+		t.Errorf("Expected initial version %s, got %s", InitialDefinitionVersion, def.Version)
+	}
+
+	_, err := def.NewVersion("1.1.0", ConfigurationSchema{}, nil)
+	if err != nil {
+		t.Errorf("Expected new version to be valid, got %v", err)
+	}
+
+	// Versions must increase
+	_, err = def.NewVersion("1.0.0", ConfigurationSchema{}, nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	_, err = def.NewVersion("0.9.0", ConfigurationSchema{}, nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	_, err = def.NewVersion("not a version", ConfigurationSchema{}, nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func upgradeHistory(t *testing.T) []IntegrationDefinition {
+
+	v1, _ := NewIntegrationDefinition("name", "source", ConfigurationSchema{
+		SchemaField{Label: "token", Type: "string", Required: true, Array: false, Fields: nil},
+		SchemaField{Label: "legacy", Type: "boolean", Required: false, Array: false, Fields: nil},
+	})
+	v1.ID = "def"
+
+	v2, err := v1.NewVersion("1.1.0", ConfigurationSchema{
+		SchemaField{Label: "api_key", Type: "string", Required: true, Array: false, Fields: nil},
+		SchemaField{Label: "legacy", Type: "boolean", Required: false, Array: false, Fields: nil},
+	}, []MigrationStep{
+		{Operation: "rename_field", Field: "token", To: "api_key"},
+	})
+	if err != nil {
+		t.Fatalf("Error creating version 1.1.0: %v", err)
+	}
+
+	v3, err := v2.NewVersion("2.0.0", ConfigurationSchema{
+		SchemaField{Label: "api_key", Type: "string", Required: true, Array: false, Fields: nil},
+		SchemaField{Label: "timeout", Type: "int", Required: true, Array: false, Fields: nil},
+		SchemaField{Label: "region", Type: "string", Required: true, Array: false, Fields: nil},
+	}, []MigrationStep{
+		{Operation: "drop_field", Field: "legacy"},
+		{Operation: "set_default", Field: "timeout", Value: 30},
+	})
+	if err != nil {
+		t.Fatalf("Error creating version 2.0.0: %v", err)
+	}
+
+	v1, _ = v1.Transition("published")
+	v2, _ = v2.Transition("published")
+	v3, _ = v3.Transition("published")
+
+	// Unordered on purpose
+	return []IntegrationDefinition{v3, v1, v2}
+}
+
+func TestUpgradeIntegration(t *testing.T) {
+
+	history := upgradeHistory(t)
+	integration, err := NewIntegration("integration", "workspace", history[1], IntegrationConfig{"token": "abc", "legacy": true}, nil)
+	if err != nil {
+		t.Fatalf("Error creating integration: %v", err)
+	}
+	integration.LastCheck = &CheckResult{Status: "succeeded"}
+
+	upgraded, err := integration.Upgrade(history, "1.1.0", nil)
+	if err != nil {
+		t.Fatalf("Expected upgrade to succeed, got %v", err)
+	}
+	if upgraded.DefinitionVersion != "1.1.0" {
+		t.Errorf("Expected version 1.1.0, got %s", upgraded.DefinitionVersion)
+	}
+	if upgraded.Configuration["api_key"] != "abc" {
+		t.Errorf("Expected api_key to be migrated, got %v", upgraded.Configuration)
+	}
+	if upgraded.LastCheck != nil {
+		t.Errorf("Expected the last check to be cleared, got %v", upgraded.LastCheck)
+	}
+
+	// Upgrading to the current version is a no-op
+	same, err := upgraded.Upgrade(history, "1.1.0", nil)
+	if err != nil || same.DefinitionVersion != "1.1.0" {
+		t.Errorf("Expected upgrade to the same version to be a no-op, got %v", err)
+	}
+
+	// Downgrades are not allowed
+	_, err = upgraded.Upgrade(history, "1.0.0", nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+
+	// Unknown versions fail
+	_, err = integration.Upgrade(history, "1.5.0", nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+
+	// Draft versions can't be upgraded to
+	history[2].Status = "draft"
+	_, err = integration.Upgrade(history, "1.1.0", nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestUpgradeIntegrationRequiresManualChanges(t *testing.T) {
+
+	// 2.0.0 adds a required field without default, so the config can't be upgraded automatically
+	history := upgradeHistory(t)
+	integration, _ := NewIntegration("integration", "workspace", history[1], IntegrationConfig{"token": "abc", "legacy": true}, nil)
+
+	upgraded, err := integration.Upgrade(history, "2.0.0", nil)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	if upgraded.DefinitionVersion != "1.0.0" {
+		t.Errorf("Expected failed upgrade to keep version 1.0.0, got %s", upgraded.DefinitionVersion)
+	}
+
+	// With the missing value, every migration is applied in order
+	integration.Configuration["region"] = "us"
+	upgraded, err = integration.Upgrade(history, "2.0.0", nil)
+	if err != nil {
+		t.Fatalf("Expected upgrade to succeed, got %v", err)
+	}
+	expected := IntegrationConfig{"api_key": "abc", "timeout": 30, "region": "us"}
+	for key, value := range expected {
+		if upgraded.Configuration[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, upgraded.Configuration[key])
+		}
+	}
+	if _, ok := upgraded.Configuration["legacy"]; ok {
+		t.Errorf("Expected legacy to be dropped")
+	}
+}
+
+func TestNormalizeConfig(t *testing.T) {
+
+	// Values as decoded from JSON
+	schema := ConfigurationSchema{
+		SchemaField{Label: "count", Type: "int", Required: true, Array: false, Fields: nil},
+		SchemaField{Label: "ids", Type: "int", Required: true, Array: true, Fields: nil},
+		SchemaField{Label: "nested", Type: "object", Required: true, Array: false, Fields: ConfigurationSchema{
+			SchemaField{Label: "depth", Type: "int", Required: true, Array: false, Fields: nil},
+		}},
+	}
+	config := IntegrationConfig{
+		"count": float64(3),
+		"ids": []interface{}{float64(1), float64(2)},
+		"nested": map[string]interface{}{"depth": float64(2)},
+	}
+
+	err := config.Normalize(schema).Validate(schema)
+	if err != nil {
+		t.Errorf("Expected normalized configuration to be valid, got %v", err)
+	}
+
+	// Non integral numbers are not converted
+	config["count"] = 3.5
+	err = config.Normalize(schema).Validate(schema)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestUpgradeIntegrationWithVariables(t *testing.T) {
+
+	v1, _ := NewIntegrationDefinition("name", "source", ConfigurationSchema{
+		SchemaField{Label: "region", Type: "string", Required: true, Enum: []interface{}{ "eu", "us" }},
+	})
+	v1.ID = "def"
+	v2, err := v1.NewVersion("1.1.0", v1.ConfigurationSchema, nil)
+	if err != nil {
+		t.Fatalf("Error creating version 1.1.0: %v", err)
+	}
+	v1, _ = v1.Transition("published")
+	v2, _ = v2.Transition("published")
+	history := []IntegrationDefinition{v1, v2}
+
+	variables := []WorkspaceVariable{ { Name: "region", Value: "eu" } }
+	integration, err := NewIntegration("integration", "workspace", v1, IntegrationConfig{"region": "${var.region}"}, variables)
+	if err != nil {
+		t.Fatalf("Error creating integration: %v", err)
+	}
+
+	upgraded, err := integration.Upgrade(history, "1.1.0", variables)
+	if err != nil {
+		t.Fatalf("Expected the references to be resolved to validate the upgrade, got %v", err)
+	}
+	if upgraded.Configuration["region"] != "${var.region}" {
+		t.Errorf("Expected the reference to be kept, got %v", upgraded.Configuration)
+	}
+
+	_, err = integration.Upgrade(history, "1.1.0", []WorkspaceVariable{ { Name: "region", Value: "asia" } })
+	if err == nil {
+		t.Errorf("Expected error for an invalid variable value, got nil")
+	}
+}
+
+func TestSortDefinitionVersions(t *testing.T) {
+
+	versions := []IntegrationDefinition{
+		{Version: "1.10.0"}, {Version: "b"}, {Version: "1.2.0"}, {Version: "2.0.0-beta"}, {Version: "a"}, {Version: "0.9.0"}, {Version: "2.0.0"},
+	}
+	SortDefinitionVersions(versions)
+
+	// Invalid versions first, whatever the order of the input
+	expected := []string{ "a", "b", "0.9.0", "1.2.0", "1.10.0", "2.0.0-beta", "2.0.0" }
+	for idx, version := range expected {
+		if versions[idx].Version != version {
+			t.Errorf("Expected version %s at index %d, got %s", version, idx, versions[idx].Version)
+		}
+	}
+}
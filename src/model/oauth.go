@@ -0,0 +1,217 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth2 provider a definition authenticates with, through the authorization code flow with PKCE.
+// The tokens are stored in top level fields of the configuration of the integrations.
+type OAuthProvider struct {
+	AuthURL string `json:"auth_url" firestore:"auth_url"`
+	TokenURL string `json:"token_url" firestore:"token_url"`
+	Scopes []string `json:"scopes" firestore:"scopes"`
+	ClientCredentials string `json:"client_credentials" firestore:"client_credentials"` // Name of the client credentials configured in the deployment, e.g "GOOGLE_ADS"
+	AccessTokenField string `json:"access_token_field" firestore:"access_token_field"` // Secret field receiving the access token
+	RefreshTokenField string `json:"refresh_token_field,omitempty" firestore:"refresh_token_field,omitempty"` // Secret field receiving the refresh token. Tokens aren't refreshed without one
+	ExpiresAtField string `json:"expires_at_field,omitempty" firestore:"expires_at_field,omitempty"` // Field receiving the RFC 3339 expiration of the access token
+}
+
+// Client registered with a provider
+type OAuthClient struct {
+	ID string
+	Secret string
+}
+
+// Authorization started by a user, waiting for the provider to call back
+type OAuthSession struct {
+	State string `json:"state" firestore:"state"` // Identifies the session in the callback
+	CodeVerifier string `json:"code_verifier" firestore:"code_verifier"`
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	IntegrationID string `json:"integration_id" firestore:"integration_id"`
+	PrincipalID string `json:"principal_id" firestore:"principal_id"` // ID of the user who started the authorization
+	ExpiresAt time.Time `json:"expires_at" firestore:"expires_at"`
+}
+
+// Token response of a provider
+// https://www.rfc-editor.org/rfc/rfc6749#section-5.1
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn int64 `json:"expires_in,omitempty"` // Seconds
+	Scope string `json:"scope,omitempty"`
+}
+
+// How long users have to complete an authorization
+const OAuthSessionTTL = 10 * time.Minute
+
+func (p OAuthProvider) Validate(schema ConfigurationSchema) error {
+
+	for _, endpoint := range []struct{ name, value string }{ { "auth URL", p.AuthURL }, { "token URL", p.TokenURL } } {
+		u, err := url.Parse(endpoint.value)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("Invalid %s %s", endpoint.name, endpoint.value)
+		}
+	}
+	if p.ClientCredentials == "" {
+		return errors.New("Client credentials are required")
+	}
+	if p.AccessTokenField == "" {
+		return errors.New("Access token field is required")
+	}
+
+	// Tokens are only known once authorized, so their fields can't be required
+	fields := []struct {
+		label string
+		secret bool
+	}{
+		{ p.AccessTokenField, true },
+		{ p.RefreshTokenField, true },
+		{ p.ExpiresAtField, false },
+	}
+	for _, f := range fields {
+		if f.label == "" {
+			continue
+		}
+		field, ok := schema.Field(f.label)
+		if !ok {
+			return fmt.Errorf("Field %s is not in the configuration schema", f.label)
+		}
+		if field.Type != "string" || field.Array || field.Required || field.Secret != f.secret {
+			kind := "non secret"
+			if f.secret {
+				kind = "secret"
+			}
+			return fmt.Errorf("Field %s must be an optional %s string", f.label, kind)
+		}
+	}
+
+	return nil
+}
+
+// Sets the OAuth provider of the definition. Nil removes it
+func (d IntegrationDefinition) WithOAuth(provider *OAuthProvider) (IntegrationDefinition, error) {
+	d.OAuth = provider
+	err := d.Validate()
+	if err != nil {
+		return d, fmt.Errorf("Invalid definition: %v", err)
+	}
+	return d, nil
+}
+
+func NewOAuthSession(workspaceID string, integrationID string, principalID string, now time.Time) (OAuthSession, error) {
+
+	var session OAuthSession
+
+	state, err := randomToken()
+	if err != nil {
+		return session, err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return session, err
+	}
+
+	return OAuthSession{ state, verifier, workspaceID, integrationID, principalID, now.Add(OAuthSessionTTL) }, nil
+}
+
+// S256 challenge of the code verifier
+// https://www.rfc-editor.org/rfc/rfc7636#section-4.2
+func (s OAuthSession) CodeChallenge() string {
+	return CodeChallenge(s.CodeVerifier)
+}
+
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (s OAuthSession) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// URL the user is sent to in order to authorize the client
+func (p OAuthProvider) AuthorizationURL(client OAuthClient, redirectURI string, session OAuthSession) (string, error) {
+
+	u, err := url.Parse(p.AuthURL)
+	if err != nil {
+		return "", fmt.Errorf("Invalid auth URL: %v", err)
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", client.ID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", session.State)
+	query.Set("code_challenge", session.CodeChallenge())
+	query.Set("code_challenge_method", "S256")
+	if len(p.Scopes) > 0 {
+		query.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Returns a copy of the configuration holding the token. Providers may not return a new
+// refresh token when refreshing, the current one is kept then.
+func (c IntegrationConfig) WithOAuthToken(p OAuthProvider, token OAuthToken, now time.Time) IntegrationConfig {
+
+	config := IntegrationConfig{}
+	for key, value := range c {
+		config[key] = value
+	}
+
+	config[p.AccessTokenField] = token.AccessToken
+	if p.RefreshTokenField != "" && token.RefreshToken != "" {
+		config[p.RefreshTokenField] = token.RefreshToken
+	}
+	if p.ExpiresAtField != "" {
+		if token.ExpiresIn > 0 {
+			config[p.ExpiresAtField] = now.Add(time.Duration(token.ExpiresIn) * time.Second).UTC().Format(time.RFC3339)
+		} else {
+			delete(config, p.ExpiresAtField)
+		}
+	}
+
+	return config
+}
+
+// Whether the access token of the configuration should be refreshed: it expires before the deadline
+// and there is a refresh token. Tokens without a known expiration are never refreshed.
+func (c IntegrationConfig) NeedsOAuthRefresh(p OAuthProvider, deadline time.Time) bool {
+
+	if p.RefreshTokenField == "" || p.ExpiresAtField == "" {
+		return false
+	}
+	if token, ok := c[p.RefreshTokenField].(string); !ok || token == "" {
+		return false
+	}
+	value, ok := c[p.ExpiresAtField].(string)
+	if !ok {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return false
+	}
+
+	return expiresAt.Before(deadline)
+}
+
+// 32 random bytes, base64url encoded
+func randomToken() (string, error) {
+	data := make([]byte, 32)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", fmt.Errorf("Error generating random token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func oauthSchema() ConfigurationSchema {
+	return ConfigurationSchema{
+		{Label: "account_id", Type: "string", Required: true},
+		{Label: "access_token", Type: "string", Secret: true},
+		{Label: "refresh_token", Type: "string", Secret: true},
+		{Label: "token_expires_at", Type: "string"},
+	}
+}
+
+func oauthProvider() OAuthProvider {
+	return OAuthProvider{
+		AuthURL: "https://provider.example.com/authorize?prompt=consent",
+		TokenURL: "https://provider.example.com/token",
+		Scopes: []string{"ads.read", "reports.read"},
+		ClientCredentials: "EXAMPLE",
+		AccessTokenField: "access_token",
+		RefreshTokenField: "refresh_token",
+		ExpiresAtField: "token_expires_at",
+	}
+}
+
+func TestOAuthProviderValidate(t *testing.T) {
+
+	if err := oauthProvider().Validate(oauthSchema()); err != nil {
+		t.Fatalf("Expected provider to be valid, got %v", err)
+	}
+
+	def, err := NewIntegrationDefinition("ads", "source", oauthSchema())
+	if err != nil {
+		t.Fatalf("Expected definition to be valid, got %v", err)
+	}
+	provider := oauthProvider()
+	def, err = def.WithOAuth(&provider)
+	if err != nil {
+		t.Fatalf("Expected provider to be accepted, got %v", err)
+	}
+	next, err := def.NewVersion("1.1.0", oauthSchema(), nil)
+	if err != nil || next.OAuth == nil {
+		t.Errorf("Expected new versions to keep the provider, got %+v, %v", next.OAuth, err)
+	}
+	if _, err := def.NewVersion("1.2.0", oauthSchema()[:1], nil); err == nil {
+		t.Errorf("Expected error for a new version without the token fields, got nil")
+	}
+
+	// Private definitions would have the client secrets of the deployment posted to a URL of their choice
+	if _, err := def.WithScope(DefinitionScope{ DefinitionOrganization, "agency", "" }); err == nil {
+		t.Errorf("Expected error for a private definition using OAuth, got nil")
+	}
+
+	invalid := []func(p *OAuthProvider){
+		func(p *OAuthProvider) { p.AuthURL = "provider.example.com/authorize" },
+		func(p *OAuthProvider) { p.TokenURL = "" },
+		func(p *OAuthProvider) { p.ClientCredentials = "" },
+		func(p *OAuthProvider) { p.AccessTokenField = "" },
+		func(p *OAuthProvider) { p.AccessTokenField = "unknown" },
+		// Required field
+		func(p *OAuthProvider) { p.AccessTokenField = "account_id" },
+		// Tokens must be secret, and the expiration must not
+		func(p *OAuthProvider) { p.RefreshTokenField = "token_expires_at" },
+		func(p *OAuthProvider) { p.ExpiresAtField = "refresh_token" },
+	}
+	for idx, edit := range invalid {
+		provider := oauthProvider()
+		edit(&provider)
+		if err := provider.Validate(oauthSchema()); err == nil {
+			t.Errorf("Expected error for provider at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestOAuthAuthorizationURL(t *testing.T) {
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	session, err := NewOAuthSession("workspace", "integration", "user", now)
+	if err != nil {
+		t.Fatalf("Expected session to be created, got %v", err)
+	}
+	other, _ := NewOAuthSession("workspace", "integration", "user", now)
+	if session.State == other.State || session.CodeVerifier == other.CodeVerifier || len(session.CodeVerifier) < 43 {
+		t.Errorf("Expected random states and verifiers of at least 43 characters, got %+v and %+v", session, other)
+	}
+	if session.Expired(now.Add(OAuthSessionTTL - time.Second)) || !session.Expired(now.Add(OAuthSessionTTL)) {
+		t.Errorf("Expected session to expire after %v", OAuthSessionTTL)
+	}
+
+	// Example of RFC 7636 appendix B
+	if challenge := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("Unexpected code challenge %s", challenge)
+	}
+
+	raw, err := oauthProvider().AuthorizationURL(OAuthClient{ID: "client"}, "https://api.example.com/oauth/callback", session)
+	if err != nil {
+		t.Fatalf("Expected authorization URL, got %v", err)
+	}
+	u, _ := url.Parse(raw)
+	query := u.Query()
+	expected := map[string]string{
+		"prompt": "consent",
+		"response_type": "code",
+		"client_id": "client",
+		"redirect_uri": "https://api.example.com/oauth/callback",
+		"state": session.State,
+		"code_challenge": session.CodeChallenge(),
+		"code_challenge_method": "S256",
+		"scope": "ads.read reports.read",
+	}
+	for key, value := range expected {
+		if query.Get(key) != value {
+			t.Errorf("Expected %s to be %s, got %s", key, value, query.Get(key))
+		}
+	}
+}
+
+func TestWithOAuthToken(t *testing.T) {
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	provider := oauthProvider()
+
+	config := IntegrationConfig{ "account_id": "123" }.WithOAuthToken(provider, OAuthToken{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 3600}, now)
+	if config["access_token"] != "access" || config["refresh_token"] != "refresh" || config["token_expires_at"] != "2024-05-01T13:00:00Z" {
+		t.Fatalf("Unexpected configuration %v", config)
+	}
+	if err := config.Validate(oauthSchema()); err != nil {
+		t.Errorf("Expected configuration to be valid, got %v", err)
+	}
+
+	if config.NeedsOAuthRefresh(provider, now.Add(50 * time.Minute)) {
+		t.Errorf("Expected token not to need a refresh yet")
+	}
+	if !config.NeedsOAuthRefresh(provider, now.Add(61 * time.Minute)) {
+		t.Errorf("Expected token to need a refresh")
+	}
+
+	// Refresh responses without a refresh token keep the current one
+	refreshed := config.WithOAuthToken(provider, OAuthToken{AccessToken: "access2"}, now)
+	if refreshed["access_token"] != "access2" || refreshed["refresh_token"] != "refresh" {
+		t.Errorf("Expected refresh token to be kept, got %v", refreshed)
+	}
+	if _, ok := refreshed["token_expires_at"]; ok || refreshed.NeedsOAuthRefresh(provider, now.Add(24 * time.Hour)) {
+		t.Errorf("Expected tokens without expiration never to be refreshed, got %v", refreshed)
+	}
+
+	noRefresh := provider
+	noRefresh.RefreshTokenField = ""
+	if config.NeedsOAuthRefresh(noRefresh, now.Add(24 * time.Hour)) {
+		t.Errorf("Expected providers without refresh token field never to refresh")
+	}
+}
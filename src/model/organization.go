@@ -0,0 +1,140 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Organization owning workspaces and users, e.g. an agency and the workspaces of its clients.
+// Users belong to organizations through their membership. Organizations are isolated from each other:
+// their members only reach the resources of their organizations
+type Organization struct {
+	ID string `json:"id" firestore:"id"`
+	Name string `json:"name" firestore:"name"`
+	Members []OrganizationMember `json:"members" firestore:"members"`
+	CreatedBy string `json:"created_by" firestore:"created_by"` // ID of the user who created the organization
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+// Roles of the members of organizations. Admins manage the organization and own its workspaces, members
+// view its workspaces and create new ones, billing members only manage billing. Guests are only members
+// to reach the workspaces they were invited to
+const (
+	OrganizationAdmin = "admin"
+	OrganizationMemberRole = "member"
+	OrganizationBilling = "billing"
+	OrganizationGuest = "guest"
+)
+
+// Workspace roles the organization roles cascade into, in every workspace of the organization
+var organizationGrants = map[string]string{ OrganizationAdmin: "owner", OrganizationMemberRole: "viewer" }
+
+type OrganizationMember struct {
+	Principal string `json:"principal" firestore:"principal"` // Principal ID of the user
+	Role string `json:"role" firestore:"role"` // "admin", "member", "billing" or "guest"
+	Email string `json:"user,omitempty" firestore:"-"` // For display only, filled on reads
+}
+
+func NewOrganization(name string, members []OrganizationMember, createdBy string, now time.Time) (Organization, error) {
+
+	organization := Organization{ "", name, members, createdBy, now, now }
+
+	err := organization.Validate()
+	if err != nil {
+		return organization, err
+	}
+	if !organization.Administered() {
+		return organization, errors.New("Organizations need at least one admin")
+	}
+
+	return organization, nil
+}
+
+func (o Organization) Validate() error {
+
+	if strings.TrimSpace(o.Name) == "" {
+		return errors.New("Name is required")
+	}
+
+	seen := map[string]bool{}
+	for idx, member := range o.Members {
+		kind, _, err := ParsePrincipal(member.Principal)
+		if err != nil {
+			return fmt.Errorf("Invalid member at index %d: %v", idx, err)
+		}
+		if kind != PrincipalUser {
+			return fmt.Errorf("Invalid member at index %d: only users can be members of organizations", idx)
+		}
+		switch member.Role {
+		case OrganizationAdmin, OrganizationMemberRole, OrganizationBilling, OrganizationGuest:
+		default:
+			return fmt.Errorf("Invalid role %s of member at index %d. Valid roles are \"admin\", \"member\", \"billing\" and \"guest\"", member.Role, idx)
+		}
+		if seen[member.Principal] {
+			return fmt.Errorf("Member %s is listed more than once", member.Principal)
+		}
+		seen[member.Principal] = true
+	}
+
+	return nil
+}
+
+// Role of the member. Empty for non members
+func (o Organization) RoleOf(principal string) string {
+	for _, member := range o.Members {
+		if member.Principal == principal {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+func (o Organization) AdministeredBy(principal string) bool {
+	return o.RoleOf(principal) == OrganizationAdmin
+}
+
+// Whether the organization has at least one admin left, so it can still be managed
+func (o Organization) Administered() bool {
+	for _, member := range o.Members {
+		if member.Role == OrganizationAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of the organization with the member, in place of any other role of its principal
+func (o Organization) WithMember(member OrganizationMember) Organization {
+
+	members := []OrganizationMember{}
+	for _, m := range o.Members {
+		if m.Principal != member.Principal {
+			members = append(members, m)
+		}
+	}
+	o.Members = append(members, OrganizationMember{ member.Principal, member.Role, "" })
+
+	return o
+}
+
+func (o Organization) WithoutMember(principal string) Organization {
+
+	members := []OrganizationMember{}
+	for _, m := range o.Members {
+		if m.Principal != principal {
+			members = append(members, m)
+		}
+	}
+	o.Members = members
+
+	return o
+}
+
+// Principal of the members of the organization with the role, e.g. "org:<organization id>#admin".
+// Organization roles aren't granted by workspaces, they cascade into every workspace of the organization
+func OrganizationPrincipal(organizationID string, role string) string {
+	return PrincipalOrganization + ":" + organizationID + "#" + role
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrganization(t *testing.T) {
+
+	now := time.Now()
+	organization, err := NewOrganization("Agency", []OrganizationMember{ { "user:ana", "admin", "" }, { "user:bob", "billing", "" } }, "ana", now)
+	if err != nil {
+		t.Fatalf("Expected organization to be valid, got %v", err)
+	}
+
+	invalid := [][]OrganizationMember{
+		{},
+		{ { "user:bob", "member", "" } },
+		{ { "ana@example.com", "admin", "" } },
+		{ { "group:admins", "admin", "" } },
+		{ { "user:ana", "owner", "" } },
+		{ { "user:ana", "admin", "" }, { "user:ana", "guest", "" } },
+	}
+	for idx, members := range invalid {
+		if _, err := NewOrganization("Agency", members, "ana", now); err == nil {
+			t.Errorf("Expected error for members at index %d, got nil", idx)
+		}
+	}
+	if _, err := NewOrganization(" ", []OrganizationMember{ { "user:ana", "admin", "" } }, "ana", now); err == nil {
+		t.Errorf("Expected error for an organization without name, got nil")
+	}
+
+	if !organization.AdministeredBy("user:ana") || organization.AdministeredBy("user:bob") || organization.RoleOf("user:bob") != "billing" || organization.RoleOf("user:eve") != "" {
+		t.Errorf("Unexpected members %+v", organization.Members)
+	}
+
+	promoted := organization.WithMember(OrganizationMember{ "user:bob", "admin", "bob@example.com" })
+	if len(promoted.Members) != 2 || !promoted.AdministeredBy("user:bob") || promoted.Members[1].Email != "" {
+		t.Errorf("Expected member to be promoted, got %+v", promoted.Members)
+	}
+	if organization.WithoutMember("user:ana").Administered() || !promoted.WithoutMember("user:ana").Administered() {
+		t.Errorf("Expected organizations to be administered as long as they have an admin")
+	}
+	if OrganizationPrincipal("agency", "admin") != "org:agency#admin" {
+		t.Errorf("Unexpected principal %s", OrganizationPrincipal("agency", "admin"))
+	}
+}
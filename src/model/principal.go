@@ -0,0 +1,48 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permissions reference principals by stable IDs, "<kind>:<id>". Never by email: users can change
+// theirs, and whoever registers an email afterwards must not inherit the access of its former owner
+const (
+	PrincipalUser = "user"
+	PrincipalGroup = "group"
+	PrincipalOrganization = "org" // Members of an organization with a role, "org:<organization id>#<role>"
+	PrincipalApp = "app" // Users with an app role, "app:<role>"
+)
+
+// Principal of the Super Admins, who administer every workspace
+const SuperAdminPrincipal = PrincipalApp + ":super-admin"
+
+func UserPrincipal(userID string) string {
+	return PrincipalUser + ":" + userID
+}
+
+func GroupPrincipal(groupID string) string {
+	return PrincipalGroup + ":" + groupID
+}
+
+// Principal ID of the user. Empty for users without an identity
+func (u User) Principal() string {
+	if !u.HasIdentity() {
+		return ""
+	}
+	return UserPrincipal(u.ID)
+}
+
+// Splits the principal ID into its kind and the ID of the principal in its kind
+func ParsePrincipal(principal string) (string, string, error) {
+
+	kind, id, found := strings.Cut(principal, ":")
+	if !found || id == "" {
+		return kind, id, fmt.Errorf("Invalid principal %s. Principals are \"<kind>:<id>\"", principal)
+	}
+	if kind != PrincipalUser && kind != PrincipalGroup && kind != PrincipalOrganization && kind != PrincipalApp {
+		return kind, id, fmt.Errorf("Unknown kind of principal %s", kind)
+	}
+
+	return kind, id, nil
+}
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Run struct {
+	/*
+		A sync executed by a connector runtime, either for a single integration
+		or for a connection. Connection runs count for both of its ends.
+	*/
+	ID string `json:"id" firestore:"id"`
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	ConnectionID string `json:"connection_id,omitempty" firestore:"connection_id,omitempty"`
+	IntegrationIDs []string `json:"integration_ids" firestore:"integration_ids"`
+	Status string `json:"status" firestore:"status"` // "running", "succeeded", "failed" or "cancelled"
+	StartedAt time.Time `json:"started_at" firestore:"started_at"`
+	EndedAt *time.Time `json:"ended_at,omitempty" firestore:"ended_at,omitempty"` // Set once the run is over
+	RecordsRead int64 `json:"records_read" firestore:"records_read"`
+	RecordsWritten int64 `json:"records_written" firestore:"records_written"`
+	Bytes int64 `json:"bytes" firestore:"bytes"`
+	Error string `json:"error,omitempty" firestore:"error,omitempty"`
+	ReportedBy string `json:"reported_by" firestore:"reported_by"` // ID of the user of the runtime
+}
+
+// Values a runtime reports about a run
+type RunReport struct {
+	Status string `json:"status"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt *time.Time `json:"ended_at"`
+	RecordsRead int64 `json:"records_read"`
+	RecordsWritten int64 `json:"records_written"`
+	Bytes int64 `json:"bytes"`
+	Error string `json:"error"`
+}
+
+var runStatuses = map[string]bool{
+	"running": true,
+	"succeeded": true,
+	"failed": true,
+	"cancelled": true,
+}
+
+// Runs of a single integration. Connection runs are created with NewConnectionRun
+func NewIntegrationRun(integration Integration, report RunReport, reportedBy string) (Run, error) {
+	run := Run{ "", integration.WorkspaceID, "", []string{integration.ID}, "", time.Time{}, nil, 0, 0, 0, "", reportedBy }
+	return run.Apply(report)
+}
+
+func NewConnectionRun(connection Connection, report RunReport, reportedBy string) (Run, error) {
+	run := Run{ "", connection.WorkspaceID, connection.ID, []string{connection.SourceID, connection.DestinationID}, "", time.Time{}, nil, 0, 0, 0, "", reportedBy }
+	return run.Apply(report)
+}
+
+// Updates the run with a new report. Finished runs can't change
+func (r Run) Apply(report RunReport) (Run, error) {
+
+	if r.Finished() {
+		return r, fmt.Errorf("Run is already %s", r.Status)
+	}
+
+	r.Status = report.Status
+	r.StartedAt = report.StartedAt
+	r.EndedAt = report.EndedAt
+	r.RecordsRead = report.RecordsRead
+	r.RecordsWritten = report.RecordsWritten
+	r.Bytes = report.Bytes
+	r.Error = report.Error
+
+	err := r.Validate()
+	if err != nil {
+		return r, fmt.Errorf("Invalid run: %v", err)
+	}
+
+	return r, nil
+}
+
+func (r Run) Validate() error {
+
+	if !runStatuses[r.Status] {
+		return fmt.Errorf("Invalid status %s. Valid statuses are \"running\", \"succeeded\", \"failed\" and \"cancelled\"", r.Status)
+	}
+	if len(r.IntegrationIDs) == 0 {
+		return errors.New("Run must belong to an integration or a connection")
+	}
+	if r.StartedAt.IsZero() {
+		return errors.New("Start time is required")
+	}
+
+	if r.Status == "running" {
+		if r.EndedAt != nil {
+			return errors.New("Running runs can't have an end time")
+		}
+	} else {
+		if r.EndedAt == nil {
+			return fmt.Errorf("End time is required for %s runs", r.Status)
+		}
+		if r.EndedAt.Before(r.StartedAt) {
+			return errors.New("End time is before the start time")
+		}
+	}
+
+	if r.RecordsRead < 0 || r.RecordsWritten < 0 || r.Bytes < 0 {
+		return errors.New("Counts can't be negative")
+	}
+	if r.Error != "" && r.Status != "failed" {
+		return errors.New("Only failed runs have an error")
+	}
+
+	return nil
+}
+
+func (r Run) Finished() bool {
+	return r.Status != "" && r.Status != "running"
+}
+
+func (r Run) HasIntegration(id string) bool {
+	return containsString(r.IntegrationIDs, id)
+}
+
+// Criteria to list runs. Empty fields don't filter
+type RunFilter struct {
+	WorkspaceID string
+	IntegrationID string
+	ConnectionID string
+	Status string
+	Since time.Time // Started at or after
+	Until time.Time // Started before
+	Limit int
+}
+
+func (f RunFilter) Matches(r Run) bool {
+	switch {
+	case f.WorkspaceID != "" && r.WorkspaceID != f.WorkspaceID:
+		return false
+	case f.IntegrationID != "" && !r.HasIntegration(f.IntegrationID):
+		return false
+	case f.ConnectionID != "" && r.ConnectionID != f.ConnectionID:
+		return false
+	case f.Status != "" && r.Status != f.Status:
+		return false
+	case !f.Since.IsZero() && r.StartedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.StartedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Integrations without a successful run for this long are stale
+const DefaultStaleAfter = 24 * time.Hour
+
+type IntegrationHealth struct {
+	State string `json:"state"` // "healthy", "failing" or "stale"
+	Reason string `json:"reason"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+// Derives the health of an integration from its runs, in any order.
+// Returns nil when no run has finished yet. Cancelled runs are ignored.
+//   - failing: the last finished run failed
+//   - stale: the last successful run ended more than staleAfter ago
+//   - healthy: otherwise
+func HealthFromRuns(runs []Run, now time.Time, staleAfter time.Duration) *IntegrationHealth {
+
+	var lastRun, lastSuccess *Run
+	for idx := range runs {
+		run := &runs[idx]
+		if run.Status != "succeeded" && run.Status != "failed" {
+			continue
+		}
+		if lastRun == nil || run.EndedAt.After(*lastRun.EndedAt) {
+			lastRun = run
+		}
+		if run.Status == "succeeded" && (lastSuccess == nil || run.EndedAt.After(*lastSuccess.EndedAt)) {
+			lastSuccess = run
+		}
+	}
+
+	if lastRun == nil {
+		return nil
+	}
+
+	health := IntegrationHealth{ "healthy", "", lastRun.EndedAt, nil }
+	if lastSuccess != nil {
+		health.LastSuccessAt = lastSuccess.EndedAt
+	}
+
+	switch {
+	case lastRun.Status == "failed":
+		health.State = "failing"
+		health.Reason = fmt.Sprintf("Last run failed: %s", lastRun.Error)
+		if lastRun.Error == "" {
+			health.Reason = "Last run failed"
+		}
+	case now.Sub(*lastSuccess.EndedAt) > staleAfter:
+		health.State = "stale"
+		health.Reason = fmt.Sprintf("No successful run since %s", lastSuccess.EndedAt.Format(time.RFC3339))
+	default:
+		health.Reason = "Last run succeeded"
+	}
+
+	return &health
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+var runStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func finishedRun(id string, status string, endedAt time.Time, runError string) Run {
+	return Run{ID: id, WorkspaceID: "workspace", IntegrationIDs: []string{"source"}, Status: status, StartedAt: endedAt.Add(-time.Minute), EndedAt: &endedAt, Error: runError}
+}
+
+func TestReportRun(t *testing.T) {
+
+	source, destination := connectionEnds()
+	connection, err := NewConnection("sync", "workspace", source, destination, Schedule{}, NamespaceMapping{}, true, time.Now())
+	if err != nil {
+		t.Fatalf("Expected connection to be valid, got %v", err)
+	}
+	connection.ID = "connection"
+
+	run, err := NewConnectionRun(connection, RunReport{Status: "running", StartedAt: runStart}, "runtime")
+	if err != nil {
+		t.Fatalf("Expected run to be valid, got %v", err)
+	}
+	if !run.HasIntegration("source") || !run.HasIntegration("destination") || run.ConnectionID != "connection" {
+		t.Errorf("Expected connection run to count for both ends, got %+v", run)
+	}
+
+	end := runStart.Add(time.Hour)
+	run, err = run.Apply(RunReport{Status: "failed", StartedAt: runStart, EndedAt: &end, RecordsRead: 10, Error: "Timeout"})
+	if err != nil {
+		t.Fatalf("Expected run to finish, got %v", err)
+	}
+	if run.Status != "failed" || run.RecordsRead != 10 || run.Error != "Timeout" {
+		t.Errorf("Expected report to be applied, got %+v", run)
+	}
+
+	_, err = run.Apply(RunReport{Status: "succeeded", StartedAt: runStart, EndedAt: &end})
+	if err == nil {
+		t.Errorf("Expected error updating a finished run, got nil")
+	}
+
+	before := runStart.Add(-time.Minute)
+	invalid := []RunReport{
+		{ Status: "done", StartedAt: runStart },
+		{ Status: "running" },
+		{ Status: "running", StartedAt: runStart, EndedAt: &end },
+		{ Status: "succeeded", StartedAt: runStart },
+		{ Status: "succeeded", StartedAt: runStart, EndedAt: &before },
+		{ Status: "succeeded", StartedAt: runStart, EndedAt: &end, RecordsWritten: -1 },
+		{ Status: "succeeded", StartedAt: runStart, EndedAt: &end, Error: "Timeout" },
+	}
+	for idx, report := range invalid {
+		_, err := NewIntegrationRun(source, report, "runtime")
+		if err == nil {
+			t.Errorf("Expected error for report at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+
+	run := finishedRun("run", "succeeded", runStart, "")
+	run.ConnectionID = "connection"
+
+	tests := []struct {
+		filter RunFilter
+		expected bool
+	}{
+		{ RunFilter{}, true },
+		{ RunFilter{WorkspaceID: "workspace", IntegrationID: "source", ConnectionID: "connection", Status: "succeeded"}, true },
+		{ RunFilter{WorkspaceID: "other"}, false },
+		{ RunFilter{IntegrationID: "destination"}, false },
+		{ RunFilter{ConnectionID: "other"}, false },
+		{ RunFilter{Status: "failed"}, false },
+		// Runs started a minute before their end
+		{ RunFilter{Since: runStart.Add(-time.Minute), Until: runStart}, true },
+		{ RunFilter{Since: runStart}, false },
+		{ RunFilter{Until: runStart.Add(-time.Minute)}, false },
+	}
+	for idx, test := range tests {
+		if matches := test.filter.Matches(run); matches != test.expected {
+			t.Errorf("Expected filter at index %d to match %v, got %v", idx, test.expected, matches)
+		}
+	}
+}
+
+func TestHealthFromRuns(t *testing.T) {
+
+	now := runStart.Add(48 * time.Hour)
+	running := Run{ID: "running", IntegrationIDs: []string{"source"}, Status: "running", StartedAt: now}
+	cancelled := finishedRun("cancelled", "cancelled", now, "")
+
+	if health := HealthFromRuns([]Run{running, cancelled}, now, DefaultStaleAfter); health != nil {
+		t.Errorf("Expected no health without finished runs, got %+v", health)
+	}
+
+	tests := []struct {
+		runs []Run
+		expected string
+	}{
+		{ []Run{finishedRun("a", "succeeded", now.Add(-time.Hour), "")}, "healthy" },
+		{ []Run{finishedRun("a", "succeeded", now.Add(-25 * time.Hour), "")}, "stale" },
+		// The most recent run decides, whatever the order
+		{ []Run{finishedRun("a", "failed", now.Add(-time.Hour), "Timeout"), finishedRun("b", "succeeded", now.Add(-2 * time.Hour), "")}, "failing" },
+		{ []Run{finishedRun("a", "succeeded", now.Add(-time.Hour), ""), finishedRun("b", "failed", now.Add(-2 * time.Hour), "Timeout")}, "healthy" },
+		// Failing wins over stale
+		{ []Run{finishedRun("a", "failed", now.Add(-30 * time.Hour), "")}, "failing" },
+		{ []Run{finishedRun("a", "succeeded", now.Add(-time.Hour), ""), cancelled, running}, "healthy" },
+	}
+	for idx, test := range tests {
+		health := HealthFromRuns(test.runs, now, DefaultStaleAfter)
+		if health == nil || health.State != test.expected {
+			t.Errorf("Expected %s health for runs at index %d, got %+v", test.expected, idx, health)
+		}
+	}
+
+	failed := finishedRun("a", "failed", now.Add(-time.Hour), "Timeout")
+	succeeded := finishedRun("b", "succeeded", now.Add(-2 * time.Hour), "")
+	health := HealthFromRuns([]Run{failed, succeeded}, now, DefaultStaleAfter)
+	if !health.LastRunAt.Equal(*failed.EndedAt) || !health.LastSuccessAt.Equal(*succeeded.EndedAt) || health.Reason != "Last run failed: Timeout" {
+		t.Errorf("Unexpected health details %+v", health)
+	}
+
+	integration := Integration{ID: "destination"}.WithHealth([]Run{succeeded}, now)
+	if integration.Health != nil {
+		t.Errorf("Expected runs of other integrations to be ignored, got %+v", integration.Health)
+	}
+}
@@ -0,0 +1,256 @@
+package model
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Effective configuration of an integration, as read by connector workers.
+// Unlike user facing views, secrets are not masked
+type RuntimeConfig struct {
+	IntegrationID string `json:"integration_id"`
+	WorkspaceID string `json:"workspace_id"`
+	Environment string `json:"environment,omitempty"` // Empty for the configuration of the workspace
+	Name string `json:"name"`
+	Definition RuntimeDefinition `json:"definition"`
+	Configuration IntegrationConfig `json:"configuration"`
+	Streams []StreamSelection `json:"streams"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// Metadata of the definition version the integration is pinned to
+type RuntimeDefinition struct {
+	ID string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Version string `json:"version"`
+	Status string `json:"status"`
+}
+
+func NewRuntimeConfig(integration Integration, def IntegrationDefinition, now time.Time) RuntimeConfig {
+	return RuntimeConfig{
+		integration.ID,
+		integration.WorkspaceID,
+		"",
+		integration.Name,
+		RuntimeDefinition{ def.ID, def.Name, def.Type, def.Version, def.Status },
+		integration.Configuration.WithDefaults(def.ConfigurationSchema),
+		integration.Streams,
+		now,
+	}
+}
+
+// Returns a copy of the configuration where missing fields with a default get their default,
+// including fields of nested objects and of arrays of objects
+func (c IntegrationConfig) WithDefaults(schema ConfigurationSchema) IntegrationConfig {
+
+	config := IntegrationConfig{}
+	for key, value := range c {
+		config[key] = value
+	}
+
+	for _, field := range schema {
+		value, ok := config[field.Label]
+		if !ok || value == nil {
+			if field.Default != nil {
+				config[field.Label] = normalizeDefault(field)
+			}
+			continue
+		}
+		if field.Type != "object" {
+			continue
+		}
+
+		if !field.Array {
+			config[field.Label] = objectWithDefaults(field, value)
+			continue
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		withDefaults := make([]interface{}, len(items))
+		for idx, item := range items {
+			withDefaults[idx] = objectWithDefaults(field, item)
+		}
+		config[field.Label] = withDefaults
+	}
+
+	return config
+}
+
+func normalizeDefault(f SchemaField) interface{} {
+	return IntegrationConfig{ f.Label: f.Default }.Normalize(ConfigurationSchema{f})[f.Label]
+}
+
+func objectWithDefaults(f SchemaField, value interface{}) interface{} {
+	object, ok := asConfig(value)
+	if !ok {
+		return value
+	}
+	fields, err := f.ObjectSchema(object)
+	if err != nil {
+		fields = f.Fields
+	}
+	return object.WithDefaults(fields)
+}
+
+// Paths of the secrets set in the configuration, sorted. Fields of nested objects are
+// separated by dots and items of arrays are indexed, e.g. "auth.password" or "hosts[0].key"
+func (c IntegrationConfig) SecretPaths(schema ConfigurationSchema) []string {
+	paths := []string{}
+	c.collectSecretPaths(schema, "", &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func (c IntegrationConfig) collectSecretPaths(schema ConfigurationSchema, prefix string, paths *[]string) {
+
+	for _, field := range schema {
+		value, ok := c[field.Label]
+		if !ok || value == nil {
+			continue
+		}
+		path := prefix + field.Label
+
+		if field.Secret {
+			*paths = append(*paths, path)
+			continue
+		}
+		if field.Type != "object" {
+			continue
+		}
+
+		items := []interface{}{ value }
+		if field.Array {
+			items, ok = value.([]interface{})
+			if !ok {
+				continue
+			}
+		}
+		for idx, item := range items {
+			object, ok := asConfig(item)
+			if !ok {
+				continue
+			}
+			fields, err := field.ObjectSchema(object)
+			if err != nil {
+				fields = field.Fields
+			}
+			itemPath := path
+			if field.Array {
+				itemPath += "[" + strconv.Itoa(idx) + "]"
+			}
+			object.collectSecretPaths(fields, itemPath + ".", paths)
+		}
+	}
+}
+
+// Runtime configuration sealed with keys shared with the workers, so they can cache it and pass it
+// around until it expires. The payload holds secrets in clear once decrypted, so it is encrypted with
+// AES-256-GCM, then signed with a separate key: caches and logs only ever see ciphertext, and leaking
+// the signing key alone doesn't reveal secrets
+type RuntimeConfigBundle struct {
+	Payload string `json:"payload"` // base64url encoded nonce and AES-256-GCM ciphertext of the JSON of the RuntimeConfig
+	ExpiresAt int64 `json:"expires_at"` // Unix time
+	Signature string `json:"signature"` // hex encoded HMAC-SHA256 of "<payload>.<expires_at>"
+}
+
+// Size in bytes of the keys encrypting runtime config bundles
+const RuntimeBundleEncryptionKeySize = 32
+
+func SealRuntimeConfig(config RuntimeConfig, signingKey []byte, encryptionKey []byte, expiresAt time.Time) (RuntimeConfigBundle, error) {
+
+	var bundle RuntimeConfigBundle
+
+	if len(signingKey) == 0 {
+		return bundle, errors.New("Signing key is required")
+	}
+	aead, err := bundleCipher(encryptionKey)
+	if err != nil {
+		return bundle, err
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return bundle, fmt.Errorf("Config can't be encoded: %v", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return bundle, fmt.Errorf("Nonce can't be generated: %v", err)
+	}
+
+	bundle.ExpiresAt = expiresAt.Unix()
+	sealed := aead.Seal(nonce, nonce, data, []byte(strconv.FormatInt(bundle.ExpiresAt, 10)))
+	bundle.Payload = base64.RawURLEncoding.EncodeToString(sealed)
+	bundle.Signature = hex.EncodeToString(bundle.mac(signingKey))
+
+	return bundle, nil
+}
+
+// Checks the signature and the expiration of the bundle, then decrypts and decodes its config
+func (b RuntimeConfigBundle) Open(signingKey []byte, encryptionKey []byte, now time.Time) (RuntimeConfig, error) {
+
+	var config RuntimeConfig
+
+	if len(signingKey) == 0 {
+		return config, errors.New("Signing key is required")
+	}
+	aead, err := bundleCipher(encryptionKey)
+	if err != nil {
+		return config, err
+	}
+
+	signature, err := hex.DecodeString(b.Signature)
+	if err != nil || !hmac.Equal(signature, b.mac(signingKey)) {
+		return config, errors.New("Invalid signature")
+	}
+	if now.Unix() >= b.ExpiresAt {
+		return config, fmt.Errorf("Bundle expired at %s", time.Unix(b.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(b.Payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return config, errors.New("Invalid payload")
+	}
+	data, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(strconv.FormatInt(b.ExpiresAt, 10)))
+	if err != nil {
+		return config, errors.New("Payload can't be decrypted")
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return config, fmt.Errorf("Invalid payload: %v", err)
+	}
+
+	return config, nil
+}
+
+func bundleCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != RuntimeBundleEncryptionKeySize {
+		return nil, fmt.Errorf("Encryption key of %d bytes is required", RuntimeBundleEncryptionKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (b RuntimeConfigBundle) mac(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(b.Payload + "." + strconv.FormatInt(b.ExpiresAt, 10)))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runtimeSchema() ConfigurationSchema {
+	return ConfigurationSchema{
+		{Label: "host", Type: "string", Required: true},
+		{Label: "port", Type: "int", Default: float64(5432)},
+		{Label: "password", Type: "string", Secret: true},
+		{Label: "ssl", Type: "object", Fields: ConfigurationSchema{
+			{Label: "mode", Type: "string", Default: "require"},
+			{Label: "key", Type: "string", Secret: true},
+		}},
+		{Label: "replicas", Type: "object", Array: true, Fields: ConfigurationSchema{
+			{Label: "host", Type: "string", Required: true},
+			{Label: "port", Type: "int", Default: 5432},
+			{Label: "token", Type: "string", Secret: true},
+		}},
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+
+	config := IntegrationConfig{
+		"host": "db",
+		"ssl": map[string]interface{}{ "key": "pem" },
+		"replicas": []interface{}{
+			map[string]interface{}{ "host": "r1" },
+			map[string]interface{}{ "host": "r2", "port": 6432 },
+		},
+	}
+
+	resolved := config.WithDefaults(runtimeSchema())
+	expected := IntegrationConfig{
+		"host": "db",
+		"port": 5432,
+		"ssl": IntegrationConfig{ "mode": "require", "key": "pem" },
+		"replicas": []interface{}{
+			IntegrationConfig{ "host": "r1", "port": 5432 },
+			IntegrationConfig{ "host": "r2", "port": 6432 },
+		},
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("Expected %v, got %v", expected, resolved)
+	}
+	if _, ok := config["port"]; ok {
+		t.Errorf("Expected the original configuration not to change, got %v", config)
+	}
+	if err := resolved.Validate(runtimeSchema()); err != nil {
+		t.Errorf("Expected resolved configuration to be valid, got %v", err)
+	}
+}
+
+func TestSecretPaths(t *testing.T) {
+
+	config := IntegrationConfig{
+		"host": "db",
+		"password": "hunter2",
+		"ssl": IntegrationConfig{ "key": "pem" },
+		"replicas": []interface{}{
+			IntegrationConfig{ "host": "r1" },
+			IntegrationConfig{ "host": "r2", "token": "abc" },
+		},
+	}
+
+	paths := config.SecretPaths(runtimeSchema())
+	expected := []string{"password", "replicas[1].token", "ssl.key"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected secret paths %v, got %v", expected, paths)
+	}
+
+	if paths := (IntegrationConfig{ "host": "db" }).SecretPaths(runtimeSchema()); len(paths) != 0 {
+		t.Errorf("Expected no secret paths, got %v", paths)
+	}
+}
+
+func TestRuntimeConfigBundle(t *testing.T) {
+
+	def := IntegrationDefinition{ID: "postgres", Name: "Postgres", Type: "source", Version: "1.0.0", Status: "published", ConfigurationSchema: runtimeSchema()}
+	integration := Integration{ID: "integration", WorkspaceID: "workspace", Name: "db", Configuration: IntegrationConfig{ "host": "db", "password": "hunter2" }}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	config := NewRuntimeConfig(integration, def, now)
+	if config.Definition.Version != "1.0.0" || config.Configuration["port"] != 5432 || config.Configuration["password"] != "hunter2" {
+		t.Fatalf("Expected defaults applied and secrets in clear, got %+v", config)
+	}
+
+	key := []byte("key")
+	encryptionKey := []byte("0123456789abcdef0123456789abcdef")
+	bundle, err := SealRuntimeConfig(config, key, encryptionKey, now.Add(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("Expected bundle to be sealed, got %v", err)
+	}
+	if payload, _ := base64.RawURLEncoding.DecodeString(bundle.Payload); strings.Contains(string(payload), "hunter2") {
+		t.Errorf("Expected payload to be encrypted")
+	}
+
+	opened, err := bundle.Open(key, encryptionKey, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Expected bundle to open, got %v", err)
+	}
+	if opened.IntegrationID != "integration" || opened.Configuration["password"] != "hunter2" {
+		t.Errorf("Unexpected config in bundle %+v", opened)
+	}
+
+	if _, err := bundle.Open(key, encryptionKey, now.Add(5 * time.Minute)); err == nil {
+		t.Errorf("Expected error opening an expired bundle, got nil")
+	}
+	if _, err := bundle.Open([]byte("other"), encryptionKey, now); err == nil {
+		t.Errorf("Expected error opening a bundle with another key, got nil")
+	}
+	if _, err := bundle.Open(key, []byte("fedcba9876543210fedcba9876543210"), now); err == nil {
+		t.Errorf("Expected error opening a bundle with another encryption key, got nil")
+	}
+
+	tampered := bundle
+	tampered.ExpiresAt += 3600
+	if _, err := tampered.Open(key, encryptionKey, now); err == nil {
+		t.Errorf("Expected error opening a bundle with a changed expiration, got nil")
+	}
+	tampered = bundle
+	tampered.Payload = tampered.Payload[1:]
+	if _, err := tampered.Open(key, encryptionKey, now); err == nil {
+		t.Errorf("Expected error opening a bundle with a changed payload, got nil")
+	}
+
+	if _, err := SealRuntimeConfig(config, nil, encryptionKey, now); err == nil {
+		t.Errorf("Expected error sealing without a signing key, got nil")
+	}
+	if _, err := SealRuntimeConfig(config, key, []byte("short"), now); err == nil {
+		t.Errorf("Expected error sealing with an encryption key of the wrong size, got nil")
+	}
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// When a connection syncs
+type Schedule struct {
+	Type string `json:"type" firestore:"type"` // "manual", "interval" or "cron"
+	IntervalMinutes int `json:"interval_minutes,omitempty" firestore:"interval_minutes,omitempty"` // Only for "interval"
+	Cron string `json:"cron,omitempty" firestore:"cron,omitempty"` // Only for "cron", e.g "30 2 * * 1-5"
+	Timezone string `json:"timezone,omitempty" firestore:"timezone,omitempty"` // IANA name the cron expression is evaluated in. Defaults to UTC
+}
+
+// A connection the scheduler should start
+type DueConnection struct {
+	ConnectionID string `json:"connection_id"`
+	WorkspaceID string `json:"workspace_id"`
+	RunAt time.Time `json:"run_at"`
+}
+
+// Upper bound for the number of runs computed at once
+const MaxScheduledRuns = 100
+
+// Cron expressions must have a run after now
+func (s Schedule) Validate(now time.Time) error {
+
+	if s.Type != "interval" && s.IntervalMinutes != 0 {
+		return errors.New("Interval is only allowed for interval schedules")
+	}
+	if s.Type != "cron" && (s.Cron != "" || s.Timezone != "") {
+		return errors.New("Cron expression and timezone are only allowed for cron schedules")
+	}
+
+	switch s.Type {
+	case "manual":
+	case "interval":
+		if s.IntervalMinutes <= 0 {
+			return errors.New("Interval must be a positive number of minutes")
+		}
+	case "cron":
+		cron, loc, err := s.cron()
+		if err != nil {
+			return err
+		}
+		// Expressions like "0 0 30 2 *" are valid but never run
+		_, err = cron.Next(now, loc)
+		if err != nil {
+			return fmt.Errorf("Invalid cron expression: %v", err)
+		}
+	default:
+		return fmt.Errorf("Invalid schedule type %s. Valid types are \"manual\", \"interval\" and \"cron\"", s.Type)
+	}
+
+	return nil
+}
+
+func (s Schedule) cron() (CronExpression, *time.Location, error) {
+
+	cron, err := ParseCronExpression(s.Cron)
+	if err != nil {
+		return cron, nil, fmt.Errorf("Invalid cron expression: %v", err)
+	}
+
+	timezone := s.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return cron, nil, fmt.Errorf("Invalid timezone %s", s.Timezone)
+	}
+
+	return cron, loc, nil
+}
+
+// Returns the next n runs strictly after the given time. Manual schedules never run.
+// Interval schedules run on multiples of the interval since the Unix epoch, so an hourly
+// schedule runs at the top of every hour.
+func (s Schedule) NextRuns(after time.Time, n int) ([]time.Time, error) {
+
+	runs := []time.Time{}
+	if n <= 0 || n > MaxScheduledRuns {
+		return runs, fmt.Errorf("Number of runs must be between 1 and %d", MaxScheduledRuns)
+	}
+
+	switch s.Type {
+	case "manual":
+		return runs, nil
+	case "interval":
+		if s.IntervalMinutes <= 0 {
+			return runs, errors.New("Interval must be a positive number of minutes")
+		}
+		interval := int64(s.IntervalMinutes) * 60
+		next := (after.Unix() / interval + 1) * interval
+		for len(runs) < n {
+			runs = append(runs, time.Unix(next, 0).UTC())
+			next += interval
+		}
+		return runs, nil
+	case "cron":
+		cron, loc, err := s.cron()
+		if err != nil {
+			return runs, err
+		}
+		next := after
+		for len(runs) < n {
+			next, err = cron.Next(next, loc)
+			if err != nil {
+				// Only the runs found so far
+				return runs, nil
+			}
+			runs = append(runs, next.In(loc))
+		}
+		return runs, nil
+	}
+
+	return runs, fmt.Errorf("Invalid schedule type %s", s.Type)
+}
+
+// Returns the first run within [from, to), if any
+func (s Schedule) FirstRunBetween(from time.Time, to time.Time) (time.Time, bool, error) {
+
+	runs, err := s.NextRuns(from.Add(-time.Nanosecond), 1)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if len(runs) == 0 || !runs[0].Before(to) {
+		return time.Time{}, false, nil
+	}
+
+	return runs[0], true, nil
+}
@@ -0,0 +1,253 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Error loading location %s: %v", name, err)
+	}
+	return loc
+}
+
+func formatRuns(runs []time.Time) []string {
+	formatted := []string{}
+	for _, run := range runs {
+		formatted = append(formatted, run.Format(time.RFC3339))
+	}
+	return formatted
+}
+
+func expectRuns(t *testing.T, name string, schedule Schedule, after time.Time, expected []string) {
+	t.Helper()
+	runs, err := schedule.NextRuns(after, len(expected))
+	if err != nil {
+		t.Fatalf("%s: expected runs, got %v", name, err)
+	}
+	got := formatRuns(runs)
+	if len(got) != len(expected) {
+		t.Fatalf("%s: expected %v, got %v", name, expected, got)
+	}
+	for idx := range expected {
+		if got[idx] != expected[idx] {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+			return
+		}
+	}
+}
+
+func TestParseCronExpression(t *testing.T) {
+
+	valid := []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 9-17 * * 1-5",
+		"0,30 8 1,15 * *",
+		"5/10 * * * *",
+		"0 0 * JAN-MAR sun",
+		"0 0 * * 7",
+		"@hourly",
+		"@Daily",
+		"  0 0 1 1 *  ",
+	}
+	for _, expression := range valid {
+		_, err := ParseCronExpression(expression)
+		if err != nil {
+			t.Errorf("Expected %q to be valid, got %v", expression, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every 5m",
+	}
+	for _, expression := range invalid {
+		_, err := ParseCronExpression(expression)
+		if err == nil {
+			t.Errorf("Expected error for %q, got nil", expression)
+		}
+	}
+}
+
+func TestCronNextRuns(t *testing.T) {
+
+	after := time.Date(2024, 1, 31, 10, 7, 30, 0, time.UTC) // Wednesday
+
+	expectRuns(t, "every 15 minutes", Schedule{Type: "cron", Cron: "*/15 * * * *"}, after, []string{
+		"2024-01-31T10:15:00Z", "2024-01-31T10:30:00Z", "2024-01-31T10:45:00Z", "2024-01-31T11:00:00Z",
+	})
+	expectRuns(t, "business hours", Schedule{Type: "cron", Cron: "0 9-17/4 * * mon-fri"}, after, []string{
+		"2024-01-31T13:00:00Z", "2024-01-31T17:00:00Z", "2024-02-01T09:00:00Z", "2024-02-01T13:00:00Z",
+	})
+	expectRuns(t, "end of month skips short months", Schedule{Type: "cron", Cron: "0 0 31 * *"}, after, []string{
+		"2024-03-31T00:00:00Z", "2024-05-31T00:00:00Z", "2024-07-31T00:00:00Z",
+	})
+	expectRuns(t, "leap day", Schedule{Type: "cron", Cron: "0 12 29 2 *"}, after, []string{
+		"2024-02-29T12:00:00Z", "2028-02-29T12:00:00Z",
+	})
+	// Both day fields restricted: either one matches
+	expectRuns(t, "day of month or day of week", Schedule{Type: "cron", Cron: "0 0 1 * fri"}, after, []string{
+		"2024-02-01T00:00:00Z", "2024-02-02T00:00:00Z", "2024-02-09T00:00:00Z",
+	})
+	// A step on a wildcard doesn't restrict the day
+	expectRuns(t, "every day of month stepped", Schedule{Type: "cron", Cron: "0 0 */1 * fri"}, after, []string{
+		"2024-02-02T00:00:00Z", "2024-02-09T00:00:00Z",
+	})
+	expectRuns(t, "every day of week stepped", Schedule{Type: "cron", Cron: "0 0 1 * */2"}, after, []string{
+		"2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z",
+	})
+	expectRuns(t, "sunday as 7", Schedule{Type: "cron", Cron: "@weekly"}, after, []string{
+		"2024-02-04T00:00:00Z", "2024-02-11T00:00:00Z",
+	})
+
+	// Evaluated on the wall clock of the timezone
+	expectRuns(t, "timezone", Schedule{Type: "cron", Cron: "0 9 * * *", Timezone: "Asia/Tokyo"}, after, []string{
+		"2024-02-01T09:00:00+09:00", "2024-02-02T09:00:00+09:00",
+	})
+}
+
+func TestCronDaylightSavingTime(t *testing.T) {
+
+	// Clocks move forward on 2024-03-10 at 02:00 in New York, 02:xx doesn't exist
+	before := time.Date(2024, 3, 9, 12, 0, 0, 0, mustLocation(t, "America/New_York"))
+	expectRuns(t, "skipped time runs at the transition", Schedule{Type: "cron", Cron: "30 2 * * *", Timezone: "America/New_York"}, before, []string{
+		"2024-03-10T03:00:00-04:00", "2024-03-11T02:30:00-04:00",
+	})
+	expectRuns(t, "skipped hour runs once", Schedule{Type: "cron", Cron: "*/20 1-3 10 3 *", Timezone: "America/New_York"}, before, []string{
+		"2024-03-10T01:00:00-05:00", "2024-03-10T01:20:00-05:00", "2024-03-10T01:40:00-05:00",
+		"2024-03-10T03:00:00-04:00", "2024-03-10T03:20:00-04:00", "2024-03-10T03:40:00-04:00",
+	})
+	expectRuns(t, "daily across spring forward", Schedule{Type: "cron", Cron: "0 9 * * *", Timezone: "America/New_York"}, before, []string{
+		"2024-03-10T09:00:00-04:00", "2024-03-11T09:00:00-04:00",
+	})
+
+	// Clocks move back on 2024-11-03 at 02:00 in New York, 01:xx happens twice
+	before = time.Date(2024, 11, 2, 12, 0, 0, 0, mustLocation(t, "America/New_York"))
+	expectRuns(t, "repeated time runs once", Schedule{Type: "cron", Cron: "30 1 * * *", Timezone: "America/New_York"}, before, []string{
+		"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00",
+	})
+	expectRuns(t, "hourly across fall back", Schedule{Type: "cron", Cron: "0 * 3 11 *", Timezone: "America/New_York"}, time.Date(2024, 11, 3, 4, 30, 0, 0, time.UTC), []string{
+		"2024-11-03T01:00:00-04:00", "2024-11-03T02:00:00-05:00", "2024-11-03T03:00:00-05:00",
+	})
+
+	// Starting within the repeated hour, after its first occurrence
+	second := time.Date(2024, 11, 3, 6, 10, 0, 0, time.UTC) // 01:10 EST
+	expectRuns(t, "within repeated hour", Schedule{Type: "cron", Cron: "30 1 * * *", Timezone: "America/New_York"}, second, []string{
+		"2024-11-04T01:30:00-05:00",
+	})
+
+	// Europe moves clocks at 01:00 UTC
+	before = time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)
+	expectRuns(t, "Berlin spring forward", Schedule{Type: "cron", Cron: "15 2 * * *", Timezone: "Europe/Berlin"}, before, []string{
+		"2024-03-31T03:00:00+02:00", "2024-04-01T02:15:00+02:00",
+	})
+	before = time.Date(2024, 10, 26, 12, 0, 0, 0, time.UTC)
+	expectRuns(t, "Berlin fall back", Schedule{Type: "cron", Cron: "15 2 * * *", Timezone: "Europe/Berlin"}, before, []string{
+		"2024-10-27T02:15:00+02:00", "2024-10-28T02:15:00+01:00",
+	})
+
+	// Intervals are fixed durations, unaffected by transitions
+	expectRuns(t, "interval across transition", Schedule{Type: "interval", IntervalMinutes: 60}, time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC), []string{
+		"2024-03-10T07:00:00Z", "2024-03-10T08:00:00Z",
+	})
+}
+
+func TestIntervalNextRuns(t *testing.T) {
+
+	after := time.Date(2024, 1, 31, 10, 7, 30, 0, time.UTC)
+	expectRuns(t, "every 30 minutes", Schedule{Type: "interval", IntervalMinutes: 30}, after, []string{
+		"2024-01-31T10:30:00Z", "2024-01-31T11:00:00Z", "2024-01-31T11:30:00Z",
+	})
+	expectRuns(t, "daily", Schedule{Type: "interval", IntervalMinutes: 24 * 60}, after, []string{
+		"2024-02-01T00:00:00Z", "2024-02-02T00:00:00Z",
+	})
+
+	// Runs are strictly after the given time
+	expectRuns(t, "on a run", Schedule{Type: "interval", IntervalMinutes: 30}, time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC), []string{
+		"2024-01-31T11:00:00Z",
+	})
+
+	runs, err := Schedule{Type: "manual"}.NextRuns(after, 5)
+	if err != nil || len(runs) != 0 {
+		t.Errorf("Expected manual schedules never to run, got %v %v", runs, err)
+	}
+
+	_, err = Schedule{Type: "interval", IntervalMinutes: 30}.NextRuns(after, MaxScheduledRuns + 1)
+	if err == nil {
+		t.Errorf("Expected error for too many runs, got nil")
+	}
+}
+
+func TestScheduleValidation(t *testing.T) {
+
+	now := time.Date(2024, 1, 31, 10, 7, 30, 0, time.UTC)
+
+	valid := []Schedule{
+		{Type: "manual"},
+		{Type: "interval", IntervalMinutes: 15},
+		{Type: "cron", Cron: "0 * * * *"},
+		{Type: "cron", Cron: "0 9 * * 1-5", Timezone: "Europe/Paris"},
+	}
+	for idx, schedule := range valid {
+		err := schedule.Validate(now)
+		if err != nil {
+			t.Errorf("Expected schedule at index %d to be valid, got %v", idx, err)
+		}
+	}
+
+	invalid := []Schedule{
+		{Type: ""},
+		{Type: "manual", IntervalMinutes: 5},
+		{Type: "manual", Cron: "* * * * *"},
+		{Type: "interval"},
+		{Type: "interval", IntervalMinutes: -5},
+		{Type: "interval", IntervalMinutes: 5, Timezone: "UTC"},
+		{Type: "cron"},
+		{Type: "cron", Cron: "61 * * * *"},
+		{Type: "cron", Cron: "0 * * * *", Timezone: "Mars/Olympus"},
+		{Type: "cron", Cron: "0 * * * *", IntervalMinutes: 5},
+		// Never runs
+		{Type: "cron", Cron: "0 0 30 2 *"},
+	}
+	for idx, schedule := range invalid {
+		err := schedule.Validate(now)
+		if err == nil {
+			t.Errorf("Expected error for schedule at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestFirstRunBetween(t *testing.T) {
+
+	schedule := Schedule{Type: "cron", Cron: "*/15 * * * *"}
+	from := time.Date(2024, 1, 31, 10, 15, 0, 0, time.UTC)
+
+	// The window includes its start and excludes its end
+	run, due, err := schedule.FirstRunBetween(from, from.Add(time.Minute))
+	if err != nil || !due || !run.Equal(from) {
+		t.Errorf("Expected run at %v, got %v %v %v", from, run, due, err)
+	}
+	_, due, _ = schedule.FirstRunBetween(from.Add(time.Second), from.Add(15 * time.Minute))
+	if due {
+		t.Errorf("Expected no run in the window")
+	}
+	_, due, _ = Schedule{Type: "manual"}.FirstRunBetween(from, from.Add(24 * time.Hour))
+	if due {
+		t.Errorf("Expected manual schedules never to be due")
+	}
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semantic version (https://semver.org) used to pin integrations to a definition version.
+// Build metadata is accepted but ignored for precedence, as required by the spec.
+type SemanticVersion struct {
+	Major int
+	Minor int
+	Patch int
+	PreRelease []string
+}
+
+func ParseSemanticVersion(s string) (SemanticVersion, error) {
+
+	var version SemanticVersion
+
+	// Drop build metadata
+	core := strings.SplitN(s, "+", 2)[0]
+
+	// Split pre-release identifiers
+	parts := strings.SplitN(core, "-", 2)
+	if len(parts) == 2 {
+		if parts[1] == "" {
+			return version, fmt.Errorf("Invalid version %s: empty pre-release", s)
+		}
+		version.PreRelease = strings.Split(parts[1], ".")
+		for _, id := range version.PreRelease {
+			if id == "" {
+				return version, fmt.Errorf("Invalid version %s: empty pre-release identifier", s)
+			}
+		}
+	}
+
+	numbers := strings.Split(parts[0], ".")
+	if len(numbers) != 3 {
+		return version, fmt.Errorf("Invalid version %s: expected MAJOR.MINOR.PATCH", s)
+	}
+
+	values := make([]int, 3)
+	for idx, n := range numbers {
+		value, err := strconv.Atoi(n)
+		if err != nil || value < 0 {
+			return version, fmt.Errorf("Invalid version %s: %s is not a valid number", s, n)
+		}
+		if len(n) > 1 && n[0] == '0' {
+			return version, fmt.Errorf("Invalid version %s: leading zeros are not allowed", s)
+		}
+		values[idx] = value
+	}
+	version.Major, version.Minor, version.Patch = values[0], values[1], values[2]
+
+	return version, nil
+}
+
+func (v SemanticVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.PreRelease) > 0 {
+		s += "-" + strings.Join(v.PreRelease, ".")
+	}
+	return s
+}
+
+// Returns -1, 0 or 1 if v is lower, equal or greater than o
+func (v SemanticVersion) Compare(o SemanticVersion) int {
+
+	for _, pair := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] != pair[1] {
+			return compareInts(pair[0], pair[1])
+		}
+	}
+
+	// A version without pre-release has higher precedence
+	if len(v.PreRelease) == 0 || len(o.PreRelease) == 0 {
+		return compareInts(len(o.PreRelease), len(v.PreRelease))
+	}
+
+	for idx := 0; idx < len(v.PreRelease) && idx < len(o.PreRelease); idx++ {
+		a, b := v.PreRelease[idx], o.PreRelease[idx]
+		if a == b {
+			continue
+		}
+		aNum, aErr := strconv.Atoi(a)
+		bNum, bErr := strconv.Atoi(b)
+		switch {
+		case aErr == nil && bErr == nil:
+			return compareInts(aNum, bNum)
+		case aErr == nil:
+			// Numeric identifiers have lower precedence than alphanumeric
+			return -1
+		case bErr == nil:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	return compareInts(len(v.PreRelease), len(o.PreRelease))
+}
+
+// Compares two version strings. Fails if any of them is not a valid semantic version
+func CompareVersions(a string, b string) (int, error) {
+	va, err := ParseSemanticVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := ParseSemanticVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+func compareInts(a int, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
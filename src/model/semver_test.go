@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestParseSemanticVersion(t *testing.T) {
+
+	valid := []string{"1.0.0", "0.1.0", "10.20.30", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0+build.5", "1.0.0-rc.1+build"}
+	for _, v := range valid {
+		_, err := ParseSemanticVersion(v)
+		if err != nil {
+			t.Errorf("Expected %s to be valid, got %v", v, err)
+		}
+	}
+
+	invalid := []string{"", "1", "1.0", "1.0.0.0", "a.b.c", "01.0.0", "1.0.0-", "1.0.0-alpha..1", "-1.0.0"}
+	for _, v := range invalid {
+		_, err := ParseSemanticVersion(v)
+		if err == nil {
+			t.Errorf("Expected %s to be invalid, got nil", v)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+
+	// Each version is lower than the next one, as in the semver spec example
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for idx := 0; idx < len(ordered) - 1; idx++ {
+		cmp, err := CompareVersions(ordered[idx], ordered[idx + 1])
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if cmp != -1 {
+			t.Errorf("Expected %s < %s, got %d", ordered[idx], ordered[idx + 1], cmp)
+		}
+
+		cmp, _ = CompareVersions(ordered[idx + 1], ordered[idx])
+		if cmp != 1 {
+			t.Errorf("Expected %s > %s, got %d", ordered[idx + 1], ordered[idx], cmp)
+		}
+	}
+
+	// Build metadata is ignored
+	cmp, _ := CompareVersions("1.0.0+build.1", "1.0.0+build.2")
+	if cmp != 0 {
+		t.Errorf("Expected build metadata to be ignored, got %d", cmp)
+	}
+}
@@ -0,0 +1,395 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Value shown in place of secrets to callers that can't read them
+const SecretMask = "**********"
+
+// Flat config.json read by Singer taps and targets
+// https://hub.meltano.com/singer/spec#config-files
+type SingerConfig map[string]interface{}
+
+// Returns a copy of the configuration with every secret value replaced by SecretMask,
+// including secrets of nested objects and of arrays of objects
+func (c IntegrationConfig) MaskSecrets(schema ConfigurationSchema) IntegrationConfig {
+	return c.replaceSecrets(schema, true)
+}
+
+// Returns a copy of the configuration without its secret values, e.g. to copy it to another workspace.
+// The copy may lack required secrets
+func (c IntegrationConfig) StripSecrets(schema ConfigurationSchema) IntegrationConfig {
+	return c.replaceSecrets(schema, false)
+}
+
+// Returns a copy of the configuration with the secrets sent back masked replaced by their stored values,
+// so clients can send back the configuration they were shown. Secrets of arrays of objects are matched
+// by index. Masked secrets without a stored value are left out
+func (c IntegrationConfig) KeepMaskedSecrets(stored IntegrationConfig, schema ConfigurationSchema) IntegrationConfig {
+
+	kept := IntegrationConfig{}
+	for key, value := range c {
+		kept[key] = value
+	}
+
+	for _, field := range schema {
+		value, ok := kept[field.Label]
+		if !ok || value == nil {
+			continue
+		}
+
+		if field.Secret {
+			if value != SecretMask {
+				continue
+			}
+			if storedValue, ok := stored[field.Label]; ok && storedValue != SecretMask {
+				kept[field.Label] = storedValue
+			} else {
+				delete(kept, field.Label)
+			}
+			continue
+		}
+		if field.Type != "object" {
+			continue
+		}
+
+		if !field.Array {
+			kept[field.Label] = keepObjectSecrets(field, value, stored[field.Label])
+			continue
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		storedItems, _ := stored[field.Label].([]interface{})
+		keptItems := make([]interface{}, len(items))
+		for idx, item := range items {
+			var storedItem interface{}
+			if idx < len(storedItems) {
+				storedItem = storedItems[idx]
+			}
+			keptItems[idx] = keepObjectSecrets(field, item, storedItem)
+		}
+		kept[field.Label] = keptItems
+	}
+
+	return kept
+}
+
+func keepObjectSecrets(f SchemaField, value interface{}, stored interface{}) interface{} {
+	object, ok := asConfig(value)
+	if !ok {
+		return value
+	}
+	storedObject, ok := asConfig(stored)
+	if !ok {
+		storedObject = IntegrationConfig{}
+	}
+	fields, err := f.ObjectSchema(object)
+	if err != nil {
+		fields = f.Fields
+	}
+	return object.KeepMaskedSecrets(storedObject, fields)
+}
+
+// Masks the secrets, or removes them
+func (c IntegrationConfig) replaceSecrets(schema ConfigurationSchema, mask bool) IntegrationConfig {
+
+	masked := IntegrationConfig{}
+	for key, value := range c {
+		masked[key] = value
+	}
+
+	for _, field := range schema {
+		value, ok := masked[field.Label]
+		if !ok || value == nil {
+			continue
+		}
+
+		if field.Secret {
+			if mask {
+				masked[field.Label] = SecretMask
+			} else {
+				delete(masked, field.Label)
+			}
+			continue
+		}
+		if field.Type != "object" {
+			continue
+		}
+
+		if !field.Array {
+			masked[field.Label] = maskObject(field, value, mask)
+			continue
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		maskedItems := make([]interface{}, len(items))
+		for idx, item := range items {
+			maskedItems[idx] = maskObject(field, item, mask)
+		}
+		masked[field.Label] = maskedItems
+	}
+
+	return masked
+}
+
+func maskObject(f SchemaField, value interface{}, mask bool) interface{} {
+	object, ok := asConfig(value)
+	if !ok {
+		return value
+	}
+	fields, err := f.ObjectSchema(object)
+	if err != nil {
+		fields = f.Fields
+	}
+	return object.replaceSecrets(fields, mask)
+}
+
+// Renders the configuration as a Singer config. Singer configs are flat, so the fields
+// of nested objects (including the discriminator of tagged unions) are moved to the top level.
+// Two fields ending up with the same key is an error. Arrays are kept as they are.
+// Unless resolveSecrets is set, secrets are masked.
+func (c IntegrationConfig) SingerConfig(schema ConfigurationSchema, resolveSecrets bool) (SingerConfig, error) {
+
+	if !resolveSecrets {
+		c = c.MaskSecrets(schema)
+	}
+
+	config := SingerConfig{}
+	paths := map[string]string{}
+	err := c.flattenSinger(schema, "", config, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c IntegrationConfig) flattenSinger(schema ConfigurationSchema, prefix string, config SingerConfig, paths map[string]string) error {
+
+	// Sorted so collisions are always reported the same way
+	labels := []string{}
+	for label := range c {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		value := c[label]
+		path := prefix + label
+
+		field, ok := schema.Field(label)
+		if object, isObject := asConfig(value); ok && isObject && field.Type == "object" && !field.Array {
+			fields, err := field.ObjectSchema(object)
+			if err != nil {
+				return fmt.Errorf("Invalid field %s: %v", path, err)
+			}
+			err = object.flattenSinger(fields, path + ".", config, paths)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if other, ok := paths[label]; ok {
+			return fmt.Errorf("Fields %s and %s both map to the Singer key %s", other, path, label)
+		}
+		paths[label] = path
+		config[label] = value
+	}
+
+	return nil
+}
+
+// catalog.json read by Singer taps, listing the streams to sync
+// https://hub.meltano.com/singer/spec#catalog-files
+type SingerCatalog struct {
+	Streams []SingerStream `json:"streams"`
+}
+
+type SingerStream struct {
+	TapStreamID string `json:"tap_stream_id"`
+	Stream string `json:"stream"`
+	Schema JSONSchema `json:"schema"`
+	KeyProperties []string `json:"key_properties"`
+	ReplicationMethod string `json:"replication_method,omitempty"`
+	ReplicationKey string `json:"replication_key,omitempty"`
+	Metadata []SingerMetadata `json:"metadata"`
+}
+
+type SingerMetadata struct {
+	Breadcrumb []string `json:"breadcrumb"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+var singerReplicationMethods = map[string]string{
+	"full_refresh": "FULL_TABLE",
+	"incremental": "INCREMENTAL",
+}
+
+// Renders the stream catalog of the definition as a Singer catalog, marking the streams
+// and fields selected by the integration. Field types aren't part of the catalog, so any value is accepted.
+func (i Integration) SingerCatalog(def IntegrationDefinition) SingerCatalog {
+
+	catalog := SingerCatalog{Streams: []SingerStream{}}
+
+	for _, stream := range def.Streams {
+		var selection *StreamSelection
+		for idx := range i.Streams {
+			if i.Streams[idx].Stream == stream.Name {
+				selection = &i.Streams[idx]
+			}
+		}
+
+		properties := map[string]interface{}{}
+		for _, field := range stream.Fields {
+			properties[field] = JSONSchema{}
+		}
+		singerStream := SingerStream{
+			TapStreamID: stream.Name,
+			Stream: stream.Name,
+			Schema: JSONSchema{"type": "object", "properties": properties},
+			KeyProperties: stream.PrimaryKey,
+		}
+
+		streamMetadata := map[string]interface{}{"selected": selection != nil}
+		if selection != nil {
+			singerStream.KeyProperties = selection.PrimaryKey
+			singerStream.ReplicationMethod = singerReplicationMethods[selection.SyncMode]
+			singerStream.ReplicationKey = selection.CursorField
+			streamMetadata["replication-method"] = singerStream.ReplicationMethod
+			if selection.CursorField != "" {
+				streamMetadata["replication-key"] = selection.CursorField
+			}
+		}
+		if singerStream.KeyProperties == nil {
+			singerStream.KeyProperties = []string{}
+		}
+		streamMetadata["table-key-properties"] = singerStream.KeyProperties
+		singerStream.Metadata = []SingerMetadata{{Breadcrumb: []string{}, Metadata: streamMetadata}}
+
+		for _, field := range stream.Fields {
+			selected := selection != nil && (len(selection.Fields) == 0 || containsString(selection.Fields, field))
+			singerStream.Metadata = append(singerStream.Metadata, SingerMetadata{
+				Breadcrumb: []string{"properties", field},
+				Metadata: map[string]interface{}{"selected": selected},
+			})
+		}
+
+		catalog.Streams = append(catalog.Streams, singerStream)
+	}
+
+	return catalog
+}
+
+// Infers a draft schema from a sample Singer config. Fields set in the sample are required,
+// and fields that look like credentials are marked as secrets. Values the schema can't
+// describe are reported as warnings and typed as strings, for the author to review.
+func InferSingerSchema(config map[string]interface{}) (ConfigurationSchema, []string) {
+	warnings := []string{}
+	schema := inferSingerObject(config, "", &warnings)
+	return schema, warnings
+}
+
+func inferSingerObject(config map[string]interface{}, prefix string, warnings *[]string) ConfigurationSchema {
+
+	labels := []string{}
+	for label := range config {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	schema := ConfigurationSchema{}
+	for _, label := range labels {
+		value := config[label]
+		field := inferSingerField(label, value, prefix + label, warnings)
+
+		// Null values and empty arrays are read as optional
+		items, isArray := value.([]interface{})
+		field.Required = value != nil && (!isArray || len(items) > 0)
+		schema = append(schema, field)
+	}
+
+	return schema
+}
+
+func inferSingerField(label string, value interface{}, path string, warnings *[]string) SchemaField {
+
+	field := SchemaField{Label: label}
+
+	switch v := value.(type) {
+	case string:
+		field.Type = "string"
+		field.Secret = singerSecretName(label)
+	case bool:
+		field.Type = "boolean"
+	case float64:
+		field.Type = "float"
+		if v == float64(int(v)) {
+			field.Type = "int"
+		}
+	case map[string]interface{}:
+		field.Type = "object"
+		field.Fields = inferSingerObject(v, path + ".", warnings)
+	case []interface{}:
+		if len(v) == 0 {
+			*warnings = append(*warnings, fmt.Sprintf("%s is an empty array, assuming strings", path))
+			field.Type = "string"
+			field.Array = true
+			return field
+		}
+		field = inferSingerField(label, v[0], path + "[0]", warnings)
+		if field.Array {
+			*warnings = append(*warnings, fmt.Sprintf("%s: nested arrays are not supported, assuming strings", path))
+			field = SchemaField{Label: label, Type: "string"}
+		}
+		field.Array = true
+
+		// Numbers of mixed kinds are floats
+		if field.Type == "int" {
+			for _, item := range v {
+				if number, ok := item.(float64); ok && number != float64(int(number)) {
+					field.Type = "float"
+				}
+			}
+		}
+	case nil:
+		*warnings = append(*warnings, fmt.Sprintf("%s is null, assuming a string", path))
+		field.Type = "string"
+		field.Secret = singerSecretName(label)
+	default:
+		*warnings = append(*warnings, fmt.Sprintf("%s has an unsupported value, assuming a string", path))
+		field.Type = "string"
+	}
+
+	return field
+}
+
+// Names used by Singer taps and targets for credentials
+var singerSecretNames = []string{"password", "secret", "token", "private_key", "api_key", "apikey", "credentials"}
+
+func singerSecretName(label string) bool {
+	label = strings.ToLower(label)
+	for _, name := range singerSecretNames {
+		if strings.Contains(label, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func asConfig(value interface{}) (IntegrationConfig, bool) {
+	switch v := value.(type) {
+	case IntegrationConfig:
+		return v, true
+	case map[string]interface{}:
+		return IntegrationConfig(v), true
+	}
+	return nil, false
+}
@@ -0,0 +1,228 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func singerSchema() ConfigurationSchema {
+	return ConfigurationSchema{
+		SchemaField{Label: "host", Type: "string", Required: true},
+		SchemaField{Label: "port", Type: "int"},
+		SchemaField{Label: "password", Type: "string", Secret: true},
+		SchemaField{Label: "credentials", Type: "object", Discriminator: "auth_type",
+			Fields: ConfigurationSchema{
+				SchemaField{Label: "user_agent", Type: "string"},
+			},
+			OneOf: []SchemaVariant{
+				{Tag: "oauth", Fields: ConfigurationSchema{
+					SchemaField{Label: "client_id", Type: "string", Required: true},
+					SchemaField{Label: "refresh_token", Type: "string", Required: true, Secret: true},
+				}},
+			},
+		},
+		SchemaField{Label: "reports", Type: "object", Array: true, Fields: ConfigurationSchema{
+			SchemaField{Label: "name", Type: "string", Required: true},
+			SchemaField{Label: "key", Type: "string", Secret: true},
+		}},
+	}
+}
+
+func TestSingerConfig(t *testing.T) {
+
+	schema := singerSchema()
+	config := IntegrationConfig{
+		"host": "localhost",
+		"port": 5432,
+		"password": "hunter2",
+		"credentials": IntegrationConfig{"auth_type": "oauth", "client_id": "id", "refresh_token": "token"},
+		"reports": []interface{}{IntegrationConfig{"name": "daily", "key": "abc"}},
+	}
+	err := config.Validate(schema)
+	if err != nil {
+		t.Fatalf("Invalid test data: %v", err)
+	}
+
+	resolved, err := config.SingerConfig(schema, true)
+	if err != nil {
+		t.Fatalf("Expected config to be exported, got %v", err)
+	}
+	expected := SingerConfig{
+		"host": "localhost",
+		"port": 5432,
+		"password": "hunter2",
+		"auth_type": "oauth",
+		"client_id": "id",
+		"refresh_token": "token",
+		"reports": []interface{}{IntegrationConfig{"name": "daily", "key": "abc"}},
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("Expected %v, got %v", expected, resolved)
+	}
+
+	masked, err := config.SingerConfig(schema, false)
+	if err != nil {
+		t.Fatalf("Expected config to be exported, got %v", err)
+	}
+	if masked["password"] != SecretMask || masked["refresh_token"] != SecretMask {
+		t.Errorf("Expected secrets to be masked, got %v", masked)
+	}
+	if masked["client_id"] != "id" {
+		t.Errorf("Expected other fields to be kept, got %v", masked)
+	}
+	report := masked["reports"].([]interface{})[0].(IntegrationConfig)
+	if report["key"] != SecretMask || report["name"] != "daily" {
+		t.Errorf("Expected secrets of array items to be masked, got %v", report)
+	}
+
+	// Masking works on a copy
+	if config["password"] != "hunter2" || config["credentials"].(IntegrationConfig)["refresh_token"] != "token" {
+		t.Errorf("Expected original config to be unchanged, got %v", config)
+	}
+}
+
+func TestStripSecrets(t *testing.T) {
+
+	schema := singerSchema()
+	config := IntegrationConfig{
+		"host": "localhost",
+		"password": "hunter2",
+		"credentials": IntegrationConfig{"auth_type": "oauth", "client_id": "id", "refresh_token": "token"},
+		"reports": []interface{}{IntegrationConfig{"name": "daily", "key": "abc"}},
+	}
+
+	stripped := config.StripSecrets(schema)
+	expected := IntegrationConfig{
+		"host": "localhost",
+		"credentials": IntegrationConfig{"auth_type": "oauth", "client_id": "id"},
+		"reports": []interface{}{IntegrationConfig{"name": "daily"}},
+	}
+	if !reflect.DeepEqual(stripped, expected) {
+		t.Errorf("Expected %v, got %v", expected, stripped)
+	}
+	if config["password"] != "hunter2" || config["credentials"].(IntegrationConfig)["refresh_token"] != "token" {
+		t.Errorf("Expected original config to be unchanged, got %v", config)
+	}
+}
+
+func TestKeepMaskedSecrets(t *testing.T) {
+
+	schema := singerSchema()
+	stored := IntegrationConfig{
+		"host": "localhost",
+		"password": "hunter2",
+		"credentials": IntegrationConfig{"auth_type": "oauth", "client_id": "id", "refresh_token": "token"},
+		"reports": []interface{}{IntegrationConfig{"name": "daily", "key": "abc"}},
+	}
+
+	// Sent back as shown, with a new host and a second report
+	sent := stored.MaskSecrets(schema)
+	sent["host"] = "db"
+	sent["reports"] = append(sent["reports"].([]interface{}), IntegrationConfig{"name": "weekly", "key": SecretMask})
+
+	kept := sent.KeepMaskedSecrets(stored, schema)
+	expected := IntegrationConfig{
+		"host": "db",
+		"password": "hunter2",
+		"credentials": IntegrationConfig{"auth_type": "oauth", "client_id": "id", "refresh_token": "token"},
+		"reports": []interface{}{IntegrationConfig{"name": "daily", "key": "abc"}, IntegrationConfig{"name": "weekly"}},
+	}
+	if !reflect.DeepEqual(kept, expected) {
+		t.Errorf("Expected %v, got %v", expected, kept)
+	}
+
+	changed := IntegrationConfig{"host": "db", "password": "new"}.KeepMaskedSecrets(stored, schema)
+	if changed["password"] != "new" {
+		t.Errorf("Expected new secrets to replace the stored ones, got %v", changed)
+	}
+}
+
+func TestMaskedIntegration(t *testing.T) {
+
+	integration := Integration{ Configuration: IntegrationConfig{"host": "localhost", "password": "hunter2"} }
+	if masked := integration.Masked(); len(masked.Configuration) != 0 {
+		t.Errorf("Expected the configuration to be left out without a definition, got %v", masked.Configuration)
+	}
+
+	masked := integration.WithDefinition(IntegrationDefinition{ ID: "definition", ConfigurationSchema: singerSchema() }).Masked()
+	if masked.Configuration["password"] != SecretMask || masked.Configuration["host"] != "localhost" {
+		t.Errorf("Expected secrets to be masked, got %v", masked.Configuration)
+	}
+	if integration.Configuration["password"] != "hunter2" {
+		t.Errorf("Expected original config to be unchanged")
+	}
+}
+
+func TestSingerConfigCollision(t *testing.T) {
+
+	schema := ConfigurationSchema{
+		SchemaField{Label: "user_agent", Type: "string"},
+		SchemaField{Label: "credentials", Type: "object", Fields: ConfigurationSchema{
+			SchemaField{Label: "user_agent", Type: "string"},
+		}},
+	}
+	config := IntegrationConfig{"user_agent": "a", "credentials": IntegrationConfig{"user_agent": "b"}}
+
+	_, err := config.SingerConfig(schema, true)
+	if err == nil {
+		t.Errorf("Expected error for colliding keys, got nil")
+	}
+}
+
+func TestInferSingerSchema(t *testing.T) {
+
+	raw := `{
+		"start_date": "2020-01-01T00:00:00Z",
+		"client_secret": "secret",
+		"api_token": "token",
+		"page_size": 100,
+		"ratio": 0.5,
+		"include_archived": false,
+		"account_ids": ["1", "2"],
+		"weights": [1, 2.5],
+		"tags": [],
+		"proxy": {"host": "proxy", "password": "pass"},
+		"region": null
+	}`
+	var sample map[string]interface{}
+	err := json.Unmarshal([]byte(raw), &sample)
+	if err != nil {
+		t.Fatalf("Invalid test data: %v", err)
+	}
+
+	schema, warnings := InferSingerSchema(sample)
+	expected := ConfigurationSchema{
+		SchemaField{Label: "account_ids", Type: "string", Required: true, Array: true},
+		SchemaField{Label: "api_token", Type: "string", Required: true, Secret: true},
+		SchemaField{Label: "client_secret", Type: "string", Required: true, Secret: true},
+		SchemaField{Label: "include_archived", Type: "boolean", Required: true},
+		SchemaField{Label: "page_size", Type: "int", Required: true},
+		SchemaField{Label: "proxy", Type: "object", Required: true, Fields: ConfigurationSchema{
+			SchemaField{Label: "host", Type: "string", Required: true},
+			SchemaField{Label: "password", Type: "string", Required: true, Secret: true},
+		}},
+		SchemaField{Label: "ratio", Type: "float", Required: true},
+		SchemaField{Label: "region", Type: "string"},
+		SchemaField{Label: "start_date", Type: "string", Required: true},
+		SchemaField{Label: "tags", Type: "string", Array: true},
+		SchemaField{Label: "weights", Type: "float", Required: true, Array: true},
+	}
+	if !reflect.DeepEqual(normalizeForComparison(schema), normalizeForComparison(expected)) {
+		t.Errorf("Expected %v, got %v", expected, schema)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("Expected warnings for the empty array and the null value, got %v", warnings)
+	}
+
+	// The sample is valid against its own schema
+	_, err = NewIntegrationDefinition("tap", "source", schema)
+	if err != nil {
+		t.Fatalf("Expected inferred schema to be valid, got %v", err)
+	}
+	sample["region"] = "us"
+	err = IntegrationConfig(sample).Normalize(schema).Validate(schema)
+	if err != nil {
+		t.Errorf("Expected sample to be valid against the inferred schema, got %v", err)
+	}
+}
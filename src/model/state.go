@@ -0,0 +1,119 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type ConnectorState struct {
+	/*
+		Bookmarks an integration persists between runs, e.g. the cursor of incremental streams.
+		Writes are compare-and-swap: they must carry the version they were based on,
+		and the version is incremented on each write.
+	*/
+	IntegrationID string `json:"integration_id" firestore:"integration_id"`
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	Global map[string]interface{} `json:"global" firestore:"global"` // State shared by every stream
+	Streams map[string]map[string]interface{} `json:"streams" firestore:"streams"` // [stream] => state
+	Version int64 `json:"version" firestore:"version"` // 0 until the first write
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
+	UpdatedBy string `json:"updated_by,omitempty" firestore:"updated_by,omitempty"` // ID of the user of the last write
+}
+
+// Explicit reset of the state by a workspace editor. Keeps the state it replaced
+type StateReset struct {
+	ID string `json:"id" firestore:"id"`
+	IntegrationID string `json:"integration_id" firestore:"integration_id"`
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	Streams []string `json:"streams" firestore:"streams"` // Empty when the whole state was reset
+	Previous ConnectorState `json:"previous" firestore:"previous"`
+	ResetAt time.Time `json:"reset_at" firestore:"reset_at"`
+	ResetBy string `json:"reset_by" firestore:"reset_by"`
+}
+
+// Returned when a write is based on a version that is not the current one anymore
+type StateConflictError struct {
+	IntegrationID string
+	Expected int64
+	Current int64
+}
+
+func (e *StateConflictError) Error() string {
+	return fmt.Sprintf("State of integration %s is at version %d, the write expected version %d", e.IntegrationID, e.Current, e.Expected)
+}
+
+// State of an integration that was never written
+func NewConnectorState(integration Integration) ConnectorState {
+	return ConnectorState{ integration.ID, integration.WorkspaceID, map[string]interface{}{}, map[string]map[string]interface{}{}, 0, time.Time{}, "" }
+}
+
+// Replaces the whole state
+func (s ConnectorState) WithState(global map[string]interface{}, streams map[string]map[string]interface{}) ConnectorState {
+	if global == nil {
+		global = map[string]interface{}{}
+	}
+	if streams == nil {
+		streams = map[string]map[string]interface{}{}
+	}
+	s.Global = global
+	s.Streams = streams
+	return s
+}
+
+// Replaces the state of a single stream. Nil removes it
+func (s ConnectorState) WithStream(stream string, state map[string]interface{}) ConnectorState {
+	streams := map[string]map[string]interface{}{}
+	for name, value := range s.Streams {
+		streams[name] = value
+	}
+	if state == nil {
+		delete(streams, stream)
+	} else {
+		streams[stream] = state
+	}
+	s.Streams = streams
+	return s
+}
+
+// Clears the state of the given streams, or the whole state when none is given
+func (s ConnectorState) Reset(streams []string) ConnectorState {
+	if len(streams) == 0 {
+		return s.WithState(nil, nil)
+	}
+	for _, stream := range streams {
+		s = s.WithStream(stream, nil)
+	}
+	return s
+}
+
+func (s ConnectorState) Validate(sizeLimit int) error {
+
+	for name := range s.Streams {
+		if name == "" {
+			return errors.New("Stream name is required")
+		}
+	}
+
+	if sizeLimit > 0 {
+		size, err := s.Size()
+		if err != nil {
+			return err
+		}
+		if size > sizeLimit {
+			return fmt.Errorf("State is %d bytes, the limit is %d bytes", size, sizeLimit)
+		}
+	}
+
+	return nil
+}
+
+// Size in bytes of the JSON encoding of the global and stream states
+func (s ConnectorState) Size() (int, error) {
+	data, err := json.Marshal(map[string]interface{}{ "global": s.Global, "streams": s.Streams })
+	if err != nil {
+		return 0, fmt.Errorf("State can't be encoded: %v", err)
+	}
+	return len(data), nil
+}
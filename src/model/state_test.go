@@ -0,0 +1,57 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestConnectorState(t *testing.T) {
+
+	state := NewConnectorState(Integration{ID: "source", WorkspaceID: "workspace"})
+	if state.Version != 0 || len(state.Global) != 0 || len(state.Streams) != 0 {
+		t.Fatalf("Expected empty state at version 0, got %+v", state)
+	}
+
+	state = state.WithState(map[string]interface{}{ "since": "2024-01-01" }, nil)
+	state = state.WithStream("users", map[string]interface{}{ "cursor": 10 })
+	edited := state.WithStream("orders", map[string]interface{}{ "cursor": 20 })
+	if len(state.Streams) != 1 || len(edited.Streams) != 2 {
+		t.Errorf("Expected stream edits not to change the original state, got %+v and %+v", state.Streams, edited.Streams)
+	}
+
+	reset := edited.Reset([]string{"users", "unknown"})
+	if _, ok := reset.Streams["users"]; ok || reset.Streams["orders"] == nil || reset.Global["since"] != "2024-01-01" {
+		t.Errorf("Expected only the users stream to be reset, got %+v", reset)
+	}
+	if len(edited.Streams) != 2 {
+		t.Errorf("Expected reset not to change the original state, got %+v", edited.Streams)
+	}
+
+	reset = edited.Reset(nil)
+	if len(reset.Global) != 0 || len(reset.Streams) != 0 {
+		t.Errorf("Expected the whole state to be reset, got %+v", reset)
+	}
+}
+
+func TestConnectorStateSizeLimit(t *testing.T) {
+
+	state := NewConnectorState(Integration{ID: "source"}).WithStream("users", map[string]interface{}{ "cursor": "2024-01-01T00:00:00Z" })
+	size, err := state.Size()
+	if err != nil {
+		t.Fatalf("Expected state to be encoded, got %v", err)
+	}
+
+	if err := state.Validate(0); err != nil {
+		t.Errorf("Expected no limit to accept the state, got %v", err)
+	}
+	if err := state.Validate(size); err != nil {
+		t.Errorf("Expected state at the limit to be valid, got %v", err)
+	}
+	if err := state.Validate(size - 1); err == nil {
+		t.Errorf("Expected error for state over the limit, got nil")
+	}
+
+	invalid := state.WithStream("", map[string]interface{}{})
+	if err := invalid.Validate(0); err == nil {
+		t.Errorf("Expected error for unnamed stream, got nil")
+	}
+}
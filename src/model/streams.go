@@ -0,0 +1,187 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Stream (table or endpoint) a source definition can read
+type Stream struct {
+	Name string `json:"name" firestore:"name"`
+	Fields []string `json:"fields" firestore:"fields"`
+	SyncModes []string `json:"sync_modes" firestore:"sync_modes"` // "full_refresh" and/or "incremental"
+	DefaultCursorField string `json:"default_cursor_field,omitempty" firestore:"default_cursor_field,omitempty"` // Used by incremental syncs when none is selected
+	PrimaryKey []string `json:"primary_key,omitempty" firestore:"primary_key,omitempty"` // Used when the selection doesn't set one
+}
+
+// Stream chosen for an integration, with how it should be synced
+type StreamSelection struct {
+	Stream string `json:"stream" firestore:"stream"`
+	SyncMode string `json:"sync_mode" firestore:"sync_mode"`
+	CursorField string `json:"cursor_field,omitempty" firestore:"cursor_field,omitempty"` // Only for incremental syncs
+	PrimaryKey []string `json:"primary_key,omitempty" firestore:"primary_key,omitempty"`
+	Fields []string `json:"fields,omitempty" firestore:"fields,omitempty"` // Empty selects every field
+}
+
+var syncModes = map[string]bool{
+	"full_refresh": true,
+	"incremental": true,
+}
+
+func (s Stream) Validate() error {
+
+	if s.Name == "" {
+		return errors.New("Stream name is required")
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("Stream %s has no fields", s.Name)
+	}
+	err := uniqueStrings(s.Fields)
+	if err != nil {
+		return fmt.Errorf("Invalid fields for stream %s: %v", s.Name, err)
+	}
+
+	if len(s.SyncModes) == 0 {
+		return fmt.Errorf("Stream %s has no sync modes", s.Name)
+	}
+	for _, mode := range s.SyncModes {
+		if !syncModes[mode] {
+			return fmt.Errorf("Invalid sync mode %s for stream %s. Valid modes are \"full_refresh\" and \"incremental\"", mode, s.Name)
+		}
+	}
+
+	if s.DefaultCursorField != "" && !containsString(s.Fields, s.DefaultCursorField) {
+		return fmt.Errorf("Default cursor field %s is not a field of stream %s", s.DefaultCursorField, s.Name)
+	}
+	for _, key := range s.PrimaryKey {
+		if !containsString(s.Fields, key) {
+			return fmt.Errorf("Primary key %s is not a field of stream %s", key, s.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateCatalog(streams []Stream) error {
+
+	names := map[string]bool{}
+	for _, stream := range streams {
+		err := stream.Validate()
+		if err != nil {
+			return err
+		}
+		if names[stream.Name] {
+			return fmt.Errorf("Stream %s is duplicated", stream.Name)
+		}
+		names[stream.Name] = true
+	}
+
+	return nil
+}
+
+// Returns the stream of the catalog with the given name
+func (d IntegrationDefinition) Stream(name string) (Stream, bool) {
+	for _, stream := range d.Streams {
+		if stream.Name == name {
+			return stream, true
+		}
+	}
+	var result Stream
+	return result, false
+}
+
+// Checks the selection against the catalog of the definition, and fills in the cursor
+// field and primary key of the stream when the selection doesn't set them
+func (d IntegrationDefinition) ResolveStreamSelection(selection []StreamSelection) ([]StreamSelection, error) {
+
+	resolved := []StreamSelection{}
+	selected := map[string]bool{}
+
+	for _, s := range selection {
+		stream, ok := d.Stream(s.Stream)
+		if !ok {
+			return nil, fmt.Errorf("Stream %s is not in the catalog of %s version %s", s.Stream, d.Name, d.Version)
+		}
+		if selected[s.Stream] {
+			return nil, fmt.Errorf("Stream %s is selected more than once", s.Stream)
+		}
+		selected[s.Stream] = true
+
+		r, err := s.resolve(stream)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid selection for stream %s: %v", s.Stream, err)
+		}
+		resolved = append(resolved, r)
+	}
+
+	return resolved, nil
+}
+
+func (s StreamSelection) resolve(stream Stream) (StreamSelection, error) {
+
+	if !containsString(stream.SyncModes, s.SyncMode) {
+		return s, fmt.Errorf("Sync mode %s is not supported. Supported modes are %v", s.SyncMode, stream.SyncModes)
+	}
+
+	err := uniqueStrings(s.Fields)
+	if err != nil {
+		return s, err
+	}
+	for _, field := range s.Fields {
+		if !containsString(stream.Fields, field) {
+			return s, fmt.Errorf("Unknown field %s", field)
+		}
+	}
+	// Fields the sync depends on must be read
+	isSelected := func(field string) bool {
+		return len(s.Fields) == 0 || containsString(s.Fields, field)
+	}
+
+	switch s.SyncMode {
+	case "incremental":
+		if s.CursorField == "" {
+			s.CursorField = stream.DefaultCursorField
+		}
+		if s.CursorField == "" {
+			return s, errors.New("Incremental syncs require a cursor field")
+		}
+		if !containsString(stream.Fields, s.CursorField) {
+			return s, fmt.Errorf("Unknown cursor field %s", s.CursorField)
+		}
+		if !isSelected(s.CursorField) {
+			return s, fmt.Errorf("Cursor field %s is not selected", s.CursorField)
+		}
+	case "full_refresh":
+		if s.CursorField != "" {
+			return s, errors.New("Full refresh syncs don't have a cursor field")
+		}
+	}
+
+	if len(s.PrimaryKey) == 0 {
+		s.PrimaryKey = stream.PrimaryKey
+	}
+	for _, key := range s.PrimaryKey {
+		if !containsString(stream.Fields, key) {
+			return s, fmt.Errorf("Unknown primary key field %s", key)
+		}
+		if !isSelected(key) {
+			return s, fmt.Errorf("Primary key field %s is not selected", key)
+		}
+	}
+
+	return s, nil
+}
+
+func uniqueStrings(values []string) error {
+	seen := map[string]bool{}
+	for _, value := range values {
+		if value == "" {
+			return errors.New("Empty name")
+		}
+		if seen[value] {
+			return fmt.Errorf("%s is duplicated", value)
+		}
+		seen[value] = true
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func catalogDefinition(t *testing.T) IntegrationDefinition {
+
+	def, err := NewIntegrationDefinition("crm", "source", ConfigurationSchema{})
+	if err != nil {
+		t.Fatalf("Invalid test data: %v", err)
+	}
+
+	def, err = def.WithStreams([]Stream{
+		{Name: "contacts", Fields: []string{"id", "email", "updated_at"}, SyncModes: []string{"full_refresh", "incremental"}, DefaultCursorField: "updated_at", PrimaryKey: []string{"id"}},
+		{Name: "deals", Fields: []string{"id", "amount", "closed_at"}, SyncModes: []string{"full_refresh", "incremental"}},
+		{Name: "owners", Fields: []string{"id", "name"}, SyncModes: []string{"full_refresh"}},
+	})
+	if err != nil {
+		t.Fatalf("Invalid test data: %v", err)
+	}
+
+	return def
+}
+
+func TestStreamCatalogValidation(t *testing.T) {
+
+	def := catalogDefinition(t)
+
+	invalid := [][]Stream{
+		{{Name: "", Fields: []string{"id"}, SyncModes: []string{"full_refresh"}}},
+		{{Name: "a", SyncModes: []string{"full_refresh"}}},
+		{{Name: "a", Fields: []string{"id", "id"}, SyncModes: []string{"full_refresh"}}},
+		{{Name: "a", Fields: []string{"id"}}},
+		{{Name: "a", Fields: []string{"id"}, SyncModes: []string{"cdc"}}},
+		{{Name: "a", Fields: []string{"id"}, SyncModes: []string{"incremental"}, DefaultCursorField: "updated_at"}},
+		{{Name: "a", Fields: []string{"id"}, SyncModes: []string{"full_refresh"}, PrimaryKey: []string{"key"}}},
+		{
+			{Name: "a", Fields: []string{"id"}, SyncModes: []string{"full_refresh"}},
+			{Name: "a", Fields: []string{"id"}, SyncModes: []string{"full_refresh"}},
+		},
+	}
+	for idx, streams := range invalid {
+		_, err := def.WithStreams(streams)
+		if err == nil {
+			t.Errorf("Expected error for catalog at index %d, got nil", idx)
+		}
+	}
+
+	// Destinations don't read streams
+	destination, _ := NewIntegrationDefinition("warehouse", "destination", ConfigurationSchema{})
+	_, err := destination.WithStreams(def.Streams)
+	if err == nil {
+		t.Errorf("Expected error for destination with streams, got nil")
+	}
+
+	// New versions keep the catalog
+	next, err := def.NewVersion("1.1.0", ConfigurationSchema{}, nil)
+	if err != nil {
+		t.Fatalf("Expected new version, got %v", err)
+	}
+	if !reflect.DeepEqual(next.Streams, def.Streams) {
+		t.Errorf("Expected catalog to be kept, got %v", next.Streams)
+	}
+}
+
+func TestStreamSelection(t *testing.T) {
+
+	def := catalogDefinition(t)
+
+	resolved, err := def.ResolveStreamSelection([]StreamSelection{
+		{Stream: "contacts", SyncMode: "incremental"},
+		{Stream: "deals", SyncMode: "incremental", CursorField: "closed_at", PrimaryKey: []string{"id"}, Fields: []string{"id", "closed_at"}},
+		{Stream: "owners", SyncMode: "full_refresh"},
+	})
+	if err != nil {
+		t.Fatalf("Expected selection to be valid, got %v", err)
+	}
+
+	// Defaults come from the catalog
+	expected := StreamSelection{Stream: "contacts", SyncMode: "incremental", CursorField: "updated_at", PrimaryKey: []string{"id"}}
+	if !reflect.DeepEqual(resolved[0], expected) {
+		t.Errorf("Expected %v, got %v", expected, resolved[0])
+	}
+	if resolved[2].PrimaryKey != nil || resolved[2].CursorField != "" {
+		t.Errorf("Expected no cursor or primary key for owners, got %v", resolved[2])
+	}
+
+	invalid := [][]StreamSelection{
+		// Unknown stream
+		{{Stream: "tickets", SyncMode: "full_refresh"}},
+		// Selected twice
+		{{Stream: "owners", SyncMode: "full_refresh"}, {Stream: "owners", SyncMode: "full_refresh"}},
+		// Unsupported sync mode
+		{{Stream: "owners", SyncMode: "incremental", CursorField: "id"}},
+		{{Stream: "owners", SyncMode: "cdc"}},
+		// Incremental without cursor
+		{{Stream: "deals", SyncMode: "incremental"}},
+		// Unknown cursor
+		{{Stream: "deals", SyncMode: "incremental", CursorField: "updated_at"}},
+		// Cursor not selected
+		{{Stream: "deals", SyncMode: "incremental", CursorField: "closed_at", Fields: []string{"id"}}},
+		// Cursor on a full refresh
+		{{Stream: "deals", SyncMode: "full_refresh", CursorField: "closed_at"}},
+		// Unknown field
+		{{Stream: "deals", SyncMode: "full_refresh", Fields: []string{"probability"}}},
+		// Primary key not selected
+		{{Stream: "contacts", SyncMode: "full_refresh", Fields: []string{"email"}}},
+		{{Stream: "deals", SyncMode: "full_refresh", PrimaryKey: []string{"deal_id"}}},
+	}
+	for idx, selection := range invalid {
+		_, err := def.ResolveStreamSelection(selection)
+		if err == nil {
+			t.Errorf("Expected error for selection at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestStreamSelectionUpgrade(t *testing.T) {
+
+	v1 := catalogDefinition(t)
+	v1.ID = "crm"
+	v1.Status = "published"
+
+	integration, err := NewIntegration("crm", "workspace", v1, IntegrationConfig{}, nil)
+	if err != nil {
+		t.Fatalf("Invalid test data: %v", err)
+	}
+	integration, err = integration.SelectStreams(v1, []StreamSelection{{Stream: "owners", SyncMode: "full_refresh"}})
+	if err != nil {
+		t.Fatalf("Expected selection to be valid, got %v", err)
+	}
+
+	// Version 2 keeps the stream, version 3 removes it
+	v2, _ := v1.NewVersion("2.0.0", ConfigurationSchema{}, nil)
+	v2.Status = "published"
+	v3, _ := v2.NewVersion("3.0.0", ConfigurationSchema{}, nil)
+	v3, _ = v3.WithStreams(v1.Streams[:2])
+	v3.Status = "published"
+	history := []IntegrationDefinition{v1, v2, v3}
+
+	upgraded, err := integration.Upgrade(history, "2.0.0", nil)
+	if err != nil {
+		t.Errorf("Expected upgrade to keep the selection, got %v", err)
+	}
+	if len(upgraded.Streams) != 1 {
+		t.Errorf("Expected selection to be kept, got %v", upgraded.Streams)
+	}
+
+	_, err = integration.Upgrade(history, "3.0.0", nil)
+	if err == nil {
+		t.Errorf("Expected upgrade to fail when a selected stream is removed, got nil")
+	}
+}
+
+func TestSingerCatalog(t *testing.T) {
+
+	def := catalogDefinition(t)
+	integration := Integration{DefinitionID: def.ID}
+	integration, err := integration.SelectStreams(def, []StreamSelection{
+		{Stream: "contacts", SyncMode: "incremental", Fields: []string{"id", "updated_at"}},
+	})
+	if err != nil {
+		t.Fatalf("Invalid test data: %v", err)
+	}
+
+	catalog := integration.SingerCatalog(def)
+	if len(catalog.Streams) != 3 {
+		t.Fatalf("Expected every stream of the catalog, got %v", catalog.Streams)
+	}
+
+	contacts := catalog.Streams[0]
+	if contacts.ReplicationMethod != "INCREMENTAL" || contacts.ReplicationKey != "updated_at" || !reflect.DeepEqual(contacts.KeyProperties, []string{"id"}) {
+		t.Errorf("Unexpected replication settings %+v", contacts)
+	}
+	if contacts.Metadata[0].Metadata["selected"] != true || contacts.Metadata[0].Metadata["replication-key"] != "updated_at" {
+		t.Errorf("Expected stream metadata to select the stream, got %v", contacts.Metadata[0])
+	}
+	selected := map[string]bool{}
+	for _, metadata := range contacts.Metadata[1:] {
+		selected[metadata.Breadcrumb[1]] = metadata.Metadata["selected"].(bool)
+	}
+	if !reflect.DeepEqual(selected, map[string]bool{"id": true, "email": false, "updated_at": true}) {
+		t.Errorf("Unexpected field selection %v", selected)
+	}
+
+	if catalog.Streams[1].Metadata[0].Metadata["selected"] != false {
+		t.Errorf("Expected deals not to be selected, got %v", catalog.Streams[1].Metadata[0])
+	}
+}
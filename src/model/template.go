@@ -0,0 +1,296 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reusable set of integrations and connections, e.g. the ones an agency sets up for every new client.
+// Names and string values of the configurations may hold parameter placeholders, like "${account_id}",
+// replaced by the values given when a workspace is created from the template
+type WorkspaceTemplate struct {
+	ID string `json:"id" firestore:"id"`
+	OrganizationID string `json:"organization_id,omitempty" firestore:"organization_id,omitempty"` // Templates of organizations are shared with their members
+	Name string `json:"name" firestore:"name"`
+	Description string `json:"description,omitempty" firestore:"description,omitempty"`
+	Parameters []TemplateParameter `json:"parameters" firestore:"parameters"`
+	Integrations []TemplateIntegration `json:"integrations" firestore:"integrations"`
+	Connections []TemplateConnection `json:"connections" firestore:"connections"`
+	CreatedBy string `json:"created_by" firestore:"created_by"` // ID of the user who created the template
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+// Every parameter needs a value when the template is instantiated
+type TemplateParameter struct {
+	Name string `json:"name" firestore:"name"`
+	Description string `json:"description,omitempty" firestore:"description,omitempty"`
+}
+
+// Integration created with the workspace, pinned to the latest published version of its definition
+type TemplateIntegration struct {
+	Key string `json:"key" firestore:"key"` // Identifies the integration in the connections of the template
+	Name string `json:"name" firestore:"name"`
+	DefinitionID string `json:"definition_id" firestore:"definition_id"`
+	Configuration IntegrationConfig `json:"configuration" firestore:"configuration"`
+	Streams []StreamSelection `json:"streams,omitempty" firestore:"streams,omitempty"` // Only for sources
+}
+
+type TemplateConnection struct {
+	Name string `json:"name" firestore:"name"`
+	Source string `json:"source" firestore:"source"` // Key of the source integration
+	Destination string `json:"destination" firestore:"destination"` // Key of the destination integration
+	Schedule Schedule `json:"schedule" firestore:"schedule"`
+	Namespace NamespaceMapping `json:"namespace" firestore:"namespace"`
+	Enabled bool `json:"enabled" firestore:"enabled"`
+}
+
+var (
+	parameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	parameterPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+func NewWorkspaceTemplate(organizationID string, name string, description string, parameters []TemplateParameter, integrations []TemplateIntegration, connections []TemplateConnection, createdBy string, now time.Time) (WorkspaceTemplate, error) {
+
+	if parameters == nil {
+		parameters = []TemplateParameter{}
+	}
+	if integrations == nil {
+		integrations = []TemplateIntegration{}
+	}
+	if connections == nil {
+		connections = []TemplateConnection{}
+	}
+	template := WorkspaceTemplate{ "", organizationID, name, description, parameters, integrations, connections, createdBy, now, now }
+
+	err := template.Validate()
+	if err != nil {
+		return template, err
+	}
+
+	return template, nil
+}
+
+// Checks the structure of the template. Configurations can only be validated against their definitions
+// once rendered
+func (t WorkspaceTemplate) Validate() error {
+
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("Name is required")
+	}
+
+	declared := map[string]bool{}
+	for idx, parameter := range t.Parameters {
+		if !parameterName.MatchString(parameter.Name) {
+			return fmt.Errorf("Invalid name %q of parameter at index %d. Names are made of letters, digits and underscores", parameter.Name, idx)
+		}
+		if declared[parameter.Name] {
+			return fmt.Errorf("Parameter %s is declared more than once", parameter.Name)
+		}
+		declared[parameter.Name] = true
+	}
+
+	checkPlaceholders := func(value interface{}, where string) error {
+		for _, name := range placeholders(value) {
+			if !declared[name] {
+				return fmt.Errorf("%s references undeclared parameter %s", where, name)
+			}
+		}
+		return nil
+	}
+
+	keys := map[string]bool{}
+	for idx, integration := range t.Integrations {
+		if integration.Key == "" {
+			return fmt.Errorf("Integration at index %d has no key", idx)
+		}
+		if keys[integration.Key] {
+			return fmt.Errorf("Integration key %s is used more than once", integration.Key)
+		}
+		keys[integration.Key] = true
+		if strings.TrimSpace(integration.Name) == "" {
+			return fmt.Errorf("Integration %s has no name", integration.Key)
+		}
+		if integration.DefinitionID == "" {
+			return fmt.Errorf("Integration %s has no definition", integration.Key)
+		}
+		err := checkPlaceholders(integration.Name, "Name of integration " + integration.Key)
+		if err != nil {
+			return err
+		}
+		err = checkPlaceholders(map[string]interface{}(integration.Configuration), "Configuration of integration " + integration.Key)
+		if err != nil {
+			return err
+		}
+		err = checkNoVariables(integration)
+		if err != nil {
+			return err
+		}
+	}
+
+	for idx, connection := range t.Connections {
+		if strings.TrimSpace(connection.Name) == "" {
+			return fmt.Errorf("Connection at index %d has no name", idx)
+		}
+		err := checkPlaceholders(connection.Name, "Name of connection " + connection.Name)
+		if err != nil {
+			return err
+		}
+		for _, key := range []string{ connection.Source, connection.Destination } {
+			if !keys[key] {
+				return fmt.Errorf("Connection %s references unknown integration %s", connection.Name, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Copy of the template with its placeholders replaced by the values. Every parameter needs a value,
+// and values of undeclared parameters are rejected to catch typos. A string made of a single
+// placeholder takes the value as it is, so numbers and objects keep their type; placeholders within
+// longer strings only take strings, numbers and booleans
+func (t WorkspaceTemplate) Render(values map[string]interface{}) (WorkspaceTemplate, error) {
+
+	rendered := t
+
+	declared := map[string]bool{}
+	for _, parameter := range t.Parameters {
+		declared[parameter.Name] = true
+		if _, ok := values[parameter.Name]; !ok {
+			return rendered, fmt.Errorf("Missing value for parameter %s", parameter.Name)
+		}
+	}
+	for name := range values {
+		if !declared[name] {
+			return rendered, fmt.Errorf("Unknown parameter %s", name)
+		}
+	}
+
+	rendered.Integrations = make([]TemplateIntegration, len(t.Integrations))
+	for idx, integration := range t.Integrations {
+		name, err := interpolate(integration.Name, values)
+		if err != nil {
+			return rendered, fmt.Errorf("Invalid name of integration %s: %v", integration.Key, err)
+		}
+		integration.Name = name
+
+		config, err := renderValue(map[string]interface{}(integration.Configuration), values)
+		if err != nil {
+			return rendered, fmt.Errorf("Invalid configuration of integration %s: %v", integration.Key, err)
+		}
+		integration.Configuration = IntegrationConfig(config.(map[string]interface{}))
+		err = checkNoVariables(integration)
+		if err != nil {
+			return rendered, err
+		}
+		rendered.Integrations[idx] = integration
+	}
+
+	rendered.Connections = make([]TemplateConnection, len(t.Connections))
+	for idx, connection := range t.Connections {
+		name, err := interpolate(connection.Name, values)
+		if err != nil {
+			return rendered, fmt.Errorf("Invalid name of connection %s: %v", connection.Name, err)
+		}
+		connection.Name = name
+		rendered.Connections[idx] = connection
+	}
+
+	return rendered, nil
+}
+
+// New workspaces start without variables, so the references would never resolve
+func checkNoVariables(integration TemplateIntegration) error {
+	references := integration.Configuration.VariableReferences()
+	if len(references) > 0 {
+		return fmt.Errorf("Configuration of integration %s references variable %s. Templates can't reference variables, use a parameter instead", integration.Key, references[0])
+	}
+	return nil
+}
+
+// Names of the parameters referenced by a value, in strings at any depth
+func placeholders(value interface{}) []string {
+	names := []string{}
+	switch v := value.(type) {
+	case string:
+		for _, match := range parameterPlaceholder.FindAllStringSubmatch(v, -1) {
+			names = append(names, match[1])
+		}
+	case IntegrationConfig:
+		return placeholders(map[string]interface{}(v))
+	case map[string]interface{}:
+		for _, item := range v {
+			names = append(names, placeholders(item)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, placeholders(item)...)
+		}
+	}
+	return names
+}
+
+func renderValue(value interface{}, values map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return renderString(v, values)
+	case IntegrationConfig:
+		return renderValue(map[string]interface{}(v), values)
+	case map[string]interface{}:
+		rendered := map[string]interface{}{}
+		for key, item := range v {
+			r, err := renderValue(item, values)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			rendered[key] = r
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for idx, item := range v {
+			r, err := renderValue(item, values)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", idx, err)
+			}
+			rendered[idx] = r
+		}
+		return rendered, nil
+	}
+	return value, nil
+}
+
+func renderString(s string, values map[string]interface{}) (interface{}, error) {
+	if match := parameterPlaceholder.FindStringSubmatch(s); match != nil && match[0] == s {
+		return values[match[1]], nil
+	}
+	return interpolate(s, values)
+}
+
+// Replaces the placeholders within the string
+func interpolate(s string, values map[string]interface{}) (string, error) {
+
+	var err error
+	rendered := parameterPlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := parameterPlaceholder.FindStringSubmatch(placeholder)[1]
+		switch v := values[name].(type) {
+		case string:
+			return v
+		case bool:
+			return strconv.FormatBool(v)
+		case int:
+			return strconv.Itoa(v)
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		err = fmt.Errorf("Parameter %s can't be embedded in a string, its value is a %T", name, values[name])
+		return placeholder
+	})
+
+	return rendered, err
+}
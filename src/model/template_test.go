@@ -0,0 +1,93 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func workspaceTemplate() WorkspaceTemplate {
+	return WorkspaceTemplate{
+		Name: "Agency client",
+		Parameters: []TemplateParameter{ { Name: "account_id" }, { Name: "client" }, { Name: "days" } },
+		Integrations: []TemplateIntegration{
+			{ Key: "ads", Name: "Ads ${client}", DefinitionID: "ads", Configuration: IntegrationConfig{
+				"account_id": "${account_id}",
+				"lookback_days": "${days}",
+				"reports": []interface{}{ map[string]interface{}{ "name": "${client}_daily" } },
+			} },
+			{ Key: "warehouse", Name: "Warehouse", DefinitionID: "bigquery", Configuration: IntegrationConfig{ "dataset": "client_${account_id}" } },
+		},
+		Connections: []TemplateConnection{ { Name: "Ads of ${client}", Source: "ads", Destination: "warehouse" } },
+	}
+}
+
+func TestWorkspaceTemplateValidate(t *testing.T) {
+
+	template := workspaceTemplate()
+	if err := template.Validate(); err != nil {
+		t.Fatalf("Expected template to be valid, got %v", err)
+	}
+
+	invalid := map[string]func(*WorkspaceTemplate){
+		"no name": func(w *WorkspaceTemplate) { w.Name = " " },
+		"invalid parameter name": func(w *WorkspaceTemplate) { w.Parameters[0].Name = "account-id" },
+		"duplicate parameter": func(w *WorkspaceTemplate) { w.Parameters[1].Name = "account_id" },
+		"undeclared parameter": func(w *WorkspaceTemplate) { w.Parameters = w.Parameters[1:] },
+		"duplicate key": func(w *WorkspaceTemplate) { w.Integrations[1].Key = "ads" },
+		"no definition": func(w *WorkspaceTemplate) { w.Integrations[0].DefinitionID = "" },
+		"unknown end": func(w *WorkspaceTemplate) { w.Connections[0].Destination = "lake" },
+		"variable reference": func(w *WorkspaceTemplate) { w.Integrations[1].Configuration["project"] = "${var.project}" },
+	}
+	for name, mutate := range invalid {
+		template := workspaceTemplate()
+		mutate(&template)
+		if err := template.Validate(); err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+	}
+
+	if _, err := NewWorkspaceTemplate("", "Empty", "", nil, nil, nil, "ana", time.Now()); err != nil {
+		t.Errorf("Expected empty template to be valid, got %v", err)
+	}
+}
+
+func TestWorkspaceTemplateRender(t *testing.T) {
+
+	template := workspaceTemplate()
+	values := map[string]interface{}{ "account_id": "123", "client": "acme", "days": 30.0 }
+
+	rendered, err := template.Render(values)
+	if err != nil {
+		t.Fatalf("Expected template to be rendered, got %v", err)
+	}
+	expected := IntegrationConfig{
+		"account_id": "123",
+		"lookback_days": 30.0,
+		"reports": []interface{}{ map[string]interface{}{ "name": "acme_daily" } },
+	}
+	if !reflect.DeepEqual(rendered.Integrations[0].Configuration, expected) {
+		t.Errorf("Expected %v, got %v", expected, rendered.Integrations[0].Configuration)
+	}
+	if rendered.Integrations[1].Configuration["dataset"] != "client_123" {
+		t.Errorf("Expected placeholder within a string to be replaced, got %v", rendered.Integrations[1].Configuration)
+	}
+	if rendered.Integrations[0].Name != "Ads acme" || rendered.Connections[0].Name != "Ads of acme" {
+		t.Errorf("Expected names to be rendered, got %s and %s", rendered.Integrations[0].Name, rendered.Connections[0].Name)
+	}
+	if template.Integrations[0].Configuration["account_id"] != "${account_id}" || template.Connections[0].Name != "Ads of ${client}" {
+		t.Errorf("Expected template to be left unchanged")
+	}
+
+	invalid := []map[string]interface{}{
+		{ "account_id": "123", "client": "acme" },
+		{ "account_id": "123", "client": "acme", "days": 30, "region": "eu" },
+		{ "account_id": map[string]interface{}{ "id": "123" }, "client": "acme", "days": 30 },
+		{ "account_id": "${var.account}", "client": "acme", "days": 30 },
+	}
+	for idx, values := range invalid {
+		if _, err := template.Render(values); err == nil {
+			t.Errorf("Expected error rendering values at index %d, got nil", idx)
+		}
+	}
+}
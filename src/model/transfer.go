@@ -0,0 +1,96 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Transfer of the ownership of a workspace to another user, who has to confirm it. Once accepted, the new
+// owner holds the owner role and the owner who started the transfer is left an editor
+type OwnershipTransfer struct {
+	From string `json:"from" firestore:"from"` // Principal ID of the owner who started the transfer
+	To string `json:"to" firestore:"to"` // Principal ID of the new owner
+	Email string `json:"user,omitempty" firestore:"-"` // Email of the new owner, for display only
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" firestore:"expires_at"`
+}
+
+const DefaultTransferTTL = 7 * 24 * time.Hour
+
+func NewOwnershipTransfer(from string, to string, now time.Time, ttl time.Duration) (OwnershipTransfer, error) {
+
+	transfer := OwnershipTransfer{ from, to, "", now, now.Add(ttl) }
+
+	for _, principal := range []string{ from, to } {
+		kind, _, err := ParsePrincipal(principal)
+		if err != nil {
+			return transfer, fmt.Errorf("Invalid principal: %v", err)
+		}
+		if kind != PrincipalUser {
+			return transfer, fmt.Errorf("Ownership can only be transferred between users, got %s", principal)
+		}
+	}
+	if from == to {
+		return transfer, errors.New("Ownership can't be transferred to the current owner")
+	}
+
+	return transfer, nil
+}
+
+func (t OwnershipTransfer) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// Completes the pending transfer on behalf of the new owner. Their permission is replaced by the owner
+// role, and the owner permission of the owner who started the transfer becomes an editor one
+func (w Workspace) AcceptTransfer(principal string, now time.Time) (Workspace, error) {
+
+	if w.Transfer == nil {
+		return w, errors.New("No ownership transfer is pending")
+	}
+	if w.Transfer.To != principal {
+		return w, errors.New("Ownership transfer was offered to another user")
+	}
+	if w.Transfer.Expired(now) {
+		return w, errors.New("Ownership transfer expired")
+	}
+
+	permissions := []WorkspacePermission{}
+	for _, perm := range w.Permissions {
+		if perm.Principal == principal {
+			continue
+		}
+		if perm.Principal == w.Transfer.From && perm.Role == "owner" {
+			perm.Role = "editor"
+		}
+		permissions = append(permissions, perm)
+	}
+	w.Permissions = append(permissions, WorkspacePermission{ principal, "owner", "", "" })
+	w.Transfer = nil
+	w.UpdatedAt = now
+
+	return w, nil
+}
+
+// Principals the permissions make owners who don't own the workspace through its permissions yet.
+// Ownership is given by transferring it, so the new owner confirms it
+func (w Workspace) NewOwners(permissions []WorkspacePermission) []string {
+
+	owners := map[string]bool{}
+	for _, perm := range w.Permissions {
+		if perm.Role == "owner" {
+			owners[perm.Principal] = true
+		}
+	}
+
+	added := []string{}
+	for _, perm := range permissions {
+		if perm.Role == "owner" && !owners[perm.Principal] {
+			owners[perm.Principal] = true
+			added = append(added, perm.Principal)
+		}
+	}
+
+	return added
+}
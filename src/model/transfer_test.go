@@ -0,0 +1,71 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOwnershipTransfer(t *testing.T) {
+
+	now := time.Now()
+	invalid := [][]string{
+		{ "user:ana", "user:ana" },
+		{ "user:ana", "group:analysts" },
+		{ "user:ana", "bob@example.com" },
+		{ "org:agency#admin", "user:bob" },
+	}
+	for idx, principals := range invalid {
+		if _, err := NewOwnershipTransfer(principals[0], principals[1], now, time.Hour); err == nil {
+			t.Errorf("Expected error for transfer at index %d, got nil", idx)
+		}
+	}
+
+	transfer, err := NewOwnershipTransfer("user:ana", "user:bob", now, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected transfer to be valid, got %v", err)
+	}
+	workspace := Workspace{ ID: "workspace", Permissions: []WorkspacePermission{
+		{ "user:ana", "owner", "", "" },
+		{ "user:bob", "viewer", "", "" },
+		{ "group:analysts", "owner", "", "" },
+	}, Transfer: &transfer }
+
+	if _, err := workspace.AcceptTransfer("user:eve", now); err == nil {
+		t.Errorf("Expected error accepting a transfer offered to another user, got nil")
+	}
+	if _, err := workspace.AcceptTransfer("user:bob", now.Add(time.Hour)); err == nil {
+		t.Errorf("Expected error accepting an expired transfer, got nil")
+	}
+	if _, err := (Workspace{}).AcceptTransfer("user:bob", now); err == nil {
+		t.Errorf("Expected error without pending transfer, got nil")
+	}
+
+	accepted, err := workspace.AcceptTransfer("user:bob", now)
+	if err != nil {
+		t.Fatalf("Expected transfer to be accepted, got %v", err)
+	}
+	if accepted.Transfer != nil || len(accepted.Permissions) != 3 {
+		t.Errorf("Unexpected workspace %+v", accepted)
+	}
+	if accepted.Access("user:bob", nil).Role != "owner" || accepted.Access("user:ana", nil).Role != "editor" || !accepted.EditableBy("group:analysts") {
+		t.Errorf("Expected the ownership to move from ana to bob, got %+v", accepted.Permissions)
+	}
+	if workspace.Permissions[0].Role != "owner" {
+		t.Errorf("Expected the workspace to be left unchanged")
+	}
+}
+
+func TestNewOwners(t *testing.T) {
+
+	workspace := Workspace{ Permissions: []WorkspacePermission{ { Principal: "user:ana", Role: "owner" }, { Principal: "user:bob", Role: "viewer" } } }
+
+	kept := []WorkspacePermission{ { Principal: "user:ana", Role: "owner" }, { Principal: "user:bob", Role: "editor" } }
+	if added := workspace.NewOwners(kept); len(added) != 0 {
+		t.Errorf("Expected no new owner, got %v", added)
+	}
+
+	promoted := []WorkspacePermission{ { Principal: "user:ana", Role: "owner" }, { Principal: "user:bob", Role: "owner" }, { Principal: "group:g", Role: "owner" } }
+	if added := workspace.NewOwners(promoted); len(added) != 2 || added[0] != "user:bob" || added[1] != "group:g" {
+		t.Errorf("Expected bob and the group to be new owners, got %v", added)
+	}
+}
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Value shared by the integrations of a workspace, e.g. a GCP project ID. String values of configurations
+// reference it as "${var.<name>}". References are kept as they are in the stored configurations and
+// only resolved for runtimes, so secret variables are never shown to users
+type WorkspaceVariable struct {
+	WorkspaceID string `json:"workspace_id" firestore:"workspace_id"`
+	Name string `json:"name" firestore:"name"`
+	Value string `json:"value" firestore:"value"`
+	Secret bool `json:"secret" firestore:"secret"` // Masked like secret fields
+	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+var variableReference = regexp.MustCompile(`\$\{var\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func NewWorkspaceVariable(workspaceID string, name string, value string, secret bool, now time.Time) (WorkspaceVariable, error) {
+
+	variable := WorkspaceVariable{ workspaceID, name, value, secret, now }
+
+	if !parameterName.MatchString(name) {
+		return variable, fmt.Errorf("Invalid variable name %q. Names are made of letters, digits and underscores", name)
+	}
+
+	return variable, nil
+}
+
+// Copy of the variable with its value masked when secret
+func (v WorkspaceVariable) Masked() WorkspaceVariable {
+	if v.Secret {
+		v.Value = SecretMask
+	}
+	return v
+}
+
+// Names of the variables referenced in the configuration, sorted
+func (c IntegrationConfig) VariableReferences() []string {
+
+	seen := map[string]bool{}
+	collectReferences(map[string]interface{}(c), seen)
+
+	names := []string{}
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func collectReferences(value interface{}, seen map[string]bool) {
+	switch v := value.(type) {
+	case string:
+		for _, match := range variableReference.FindAllStringSubmatch(v, -1) {
+			seen[match[1]] = true
+		}
+	case IntegrationConfig:
+		collectReferences(map[string]interface{}(v), seen)
+	case map[string]interface{}:
+		for _, item := range v {
+			collectReferences(item, seen)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectReferences(item, seen)
+		}
+	}
+}
+
+// Whether the configuration references the variable
+func (c IntegrationConfig) References(name string) bool {
+	for _, reference := range c.VariableReferences() {
+		if reference == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of the configuration with the references replaced by the values of the variables,
+// at any depth. Referencing a missing variable is an error
+func (c IntegrationConfig) ResolveVariables(variables []WorkspaceVariable) (IntegrationConfig, error) {
+
+	values := map[string]string{}
+	for _, variable := range variables {
+		values[variable.Name] = variable.Value
+	}
+
+	resolved, err := resolveReferences(c, values)
+	if err != nil {
+		return c, err
+	}
+
+	return resolved.(IntegrationConfig), nil
+}
+
+func resolveReferences(value interface{}, values map[string]string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		var err error
+		resolved := variableReference.ReplaceAllStringFunc(v, func(reference string) string {
+			name := variableReference.FindStringSubmatch(reference)[1]
+			value, ok := values[name]
+			if !ok {
+				err = fmt.Errorf("Variable %s is not defined", name)
+			}
+			return value
+		})
+		return resolved, err
+	case IntegrationConfig:
+		resolved := IntegrationConfig{}
+		for key, item := range v {
+			r, err := resolveReferences(item, values)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case map[string]interface{}:
+		resolved := map[string]interface{}{}
+		for key, item := range v {
+			r, err := resolveReferences(item, values)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for idx, item := range v {
+			r, err := resolveReferences(item, values)
+			if err != nil {
+				return nil, err
+			}
+			resolved[idx] = r
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
+
+// Checks the integration with the references of its configuration resolved, so the values of the
+// variables are validated against the schema too
+func (i Integration) ValidateWithVariables(def IntegrationDefinition, variables []WorkspaceVariable) error {
+	resolved, err := i.Configuration.ResolveVariables(variables)
+	if err != nil {
+		return fmt.Errorf("Invalid configuration: %v", err)
+	}
+	i.Configuration = resolved
+	err = i.Validate(def)
+	if err != nil {
+		return redactVariables(err, variables)
+	}
+	return nil
+}
+
+// Checks the configuration with its references resolved, like ValidateWithVariables
+func (c IntegrationConfig) ValidateWithVariables(schema ConfigurationSchema, variables []WorkspaceVariable) error {
+	resolved, err := c.ResolveVariables(variables)
+	if err != nil {
+		return err
+	}
+	err = resolved.Validate(schema)
+	if err != nil {
+		return redactVariables(err, variables)
+	}
+	return nil
+}
+
+// Errors quote the invalid values, which may come from secret variables. Those are masked
+func redactVariables(err error, variables []WorkspaceVariable) error {
+	message := err.Error()
+	for _, variable := range variables {
+		if variable.Secret && variable.Value != "" {
+			message = strings.ReplaceAll(message, variable.Value, SecretMask)
+		}
+	}
+	if message == err.Error() {
+		return err
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkspaceVariable(t *testing.T) {
+
+	now := time.Now()
+	for _, name := range []string{ "", "gcp-project", "1st", "var.project" } {
+		if _, err := NewWorkspaceVariable("workspace", name, "value", false, now); err == nil {
+			t.Errorf("Expected error for variable name %q, got nil", name)
+		}
+	}
+
+	variable, err := NewWorkspaceVariable("workspace", "api_key", "abc", true, now)
+	if err != nil {
+		t.Fatalf("Expected variable to be valid, got %v", err)
+	}
+	if variable.Masked().Value != SecretMask || variable.Value != "abc" {
+		t.Errorf("Expected a masked copy of the secret variable, got %+v", variable.Masked())
+	}
+	plain := WorkspaceVariable{ Name: "project", Value: "analytics" }
+	if plain.Masked().Value != "analytics" {
+		t.Errorf("Expected plain variables not to be masked")
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+
+	config := IntegrationConfig{
+		"project": "${var.project}",
+		"table": "${var.project}.${var.dataset}.events",
+		"days": 30,
+		"reports": []interface{}{ IntegrationConfig{ "start": "${var.start_date}" } },
+		"template": "${account_id}",
+	}
+	if references := config.VariableReferences(); !reflect.DeepEqual(references, []string{ "dataset", "project", "start_date" }) {
+		t.Errorf("Unexpected references %v", references)
+	}
+	if !config.References("dataset") || config.References("account_id") {
+		t.Errorf("Expected only variable references to count")
+	}
+
+	variables := []WorkspaceVariable{
+		{ Name: "project", Value: "analytics" },
+		{ Name: "dataset", Value: "raw" },
+		{ Name: "start_date", Value: "2024-01-01" },
+	}
+	resolved, err := config.ResolveVariables(variables)
+	if err != nil {
+		t.Fatalf("Expected references to be resolved, got %v", err)
+	}
+	expected := IntegrationConfig{
+		"project": "analytics",
+		"table": "analytics.raw.events",
+		"days": 30,
+		"reports": []interface{}{ IntegrationConfig{ "start": "2024-01-01" } },
+		"template": "${account_id}",
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("Expected %v, got %v", expected, resolved)
+	}
+	if config["project"] != "${var.project}" {
+		t.Errorf("Expected original config to be unchanged")
+	}
+
+	if _, err := config.ResolveVariables(variables[:2]); err == nil {
+		t.Errorf("Expected error for a missing variable, got nil")
+	}
+}
+
+func TestValidateWithVariables(t *testing.T) {
+
+	def := IntegrationDefinition{ ID: "ads", Version: "1.0.0", Status: "published", ConfigurationSchema: ConfigurationSchema{
+		SchemaField{Label: "region", Type: "string", Required: true, Enum: []interface{}{ "eu", "us" }},
+	} }
+	config := IntegrationConfig{ "region": "${var.region}" }
+
+	if _, err := NewIntegration("ads", "workspace", def, config, []WorkspaceVariable{ { Name: "region", Value: "eu" } }); err != nil {
+		t.Errorf("Expected integration to be valid, got %v", err)
+	}
+	if _, err := NewIntegration("ads", "workspace", def, config, []WorkspaceVariable{ { Name: "region", Value: "asia" } }); err == nil {
+		t.Errorf("Expected error for an invalid variable value, got nil")
+	}
+	if _, err := NewIntegration("ads", "workspace", def, config, nil); err == nil {
+		t.Errorf("Expected error for a missing variable, got nil")
+	}
+
+	// Errors don't reveal the values of secret variables
+	_, err := NewIntegration("ads", "workspace", def, config, []WorkspaceVariable{ { Name: "region", Value: "hunter2", Secret: true } })
+	if err == nil || strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), SecretMask) {
+		t.Errorf("Expected the secret value to be masked in the error, got %v", err)
+	}
+}
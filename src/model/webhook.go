@@ -0,0 +1,342 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration change, written to the outbox together with the change itself.
+// Events are dispatched to the matching webhook subscriptions afterwards.
+type Event struct {
+	ID string `json:"id" firestore:"id"`
+	Type string `json:"type" firestore:"type"` // e.g. "integration.created"
+	WorkspaceID string `json:"workspace_id,omitempty" firestore:"workspace_id,omitempty"` // Empty for global resources, e.g. definitions
+	OrganizationID string `json:"organization_id,omitempty" firestore:"organization_id,omitempty"` // Only for resources of an organization, whose events stay in its workspaces
+	ResourceType string `json:"resource_type" firestore:"resource_type"`
+	ResourceID string `json:"resource_id" firestore:"resource_id"`
+	PrincipalID string `json:"principal_id,omitempty" firestore:"principal_id,omitempty"` // ID of the user behind the change. Empty for background jobs
+	OccurredAt time.Time `json:"occurred_at" firestore:"occurred_at"`
+	Data interface{} `json:"data" firestore:"data"` // The resource after the change, secrets masked
+	Dispatched bool `json:"-" firestore:"dispatched"` // Set once the deliveries of the event are created
+}
+
+const (
+	EventIntegrationCreated = "integration.created"
+	EventIntegrationUpdated = "integration.updated"
+	EventIntegrationDeleted = "integration.deleted"
+	EventConnectionCreated = "connection.created"
+	EventConnectionUpdated = "connection.updated"
+	EventConnectionDeleted = "connection.deleted"
+	EventWorkspacePermissionsChanged = "workspace.permissions_changed"
+	EventWorkspaceDeleted = "workspace.deleted" // Moved to the trash
+	EventWorkspaceRestored = "workspace.restored"
+	EventWorkspacePurged = "workspace.purged"
+	EventWorkspaceTransferStarted = "workspace.transfer_started"
+	EventWorkspaceTransferCancelled = "workspace.transfer_cancelled" // Declined by the new owner or cancelled by an owner
+	EventDefinitionPublished = "definition.published"
+	EventRunFinished = "run.finished"
+)
+
+var EventTypes = []string{
+	EventIntegrationCreated,
+	EventIntegrationUpdated,
+	EventIntegrationDeleted,
+	EventConnectionCreated,
+	EventConnectionUpdated,
+	EventConnectionDeleted,
+	EventWorkspacePermissionsChanged,
+	EventWorkspaceDeleted,
+	EventWorkspaceRestored,
+	EventWorkspacePurged,
+	EventWorkspaceTransferStarted,
+	EventWorkspaceTransferCancelled,
+	EventDefinitionPublished,
+	EventRunFinished,
+}
+
+func NewEvent(t string, workspaceID string, resourceType string, resourceID string, data interface{}, principalID string, now time.Time) Event {
+	return Event{ "", t, workspaceID, "", resourceType, resourceID, principalID, now, data, false }
+}
+
+// Definitions are global, or private to the organization or the workspace of their scope
+func NewDefinitionEvent(t string, definition IntegrationDefinition, principalID string, now time.Time) Event {
+	event := NewEvent(t, definition.Scope.WorkspaceID, "definition", definition.ID, definition, principalID, now)
+	event.OrganizationID = definition.Scope.OrganizationID
+	return event
+}
+
+// Whether the event concerns the workspace, a workspace of the organization: events of the workspace,
+// events of its organization and global events
+func (e Event) Reaches(workspaceID string, organizationID string) bool {
+	if e.WorkspaceID != "" {
+		return e.WorkspaceID == workspaceID
+	}
+	return e.OrganizationID == "" || e.OrganizationID == organizationID
+}
+
+// Integrations are sent with their secrets masked, the schema of their pinned version tells which fields are secrets
+func NewIntegrationEvent(t string, integration Integration, schema ConfigurationSchema, principalID string, now time.Time) Event {
+	integration.Configuration = integration.Configuration.MaskSecrets(schema)
+	return NewEvent(t, integration.WorkspaceID, "integration", integration.ID, integration, principalID, now)
+}
+
+func NewConnectionEvent(t string, connection Connection, principalID string, now time.Time) Event {
+	return NewEvent(t, connection.WorkspaceID, "connection", connection.ID, connection, principalID, now)
+}
+
+// Identifies the resource of events created before it was inserted
+func (e Event) WithResourceID(id string) Event {
+	e.ResourceID = id
+	switch data := e.Data.(type) {
+	case Integration:
+		data.ID = id
+		e.Data = data
+	case Connection:
+		data.ID = id
+		e.Data = data
+	case Run:
+		data.ID = id
+		e.Data = data
+	}
+	return e
+}
+
+// Subscription of an endpoint to events. Subscriptions of a workspace receive the events of the workspace,
+// global subscriptions receive the events of every workspace. Events of global resources go to both,
+// events of organization resources to the subscriptions of the organization's workspaces and the global ones
+type WebhookSubscription struct {
+	ID string `json:"id" firestore:"id"`
+	WorkspaceID string `json:"workspace_id,omitempty" firestore:"workspace_id,omitempty"` // Empty for global subscriptions
+	URL string `json:"url" firestore:"url"`
+	Events []string `json:"events" firestore:"events"`
+	Secret string `json:"secret,omitempty" firestore:"secret"` // Signs deliveries. Only returned when the subscription is created
+	Active bool `json:"active" firestore:"active"`
+	CreatedBy string `json:"created_by" firestore:"created_by"`
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+}
+
+// insecure allows http endpoints and private addresses, only meant for tests
+func NewWebhookSubscription(workspaceID string, u string, events []string, principalID string, now time.Time, insecure bool) (WebhookSubscription, error) {
+
+	var subscription WebhookSubscription
+
+	secret, err := randomToken()
+	if err != nil {
+		return subscription, err
+	}
+
+	subscription = WebhookSubscription{ "", workspaceID, u, events, secret, true, principalID, now }
+	err = subscription.Validate(insecure)
+	if err != nil {
+		return subscription, fmt.Errorf("Invalid webhook subscription: %v", err)
+	}
+
+	return subscription, nil
+}
+
+func (s WebhookSubscription) Validate(insecure bool) error {
+
+	err := CheckWebhookURL(s.URL, insecure)
+	if err != nil {
+		return err
+	}
+
+	if len(s.Events) == 0 {
+		return errors.New("At least one event is required")
+	}
+	for _, event := range s.Events {
+		known := false
+		for _, t := range EventTypes {
+			known = known || event == t
+		}
+		if !known {
+			return fmt.Errorf("Unknown event %s. Valid events are %s", event, strings.Join(EventTypes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// Endpoints must use https and can't point to private addresses, so workspace admins can't reach
+// internal services. Hosts resolved by name are checked again when delivering.
+// insecure allows http endpoints and private addresses, only meant for tests
+func CheckWebhookURL(u string, insecure bool) error {
+
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("Invalid URL %s", u)
+	}
+	if insecure {
+		return nil
+	}
+
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("URL %s must use https", u)
+	}
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("URL %s points to a private address", u)
+	}
+	if ip := net.ParseIP(host); ip != nil && !PublicIP(ip) {
+		return fmt.Errorf("URL %s points to a private address", u)
+	}
+
+	return nil
+}
+
+// Carrier grade NAT range, not covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{ IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32) }
+
+// Whether the address is reachable on the internet, as opposed to loopback, private, link-local
+// (e.g. cloud metadata services) or unspecified addresses
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || sharedAddressSpace.Contains(ip))
+}
+
+// organizationID is the organization of the subscription's workspace, ignored for global subscriptions
+func (s WebhookSubscription) Matches(event Event, organizationID string) bool {
+
+	if !s.Active {
+		return false
+	}
+	if s.WorkspaceID != "" && !event.Reaches(s.WorkspaceID, organizationID) {
+		return false
+	}
+	for _, t := range s.Events {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Copy of the subscription without its secret, for listings
+func (s WebhookSubscription) WithoutSecret() WebhookSubscription {
+	s.Secret = ""
+	return s
+}
+
+// Attempts to deliver an event to a subscription. Failed attempts are retried with an exponential
+// backoff, until MaxWebhookAttempts. Replaying a delivery creates a new one, the log is never rewritten
+type WebhookDelivery struct {
+	ID string `json:"id" firestore:"id"`
+	SubscriptionID string `json:"subscription_id" firestore:"subscription_id"`
+	EventID string `json:"event_id" firestore:"event_id"`
+	EventType string `json:"event_type" firestore:"event_type"`
+	Status string `json:"status" firestore:"status"` // "pending", "succeeded" or "failed"
+	Attempts int `json:"attempts" firestore:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at" firestore:"next_attempt_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty" firestore:"last_attempt_at,omitempty"`
+	LastStatusCode int `json:"last_status_code,omitempty" firestore:"last_status_code,omitempty"`
+	LastError string `json:"last_error,omitempty" firestore:"last_error,omitempty"`
+	ReplayOf string `json:"replay_of,omitempty" firestore:"replay_of,omitempty"` // ID of the replayed delivery
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+}
+
+const MaxWebhookAttempts = 8
+const webhookBaseBackoff = 30 * time.Second
+const webhookMaxBackoff = time.Hour
+
+func NewWebhookDelivery(subscription WebhookSubscription, event Event, now time.Time) WebhookDelivery {
+	return WebhookDelivery{ "", subscription.ID, event.ID, event.Type, "pending", 0, now, nil, 0, "", "", now }
+}
+
+// Delay before the next attempt after the given number of attempts: 30s, 1m, 2m... up to an hour
+func WebhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff
+}
+
+func (d WebhookDelivery) Due(now time.Time) bool {
+	return d.Status == "pending" && !d.NextAttemptAt.After(now)
+}
+
+// Records the outcome of an attempt. Status code is 0 when the endpoint couldn't be reached
+func (d WebhookDelivery) Record(statusCode int, err error, now time.Time) WebhookDelivery {
+
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.LastStatusCode = statusCode
+	d.LastError = ""
+
+	if err == nil {
+		d.Status = "succeeded"
+		return d
+	}
+
+	d.LastError = err.Error()
+	if d.Attempts >= MaxWebhookAttempts {
+		d.Status = "failed"
+		return d
+	}
+	d.NextAttemptAt = now.Add(WebhookBackoff(d.Attempts))
+	return d
+}
+
+// Fails the delivery without attempting it, e.g. when its subscription was deleted
+func (d WebhookDelivery) Abandon(reason string) WebhookDelivery {
+	d.Status = "failed"
+	d.LastError = reason
+	return d
+}
+
+func (d WebhookDelivery) Replay(now time.Time) WebhookDelivery {
+	return WebhookDelivery{ "", d.SubscriptionID, d.EventID, d.EventType, "pending", 0, now, nil, 0, "", d.ID, now }
+}
+
+// Value of the signature header of a delivery: "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">".
+// Receivers should recompute it with the secret of the subscription and reject old timestamps
+func SignWebhook(secret string, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(webhookMAC(secret, timestamp, body))
+}
+
+// Checks a signature header, as receivers should. Signatures older than the tolerance are rejected
+func VerifyWebhookSignature(secret string, body []byte, header string, now time.Time, tolerance time.Duration) error {
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	at, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("Invalid signature timestamp")
+	}
+	mac, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, webhookMAC(secret, timestamp, body)) {
+		return errors.New("Invalid signature")
+	}
+	if age := now.Sub(time.Unix(at, 0)); age > tolerance || age < -tolerance {
+		return errors.New("Signature timestamp out of tolerance")
+	}
+
+	return nil
+}
+
+func webhookMAC(secret string, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
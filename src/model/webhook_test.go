@@ -0,0 +1,201 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWebhookSubscription(t *testing.T) {
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	subscription, err := NewWebhookSubscription("workspace", "https://hooks.example.com", []string{ EventIntegrationCreated }, "user", now, false)
+	if err != nil {
+		t.Fatalf("Expected subscription to be valid, got %v", err)
+	}
+	if len(subscription.Secret) < 43 || !subscription.Active {
+		t.Errorf("Expected an active subscription with a random secret, got %+v", subscription)
+	}
+	if subscription.WithoutSecret().Secret != "" {
+		t.Errorf("Expected secret to be removed")
+	}
+
+	invalid := [][]string{ {}, { "integration.renamed" } }
+	for idx, events := range invalid {
+		if _, err := NewWebhookSubscription("workspace", "https://hooks.example.com", events, "user", now, false); err == nil {
+			t.Errorf("Expected error for events at index %d, got nil", idx)
+		}
+	}
+	if _, err := NewWebhookSubscription("", "hooks.example.com", []string{ EventIntegrationCreated }, "user", now, false); err == nil {
+		t.Errorf("Expected error for invalid URL, got nil")
+	}
+
+	global, _ := NewWebhookSubscription("", "https://hooks.example.com", []string{ EventIntegrationCreated, EventDefinitionPublished }, "user", now, false)
+	subscription.Events = append(subscription.Events, EventDefinitionPublished)
+	tests := []struct {
+		event Event
+		workspace bool
+		global bool
+	}{
+		{ NewEvent(EventIntegrationCreated, "workspace", "integration", "1", nil, "user", now), true, true },
+		{ NewEvent(EventIntegrationCreated, "other", "integration", "2", nil, "user", now), false, true },
+		{ NewEvent(EventIntegrationUpdated, "workspace", "integration", "1", nil, "user", now), false, false },
+		// Events of global resources go to every subscription
+		{ NewEvent(EventDefinitionPublished, "", "definition", "3", nil, "user", now), true, true },
+		// Events of organization resources stay in the organization
+		{ NewDefinitionEvent(EventDefinitionPublished, IntegrationDefinition{ ID: "4", Scope: DefinitionScope{ DefinitionOrganization, "organization", "" } }, "user", now), true, true },
+		{ NewDefinitionEvent(EventDefinitionPublished, IntegrationDefinition{ ID: "5", Scope: DefinitionScope{ DefinitionOrganization, "other", "" } }, "user", now), false, true },
+		{ NewDefinitionEvent(EventDefinitionPublished, IntegrationDefinition{ ID: "6", Scope: DefinitionScope{ DefinitionWorkspace, "", "other" } }, "user", now), false, true },
+	}
+	for idx, test := range tests {
+		if subscription.Matches(test.event, "organization") != test.workspace || global.Matches(test.event, "") != test.global {
+			t.Errorf("Unexpected match for event at index %d", idx)
+		}
+	}
+
+	subscription.Active = false
+	if subscription.Matches(tests[0].event, "organization") {
+		t.Errorf("Expected inactive subscriptions not to match")
+	}
+}
+
+func TestIntegrationEvent(t *testing.T) {
+
+	integration := Integration{ WorkspaceID: "workspace", Configuration: IntegrationConfig{ "host": "db", "password": "hunter2" } }
+	event := NewIntegrationEvent(EventIntegrationCreated, integration, runtimeSchema(), "user", time.Now())
+	if integration.Configuration["password"] != "hunter2" {
+		t.Errorf("Expected the configuration of the integration to be left untouched")
+	}
+
+	// Integrations are inserted after their event is created
+	event = event.WithResourceID("integration")
+	data, ok := event.Data.(Integration)
+	if !ok || event.ResourceID != "integration" || data.ID != "integration" {
+		t.Fatalf("Expected event to identify the integration, got %+v", event)
+	}
+	if data.Configuration["password"] != SecretMask || data.Configuration["host"] != "db" {
+		t.Errorf("Expected secrets to be masked, got %v", data.Configuration)
+	}
+}
+
+func TestConnectionEvent(t *testing.T) {
+
+	connection := Connection{ Name: "orders", WorkspaceID: "workspace" }
+	event := NewConnectionEvent(EventConnectionCreated, connection, "user", time.Now()).WithResourceID("connection")
+	data, ok := event.Data.(Connection)
+	if !ok || event.ResourceID != "connection" || data.ID != "connection" || event.WorkspaceID != "workspace" {
+		t.Fatalf("Expected event to identify the connection, got %+v", event)
+	}
+
+	subscription := WebhookSubscription{ WorkspaceID: "workspace", Events: []string{ EventConnectionCreated }, Active: true }
+	if !subscription.Matches(event, "organization") {
+		t.Errorf("Expected subscription to receive connection events")
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expected := []time.Duration{ 30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute }
+	for idx, backoff := range expected {
+		if WebhookBackoff(idx + 1) != backoff {
+			t.Errorf("Expected backoff %v after %d attempts, got %v", backoff, idx + 1, WebhookBackoff(idx + 1))
+		}
+	}
+	if WebhookBackoff(20) != time.Hour {
+		t.Errorf("Expected backoff to be capped at an hour, got %v", WebhookBackoff(20))
+	}
+
+	subscription := WebhookSubscription{ ID: "subscription" }
+	event := Event{ ID: "event", Type: EventIntegrationUpdated }
+	delivery := NewWebhookDelivery(subscription, event, now)
+	if !delivery.Due(now) {
+		t.Fatalf("Expected new deliveries to be due")
+	}
+
+	delivery = delivery.Record(500, errors.New("Endpoint answered with status 500"), now)
+	if delivery.Status != "pending" || delivery.Due(now.Add(29 * time.Second)) || !delivery.Due(now.Add(30 * time.Second)) {
+		t.Errorf("Expected delivery to be retried after 30 seconds, got %+v", delivery)
+	}
+	for delivery.Status == "pending" {
+		delivery = delivery.Record(0, errors.New("Connection refused"), now)
+	}
+	if delivery.Status != "failed" || delivery.Attempts != MaxWebhookAttempts || delivery.Due(now.Add(24 * time.Hour)) {
+		t.Errorf("Expected delivery to fail after %d attempts, got %+v", MaxWebhookAttempts, delivery)
+	}
+
+	replay := delivery.Replay(now)
+	if replay.Status != "pending" || replay.Attempts != 0 || replay.ReplayOf != delivery.ID || replay.EventID != "event" {
+		t.Errorf("Unexpected replay %+v", replay)
+	}
+	replay = replay.Record(200, nil, now)
+	if replay.Status != "succeeded" || replay.LastError != "" {
+		t.Errorf("Expected replay to succeed, got %+v", replay)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"integration.created"}`)
+	header := SignWebhook("secret", body, now)
+
+	if err := VerifyWebhookSignature("secret", body, header, now.Add(time.Minute), 5 * time.Minute); err != nil {
+		t.Errorf("Expected signature to be valid, got %v", err)
+	}
+
+	invalid := []struct {
+		secret string
+		body string
+		header string
+		at time.Time
+	}{
+		{ "other", string(body), header, now },
+		{ "secret", `{"type":"integration.deleted"}`, header, now },
+		{ "secret", string(body), header, now.Add(time.Hour) },
+		{ "secret", string(body), "v1=abc", now },
+	}
+	for idx, test := range invalid {
+		if err := VerifyWebhookSignature(test.secret, []byte(test.body), test.header, test.at, 5 * time.Minute); err == nil {
+			t.Errorf("Expected error for signature at index %d, got nil", idx)
+		}
+	}
+}
+
+func TestWebhookURL(t *testing.T) {
+
+	private := []string{
+		"http://hooks.example.com",
+		"https://localhost:8080/hook",
+		"https://api.localhost",
+		"https://127.0.0.1/hook",
+		"https://10.0.0.5",
+		"https://192.168.1.1",
+		"https://169.254.169.254/latest/meta-data",
+		"https://100.64.0.1",
+		"https://0.0.0.0",
+		"https://[::1]/hook",
+		"https://[fd00::1]",
+		"https://[::ffff:127.0.0.1]",
+	}
+	for _, u := range private {
+		if err := CheckWebhookURL(u, false); err == nil {
+			t.Errorf("Expected error for %s, got nil", u)
+		}
+	}
+
+	public := []string{ "https://hooks.example.com/path", "https://93.184.216.34", "https://[2606:4700::1111]" }
+	for _, u := range public {
+		if err := CheckWebhookURL(u, false); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", u, err)
+		}
+	}
+
+	// Only when explicitly allowed
+	if err := CheckWebhookURL("http://127.0.0.1:8080", true); err != nil {
+		t.Errorf("Expected insecure URL to be allowed, got %v", err)
+	}
+	if err := CheckWebhookURL("ftp://127.0.0.1", true); err == nil {
+		t.Errorf("Expected error for invalid scheme, got nil")
+	}
+}
@@ -1,21 +1,28 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
 
 type Workspace struct {
 	ID string `json:"id" firestore:"id"`
+	OrganizationID string `json:"organization_id,omitempty" firestore:"organization_id,omitempty"` // Empty for workspaces outside organizations
 	Name string `json:"name" firestore:"name"`
 	Permissions []WorkspacePermission `json:"permissions" firestore:"permissions"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt *time.Time `json:"deleted_at,omitempty" firestore:"deleted_at,omitempty"` // Set while the workspace is in the trash
+	Transfer *OwnershipTransfer `json:"transfer,omitempty" firestore:"transfer,omitempty"` // Ownership transfer waiting for the confirmation of the new owner
 }
 
-func NewWorkspace(name string, perms []WorkspacePermission) (Workspace, error) {
+// Trashed workspaces can be restored during the retention, then they are purged
+const DefaultTrashRetention = 30 * 24 * time.Hour
 
-	workspace := Workspace{ "", name, perms, time.Now(), time.Now()}
+func NewWorkspace(organizationID string, name string, perms []WorkspacePermission) (Workspace, error) {
+
+	workspace := Workspace{ "", organizationID, name, perms, time.Now(), time.Now(), nil, nil }
 
 	// Validate permissions
 	for idx, perm := range perms {
@@ -28,47 +35,141 @@ func NewWorkspace(name string, perms []WorkspacePermission) (Workspace, error) {
 	return workspace, nil
 }
 
-func (w Workspace) ViewableBy(principal string) bool {
+// Moves the workspace to the trash. Pending ownership transfers are dropped
+func (w Workspace) Trash(now time.Time) (Workspace, error) {
 
-	// If principal has any valid role for workspace, they can view
-	for _, perm := range w.Permissions {
-		if perm.Principal == principal {
-			err := perm.Validate()
-			if err == nil {
-				return true
-			}
+	if w.DeletedAt != nil {
+		return w, errors.New("Workspace is already in the trash")
+	}
+
+	w.DeletedAt = &now
+	w.Transfer = nil
+	w.UpdatedAt = now
+	return w, nil
+}
+
+// Takes the workspace out of the trash, as long as the retention isn't over
+func (w Workspace) Restore(now time.Time, retention time.Duration) (Workspace, error) {
+
+	if w.DeletedAt == nil {
+		return w, errors.New("Workspace is not in the trash")
+	}
+	if w.Purgeable(now, retention) {
+		return w, fmt.Errorf("Workspace can't be restored after %s in the trash", retention)
+	}
+
+	w.DeletedAt = nil
+	w.UpdatedAt = now
+	return w, nil
+}
+
+// Whether the workspace spent the whole retention in the trash
+func (w Workspace) Purgeable(now time.Time, retention time.Duration) bool {
+	return w.DeletedAt != nil && !now.Before(w.DeletedAt.Add(retention))
+}
+
+// Permission giving the principals their access to the workspace, e.g. a user and the groups they
+// belong to: the permission with the highest role among theirs
+func (w Workspace) Grant(principals ...string) (WorkspacePermission, bool) {
+
+	var grant WorkspacePermission
+	found := false
+
+	for _, perm := range append(w.ImplicitPermissions(), w.Permissions...) {
+		if perm.Validate() != nil || !hasPrincipal(principals, perm.Principal) {
+			continue
+		}
+		if !found || roleRanks[perm.Role] > roleRanks[grant.Role] {
+			grant = perm
+			found = true
 		}
 	}
-	return false
+
+	return grant, found
 }
-func (w Workspace) EditableBy(principal string) bool {
+
+func (w Workspace) ViewableBy(principals ...string) bool {
 
 	// If principal has any valid role for workspace, they can view
-	for _, perm := range w.Permissions {
-		if perm.Principal == principal {
-			err := perm.Validate()
-			if err == nil {
-				if perm.Role == "admin" {
-					return true
-				} else {
-					return false
-				}
-			} else {
-				return false
-			}
+	_, ok := w.Grant(principals...)
+	return ok
+}
+
+func (w Workspace) EditableBy(principals ...string) bool {
+
+	// Only owners administer the workspace: its permissions, invitations, webhooks...
+	grant, ok := w.Grant(principals...)
+	return ok && grant.Role == "owner"
+}
+
+func (w Workspace) ContentEditableBy(principals ...string) bool {
+
+	// Editors and owners can manage the integrations of the workspace
+	grant, ok := w.Grant(principals...)
+	return ok && (grant.Role == "editor" || grant.Role == "owner")
+}
+
+// Permissions the workspace grants without listing them: the roles of its organization cascade
+// into it, and Super Admins administer every workspace
+func (w Workspace) ImplicitPermissions() []WorkspacePermission {
+
+	permissions := []WorkspacePermission{ { SuperAdminPrincipal, "owner", "", "" } }
+	if w.OrganizationID != "" {
+		for _, role := range []string{ OrganizationAdmin, OrganizationMemberRole } {
+			permissions = append(permissions, WorkspacePermission{ OrganizationPrincipal(w.OrganizationID, role), organizationGrants[role], "", "" })
+		}
+	}
+
+	return permissions
+}
+
+// Access of a user to a workspace, and the permission it comes from
+type WorkspaceAccess struct {
+	WorkspaceID string `json:"workspace_id"`
+	Principal string `json:"principal"` // Principal ID of the user
+	Role string `json:"role"` // Empty without access
+	Grant *WorkspacePermission `json:"grant,omitempty"` // Permission of the user, of one of their groups, or of their role in the organization
+}
+
+// Access of the user, given their other principals, e.g. of the groups they belong to
+func (w Workspace) Access(user string, principals []string) WorkspaceAccess {
+
+	access := WorkspaceAccess{ w.ID, user, "", nil }
+
+	grant, ok := w.Grant(append([]string{ user }, principals...)...)
+	if ok {
+		access.Role = grant.Role
+		access.Grant = &grant
+	}
+
+	return access
+}
+
+var roleRanks = map[string]int{ "viewer": 1, "editor": 2, "owner": 3 }
+
+func hasPrincipal(principals []string, principal string) bool {
+	for _, p := range principals {
+		if p != "" && p == principal {
+			return true
 		}
 	}
 	return false
 }
 
 type WorkspacePermission struct {
-	Principal string `json:"user" firestore:"user"`
-	Role string `json:"role" firestore:"role"` // "admin",  "editor", "viewer"
+	Principal string `json:"principal" firestore:"principal"` // Principal ID, e.g. "user:<user id>"
+	Role string `json:"role" firestore:"role"` // "owner",  "editor", "viewer"
+
+	// Email of the user, for display only. Permissions used to be keyed on it, under the same name:
+	// callers may still grant access to users by email, and permissions stored before principal IDs
+	// only have it until migrated. It never grants access by itself
+	Email string `json:"user,omitempty" firestore:"user,omitempty"`
+	GroupName string `json:"group,omitempty" firestore:"-"` // Name of the group, for display only
 }
 
-func NewWorkspacePermission(userEmail string, role string) (WorkspacePermission, error) {
+func NewWorkspacePermission(principal string, role string) (WorkspacePermission, error) {
 	
-	perm := WorkspacePermission{ userEmail, role }
+	perm := WorkspacePermission{ principal, role, "", "" }
 	err := perm.Validate(); if err != nil {
 		return perm, fmt.Errorf("Invalid permission: %v", err)
 	}
@@ -82,9 +183,14 @@ func (p WorkspacePermission) Validate() error {
 	// the constructor method. Sometimes, they will be marshalled from JSON.
 	// In that case, we'll need to parse them first and then validate it
 	
-	// Checks if the role is valid
-	if p.Role != "viewer" && p.Role != "editor" && p.Role != "owner" {
-		return fmt.Errorf("Invalid role %s. Valid roles are \"viewer\", \"editor\" and  \"owner\"", p.Role)
+	err := validateRole(p.Role)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = ParsePrincipal(p.Principal)
+	if err != nil {
+		return err
 	}
 
 	// NOTE: I thought about addind a validation to check if user principal is existing BUT
@@ -92,3 +198,72 @@ func (p WorkspacePermission) Validate() error {
 
 	return nil
 }
+
+func validateRole(role string) error {
+	if role != "viewer" && role != "editor" && role != "owner" {
+		return fmt.Errorf("Invalid role %s. Valid roles are \"viewer\", \"editor\" and  \"owner\"", role)
+	}
+	return nil
+}
+
+// Whether both lists grant the same roles to the same principals, in any order
+func SamePermissions(a []WorkspacePermission, b []WorkspacePermission) bool {
+
+	count := map[WorkspacePermission]int{}
+	for _, perm := range a {
+		count[perm]++
+	}
+	for _, perm := range b {
+		count[perm]--
+	}
+	for _, n := range count {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a copy of the workspace granting the permission, in place of any other role of its principal.
+// Principals already granted a higher role keep it, so e.g. an old invitation doesn't demote them
+func (w Workspace) WithPermission(permission WorkspacePermission) Workspace {
+
+	permissions := []WorkspacePermission{}
+	for _, perm := range w.Permissions {
+		if perm.Principal != permission.Principal {
+			permissions = append(permissions, perm)
+		} else if roleRanks[perm.Role] > roleRanks[permission.Role] {
+			permission = perm
+		}
+	}
+	w.Permissions = append(permissions, permission)
+
+	return w
+}
+
+// Whether the permission is keyed on the email of a user, as stored before principal IDs
+func (p WorkspacePermission) Legacy() bool {
+	return p.Principal == "" && p.Email != ""
+}
+
+// Converts the legacy permissions to the principal resolved from their email, which is forgotten.
+// Permissions whose email doesn't resolve are dropped, and their emails returned
+func MigratePermissions(perms []WorkspacePermission, resolve func(email string) (string, bool)) ([]WorkspacePermission, []string) {
+
+	migrated := []WorkspacePermission{}
+	dropped := []string{}
+
+	for _, perm := range perms {
+		if perm.Legacy() {
+			principal, ok := resolve(perm.Email)
+			if !ok {
+				dropped = append(dropped, perm.Email)
+				continue
+			}
+			perm = WorkspacePermission{ principal, perm.Role, "", "" }
+		}
+		migrated = append(migrated, perm)
+	}
+
+	return migrated, dropped
+}
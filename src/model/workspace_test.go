@@ -0,0 +1,185 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWorkspacePermission(t *testing.T) {
+
+	valid := []WorkspacePermission{
+		{ "user:ana", "owner", "", "" },
+		{ "user:ana", "viewer", "ana@example.com", "" },
+	}
+	for idx, perm := range valid {
+		if err := perm.Validate(); err != nil {
+			t.Errorf("Expected permission at index %d to be valid, got %v", idx, err)
+		}
+	}
+
+	invalid := []WorkspacePermission{
+		{ "user:ana", "admin", "", "" },
+		{ "", "viewer", "ana@example.com", "" },
+		{ "ana@example.com", "viewer", "", "" },
+		{ "user:", "viewer", "", "" },
+		{ "robot:ana", "viewer", "", "" },
+	}
+	for idx, perm := range invalid {
+		if err := perm.Validate(); err == nil {
+			t.Errorf("Expected error for permission at index %d, got nil", idx)
+		}
+	}
+
+	// Emails never grant access, only principal IDs do
+	workspace := Workspace{ Permissions: []WorkspacePermission{ { "user:ana", "owner", "ana@example.com", "" }, { "", "owner", "bob@example.com", "" } } }
+	if !workspace.EditableBy("user:ana") || workspace.ViewableBy("ana@example.com") || workspace.ViewableBy("bob@example.com") || workspace.ViewableBy("") {
+		t.Errorf("Expected access only through principal IDs")
+	}
+	if (User{ Email: "ana@example.com" }).Principal() != "" || (User{ ID: "ana" }).Principal() != "user:ana" {
+		t.Errorf("Expected principals only for users with an identity")
+	}
+}
+
+func TestWorkspaceAccess(t *testing.T) {
+
+	workspace := Workspace{ ID: "workspace", Permissions: []WorkspacePermission{
+		{ "user:ana", "viewer", "", "" },
+		{ "group:analysts", "editor", "", "" },
+		{ "group:admins", "owner", "", "" },
+	} }
+
+	// The highest role among the grants of the user and their groups gives the access
+	access := workspace.Access("user:ana", []string{ "group:analysts" })
+	if access.Role != "editor" || access.Grant == nil || access.Grant.Principal != "group:analysts" {
+		t.Errorf("Expected access through the group, got %+v", access)
+	}
+	if !workspace.ContentEditableBy("user:ana", "group:analysts") || workspace.EditableBy("user:ana", "group:analysts") {
+		t.Errorf("Expected editor access through the group")
+	}
+	if !workspace.EditableBy("user:bob", "group:admins") || workspace.ContentEditableBy("user:ana") {
+		t.Errorf("Unexpected access")
+	}
+
+	access = workspace.Access("user:eve", []string{ "group:other" })
+	if access.Role != "" || access.Grant != nil {
+		t.Errorf("Expected no access, got %+v", access)
+	}
+	if workspace.ViewableBy() || workspace.ViewableBy("", "group:") {
+		t.Errorf("Expected no access without principals")
+	}
+
+	// Organization roles cascade into the workspaces of the organization, Super Admins own every workspace
+	workspace.OrganizationID = "agency"
+	access = workspace.Access("user:eve", []string{ OrganizationPrincipal("agency", "admin") })
+	if access.Role != "owner" || access.Grant == nil || access.Grant.Principal != "org:agency#admin" {
+		t.Errorf("Expected ownership through the organization, got %+v", access)
+	}
+	if workspace.Access("user:eve", []string{ OrganizationPrincipal("agency", "member") }).Role != "viewer" {
+		t.Errorf("Expected organization members to view the workspace")
+	}
+	if workspace.ViewableBy("user:eve", OrganizationPrincipal("agency", "billing"), OrganizationPrincipal("agency", "guest"), OrganizationPrincipal("other", "admin")) {
+		t.Errorf("Expected no access for billing members, guests and other organizations")
+	}
+	if access = workspace.Access("user:ana", []string{ OrganizationPrincipal("agency", "member") }); access.Role != "viewer" {
+		t.Errorf("Expected the highest role, got %+v", access)
+	}
+	if !workspace.EditableBy("user:eve", SuperAdminPrincipal) {
+		t.Errorf("Expected Super Admins to own every workspace")
+	}
+}
+
+func TestImplicitPermissions(t *testing.T) {
+
+	// Outside organizations, only Super Admins are implied
+	standalone := Workspace{ ID: "standalone" }
+	if permissions := standalone.ImplicitPermissions(); len(permissions) != 1 || permissions[0].Principal != SuperAdminPrincipal || permissions[0].Role != "owner" {
+		t.Errorf("Unexpected implicit permissions %+v", permissions)
+	}
+	if standalone.ViewableBy(OrganizationPrincipal("agency", "admin")) {
+		t.Errorf("Expected organization roles not to reach workspaces outside the organization")
+	}
+
+	workspace := Workspace{ ID: "workspace", OrganizationID: "agency", Permissions: []WorkspacePermission{ { "user:ana", "editor", "", "" } } }
+	expected := []WorkspacePermission{
+		{ SuperAdminPrincipal, "owner", "", "" },
+		{ "org:agency#admin", "owner", "", "" },
+		{ "org:agency#member", "viewer", "", "" },
+	}
+	if permissions := workspace.ImplicitPermissions(); !reflect.DeepEqual(permissions, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, permissions)
+	}
+	if len(workspace.Permissions) != 1 {
+		t.Errorf("Expected implicit permissions not to be stored with the workspace")
+	}
+
+	// Explicit grants raise the cascaded role, never lower it
+	if workspace.Access("user:ana", []string{ OrganizationPrincipal("agency", "member") }).Role != "editor" {
+		t.Errorf("Expected the explicit editor grant to win over the member role")
+	}
+	if workspace.Access("user:ana", []string{ OrganizationPrincipal("agency", "admin") }).Role != "owner" {
+		t.Errorf("Expected the admin role to win over the explicit editor grant")
+	}
+
+	// Roles of an organization don't cascade into the workspaces of another one
+	other := Workspace{ ID: "other", OrganizationID: "rival" }
+	if other.ViewableBy("user:ana", OrganizationPrincipal("agency", "admin"), OrganizationPrincipal("agency", "member")) {
+		t.Errorf("Expected no access to the workspaces of another organization")
+	}
+}
+
+func TestMigratePermissions(t *testing.T) {
+
+	users := map[string]string{ "ana@example.com": "user:ana" }
+	resolve := func(email string) (string, bool) {
+		principal, ok := users[email]
+		return principal, ok
+	}
+
+	legacy := []WorkspacePermission{
+		{ "", "owner", "ana@example.com", "" },
+		{ "", "viewer", "gone@example.com", "" },
+		{ "user:bob", "editor", "", "" },
+	}
+	migrated, dropped := MigratePermissions(legacy, resolve)
+
+	expected := []WorkspacePermission{ { "user:ana", "owner", "", "" }, { "user:bob", "editor", "", "" } }
+	if !reflect.DeepEqual(migrated, expected) || !reflect.DeepEqual(dropped, []string{ "gone@example.com" }) {
+		t.Errorf("Unexpected migration %+v, dropped %v", migrated, dropped)
+	}
+
+	again, dropped := MigratePermissions(migrated, resolve)
+	if !SamePermissions(again, migrated) || len(dropped) != 0 {
+		t.Errorf("Expected migrated permissions to be left alone, got %+v", again)
+	}
+}
+
+func TestWorkspaceTrash(t *testing.T) {
+
+	now := time.Now()
+	workspace := Workspace{ ID: "workspace", Transfer: &OwnershipTransfer{ From: "user:ana", To: "user:bob" } }
+
+	trashed, err := workspace.Trash(now)
+	if err != nil || trashed.DeletedAt == nil || trashed.Transfer != nil {
+		t.Fatalf("Expected workspace in the trash without its transfer, got %+v, %v", trashed, err)
+	}
+	if _, err := trashed.Trash(now); err == nil {
+		t.Errorf("Expected error trashing a trashed workspace, got nil")
+	}
+
+	retention := 24 * time.Hour
+	if trashed.Purgeable(now.Add(retention - time.Minute), retention) || !trashed.Purgeable(now.Add(retention), retention) || workspace.Purgeable(now.Add(retention), retention) {
+		t.Errorf("Expected trashed workspaces to be purgeable once the retention is over")
+	}
+
+	restored, err := trashed.Restore(now.Add(time.Hour), retention)
+	if err != nil || restored.DeletedAt != nil {
+		t.Errorf("Expected workspace to be restored, got %+v, %v", restored, err)
+	}
+	if _, err := trashed.Restore(now.Add(retention), retention); err == nil {
+		t.Errorf("Expected error restoring after the retention, got nil")
+	}
+	if _, err := workspace.Restore(now, retention); err == nil {
+		t.Errorf("Expected error restoring a workspace out of the trash, got nil")
+	}
+}
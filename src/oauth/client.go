@@ -0,0 +1,99 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"smartgrowth-connectors/configapi/model"
+	"strings"
+	"time"
+)
+
+// Talks to the token endpoints of OAuth2 providers
+type Client struct {
+	HTTP *http.Client
+}
+
+func NewClient(timeout time.Duration) *Client {
+	return &Client{ &http.Client{ Timeout: timeout } }
+}
+
+// Exchanges an authorization code for a token, proving the code verifier of the session
+// https://www.rfc-editor.org/rfc/rfc7636#section-4.5
+func (c *Client) Exchange(provider model.OAuthProvider, client model.OAuthClient, code string, verifier string, redirectURI string) (model.OAuthToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", verifier)
+	return c.requestToken(provider, client, form)
+}
+
+// https://www.rfc-editor.org/rfc/rfc6749#section-6
+func (c *Client) Refresh(provider model.OAuthProvider, client model.OAuthClient, refreshToken string) (model.OAuthToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return c.requestToken(provider, client, form)
+}
+
+// Error response of a provider
+// https://www.rfc-editor.org/rfc/rfc6749#section-5.2
+type tokenError struct {
+	Error string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (c *Client) requestToken(provider model.OAuthProvider, client model.OAuthClient, form url.Values) (model.OAuthToken, error) {
+
+	var token model.OAuthToken
+
+	// Client credentials are sent in the body, as most providers accept them there
+	form.Set("client_id", client.ID)
+	form.Set("client_secret", client.Secret)
+
+	request, err := http.NewRequest(http.MethodPost, provider.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token, fmt.Errorf("Error creating token request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return token, fmt.Errorf("Error requesting token: %v", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1 << 20))
+	if err != nil {
+		return token, fmt.Errorf("Error reading token response: %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var providerError tokenError
+		if json.Unmarshal(body, &providerError) == nil && providerError.Error != "" {
+			if providerError.Description != "" {
+				return token, fmt.Errorf("Provider refused the token request: %s (%s)", providerError.Error, providerError.Description)
+			}
+			return token, fmt.Errorf("Provider refused the token request: %s", providerError.Error)
+		}
+		return token, fmt.Errorf("Provider refused the token request with status %d", response.StatusCode)
+	}
+
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return token, fmt.Errorf("Invalid token response: %v", err)
+	}
+	if token.AccessToken == "" {
+		return token, fmt.Errorf("Token response has no access token")
+	}
+
+	return token, nil
+}
@@ -0,0 +1,157 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"smartgrowth-connectors/configapi/model"
+	"sync"
+	"time"
+)
+
+// Minimal OAuth2 provider for local development and tests. Authorizations are approved
+// without user interaction, PKCE (S256) is required and refresh tokens rotate on use.
+//   - GET /authorize redirects back to the redirect URI with a code
+//   - POST /token exchanges codes and refresh tokens
+type FakeProvider struct {
+	Client model.OAuthClient
+	TokenTTL time.Duration
+
+	lock sync.Mutex
+	issued int
+	codes map[string]fakeGrant
+	refreshTokens map[string]bool
+}
+
+type fakeGrant struct {
+	redirectURI string
+	challenge string
+}
+
+func NewFakeProvider(client model.OAuthClient, tokenTTL time.Duration) *FakeProvider {
+	return &FakeProvider{
+		Client: client,
+		TokenTTL: tokenTTL,
+		codes: map[string]fakeGrant{},
+		refreshTokens: map[string]bool{},
+	}
+}
+
+// Provider configuration pointing to the fake served at baseURL
+func (f *FakeProvider) Provider(baseURL string, accessTokenField string, refreshTokenField string, expiresAtField string) model.OAuthProvider {
+	return model.OAuthProvider{
+		AuthURL: baseURL + "/authorize",
+		TokenURL: baseURL + "/token",
+		Scopes: []string{"read"},
+		ClientCredentials: "FAKE",
+		AccessTokenField: accessTokenField,
+		RefreshTokenField: refreshTokenField,
+		ExpiresAtField: expiresAtField,
+	}
+}
+
+func (f *FakeProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/authorize":
+		f.authorize(w, r)
+	case "/token":
+		f.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *FakeProvider) authorize(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("client_id") != f.Client.ID {
+		http.Error(w, "invalid client or response type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	f.lock.Lock()
+	f.issued++
+	code := fmt.Sprintf("fake-code-%d", f.issued)
+	f.codes[code] = fakeGrant{ redirectURI.String(), query.Get("code_challenge") }
+	f.lock.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (f *FakeProvider) token(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		fakeTokenError(w, "invalid_request")
+		return
+	}
+	if r.PostForm.Get("client_id") != f.Client.ID || r.PostForm.Get("client_secret") != f.Client.Secret {
+		fakeTokenError(w, "invalid_client")
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		grant, ok := f.codes[code]
+		// Codes are single use
+		delete(f.codes, code)
+		if !ok || grant.redirectURI != r.PostForm.Get("redirect_uri") {
+			fakeTokenError(w, "invalid_grant")
+			return
+		}
+		if model.CodeChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+			fakeTokenError(w, "invalid_grant")
+			return
+		}
+	case "refresh_token":
+		token := r.PostForm.Get("refresh_token")
+		if !f.refreshTokens[token] {
+			fakeTokenError(w, "invalid_grant")
+			return
+		}
+		delete(f.refreshTokens, token)
+	default:
+		fakeTokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	f.issued++
+	token := model.OAuthToken{
+		AccessToken: fmt.Sprintf("fake-access-%d", f.issued),
+		TokenType: "Bearer",
+		RefreshToken: fmt.Sprintf("fake-refresh-%d", f.issued),
+		ExpiresIn: int64(f.TokenTTL / time.Second),
+	}
+	f.refreshTokens[token.RefreshToken] = true
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+func fakeTokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(tokenError{ Error: code })
+}
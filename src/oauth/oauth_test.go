@@ -0,0 +1,102 @@
+package oauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"smartgrowth-connectors/configapi/model"
+	"testing"
+	"time"
+)
+
+const redirectURI = "https://api.example.com/oauth/callback"
+
+// Follows the authorization URL on the fake provider and returns the code and state of the callback
+func authorize(t *testing.T, authorizationURL string) (string, string) {
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	response, err := client.Get(authorizationURL)
+	if err != nil {
+		t.Fatalf("Expected authorization to succeed, got %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("Expected a redirect, got status %d", response.StatusCode)
+	}
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect %v", err)
+	}
+	if location.Scheme + "://" + location.Host + location.Path != redirectURI {
+		t.Fatalf("Expected redirect to %s, got %s", redirectURI, location)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+
+	credentials := model.OAuthClient{ ID: "client", Secret: "secret" }
+	fake := NewFakeProvider(credentials, time.Hour)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	provider := fake.Provider(server.URL, "access_token", "refresh_token", "token_expires_at")
+	client := &Client{ server.Client() }
+
+	session, err := model.NewOAuthSession("workspace", "integration", "user", time.Now())
+	if err != nil {
+		t.Fatalf("Expected session, got %v", err)
+	}
+	authorizationURL, err := provider.AuthorizationURL(credentials, redirectURI, session)
+	if err != nil {
+		t.Fatalf("Expected authorization URL, got %v", err)
+	}
+
+	code, state := authorize(t, authorizationURL)
+	if state != session.State {
+		t.Errorf("Expected state %s to be sent back, got %s", session.State, state)
+	}
+
+	// The code is bound to the verifier of the session
+	_, err = client.Exchange(provider, credentials, code, "wrong-verifier", redirectURI)
+	if err == nil {
+		t.Fatalf("Expected error exchanging with another verifier, got nil")
+	}
+	// Failed exchanges consume the code too
+	_, err = client.Exchange(provider, credentials, code, session.CodeVerifier, redirectURI)
+	if err == nil {
+		t.Fatalf("Expected error exchanging a used code, got nil")
+	}
+
+	code, _ = authorize(t, authorizationURL)
+	_, err = client.Exchange(provider, model.OAuthClient{ ID: "client", Secret: "other" }, code, session.CodeVerifier, redirectURI)
+	if err == nil {
+		t.Fatalf("Expected error exchanging with other client credentials, got nil")
+	}
+
+	code, _ = authorize(t, authorizationURL)
+	token, err := client.Exchange(provider, credentials, code, session.CodeVerifier, redirectURI)
+	if err != nil {
+		t.Fatalf("Expected exchange to succeed, got %v", err)
+	}
+	if token.AccessToken == "" || token.RefreshToken == "" || token.ExpiresIn != 3600 {
+		t.Fatalf("Unexpected token %+v", token)
+	}
+
+	refreshed, err := client.Refresh(provider, credentials, token.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected refresh to succeed, got %v", err)
+	}
+	if refreshed.AccessToken == token.AccessToken || refreshed.RefreshToken == token.RefreshToken {
+		t.Errorf("Expected new tokens, got %+v", refreshed)
+	}
+
+	// Refresh tokens rotate
+	_, err = client.Refresh(provider, credentials, token.RefreshToken)
+	if err == nil {
+		t.Errorf("Expected error refreshing with a used refresh token, got nil")
+	}
+}
@@ -0,0 +1,210 @@
+package scripts
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"smartgrowth-connectors/configapi/model"
+	"smartgrowth-connectors/configapi/oauth"
+	"time"
+)
+
+const usage = `Usage: configapi <command> [arguments]
+
+Commands:
+  import-airbyte -type <source|destination> [-name <name>] <spec.json>
+        Converts an Airbyte connector spec into an integration definition
+        and prints it as JSON. Warnings are printed to stderr.
+
+  infer-singer -type <source|destination> -name <name> <config.json>
+        Infers an integration definition from a sample Singer config
+        and prints it as JSON. Warnings are printed to stderr.
+
+  singer-config -url <api url> -workspace <id> -integration <id> [-o <file>]
+  singer-catalog -url <api url> -workspace <id> -integration <id> [-o <file>]
+        Fetches the Singer config or catalog of an integration from the API and
+        writes it to the file, or to stdout. The bearer token is read from CONFIGAPI_TOKEN.
+
+  fake-oauth [-addr <host:port>] [-client-id <id>] [-client-secret <secret>] [-ttl <duration>]
+        Serves a fake OAuth2 provider for local development. Authorizations are
+        approved without user interaction.`
+
+// Runs a command line subcommand. args excludes the program name.
+func RunCommand(args []string) error {
+
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "import-airbyte":
+		return importAirbyte(args[1:])
+	case "infer-singer":
+		return inferSinger(args[1:])
+	case "singer-config":
+		return singerFile("config", args[1:])
+	case "singer-catalog":
+		return singerFile("catalog", args[1:])
+	case "fake-oauth":
+		return fakeOAuth(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+
+	return fmt.Errorf("Unknown command %s\n\n%s", args[0], usage)
+}
+
+func importAirbyte(args []string) error {
+
+	flags := flag.NewFlagSet("import-airbyte", flag.ContinueOnError)
+	t := flags.String("type", "", "Definition type, source or destination")
+	name := flags.String("name", "", "Definition name. Defaults to the title of the spec")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("Expected a single spec file")
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("Error reading spec file: %v", err)
+	}
+
+	spec, err := model.ParseAirbyteSpec(data)
+	if err != nil {
+		return err
+	}
+
+	definition, warnings, err := spec.Definition(*name, *t)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if err != nil {
+		return fmt.Errorf("Error creating integration definition: %v", err)
+	}
+
+	return printJSON(definition)
+}
+
+func inferSinger(args []string) error {
+
+	flags := flag.NewFlagSet("infer-singer", flag.ContinueOnError)
+	t := flags.String("type", "", "Definition type, source or destination")
+	name := flags.String("name", "", "Definition name")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("Expected a single config file")
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("Error reading config file: %v", err)
+	}
+
+	var sample map[string]interface{}
+	err = json.Unmarshal(data, &sample)
+	if err != nil {
+		return fmt.Errorf("Invalid config file: %v", err)
+	}
+
+	schema, warnings := model.InferSingerSchema(sample)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	definition, err := model.NewIntegrationDefinition(*name, *t, schema)
+	if err != nil {
+		return fmt.Errorf("Error creating integration definition: %v", err)
+	}
+
+	return printJSON(definition)
+}
+
+// Fetches the Singer config or catalog of an integration
+func singerFile(kind string, args []string) error {
+
+	flags := flag.NewFlagSet("singer-" + kind, flag.ContinueOnError)
+	apiURL := flags.String("url", "http://localhost:8080", "URL of the API")
+	workspaceID := flags.String("workspace", "", "Workspace ID")
+	integrationID := flags.String("integration", "", "Integration ID")
+	output := flags.String("o", "", "Output file. Defaults to stdout")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *workspaceID == "" || *integrationID == "" {
+		return errors.New("Workspace and integration are required")
+	}
+
+	path := fmt.Sprintf("/workspaces/%s/integrations/%s/singer/%s", url.PathEscape(*workspaceID), url.PathEscape(*integrationID), kind)
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(*apiURL, "/") + path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer " + os.Getenv("CONFIGAPI_TOKEN"))
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("Error fetching Singer %s: %v", kind, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading response: %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error fetching Singer %s: %s: %s", kind, response.Status, body)
+	}
+
+	if *output == "" {
+		fmt.Println(string(body))
+		return nil
+	}
+
+	// The config may hold secrets
+	err = os.WriteFile(*output, body, 0600)
+	if err != nil {
+		return fmt.Errorf("Error writing %s file: %v", kind, err)
+	}
+
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	output, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
+
+func fakeOAuth(args []string) error {
+
+	flags := flag.NewFlagSet("fake-oauth", flag.ContinueOnError)
+	addr := flags.String("addr", "localhost:9999", "Address to listen on")
+	clientID := flags.String("client-id", "fake-client", "Client ID accepted by the provider")
+	clientSecret := flags.String("client-secret", "fake-secret", "Client secret accepted by the provider")
+	ttl := flags.Duration("ttl", time.Hour, "Lifetime of the access tokens")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	provider := oauth.NewFakeProvider(model.OAuthClient{ ID: *clientID, Secret: *clientSecret }, *ttl)
+	fmt.Fprintf(os.Stderr, "Fake OAuth provider listening on http://%s (authorize: /authorize, token: /token)\n", *addr)
+	return http.ListenAndServe(*addr, provider)
+}
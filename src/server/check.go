@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Dry run of a configuration that is not saved yet
+type CheckConfigurationRequest struct {
+	DefinitionID string `json:"definition_id"`
+	Configuration model.IntegrationConfig `json:"configuration"`
+}
+
+func CheckIntegration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	result, err := ctr.CheckIntegration(workspaceID, id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error checking integration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+	return
+}
+
+func CheckConfiguration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CheckConfigurationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	result, err := ctr.CheckConfiguration(workspaceID, request.DefinitionID, request.Configuration)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error checking configuration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+	return
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateConnectionRequest struct {
+	Name string `json:"name"`
+	SourceID string `json:"source_id"`
+	DestinationID string `json:"destination_id"`
+	Schedule model.Schedule `json:"schedule"`
+	Namespace model.NamespaceMapping `json:"namespace"`
+	Enabled *bool `json:"enabled"` // Defaults to true
+}
+
+type UpdateConnectionRequest struct {
+	Name string `json:"name"`
+	Schedule model.Schedule `json:"schedule"`
+	Namespace model.NamespaceMapping `json:"namespace"`
+	Enabled bool `json:"enabled"`
+}
+
+func CreateConnection(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateConnectionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	enabled := true
+	if request.Enabled != nil {
+		enabled = *request.Enabled
+	}
+
+	workspaceID := c.Param("id")
+	connection, err := ctr.CreateConnection(workspaceID, request.Name, request.SourceID, request.DestinationID, request.Schedule, request.Namespace, enabled)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating connection: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, connection)
+	return
+}
+
+func ListConnections(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	connections, err := ctr.ListConnections(workspaceID)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing connections: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, connections)
+	return
+}
+
+func GetConnection(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("cid")
+	connection, err := ctr.ReadConnection(workspaceID, id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading connection: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, connection)
+	return
+}
+
+func UpdateConnection(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request UpdateConnectionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("cid")
+	connection, err := ctr.UpdateConnection(workspaceID, id, request.Name, request.Schedule, request.Namespace, request.Enabled)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating connection: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, connection)
+	return
+}
+
+func DeleteConnection(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("cid")
+	connection, err := ctr.DeleteConnection(workspaceID, id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting connection: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, connection)
+	return
+}
@@ -0,0 +1,312 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateIntegrationDefinitionRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	ConfigurationSchema model.ConfigurationSchema `json:"configuration_schema"`
+	JSONSchema model.JSONSchema `json:"json_schema"` // Alternative to configuration_schema
+	Streams []model.Stream `json:"streams"`
+	OAuth *model.OAuthProvider `json:"oauth"`
+	Check *model.CheckHook `json:"check"`
+	Scope model.DefinitionScope `json:"scope"` // Defaults to public
+}
+
+type CreateIntegrationDefinitionVersionRequest struct {
+	Version string `json:"version"`
+	ConfigurationSchema model.ConfigurationSchema `json:"configuration_schema"`
+	JSONSchema model.JSONSchema `json:"json_schema"` // Alternative to configuration_schema
+	Migrations []model.MigrationStep `json:"migrations"`
+	Streams []model.Stream `json:"streams"` // Defaults to the catalog of the latest version
+	OAuth *model.OAuthProvider `json:"oauth"` // Defaults to the provider of the latest version
+	Check *model.CheckHook `json:"check"` // Defaults to the hook of the latest version
+}
+
+// The schema can be sent either in the native format or as JSON Schema, but not both
+func requestSchema(schema model.ConfigurationSchema, jsonSchema model.JSONSchema) (model.ConfigurationSchema, error) {
+
+	if jsonSchema == nil {
+		return schema, nil
+	}
+	if schema != nil {
+		return schema, errors.New("Only one of configuration_schema and json_schema can be set")
+	}
+
+	schema, err := model.FromJSONSchema(jsonSchema)
+	if err != nil {
+		return schema, fmt.Errorf("Invalid JSON Schema: %v", err)
+	}
+
+	return schema, nil
+}
+
+func CreateIntegrationDefinition(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateIntegrationDefinitionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	schema, err := requestSchema(request.ConfigurationSchema, request.JSONSchema)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	definition, err := ctr.CreateIntegrationDefinition(request.Name, request.Type, schema, request.Streams, request.OAuth, request.Check, request.Scope)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating integration definition: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+	return
+}
+
+type ImportAirbyteDefinitionRequest struct {
+	Name string `json:"name"` // Defaults to the title of the spec
+	Type string `json:"type"`
+	Spec json.RawMessage `json:"spec"` // SPEC message or bare spec written by the connector
+	Scope model.DefinitionScope `json:"scope"` // Defaults to public
+}
+
+// Definitions imported from other formats come with the parts that couldn't be mapped
+type ImportDefinitionResponse struct {
+	Definition model.IntegrationDefinition `json:"definition"`
+	Warnings []string `json:"warnings"`
+}
+
+func ImportAirbyteDefinition(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request ImportAirbyteDefinitionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	definition, warnings, err := ctr.ImportAirbyteDefinition(request.Name, request.Type, request.Spec, request.Scope)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error importing Airbyte spec: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, ImportDefinitionResponse{definition, warnings})
+	return
+}
+
+func ListIntegrationDefinitions(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	// With workspace_id, only the definitions the workspace can use are listed
+	definitions, err := ctr.ListIntegrationDefinitions(c.Query("workspace_id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing integration definitions: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, definitions)
+	return
+}
+
+func GetIntegrationDefinition(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	// Without a version, the latest one is returned
+	id := c.Param("id")
+	version := c.Param("version")
+	definition, err := ctr.ReadIntegrationDefinition(id, version)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading integration definition: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+	return
+}
+
+func GetIntegrationDefinitionJSONSchema(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	// Without a version, the schema of the latest one is returned
+	id := c.Param("id")
+	version := c.Param("version")
+	definition, err := ctr.ReadIntegrationDefinition(id, version)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading integration definition: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, definition.JSONSchema())
+	return
+}
+
+func ListIntegrationDefinitionVersions(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	id := c.Param("id")
+	versions, err := ctr.ListIntegrationDefinitionVersions(id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing integration definition versions: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+	return
+}
+
+func CreateIntegrationDefinitionVersion(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateIntegrationDefinitionVersionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	schema, err := requestSchema(request.ConfigurationSchema, request.JSONSchema)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	id := c.Param("id")
+	definition, err := ctr.CreateIntegrationDefinitionVersion(id, request.Version, schema, request.Migrations, request.Streams, request.OAuth, request.Check)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating integration definition version: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+	return
+}
+
+func UpgradeIntegrations(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	// With dry_run=true, the report is computed but nothing is saved
+	id := c.Param("id")
+	version := c.Param("version")
+	dryRun := c.DefaultQuery("dry_run", "false") == "true"
+
+	report, err := ctr.UpgradeIntegrations(id, version, dryRun)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error upgrading integrations: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+	return
+}
+
+type SetIntegrationDefinitionStatusRequest struct {
+	Status string `json:"status"`
+}
+
+func SetIntegrationDefinitionStatus(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request SetIntegrationDefinitionStatusRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	id := c.Param("id")
+	version := c.Param("version")
+	definition, err := ctr.SetIntegrationDefinitionStatus(id, version, request.Status)
+	if err != nil {
+		message := fmt.Sprintf("Error changing integration definition status: %v", err)
+		if inUseResponse(c, message, err) {
+			return
+		}
+		errorResponse(c, http.StatusBadRequest, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+	return
+}
+
+func DeleteIntegrationDefinition(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	id := c.Param("id")
+	deleted, err := ctr.DeleteIntegrationDefinition(id)
+	if err != nil {
+		message := fmt.Sprintf("Error deleting integration definition: %v", err)
+		if inUseResponse(c, message, err) {
+			return
+		}
+		errorResponse(c, http.StatusBadRequest, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, deleted)
+	return
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateEnvironmentRequest struct {
+	Name string `json:"name" binding:"required"`
+	Permissions []model.WorkspacePermission `json:"permissions"` // Empty to follow the workspace
+}
+
+type SetEnvironmentPermissionsRequest struct {
+	Permissions []model.WorkspacePermission `json:"permissions"`
+}
+
+type SetEnvironmentConfigurationRequest struct {
+	Configuration model.IntegrationConfig `json:"configuration"`
+}
+
+type PromoteIntegrationRequest struct {
+	From string `json:"from" binding:"required"`
+	To string `json:"to" binding:"required"`
+	DryRun bool `json:"dry_run"` // Only returns the changes
+}
+
+func CreateEnvironment(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateEnvironmentRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	environment, err := ctr.CreateEnvironment(c.Param("id"), request.Name, request.Permissions)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating environment: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func ListEnvironments(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	environments, err := ctr.ListEnvironments(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing environments: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environments)
+	return
+}
+
+func GetEnvironment(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	environment, err := ctr.ReadEnvironment(c.Param("id"), c.Param("env"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading environment: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func SetEnvironmentPermissions(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request SetEnvironmentPermissionsRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	environment, err := ctr.SetEnvironmentPermissions(c.Param("id"), c.Param("env"), request.Permissions)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error setting environment permissions: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func DeleteEnvironment(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	environment, err := ctr.DeleteEnvironment(c.Param("id"), c.Param("env"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting environment: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func SetEnvironmentConfiguration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request SetEnvironmentConfigurationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	environment, err := ctr.SetEnvironmentConfiguration(c.Param("id"), c.Param("env"), c.Param("iid"), request.Configuration)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error setting environment configuration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func ResetEnvironmentConfiguration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	environment, err := ctr.ResetEnvironmentConfiguration(c.Param("id"), c.Param("env"), c.Param("iid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error resetting environment configuration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func SetEnvironmentVariable(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request SetWorkspaceVariableRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	environment, err := ctr.SetEnvironmentVariable(c.Param("id"), c.Param("env"), c.Param("name"), request.Value, request.Secret)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error setting variable: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func DeleteEnvironmentVariable(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	environment, err := ctr.DeleteEnvironmentVariable(c.Param("id"), c.Param("env"), c.Param("name"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting variable: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, environment)
+	return
+}
+
+func PromoteIntegration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request PromoteIntegrationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	promotion, err := ctr.PromoteIntegration(c.Param("id"), c.Param("iid"), request.From, request.To, !request.DryRun)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error promoting integration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+	return
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Comments are sent in between events so proxies don't close idle streams
+const keepAliveInterval = 15 * time.Second
+
+// Streams the events of the workspace as Server-Sent Events. Clients reconnecting with the
+// Last-Event-ID header, or the last_event_id query parameter, receive the events they missed.
+// A "reset" event tells them the missed events are lost and they should reload the workspace.
+// A "closed" event ends the stream when the server drops the subscription, e.g. when access is revoked
+// https://html.spec.whatwg.org/multipage/server-sent-events.html
+func StreamWorkspaceEvents(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	subscription, err := ctr.SubscribeWorkspaceEvents(c.Param("id"), lastEventID)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error subscribing to workspace events: %v", err))
+		return
+	}
+	defer subscription.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if subscription.Reset {
+		fmt.Fprint(c.Writer, "event: reset\ndata: {}\n\n")
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case message, ok := <-subscription.C:
+			if !ok {
+				data, _ := json.Marshal(map[string]string{ "reason": subscription.Reason() })
+				fmt.Fprintf(c.Writer, "event: closed\ndata: %s\n\n", data)
+				c.Writer.Flush()
+				return
+			}
+			data, err := json.Marshal(message.Event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", message.ID, message.Event.Type, data)
+			c.Writer.Flush()
+		}
+	}
+}
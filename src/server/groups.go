@@ -0,0 +1,167 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateGroupRequest struct {
+	OrganizationID string `json:"organization_id"` // Empty for a group outside organizations
+	Name string `json:"name" binding:"required"`
+	Members []model.GroupMember `json:"members"` // Users given by principal or by email. The creator is added as a manager
+}
+
+type RenameGroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func CreateGroup(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateGroupRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	group, err := ctr.CreateGroup(request.OrganizationID, request.Name, request.Members)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating group: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+	return
+}
+
+func ListGroups(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	groups, err := ctr.ListGroups()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing groups: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+	return
+}
+
+func GetGroup(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	group, err := ctr.ReadGroup(c.Param("gid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading group: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+	return
+}
+
+func RenameGroup(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request RenameGroupRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	group, err := ctr.RenameGroup(c.Param("gid"), request.Name)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating group: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+	return
+}
+
+func DeleteGroup(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	group, err := ctr.DeleteGroup(c.Param("gid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting group: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+	return
+}
+
+// Adds a member, given by principal or by email, or changes their role
+func SetGroupMember(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var member model.GroupMember
+	err = c.ShouldBindJSON(&member)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	group, err := ctr.SetGroupMember(c.Param("gid"), member)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error setting group member: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+	return
+}
+
+func RemoveGroupMember(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	group, err := ctr.RemoveGroupMember(c.Param("gid"), c.Param("principal"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error removing group member: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+	return
+}
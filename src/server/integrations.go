@@ -0,0 +1,167 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateIntegrationRequest struct {
+	Name string `json:"name"`
+	DefinitionID string `json:"definition_id"`
+	Configuration model.IntegrationConfig `json:"configuration"`
+}
+
+type UpdateIntegrationRequest struct {
+	Name string `json:"name"`
+	Configuration model.IntegrationConfig `json:"configuration"`
+}
+
+type UpgradeIntegrationRequest struct {
+	Version string `json:"version"` // Empty for the latest version
+}
+
+func CreateIntegration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateIntegrationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	integration, err := ctr.CreateIntegration(workspaceID, request.Name, request.DefinitionID, request.Configuration)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating integration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+	return
+}
+
+func ListIntegrations(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	integrations, err := ctr.ListIntegrations(workspaceID)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing integrations: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, integrations)
+	return
+}
+
+func GetIntegration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	integration, err := ctr.ReadIntegration(workspaceID, id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading integration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+	return
+}
+
+func UpdateIntegration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request UpdateIntegrationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	integration, err := ctr.UpdateIntegration(workspaceID, id, request.Name, request.Configuration)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating integration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+	return
+}
+
+func DeleteIntegration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	integration, err := ctr.DeleteIntegration(workspaceID, id)
+	if err != nil {
+		message := fmt.Sprintf("Error deleting integration: %v", err)
+		if inUseResponse(c, message, err) {
+			return
+		}
+		errorResponse(c, http.StatusBadRequest, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+	return
+}
+
+func UpgradeIntegration(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request UpgradeIntegrationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	integration, err := ctr.UpgradeIntegration(workspaceID, id, request.Version)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error upgrading integration: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+	return
+}
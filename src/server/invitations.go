@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func CreateInvitation(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateInvitationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	invitation, err := ctr.CreateInvitation(c.Param("id"), request.Email, request.Role)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating invitation: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+	return
+}
+
+func ListInvitations(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	invitations, err := ctr.ListInvitations(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing invitations: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+	return
+}
+
+func RevokeInvitation(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	invitation, err := ctr.RevokeInvitation(c.Param("id"), c.Param("inv"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error revoking invitation: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+	return
+}
+
+func AcceptInvitation(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request AcceptInvitationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspace, err := ctr.AcceptInvitation(request.Token)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error accepting invitation: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+	return
+}
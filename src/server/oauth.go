@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StartOAuthRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+	IntegrationID string `json:"integration_id"`
+}
+
+type StartOAuthResponse struct {
+	AuthorizationURL string `json:"authorization_url"` // Where the user should be sent to
+}
+
+type OAuthCallbackResponse struct {
+	Status string `json:"status"`
+	WorkspaceID string `json:"workspace_id"`
+	IntegrationID string `json:"integration_id"`
+}
+
+func StartOAuth(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request StartOAuthRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	authorizationURL, err := ctr.StartOAuth(request.WorkspaceID, request.IntegrationID)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error starting OAuth authorization: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, StartOAuthResponse{ authorizationURL })
+	return
+}
+
+// Called by the browser of the user, redirected by the provider. It carries no bearer token
+func OAuthCallback(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	integration, err := ctr.CompleteOAuth(c.Query("state"), c.Query("code"), c.Query("error"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error completing OAuth authorization: %v", err))
+		return
+	}
+
+	// The integration itself is not returned, its configuration holds the new tokens
+	c.JSON(http.StatusOK, OAuthCallbackResponse{ "authorized", integration.WorkspaceID, integration.ID })
+	return
+}
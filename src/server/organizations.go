@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+	Members []model.OrganizationMember `json:"members"` // Users given by principal or by email. At least one of them must be an admin
+}
+
+type RenameOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func CreateOrganization(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateOrganizationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	organization, err := ctr.CreateOrganization(request.Name, request.Members)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating organization: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+	return
+}
+
+func ListOrganizations(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	organizations, err := ctr.ListOrganizations()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing organizations: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organizations)
+	return
+}
+
+func GetOrganization(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	organization, err := ctr.ReadOrganization(c.Param("oid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading organization: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+	return
+}
+
+func RenameOrganization(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request RenameOrganizationRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	organization, err := ctr.RenameOrganization(c.Param("oid"), request.Name)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating organization: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+	return
+}
+
+func DeleteOrganization(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	organization, err := ctr.DeleteOrganization(c.Param("oid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting organization: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+	return
+}
+
+// Adds a member, given by principal or by email, or changes their role
+func SetOrganizationMember(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var member model.OrganizationMember
+	err = c.ShouldBindJSON(&member)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	organization, err := ctr.SetOrganizationMember(c.Param("oid"), member)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error setting organization member: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+	return
+}
+
+func RemoveOrganizationMember(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	organization, err := ctr.RemoveOrganizationMember(c.Param("oid"), c.Param("principal"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error removing organization member: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+	return
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportRunRequest struct {
+	IntegrationID string `json:"integration_id"` // Either an integration or a connection
+	ConnectionID string `json:"connection_id"`
+	model.RunReport
+}
+
+func ReportRun(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request ReportRunRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	run, err := ctr.ReportRun(workspaceID, request.IntegrationID, request.ConnectionID, request.RunReport)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reporting run: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+	return
+}
+
+func UpdateRun(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request model.RunReport
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("rid")
+	run, err := ctr.UpdateRun(workspaceID, id, request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating run: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+	return
+}
+
+// Filters: ?integration_id=&connection_id=&status=&since=&until=&limit=100
+func ListRuns(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 32)
+	if err != nil || limit <= 0 {
+		errorResponse(c, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	since, err := queryTime(c, "since", time.Time{})
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	until, err := queryTime(c, "until", time.Time{})
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := model.RunFilter{
+		IntegrationID: c.Query("integration_id"),
+		ConnectionID: c.Query("connection_id"),
+		Status: c.Query("status"),
+		Since: since,
+		Until: until,
+		Limit: int(limit),
+	}
+
+	workspaceID := c.Param("id")
+	runs, err := ctr.ListRuns(workspaceID, filter)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing runs: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+	return
+}
+
+func GetRun(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("rid")
+	run, err := ctr.ReadRun(workspaceID, id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading run: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+	return
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Environment: ?environment=prod. Without it, the configuration of the workspace
+func GetRuntimeConfig(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	config, err := ctr.ResolveRuntimeConfig(workspaceID, id, c.Query("environment"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error resolving runtime config: %v", err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, config)
+	return
+}
+
+func GetRuntimeConfigBundle(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	bundle, err := ctr.RuntimeConfigBundle(workspaceID, id, c.Query("environment"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating runtime config bundle: %v", err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, bundle)
+	return
+}
+
+// Pagination: ?offset=0&limit=100
+func ListAuditEntries(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	if err != nil || offset < 0 {
+		errorResponse(c, http.StatusBadRequest, "Invalid offset")
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 32)
+	if err != nil || limit <= 0 {
+		errorResponse(c, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	workspaceID := c.Param("id")
+	entries, err := ctr.ListAuditEntries(workspaceID, int(offset), int(limit))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing audit entries: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+	return
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reads an optional RFC 3339 time from the query
+func queryTime(c *gin.Context, key string, fallback time.Time) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("Invalid %s, expected an RFC 3339 time", key)
+	}
+	return t, nil
+}
+
+func GetConnectionNextRuns(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	count, err := strconv.ParseInt(c.DefaultQuery("count", "5"), 10, 32)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid count")
+		return
+	}
+	after, err := queryTime(c, "after", time.Now())
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	runs, err := ctr.NextConnectionRuns(c.Param("id"), c.Param("cid"), after, int(count))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading next runs: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+	return
+}
+
+// Connections due within [from, to). Defaults to the current minute
+func ListDueConnections(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	from, err := queryTime(c, "from", time.Now())
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	to, err := queryTime(c, "to", from.Add(time.Minute))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	due, err := ctr.DueConnections(from, to)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing due connections: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, due)
+	return
+}
@@ -4,11 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"smartgrowth-connectors/configapi/controller"
 	"smartgrowth-connectors/configapi/middleware"
+	"smartgrowth-connectors/configapi/model"
 )
 
 type Server struct {
@@ -24,6 +26,10 @@ func NewServer(controller *controller.Controller, authDomain string, identifier
 	}
 
 
+	// Providers redirect users to the OAuth callback without a bearer token.
+	// It is registered before the authentication middleware, the state authenticates it
+	server.router.GET("/oauth/callback", server.setRootController, OAuthCallback)
+
 	// Add authentication middleware
 	authMiddleware := middleware.EnsureValidToken(authDomain, identifier)
 	server.router.Use(authMiddleware)
@@ -36,6 +42,130 @@ func NewServer(controller *controller.Controller, authDomain string, identifier
 	server.router.PUT("/users/:id", UpdateUser)
 	server.router.DELETE("/users/:id", DeleteUser)
 
+	server.router.POST("/workspaces", CreateWorkspace)
+	server.router.GET("/workspaces", ListWorkspaces)
+	server.router.GET("/workspaces/trash", ListTrashedWorkspaces)
+	server.router.GET("/workspaces/transfers", ListWorkspaceTransfers)
+	server.router.GET("/workspaces/:id", GetWorkspace)
+	server.router.PUT("/workspaces/:id", UpdateWorkspace)
+	server.router.DELETE("/workspaces/:id", DeleteWorkspace)
+	server.router.POST("/workspaces/:id/restore", RestoreWorkspace)
+	server.router.POST("/workspaces/:id/clone", CloneWorkspace)
+	server.router.POST("/workspaces/:id/transfer", TransferWorkspaceOwnership)
+	server.router.POST("/workspaces/:id/transfer/accept", AcceptWorkspaceTransfer)
+	server.router.DELETE("/workspaces/:id/transfer", CancelWorkspaceTransfer)
+	server.router.GET("/workspaces/:id/audit", ListAuditEntries)
+	server.router.GET("/workspaces/:id/access", GetWorkspaceAccess)
+	server.router.GET("/workspaces/:id/events", StreamWorkspaceEvents)
+	server.router.POST("/workspaces/:id/invitations", CreateInvitation)
+	server.router.GET("/workspaces/:id/invitations", ListInvitations)
+	server.router.DELETE("/workspaces/:id/invitations/:inv", RevokeInvitation)
+	server.router.GET("/workspaces/:id/variables", ListWorkspaceVariables)
+	server.router.PUT("/workspaces/:id/variables/:name", SetWorkspaceVariable)
+	server.router.DELETE("/workspaces/:id/variables/:name", DeleteWorkspaceVariable)
+	server.router.POST("/workspaces/:id/environments", CreateEnvironment)
+	server.router.GET("/workspaces/:id/environments", ListEnvironments)
+	server.router.GET("/workspaces/:id/environments/:env", GetEnvironment)
+	server.router.DELETE("/workspaces/:id/environments/:env", DeleteEnvironment)
+	server.router.PUT("/workspaces/:id/environments/:env/permissions", SetEnvironmentPermissions)
+	server.router.PUT("/workspaces/:id/environments/:env/integrations/:iid", SetEnvironmentConfiguration)
+	server.router.DELETE("/workspaces/:id/environments/:env/integrations/:iid", ResetEnvironmentConfiguration)
+	server.router.PUT("/workspaces/:id/environments/:env/variables/:name", SetEnvironmentVariable)
+	server.router.DELETE("/workspaces/:id/environments/:env/variables/:name", DeleteEnvironmentVariable)
+	server.router.POST("/invitations/accept", AcceptInvitation)
+
+	server.router.POST("/groups", CreateGroup)
+	server.router.GET("/groups", ListGroups)
+	server.router.GET("/groups/:gid", GetGroup)
+	server.router.PUT("/groups/:gid", RenameGroup)
+	server.router.DELETE("/groups/:gid", DeleteGroup)
+	server.router.PUT("/groups/:gid/members", SetGroupMember)
+	server.router.DELETE("/groups/:gid/members/:principal", RemoveGroupMember)
+
+	server.router.POST("/organizations", CreateOrganization)
+	server.router.GET("/organizations", ListOrganizations)
+	server.router.GET("/organizations/:oid", GetOrganization)
+	server.router.PUT("/organizations/:oid", RenameOrganization)
+	server.router.DELETE("/organizations/:oid", DeleteOrganization)
+	server.router.PUT("/organizations/:oid/members", SetOrganizationMember)
+	server.router.DELETE("/organizations/:oid/members/:principal", RemoveOrganizationMember)
+
+	server.router.POST("/templates", CreateWorkspaceTemplate)
+	server.router.GET("/templates", ListWorkspaceTemplates)
+	server.router.GET("/templates/:tid", GetWorkspaceTemplate)
+	server.router.PUT("/templates/:tid", UpdateWorkspaceTemplate)
+	server.router.DELETE("/templates/:tid", DeleteWorkspaceTemplate)
+	server.router.POST("/templates/:tid/instantiate", InstantiateWorkspaceTemplate)
+
+	server.router.POST("/workspaces/:id/webhooks", CreateWebhookSubscription)
+	server.router.GET("/workspaces/:id/webhooks", ListWebhookSubscriptions)
+	server.router.GET("/workspaces/:id/webhooks/:wid", GetWebhookSubscription)
+	server.router.PUT("/workspaces/:id/webhooks/:wid", UpdateWebhookSubscription)
+	server.router.DELETE("/workspaces/:id/webhooks/:wid", DeleteWebhookSubscription)
+	server.router.GET("/workspaces/:id/webhooks/:wid/deliveries", ListWebhookDeliveries)
+	server.router.POST("/workspaces/:id/webhooks/:wid/deliveries/:did/replay", ReplayWebhookDelivery)
+
+	server.router.POST("/workspaces/:id/integrations", CreateIntegration)
+	server.router.GET("/workspaces/:id/integrations", ListIntegrations)
+	server.router.GET("/workspaces/:id/integrations/:iid", GetIntegration)
+	server.router.PUT("/workspaces/:id/integrations/:iid", UpdateIntegration)
+	server.router.DELETE("/workspaces/:id/integrations/:iid", DeleteIntegration)
+	server.router.POST("/workspaces/:id/integrations/:iid/upgrade", UpgradeIntegration)
+	server.router.POST("/workspaces/:id/integrations/:iid/check", CheckIntegration)
+	server.router.POST("/workspaces/:id/integrations/:iid/promote", PromoteIntegration)
+	server.router.POST("/workspaces/:id/integrations/check", CheckConfiguration)
+	server.router.GET("/workspaces/:id/integrations/:iid/streams", ListStreamSelection)
+	server.router.PUT("/workspaces/:id/integrations/:iid/streams", SetStreamSelection)
+	server.router.PUT("/workspaces/:id/integrations/:iid/streams/:stream", SelectStream)
+	server.router.DELETE("/workspaces/:id/integrations/:iid/streams/:stream", UnselectStream)
+	server.router.GET("/workspaces/:id/integrations/:iid/singer/config", GetSingerConfig)
+	server.router.GET("/workspaces/:id/integrations/:iid/singer/catalog", GetSingerCatalog)
+	server.router.GET("/workspaces/:id/integrations/:iid/state", GetConnectorState)
+	server.router.PUT("/workspaces/:id/integrations/:iid/state", PutConnectorState)
+	server.router.PUT("/workspaces/:id/integrations/:iid/state/streams/:stream", PutStreamState)
+	server.router.POST("/workspaces/:id/integrations/:iid/state/reset", ResetConnectorState)
+	server.router.GET("/workspaces/:id/integrations/:iid/state/resets", ListStateResets)
+	server.router.GET("/workspaces/:id/integrations/:iid/runtime/config", GetRuntimeConfig)
+	server.router.GET("/workspaces/:id/integrations/:iid/runtime/bundle", GetRuntimeConfigBundle)
+
+	server.router.POST("/workspaces/:id/connections", CreateConnection)
+	server.router.GET("/workspaces/:id/connections", ListConnections)
+	server.router.GET("/workspaces/:id/connections/:cid", GetConnection)
+	server.router.PUT("/workspaces/:id/connections/:cid", UpdateConnection)
+	server.router.DELETE("/workspaces/:id/connections/:cid", DeleteConnection)
+	server.router.GET("/workspaces/:id/connections/:cid/next_runs", GetConnectionNextRuns)
+
+	server.router.POST("/workspaces/:id/runs", ReportRun)
+	server.router.GET("/workspaces/:id/runs", ListRuns)
+	server.router.GET("/workspaces/:id/runs/:rid", GetRun)
+	server.router.PUT("/workspaces/:id/runs/:rid", UpdateRun)
+
+	server.router.GET("/schedules/due", ListDueConnections)
+
+	server.router.POST("/oauth/start", StartOAuth)
+
+	server.router.POST("/webhooks", CreateWebhookSubscription)
+	server.router.GET("/webhooks", ListWebhookSubscriptions)
+	server.router.GET("/webhooks/:wid", GetWebhookSubscription)
+	server.router.PUT("/webhooks/:wid", UpdateWebhookSubscription)
+	server.router.DELETE("/webhooks/:wid", DeleteWebhookSubscription)
+	server.router.GET("/webhooks/:wid/deliveries", ListWebhookDeliveries)
+	server.router.POST("/webhooks/:wid/deliveries/:did/replay", ReplayWebhookDelivery)
+
+	server.router.POST("/definitions", CreateIntegrationDefinition)
+	server.router.GET("/definitions", ListIntegrationDefinitions)
+	server.router.POST("/definitions/import/airbyte", ImportAirbyteDefinition)
+	server.router.POST("/definitions/import/singer", ImportSingerDefinition)
+	server.router.GET("/definitions/:id", GetIntegrationDefinition)
+	server.router.GET("/definitions/:id/versions", ListIntegrationDefinitionVersions)
+	server.router.POST("/definitions/:id/versions", CreateIntegrationDefinitionVersion)
+	server.router.GET("/definitions/:id/jsonschema", GetIntegrationDefinitionJSONSchema)
+	server.router.GET("/definitions/:id/versions/:version", GetIntegrationDefinition)
+	server.router.GET("/definitions/:id/versions/:version/jsonschema", GetIntegrationDefinitionJSONSchema)
+	server.router.POST("/definitions/:id/versions/:version/upgrade", UpgradeIntegrations)
+	server.router.PUT("/definitions/:id/versions/:version/status", SetIntegrationDefinitionStatus)
+	server.router.DELETE("/definitions/:id", DeleteIntegrationDefinition)
+
 
 	return server, nil
 } 
@@ -51,10 +181,18 @@ func (s *Server) setUser(c *gin.Context) {
 		return
 	}
 
+	userController.Scopes = strings.Fields(c.GetString("scope"))
+
 	c.Set("ctr", userController)
 	c.Next()
 }
 
+// Unauthenticated requests act through the controller of the server, which has no user
+func (s *Server) setRootController(c *gin.Context) {
+	c.Set("ctr", s.controller)
+	c.Next()
+}
+
 func getController(c *gin.Context) (*controller.Controller, error) {
 
 	ctr, ok := c.Get("ctr")
@@ -86,3 +224,35 @@ func errorResponse(c *gin.Context, status int, message string) {
 	response := apiError{ message }
 	c.IndentedJSON(status, response) 
 }
+
+type apiInUseError struct {
+	Error string `json:"error"`
+	Dependents []model.Dependent `json:"dependents"`
+}
+
+// Refusals caused by dependent resources are reported along with the dependents.
+// Returns false if the error is of any other kind.
+func inUseResponse(c *gin.Context, message string, err error) bool {
+	var inUse *model.InUseError
+	if !errors.As(err, &inUse) {
+		return false
+	}
+	c.JSON(http.StatusConflict, apiInUseError{ message, inUse.Dependents })
+	return true
+}
+
+type apiStateConflictError struct {
+	Error string `json:"error"`
+	CurrentVersion int64 `json:"current_version"`
+}
+
+// Writes based on an outdated state are reported along with the current version.
+// Returns false if the error is of any other kind.
+func stateConflictResponse(c *gin.Context, message string, err error) bool {
+	var conflict *model.StateConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	c.JSON(http.StatusConflict, apiStateConflictError{ message, conflict.Current })
+	return true
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Returns the config.json of a Singer tap or target, in the environment given as query parameter
+func GetSingerConfig(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	config, err := ctr.ExportSingerConfig(workspaceID, id, c.Query("environment"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error exporting Singer config: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+	return
+}
+
+// Returns the catalog.json of a Singer tap
+func GetSingerCatalog(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	catalog, err := ctr.ExportSingerCatalog(c.Param("id"), c.Param("iid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error exporting Singer catalog: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, catalog)
+	return
+}
+
+type ImportSingerDefinitionRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Config map[string]interface{} `json:"config"` // Sample config.json
+	Scope model.DefinitionScope `json:"scope"` // Defaults to public
+}
+
+func ImportSingerDefinition(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request ImportSingerDefinitionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	definition, warnings, err := ctr.ImportSingerDefinition(request.Name, request.Type, request.Config, request.Scope)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error importing Singer config: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, ImportDefinitionResponse{definition, warnings})
+	return
+}
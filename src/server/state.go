@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PutConnectorStateRequest struct {
+	Global map[string]interface{} `json:"global"`
+	Streams map[string]map[string]interface{} `json:"streams"`
+	Version *int64 `json:"version"` // Version the new state is based on
+}
+
+type PutStreamStateRequest struct {
+	State map[string]interface{} `json:"state"`
+	Version *int64 `json:"version"` // Version of the whole state the new stream state is based on
+}
+
+type ResetConnectorStateRequest struct {
+	Streams []string `json:"streams"` // Empty resets the whole state
+}
+
+func GetConnectorState(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	state, err := ctr.ReadConnectorState(workspaceID, id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading connector state: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+	return
+}
+
+func PutConnectorState(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request PutConnectorStateRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if request.Version == nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid request: version is required")
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	state, err := ctr.WriteConnectorState(workspaceID, id, request.Global, request.Streams, *request.Version)
+	if err != nil {
+		message := fmt.Sprintf("Error writing connector state: %v", err)
+		if stateConflictResponse(c, message, err) {
+			return
+		}
+		errorResponse(c, http.StatusBadRequest, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+	return
+}
+
+func PutStreamState(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request PutStreamStateRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if request.Version == nil {
+		errorResponse(c, http.StatusBadRequest, "Invalid request: version is required")
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	stream := c.Param("stream")
+	state, err := ctr.WriteStreamState(workspaceID, id, stream, request.State, *request.Version)
+	if err != nil {
+		message := fmt.Sprintf("Error writing stream state: %v", err)
+		if stateConflictResponse(c, message, err) {
+			return
+		}
+		errorResponse(c, http.StatusBadRequest, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+	return
+}
+
+func ResetConnectorState(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request ResetConnectorStateRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	reset, err := ctr.ResetConnectorState(workspaceID, id, request.Streams)
+	if err != nil {
+		message := fmt.Sprintf("Error resetting connector state: %v", err)
+		if stateConflictResponse(c, message, err) {
+			return
+		}
+		errorResponse(c, http.StatusBadRequest, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, reset)
+	return
+}
+
+func ListStateResets(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaceID := c.Param("id")
+	id := c.Param("iid")
+	resets, err := ctr.ListStateResets(workspaceID, id)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing state resets: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, resets)
+	return
+}
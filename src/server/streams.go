@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListStreamSelection(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	selection, err := ctr.ListStreamSelection(c.Param("id"), c.Param("iid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading stream selection: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, selection)
+	return
+}
+
+func SetStreamSelection(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request []model.StreamSelection
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	selection, err := ctr.SetStreamSelection(c.Param("id"), c.Param("iid"), request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating stream selection: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, selection)
+	return
+}
+
+type SelectStreamRequest struct {
+	SyncMode string `json:"sync_mode"`
+	CursorField string `json:"cursor_field"`
+	PrimaryKey []string `json:"primary_key"`
+	Fields []string `json:"fields"`
+}
+
+func SelectStream(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request SelectStreamRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	stream := model.StreamSelection{
+		Stream: c.Param("stream"),
+		SyncMode: request.SyncMode,
+		CursorField: request.CursorField,
+		PrimaryKey: request.PrimaryKey,
+		Fields: request.Fields,
+	}
+	selection, err := ctr.SelectStream(c.Param("id"), c.Param("iid"), stream)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error selecting stream: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, selection)
+	return
+}
+
+func UnselectStream(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	selection, err := ctr.UnselectStream(c.Param("id"), c.Param("iid"), c.Param("stream"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error unselecting stream: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, selection)
+	return
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CloneWorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type WorkspaceTemplateRequest struct {
+	OrganizationID string `json:"organization_id"` // Empty for a template outside organizations. Ignored on updates
+	Name string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Parameters []model.TemplateParameter `json:"parameters"`
+	Integrations []model.TemplateIntegration `json:"integrations"`
+	Connections []model.TemplateConnection `json:"connections"`
+}
+
+type InstantiateWorkspaceTemplateRequest struct {
+	Name string `json:"name" binding:"required"` // Name of the new workspace
+	Values map[string]interface{} `json:"values"` // Value of every parameter of the template
+}
+
+func CloneWorkspace(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CloneWorkspaceRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspace, err := ctr.CloneWorkspace(c.Param("id"), request.Name)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error cloning workspace: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+	return
+}
+
+func CreateWorkspaceTemplate(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request WorkspaceTemplateRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	template, err := ctr.CreateWorkspaceTemplate(request.OrganizationID, request.Name, request.Description, request.Parameters, request.Integrations, request.Connections)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating template: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+	return
+}
+
+func ListWorkspaceTemplates(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	templates, err := ctr.ListWorkspaceTemplates()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing templates: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+	return
+}
+
+func GetWorkspaceTemplate(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	template, err := ctr.ReadWorkspaceTemplate(c.Param("tid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading template: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+	return
+}
+
+func UpdateWorkspaceTemplate(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request WorkspaceTemplateRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	template, err := ctr.UpdateWorkspaceTemplate(c.Param("tid"), request.Name, request.Description, request.Parameters, request.Integrations, request.Connections)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating template: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+	return
+}
+
+func DeleteWorkspaceTemplate(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	template, err := ctr.DeleteWorkspaceTemplate(c.Param("tid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting template: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+	return
+}
+
+func InstantiateWorkspaceTemplate(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request InstantiateWorkspaceTemplateRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspace, err := ctr.InstantiateWorkspaceTemplate(c.Param("tid"), request.Name, request.Values)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error instantiating template: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+	return
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// New owner, given by principal or by email
+type TransferWorkspaceOwnershipRequest struct {
+	Principal string `json:"principal"`
+	Email string `json:"user"`
+}
+
+func TransferWorkspaceOwnership(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request TransferWorkspaceOwnershipRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	workspace, err := ctr.TransferWorkspaceOwnership(c.Param("id"), request.Principal, request.Email)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error transferring workspace ownership: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+	return
+}
+
+// Workspaces whose ownership is offered to the user
+func ListWorkspaceTransfers(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaces, err := ctr.ListWorkspaceTransfers()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing ownership transfers: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+	return
+}
+
+func AcceptWorkspaceTransfer(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspace, err := ctr.AcceptWorkspaceTransfer(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error accepting ownership transfer: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+	return
+}
+
+// Declined by the new owner, or cancelled by an owner
+func CancelWorkspaceTransfer(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspace, err := ctr.CancelWorkspaceTransfer(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error cancelling ownership transfer: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+	return
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SetWorkspaceVariableRequest struct {
+	Value string `json:"value"`
+	Secret bool `json:"secret"`
+}
+
+func ListWorkspaceVariables(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	variables, err := ctr.ListWorkspaceVariables(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing variables: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, variables)
+	return
+}
+
+func SetWorkspaceVariable(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request SetWorkspaceVariableRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	variable, err := ctr.SetWorkspaceVariable(c.Param("id"), c.Param("name"), request.Value, request.Secret)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error setting variable: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, variable)
+	return
+}
+
+func DeleteWorkspaceVariable(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	variable, err := ctr.DeleteWorkspaceVariable(c.Param("id"), c.Param("name"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting variable: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, variable)
+	return
+}
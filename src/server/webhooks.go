@@ -0,0 +1,174 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers serve both the subscriptions of a workspace, under /workspaces/:id/webhooks,
+// and the global ones, under /webhooks, where the workspace ID is empty
+
+type CreateWebhookSubscriptionRequest struct {
+	URL string `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+}
+
+type UpdateWebhookSubscriptionRequest struct {
+	URL string `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	Active *bool `json:"active" binding:"required"`
+}
+
+func CreateWebhookSubscription(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request CreateWebhookSubscriptionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	subscription, err := ctr.CreateWebhookSubscription(c.Param("id"), request.URL, request.Events)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error creating webhook subscription: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+	return
+}
+
+func ListWebhookSubscriptions(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	subscriptions, err := ctr.ListWebhookSubscriptions(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing webhook subscriptions: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+	return
+}
+
+func GetWebhookSubscription(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	subscription, err := ctr.ReadWebhookSubscription(c.Param("id"), c.Param("wid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading webhook subscription: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+	return
+}
+
+func UpdateWebhookSubscription(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	var request UpdateWebhookSubscriptionRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	subscription, err := ctr.UpdateWebhookSubscription(c.Param("id"), c.Param("wid"), request.URL, request.Events, *request.Active)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error updating webhook subscription: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+	return
+}
+
+func DeleteWebhookSubscription(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	subscription, err := ctr.DeleteWebhookSubscription(c.Param("id"), c.Param("wid"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error deleting webhook subscription: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+	return
+}
+
+func ListWebhookDeliveries(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	if err != nil || offset < 0 {
+		errorResponse(c, http.StatusBadRequest, "Invalid offset")
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 32)
+	if err != nil || limit <= 0 {
+		errorResponse(c, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	deliveries, err := ctr.ListWebhookDeliveries(c.Param("id"), c.Param("wid"), int(offset), int(limit))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing webhook deliveries: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+	return
+}
+
+func ReplayWebhookDelivery(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	delivery, err := ctr.ReplayWebhookDelivery(c.Param("id"), c.Param("wid"), c.Param("did"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error replaying webhook delivery: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+	return
+}
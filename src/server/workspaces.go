@@ -10,6 +10,7 @@ import (
 )
 
 type CreateWorkspaceRequest struct {
+	OrganizationID string `json:"organization_id"` // Empty for a workspace outside organizations
 	Name string `json:"name"`
 	Permissions []model.WorkspacePermission `json:"permissions"`
 }
@@ -39,7 +40,7 @@ func CreateWorkspace(c *gin.Context) {
 		return
 	}
 
-	createdWorkspace, err := ctr.CreateWorkspace(request.Name, request.Permissions)
+	createdWorkspace, err := ctr.CreateWorkspace(request.OrganizationID, request.Name, request.Permissions)
 	if err != nil {
 		message := fmt.Sprintf("Error creating workspace: %v", err)
 		response := apiError{ message }
@@ -151,3 +152,59 @@ func DeleteWorkspace(c *gin.Context) {
 	c.JSON(http.StatusOK, workspace)
 	return
 }
+
+// Access of the user, or of the user given by the principal query parameter, and the grant it comes from
+func GetWorkspaceAccess(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	access, err := ctr.ReadWorkspaceAccess(c.Param("id"), c.Query("principal"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error reading workspace access: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, access)
+	return
+}
+
+// Deleted workspaces the user owns, until they are purged
+func ListTrashedWorkspaces(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspaces, err := ctr.ListTrashedWorkspaces()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error listing deleted workspaces: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+	return
+}
+
+func RestoreWorkspace(c *gin.Context) {
+	ctr, err := getController(c)
+	if err != nil {
+		error := apiError{"Failure fetching request controller"}
+		c.JSON(http.StatusInternalServerError, error)
+		return
+	}
+
+	workspace, err := ctr.RestoreWorkspace(c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Error restoring workspace: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+	return
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"smartgrowth-connectors/configapi/model"
+	"syscall"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader = "X-Webhook-Event"
+	DeliveryHeader = "X-Webhook-Delivery" // Same for every attempt of a delivery, so receivers can deduplicate
+)
+
+// Posts events to the endpoints of webhook subscriptions
+type Client struct {
+	HTTP *http.Client
+	Insecure bool // Allows http endpoints and private addresses. Only for tests
+}
+
+// The client refuses to connect to private addresses, whatever the host of the endpoint resolves to
+// at delivery time, and doesn't follow redirects
+func NewClient(timeout time.Duration) *Client {
+	dialer := &net.Dialer{ Timeout: timeout, Control: publicAddressOnly }
+	transport := &http.Transport{
+		DialContext: dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns: 100,
+		IdleConnTimeout: 90 * time.Second,
+	}
+	return &Client{
+		HTTP: &http.Client{
+			Transport: transport,
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		Insecure: false,
+	}
+}
+
+// Runs before connecting, with the resolved address
+func publicAddressOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !model.PublicIP(ip) {
+		return errors.New("Endpoint resolves to a private address")
+	}
+	return nil
+}
+
+// Posts the event as JSON, signed with the secret of the subscription. Any 2xx answer is a success.
+// Returns the status code of the answer, 0 when the endpoint couldn't be reached
+func (c *Client) Deliver(subscription model.WebhookSubscription, delivery model.WebhookDelivery, event model.Event, now time.Time) (int, error) {
+
+	err := model.CheckWebhookURL(subscription.URL, c.Insecure)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("Event can't be encoded: %v", err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("Error creating request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, model.SignWebhook(subscription.Secret, body, now))
+	request.Header.Set(EventHeader, event.Type)
+	request.Header.Set(DeliveryHeader, delivery.ID)
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("Error posting event: %v", err)
+	}
+	defer response.Body.Close()
+	// Drained so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1 << 20))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("Endpoint answered with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
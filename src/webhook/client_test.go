@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"smartgrowth-connectors/configapi/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliver(t *testing.T) {
+
+	subscription := model.WebhookSubscription{ ID: "subscription", Secret: "secret", Active: true }
+	delivery := model.WebhookDelivery{ ID: "delivery" }
+	event := model.NewEvent(model.EventIntegrationUpdated, "workspace", "integration", "integration", map[string]string{ "name": "Postgres" }, "user", time.Now())
+
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := model.VerifyWebhookSignature("secret", body, r.Header.Get(SignatureHeader), time.Now(), time.Minute)
+		if err != nil {
+			t.Errorf("Expected valid signature, got %v", err)
+		}
+		if r.Header.Get(EventHeader) != model.EventIntegrationUpdated || r.Header.Get(DeliveryHeader) != "delivery" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		var received model.Event
+		if err := json.Unmarshal(body, &received); err != nil || received.ResourceID != "integration" {
+			t.Errorf("Unexpected body %s", body)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := &Client{ server.Client(), true }
+	subscription.URL = server.URL
+
+	code, err := client.Deliver(subscription, delivery, event, time.Now())
+	if err != nil || code != http.StatusNoContent {
+		t.Errorf("Expected delivery to succeed, got %d, %v", code, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	code, err = client.Deliver(subscription, delivery, event, time.Now())
+	if err == nil || code != http.StatusServiceUnavailable {
+		t.Errorf("Expected error for status 503, got %d, %v", code, err)
+	}
+
+	server.Close()
+	code, err = client.Deliver(subscription, delivery, event, time.Now())
+	if err == nil || code != 0 {
+		t.Errorf("Expected error for unreachable endpoint, got %d, %v", code, err)
+	}
+}
+
+func TestDeliverToPrivateAddress(t *testing.T) {
+
+	subscription := model.WebhookSubscription{ ID: "subscription", Secret: "secret", Active: true }
+	event := model.NewEvent(model.EventIntegrationUpdated, "workspace", "integration", "integration", nil, "user", time.Now())
+
+	reached := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Second)
+	for _, u := range []string{ server.URL, strings.Replace(server.URL, "https", "http", 1) } {
+		subscription.URL = u
+		code, err := client.Deliver(subscription, model.WebhookDelivery{ ID: "delivery" }, event, time.Now())
+		if err == nil || code != 0 {
+			t.Errorf("Expected error for %s, got %d, %v", u, code, err)
+		}
+	}
+	if reached {
+		t.Errorf("Expected the endpoint not to be reached")
+	}
+
+	// Names resolving to private addresses are refused when connecting
+	for _, address := range []string{ "127.0.0.1:443", "10.1.2.3:443", "169.254.169.254:80", "[::1]:443" } {
+		if err := publicAddressOnly("tcp", address, nil); err == nil {
+			t.Errorf("Expected error for %s, got nil", address)
+		}
+	}
+	if err := publicAddressOnly("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("Expected public address to be allowed, got %v", err)
+	}
+}